@@ -7,13 +7,16 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/livebud/cli"
+	"github.com/livebud/duo/internal/check"
 	"github.com/livebud/duo/internal/cli/graceful"
 	"github.com/livebud/duo/internal/cli/hot"
 	"github.com/livebud/duo/internal/cli/pubsub"
+	"github.com/livebud/duo/internal/gogen"
 	"github.com/livebud/duo/internal/static"
 	"github.com/livebud/watcher"
 	"golang.org/x/sync/errgroup"
@@ -39,6 +42,32 @@ func run() error {
 		cli.Run(cmd.Run)
 	}
 
+	{ // generate [flags] [dir]
+		cmd := new(Generate)
+		cli := cli.Command("generate", "compile .svelte files to Go")
+		cli.Flag("package", "package name, defaults to $GOPACKAGE or the directory name").String(&cmd.Package).Default(os.Getenv("GOPACKAGE"))
+		cli.Arg("dir").String(&cmd.Dir).Default(".")
+		cli.Run(cmd.Run)
+	}
+
+	{ // gen [flags] <kind> [dir]
+		cmd := new(Gen)
+		cli := cli.Command("gen", "generate Go code from .svelte files")
+		cli.Flag("package", "package name, defaults to $GOPACKAGE or the directory name").String(&cmd.Package).Default(os.Getenv("GOPACKAGE"))
+		cli.Arg("kind").String(&cmd.Kind)
+		cli.Arg("dir").String(&cmd.Dir).Default(".")
+		cli.Run(cmd.Run)
+	}
+
+	{ // check [flags] [dir]
+		cmd := new(Check)
+		cli := cli.Command("check", "check .svelte files for errors")
+		cli.Flag("format", "output format: human, json or github").String(&cmd.Format).Default("human")
+		cli.Flag("disable", "a11y rule to disable, like a11y_missing_attribute").Strings(&cmd.Disable).Default()
+		cli.Arg("dir").String(&cmd.Dir).Default(".")
+		cli.Run(cmd.Run)
+	}
+
 	return cli.Parse(context.Background(), os.Args[1:]...)
 }
 
@@ -68,9 +97,13 @@ func (s *Serve) Run(ctx context.Context) error {
 	defer ln.Close()
 	url := formatAddr(host, port)
 	fmt.Println("Listening on", url)
-	eg.Go(s.serve(ctx, ln, ps))
+	server := static.Dir(s.Dir)
+	checker := check.New(os.DirFS(s.Dir))
+	checker.Parse = server.SSR.Cache.Parse
+	s.check(checker.Dir())
+	eg.Go(s.serve(ctx, ln, ps, server))
 	if s.Live {
-		eg.Go(s.watch(ctx, ps))
+		eg.Go(s.watch(ctx, ps, server, checker))
 	}
 	if s.Browser {
 		if err := exec.CommandContext(ctx, "open", url).Run(); err != nil {
@@ -80,9 +113,9 @@ func (s *Serve) Run(ctx context.Context) error {
 	return eg.Wait()
 }
 
-func (s *Serve) serve(ctx context.Context, ln net.Listener, ps pubsub.Subscriber) func() error {
+func (s *Serve) serve(ctx context.Context, ln net.Listener, ps pubsub.Subscriber, server *static.Server) func() error {
 	return func() error {
-		return graceful.Serve(ctx, ln, s.handler(hot.New(ps), static.Dir(s.Dir)))
+		return graceful.Serve(ctx, ln, s.handler(hot.New(ps), server))
 	}
 }
 
@@ -125,12 +158,21 @@ func (s *Serve) handler(live http.Handler, fs http.Handler) http.Handler {
 	})
 }
 
-func (s *Serve) watch(ctx context.Context, ps pubsub.Publisher) func() error {
+func (s *Serve) watch(ctx context.Context, ps pubsub.Publisher, server *static.Server, checker *check.Checker) func() error {
 	return func() error {
 		return watcher.Watch(ctx, s.Dir, func(events []watcher.Event) error {
 			if len(events) == 0 {
 				return nil
 			}
+			// Drop the changed files from the document cache before reloading
+			paths := make([]string, len(events))
+			for i, event := range events {
+				paths[i] = filepath.ToSlash(event.Path)
+			}
+			server.Invalidate(paths...)
+			if hasExt(paths, ".svelte") {
+				s.check(checker.Paths(paths...))
+			}
 			event := events[0]
 			ps.Publish(string(event.Op), []byte(event.Path))
 			return nil
@@ -138,6 +180,127 @@ func (s *Serve) watch(ctx context.Context, ps pubsub.Publisher) func() error {
 	}
 }
 
+// check prints the warnings for the components being served. Problems are
+// only reported, they don't stop the server.
+func (s *Serve) check(diagnostics []*check.Diagnostic, err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	for _, diagnostic := range diagnostics {
+		fmt.Fprintln(os.Stderr, diagnostic)
+	}
+}
+
+func hasExt(paths []string, ext string) bool {
+	for _, path := range paths {
+		if filepath.Ext(path) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+type Generate struct {
+	Package string
+	Dir     string
+}
+
+// Run generates a Go file next to each .svelte file. Add
+// `//go:generate duo generate` to a Go file in the directory to run it with
+// `go generate`.
+func (g *Generate) Run(ctx context.Context) error {
+	pkg, err := packageName(g.Package, g.Dir)
+	if err != nil {
+		return err
+	}
+	generator := gogen.New(pkg)
+	generator.Warn = func(diagnostic *check.Diagnostic) {
+		fmt.Fprintln(os.Stderr, diagnostic)
+	}
+	files, err := generator.Generate(os.DirFS(g.Dir))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := os.WriteFile(filepath.Join(g.Dir, file.Path), file.Code, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type Gen struct {
+	Package string
+	Kind    string
+	Dir     string
+}
+
+// Run generates the kind of Go code. `duo gen props` writes props_gen.go
+// with a struct for each component's props and a View with typed render
+// methods. Add `//go:generate duo gen props` to a Go file in the view
+// directory to run it with `go generate`.
+func (g *Gen) Run(ctx context.Context) error {
+	switch g.Kind {
+	case "props":
+		pkg, err := packageName(g.Package, g.Dir)
+		if err != nil {
+			return err
+		}
+		file, err := gogen.New(pkg).Props(os.DirFS(g.Dir))
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(g.Dir, file.Path), file.Code, 0644)
+	default:
+		return fmt.Errorf("duo: unknown kind %q to generate, expected props", g.Kind)
+	}
+}
+
+type Check struct {
+	Format  string
+	Disable []string
+	Dir     string
+}
+
+// Run checks every .svelte file in the directory, exiting with an error if
+// any of them have errors. Use `--format=github` to annotate pull requests.
+func (c *Check) Run(ctx context.Context) error {
+	checker := check.New(os.DirFS(c.Dir))
+	for _, rule := range c.Disable {
+		if _, ok := checker.A11y.Rules[rule]; !ok {
+			return fmt.Errorf("duo: unknown a11y rule %q", rule)
+		}
+		checker.A11y.Rules[rule] = false
+	}
+	diagnostics, err := checker.Dir()
+	if err != nil {
+		return err
+	}
+	for _, diagnostic := range diagnostics {
+		diagnostic.Path = filepath.ToSlash(filepath.Join(c.Dir, diagnostic.Path))
+	}
+	if err := check.Write(os.Stdout, check.Format(c.Format), diagnostics); err != nil {
+		return err
+	}
+	if check.HasErrors(diagnostics) {
+		return fmt.Errorf("duo: check failed")
+	}
+	return nil
+}
+
+// packageName defaults to the directory name
+func packageName(pkg, dir string) (string, error) {
+	if pkg != "" {
+		return pkg, nil
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(filepath.Base(dir), "-", "_"), nil
+}
+
 func isHTML(contentType string) bool {
 	return strings.Contains(contentType, "text/html")
 }
@@ -0,0 +1,308 @@
+// Package dts generates TypeScript declarations from Go types. The server
+// serializes props to JSON for hydration, so the declarations follow
+// encoding/json: json tags rename and omit fields, time.Time is a string and
+// pointers can be null. Components import the declarations in
+// `<script lang="ts">` to type their props.
+package dts
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// New TypeScript declaration generator
+func New() *Generator {
+	return &Generator{
+		names: map[reflect.Type]string{},
+		taken: map[string]bool{},
+	}
+}
+
+// Generator collects Go types and declares them in TypeScript
+type Generator struct {
+	names map[reflect.Type]string // declared types to their names
+	taken map[string]bool
+	decls []string
+}
+
+// Add the type of v as an exported declaration. Structs are declared as
+// interfaces and other types as type aliases. An empty name uses the Go type's
+// name. Named structs within v are declared too.
+func (g *Generator) Add(name string, v interface{}) error {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return fmt.Errorf("dts: unable to declare nil")
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if name == "" {
+		name = identifier(t.Name())
+		if name == "" {
+			return fmt.Errorf("dts: %s needs a name", t)
+		}
+	}
+	if !isIdentifier(name) {
+		return fmt.Errorf("dts: %q isn't a valid TypeScript name", name)
+	}
+	existing, declared := g.names[t]
+	if declared && existing == name {
+		return nil
+	}
+	if g.taken[name] {
+		return fmt.Errorf("dts: %q is already declared", name)
+	}
+	// Alias types that were already declared, like a struct within a struct
+	if declared {
+		g.taken[name] = true
+		g.decls = append(g.decls, fmt.Sprintf("export type %s = %s;\n", name, existing))
+		return nil
+	}
+	g.declare(name, t)
+	return nil
+}
+
+// Generate the declarations
+func (g *Generator) Generate() []byte {
+	out := new(strings.Builder)
+	out.WriteString("// Code generated by duo. DO NOT EDIT.\n")
+	for _, decl := range g.decls {
+		out.WriteString("\n" + decl)
+	}
+	return []byte(out.String())
+}
+
+// declare the type, reserving the name first so recursive types refer to it
+func (g *Generator) declare(name string, t reflect.Type) string {
+	g.names[t] = name
+	g.taken[name] = true
+	i := len(g.decls)
+	g.decls = append(g.decls, "")
+	if t.Kind() == reflect.Struct && !isString(t) && !isUnknown(t) {
+		g.decls[i] = fmt.Sprintf("export interface %s %s\n", name, g.object(t, ""))
+	} else {
+		g.decls[i] = fmt.Sprintf("export type %s = %s;\n", name, g.typeOf(t, ""))
+	}
+	return name
+}
+
+// named returns the name of a named struct, declaring it if needed
+func (g *Generator) named(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	base := identifier(t.Name())
+	name := base
+	if g.taken[name] {
+		// Types from different packages can share a name
+		name = identifier(path.Base(t.PkgPath())) + base
+		for i := 2; g.taken[name]; i++ {
+			name = fmt.Sprintf("%s%d", base, i)
+		}
+	}
+	return g.declare(name, t)
+}
+
+// typeOf returns the TypeScript type of values encoded from t
+func (g *Generator) typeOf(t reflect.Type, indent string) string {
+	if t.Kind() == reflect.Pointer {
+		return g.typeOf(t.Elem(), indent) + " | null"
+	}
+	switch {
+	case isString(t):
+		return "string"
+	case isUnknown(t):
+		return "unknown"
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		return "unknown"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		// Byte slices are base64 strings
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 && !isString(t.Elem()) && !isUnknown(t.Elem()) {
+			return "string"
+		}
+		elem := g.typeOf(t.Elem(), indent)
+		if strings.Contains(elem, "|") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case reflect.Map:
+		return "Record<string, " + g.typeOf(t.Elem(), indent) + ">"
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t, indent)
+		}
+		return g.named(t)
+	default:
+		// Channels, functions and complex numbers can't be encoded
+		return "never"
+	}
+}
+
+// object returns the struct's fields as an object type
+func (g *Generator) object(t reflect.Type, indent string) string {
+	out := new(strings.Builder)
+	out.WriteString("{\n")
+	for _, field := range fields(t) {
+		out.WriteString(indent + "\t" + propertyName(field.name))
+		if field.optional {
+			out.WriteString("?")
+		}
+		typ := g.typeOf(field.typ, indent+"\t")
+		if field.quoted {
+			typ = "string"
+		}
+		out.WriteString(": " + typ + ";\n")
+	}
+	out.WriteString(indent + "}")
+	return out.String()
+}
+
+type field struct {
+	name     string
+	typ      reflect.Type
+	optional bool // omitempty
+	quoted   bool // encoded as a string with the `string` option
+}
+
+// fields returns the fields encoding/json encodes for the struct
+func fields(t reflect.Type) (fields []*field) {
+	for _, f := range reflect.VisibleFields(t) {
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if f.Anonymous {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			// Untagged embedded structs have their fields promoted
+			if name == "" && ft.Kind() == reflect.Struct {
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, &field{
+			name:     name,
+			typ:      f.Type,
+			optional: hasOption(options, "omitempty") || hasOption(options, "omitzero"),
+			quoted:   hasOption(options, "string") && isScalar(f.Type),
+		})
+	}
+	return fields
+}
+
+func hasOption(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// isString returns true for types encoded as strings, like time.Time
+func isString(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+	if implements(t, marshalerType) {
+		return false
+	}
+	return implements(t, textMarshalerType)
+}
+
+// isUnknown returns true for types with their own JSON encoding
+func isUnknown(t reflect.Type) bool {
+	return t != timeType && implements(t, marshalerType)
+}
+
+func implements(t reflect.Type, iface reflect.Type) bool {
+	if t.Kind() == reflect.Interface {
+		return false
+	}
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+// isScalar returns true for types the `string` option applies to
+func isScalar(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// identifier turns a Go type name like "Page[main.Story]" into "PageMainStory"
+func identifier(name string) string {
+	out := new(strings.Builder)
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// propertyName quotes names that aren't valid identifiers
+func propertyName(name string) string {
+	if isIdentifier(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r == '_' || r == '$' || unicode.IsLetter(r) || i > 0 && unicode.IsDigit(r) {
+			continue
+		}
+		return false
+	}
+	return true
+}
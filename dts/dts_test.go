@@ -0,0 +1,117 @@
+package dts_test
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/livebud/duo/dts"
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+)
+
+type Story struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	URL       *string   `json:"url,omitempty"`
+	Points    int64     `json:"points,string"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+	Author    *User     `json:"author"`
+	Comments  []*Comment
+	Meta      map[string]any `json:"meta,omitempty"`
+	Raw       json.RawMessage
+	Data      []byte
+	Link      url.URL `json:"-"`
+	secret    string
+}
+
+type User struct {
+	Name string `json:"name"`
+}
+
+type Comment struct {
+	Base
+	Text    string     `json:"text"`
+	Replies []*Comment `json:"replies"`
+	Edited  *time.Time `json:"edited"`
+	Counts  [3]int     `json:"counts"`
+	Nested  struct {
+		Deep bool `json:"deep"`
+	} `json:"nested"`
+}
+
+type Base struct {
+	ID int `json:"id"`
+}
+
+type Status string
+
+type IndexProps struct {
+	Stories []*Story `json:"stories"`
+	Page    int      `json:"page"`
+	Status  Status   `json:"status"`
+	OnClick func()   `json:"-"`
+}
+
+func TestGenerate(t *testing.T) {
+	is := is.New(t)
+	gen := dts.New()
+	is.NoErr(gen.Add("", IndexProps{}))
+	is.NoErr(gen.Add("", &User{}))
+	is.NoErr(gen.Add("Statuses", []Status{}))
+	diff.TestString(t, string(gen.Generate()), `// Code generated by duo. DO NOT EDIT.
+
+export interface IndexProps {
+	stories: (Story | null)[];
+	page: number;
+	status: string;
+}
+
+export interface Story {
+	id: number;
+	title: string;
+	url?: string | null;
+	points: string;
+	tags: string[];
+	created_at: string;
+	author: User | null;
+	Comments: (Comment | null)[];
+	meta?: Record<string, unknown>;
+	Raw: unknown;
+	Data: string;
+}
+
+export interface User {
+	name: string;
+}
+
+export interface Comment {
+	id: number;
+	text: string;
+	replies: (Comment | null)[];
+	edited: string | null;
+	counts: number[];
+	nested: {
+		deep: boolean;
+	};
+}
+
+export type Statuses = string[];
+`)
+}
+
+func TestErrors(t *testing.T) {
+	is := is.New(t)
+	gen := dts.New()
+	is.Equal(gen.Add("", nil).Error(), "dts: unable to declare nil")
+	is.Equal(gen.Add("", struct{}{}).Error(), "dts: struct {} needs a name")
+	is.Equal(gen.Add("my-props", User{}).Error(), `dts: "my-props" isn't a valid TypeScript name`)
+	is.NoErr(gen.Add("", User{}))
+	is.Equal(gen.Add("User", Story{}).Error(), `dts: "User" is already declared`)
+	// Declaring the same type again is fine and other names are aliases
+	is.NoErr(gen.Add("", User{}))
+	is.NoErr(gen.Add("Author", User{}))
+	diff.TestString(t, string(gen.Generate()), "// Code generated by duo. DO NOT EDIT.\n\nexport interface User {\n\tname: string;\n}\n\nexport type Author = User;\n")
+}
@@ -1,9 +1,11 @@
 package duo
 
 import (
-	"bytes"
+	"context"
+	"fmt"
 	"io/fs"
 	"net/http"
+	"reflect"
 
 	"github.com/livebud/duo/internal/resolver"
 	"github.com/livebud/duo/internal/ssr"
@@ -18,11 +20,55 @@ type View struct {
 }
 
 func (d *View) Render(w http.ResponseWriter, path string, v interface{}) {
-	html := new(bytes.Buffer)
-	if err := d.ssr.Render(html, path, v); err != nil {
+	d.RenderContext(context.Background(), w, path, v)
+}
+
+// RenderContext streams the view to the response, flushing after </head> and
+// around {#await} blocks. Pass the request's context to stop rendering when the
+// client goes away. Each component's CSS is written inline in a <style>
+// element the first time it renders. If rendering fails after the response has started
+// streaming, the connection is aborted so the client doesn't mistake the
+// truncated page for a complete one.
+func (d *View) RenderContext(ctx context.Context, w http.ResponseWriter, path string, v interface{}) {
+	rw := &responseWriter{ResponseWriter: w}
+	if err := d.ssr.RenderContext(ctx, rw, path, v); err != nil {
+		// Once we've started streaming, it's too late to change the status code
+		if rw.wrote {
+			panic(http.ErrAbortHandler)
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write(html.Bytes())
+}
+
+// Check that values like v satisfy the props declared by the component at
+// path. Call it from a test to catch missing or mistyped props before they
+// render as empty.
+func (d *View) Check(path string, v interface{}) error {
+	if v == nil {
+		return fmt.Errorf("duo: unable to check nil props for %s", path)
+	}
+	return d.ssr.Check(path, reflect.TypeOf(v))
+}
+
+// responseWriter tracks whether the response has started streaming
+type responseWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if !w.wrote {
+		w.wrote = true
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
 }
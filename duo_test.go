@@ -0,0 +1,38 @@
+package duo_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/livebud/duo"
+	"github.com/matryer/is"
+)
+
+func TestRenderError(t *testing.T) {
+	is := is.New(t)
+	view := duo.New(fstest.MapFS{
+		"index.svelte": &fstest.MapFile{Data: []byte(`<html><head><title>news</title></head><body>{#await story then s}<h1>{s.title}</h1>{/await}</body></html>`)},
+		"error.svelte": &fstest.MapFile{Data: []byte(`<p>{missing.title}</p>`)},
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		story := func() (interface{}, error) { return nil, nil }
+		view.RenderContext(r.Context(), w, r.URL.Path[1:], map[string]interface{}{"story": story})
+	}))
+	defer server.Close()
+	// Errors before streaming starts are reported with a 500
+	res, err := http.Get(server.URL + "/error.svelte")
+	is.NoErr(err)
+	defer res.Body.Close()
+	is.Equal(res.StatusCode, http.StatusInternalServerError)
+	// Errors after streaming starts abort the response
+	res, err = http.Get(server.URL + "/index.svelte")
+	is.NoErr(err)
+	defer res.Body.Close()
+	is.Equal(res.StatusCode, http.StatusOK)
+	body, err := io.ReadAll(res.Body)
+	is.Equal(err, io.ErrUnexpectedEOF)
+	is.Equal(string(body), `<html><head><title>news</title></head><body>`)
+}
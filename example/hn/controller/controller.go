@@ -23,7 +23,7 @@ func (c *Controller) Index(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	c.view.Render(w, "index.svelte", map[string]any{
+	c.view.RenderContext(r.Context(), w, "index.svelte", map[string]any{
 		"stories": stories,
 	})
 }
@@ -39,7 +39,7 @@ func (c *Controller) Show(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	c.view.Render(w, "show.svelte", map[string]any{
+	c.view.RenderContext(r.Context(), w, "show.svelte", map[string]any{
 		"story": story,
 	})
 }
@@ -0,0 +1,280 @@
+// Package a11y lints components for the accessibility mistakes that Svelte's
+// compiler warns about. Warnings can be suppressed for an element and its
+// children with a preceding `<!-- svelte-ignore a11y_... -->` comment.
+package a11y
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/livebud/duo/internal/ast"
+)
+
+// Rule codes, matching Svelte's warning codes
+const (
+	MissingAttribute         = "a11y_missing_attribute"
+	ClickEventsHaveKeyEvents = "a11y_click_events_have_key_events"
+	UnknownRole              = "a11y_unknown_role"
+	InvalidAttribute         = "a11y_invalid_attribute"
+)
+
+// Rules that are checked by default
+var Rules = []string{
+	MissingAttribute,
+	ClickEventsHaveKeyEvents,
+	UnknownRole,
+	InvalidAttribute,
+}
+
+// New linter with every rule enabled
+func New() *Linter {
+	rules := make(map[string]bool, len(Rules))
+	for _, rule := range Rules {
+		rules[rule] = true
+	}
+	return &Linter{rules}
+}
+
+// Linter checks documents against the enabled rules
+type Linter struct {
+	Rules map[string]bool // Enabled rules
+}
+
+// Warning about an element
+type Warning struct {
+	Pos     ast.Position
+	Code    string
+	Message string
+}
+
+// Lint the document
+func (l *Linter) Lint(doc *ast.Document) []*Warning {
+	w := &walker{linter: l}
+	ast.Walk(&visitor{walker: w}, doc)
+	return w.warnings
+}
+
+type walker struct {
+	linter   *Linter
+	warnings []*Warning
+}
+
+func (w *walker) warn(el *ast.Element, ignored map[string]bool, code, format string, args ...interface{}) {
+	if !w.linter.Rules[code] || ignored[code] {
+		return
+	}
+	w.warnings = append(w.warnings, &Warning{
+		Pos:     el.Pos,
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// visitor lints the fragments at one level of the markup. Codes ignored by a
+// parent are passed down to the children.
+type visitor struct {
+	*walker
+	ignored map[string]bool
+	pending map[string]bool // Codes ignored by the previous comment
+}
+
+func (v *visitor) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case nil:
+		return nil
+	case *ast.Comment:
+		v.pending = ignores(n, v.ignored)
+		return nil
+	case *ast.Text:
+		// Whitespace between the comment and the node
+		if strings.TrimSpace(n.Value) == "" {
+			return nil
+		}
+	}
+	scope := v.ignored
+	if v.pending != nil {
+		scope = v.pending
+	}
+	v.pending = nil
+	if el, ok := node.(*ast.Element); ok {
+		v.element(el, scope)
+	}
+	return &visitor{walker: v.walker, ignored: scope}
+}
+
+// ignores returns the codes ignored by a svelte-ignore comment, along with the
+// codes already ignored. Svelte 4's dashed codes are also accepted.
+func ignores(comment *ast.Comment, ignored map[string]bool) map[string]bool {
+	text := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(comment.Value, "<!--"), "-->"))
+	rest, ok := strings.CutPrefix(text, "svelte-ignore")
+	if !ok {
+		return nil
+	}
+	codes := make(map[string]bool, len(ignored))
+	for code := range ignored {
+		codes[code] = true
+	}
+	for _, code := range strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' }) {
+		codes[strings.ReplaceAll(code, "-", "_")] = true
+	}
+	return codes
+}
+
+func (w *walker) element(el *ast.Element, ignored map[string]bool) {
+	attrs := attributesOf(el)
+	name := strings.ToLower(el.Name)
+	// Missing attributes
+	switch name {
+	case "img", "area":
+		if !attrs.has("alt") {
+			w.warn(el, ignored, MissingAttribute, "`<%s>` element should have an alt attribute", name)
+		}
+	case "iframe":
+		if !attrs.has("title") {
+			w.warn(el, ignored, MissingAttribute, "`<iframe>` element should have a title attribute")
+		}
+	case "a":
+		if !attrs.has("href") && !attrs.has("id") && !attrs.has("name") {
+			w.warn(el, ignored, MissingAttribute, "`<a>` element should have an href attribute")
+		}
+	}
+	// Invalid href
+	if name == "a" {
+		if href, ok := attrs.static["href"]; ok && isInvalidHref(href) {
+			w.warn(el, ignored, InvalidAttribute, "'%s' is not a valid href attribute", href)
+		}
+	}
+	// Unknown roles
+	if role, ok := attrs.static["role"]; ok {
+		for _, r := range strings.Fields(role) {
+			if !roles[r] {
+				w.warn(el, ignored, UnknownRole, "Unknown role '%s'", r)
+			}
+		}
+	}
+	// Click handlers without keyboard handlers
+	if attrs.events["click"] && !attrs.events["keydown"] && !attrs.events["keyup"] && !attrs.events["keypress"] &&
+		!isInteractive(name, attrs) && !isHidden(name, attrs) {
+		w.warn(el, ignored, ClickEventsHaveKeyEvents, "Visible, non-interactive elements with a click event must be accompanied by a keyboard event handler. Consider whether an interactive element such as `<button type=\"button\">` or `<a>` might be more appropriate")
+	}
+}
+
+func isInvalidHref(href string) bool {
+	href = strings.TrimSpace(href)
+	return href == "" || href == "#" || strings.HasPrefix(strings.ToLower(href), "javascript:")
+}
+
+// attributes of an element
+type attributes struct {
+	keys   map[string]bool
+	static map[string]string // Attributes without expressions
+	events map[string]bool   // Lowercase event names without the "on"
+}
+
+func (a *attributes) has(key string) bool {
+	return a.keys[key]
+}
+
+func attributesOf(el *ast.Element) *attributes {
+	attrs := &attributes{
+		keys:   map[string]bool{},
+		static: map[string]string{},
+		events: map[string]bool{},
+	}
+	for _, attr := range el.Attributes {
+		key := attr.GetKey()
+		attrs.keys[key] = true
+		if name, ok := eventName(key); ok {
+			attrs.events[name] = true
+		}
+		field, ok := attr.(*ast.Field)
+		if !ok {
+			continue
+		}
+		if value, ok := staticValue(field); ok {
+			attrs.static[key] = value
+		}
+	}
+	return attrs
+}
+
+// eventName returns the event for handlers like onclick and onClick
+func eventName(key string) (string, bool) {
+	if name, ok := strings.CutPrefix(strings.ToLower(key), "on"); ok && name != "" {
+		return name, true
+	}
+	return "", false
+}
+
+// staticValue returns the field's value if it doesn't contain expressions
+func staticValue(field *ast.Field) (string, bool) {
+	value := new(strings.Builder)
+	for _, v := range field.Values {
+		text, ok := v.(*ast.Text)
+		if !ok {
+			return "", false
+		}
+		value.WriteString(text.Value)
+	}
+	return value.String(), true
+}
+
+var interactiveElements = map[string]bool{
+	"button":   true,
+	"details":  true,
+	"embed":    true,
+	"iframe":   true,
+	"input":    true,
+	"option":   true,
+	"select":   true,
+	"summary":  true,
+	"textarea": true,
+}
+
+var interactiveRoles = map[string]bool{
+	"button":           true,
+	"checkbox":         true,
+	"combobox":         true,
+	"gridcell":         true,
+	"link":             true,
+	"listbox":          true,
+	"menuitem":         true,
+	"menuitemcheckbox": true,
+	"menuitemradio":    true,
+	"option":           true,
+	"radio":            true,
+	"scrollbar":        true,
+	"searchbox":        true,
+	"slider":           true,
+	"spinbutton":       true,
+	"switch":           true,
+	"tab":              true,
+	"textbox":          true,
+	"treeitem":         true,
+}
+
+// isInteractive returns true if the element handles keyboard input itself
+func isInteractive(name string, attrs *attributes) bool {
+	if interactiveElements[name] {
+		return true
+	}
+	if (name == "a" || name == "area") && attrs.has("href") {
+		return true
+	}
+	if role, ok := attrs.static["role"]; ok {
+		for _, r := range strings.Fields(role) {
+			if interactiveRoles[r] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isHidden returns true if the element isn't visible to assistive technology
+func isHidden(name string, attrs *attributes) bool {
+	if attrs.static["aria-hidden"] == "true" {
+		return true
+	}
+	return name == "input" && attrs.static["type"] == "hidden"
+}
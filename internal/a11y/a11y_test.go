@@ -0,0 +1,90 @@
+package a11y_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/livebud/duo/internal/a11y"
+	"github.com/livebud/duo/internal/parser"
+	"github.com/matthewmueller/diff"
+)
+
+func lint(t *testing.T, linter *a11y.Linter, input, expected string) {
+	t.Helper()
+	t.Run(input, func(t *testing.T) {
+		t.Helper()
+		doc, err := parser.Parse("input.svelte", input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		warnings := linter.Lint(doc)
+		lines := make([]string, len(warnings))
+		for i, warning := range warnings {
+			lines[i] = fmt.Sprintf("%d:%d: %s: %s", warning.Pos.Line, warning.Pos.Column, warning.Code, warning.Message)
+		}
+		diff.TestString(t, strings.Join(lines, "\n"), expected)
+	})
+}
+
+func equal(t *testing.T, input, expected string) {
+	t.Helper()
+	lint(t, a11y.New(), input, expected)
+}
+
+func TestMissingAttribute(t *testing.T) {
+	equal(t, `<img src="a.png" />`, "1:1: a11y_missing_attribute: `<img>` element should have an alt attribute")
+	equal(t, `<img src="a.png" alt="" />`, ``)
+	equal(t, `<img src="a.png" alt={alt} />`, ``)
+	equal(t, `<img src="a.png" {alt} />`, ``)
+	equal(t, `<iframe src="/x"></iframe>`, "1:1: a11y_missing_attribute: `<iframe>` element should have a title attribute")
+	equal(t, `<a>x</a>`, "1:1: a11y_missing_attribute: `<a>` element should have an href attribute")
+	equal(t, `<a id="top">x</a>`, ``)
+}
+
+func TestInvalidHref(t *testing.T) {
+	equal(t, `<a href="#">x</a>`, "1:1: a11y_invalid_attribute: '#' is not a valid href attribute")
+	equal(t, `<a href="javascript:void(0)">x</a>`, "1:1: a11y_invalid_attribute: 'javascript:void(0)' is not a valid href attribute")
+	equal(t, `<a href="#top">x</a>`, ``)
+	equal(t, `<a href={url}>x</a>`, ``)
+}
+
+func TestUnknownRole(t *testing.T) {
+	equal(t, `<div role="buton">x</div>`, "1:1: a11y_unknown_role: Unknown role 'buton'")
+	equal(t, `<div role="button" onclick={go}>x</div>`, ``)
+	equal(t, `<div role={role}>x</div>`, ``)
+}
+
+func TestClickEvents(t *testing.T) {
+	equal(t, `<div onclick={go}>x</div>`, "1:1: a11y_click_events_have_key_events: Visible, non-interactive elements with a click event must be accompanied by a keyboard event handler. Consider whether an interactive element such as `<button type=\"button\">` or `<a>` might be more appropriate")
+	equal(t, `<div onClick={go} onKeyDown={go}>x</div>`, ``)
+	equal(t, `<div {onclick} onkeyup={go}>x</div>`, ``)
+	equal(t, `<button onclick={go}>x</button>`, ``)
+	equal(t, `<a href="/x" onclick={go}>x</a>`, ``)
+	equal(t, `<div aria-hidden="true" onclick={go}>x</div>`, ``)
+}
+
+func TestPosition(t *testing.T) {
+	equal(t, "<main>\n  <p>hi</p>\n  <img src=\"a.png\" />\n  {#if x}\n    <span><img src=\"b.png\" /></span>\n  {/if}\n</main>", "3:3: a11y_missing_attribute: `<img>` element should have an alt attribute\n5:11: a11y_missing_attribute: `<img>` element should have an alt attribute")
+}
+
+func TestIgnore(t *testing.T) {
+	equal(t, `<!-- svelte-ignore a11y-missing-attribute --><img src="a.png" />`, ``)
+	equal(t, "<!-- svelte-ignore a11y_missing_attribute -->\n<img src=\"a.png\" />", ``)
+	// Only the next node is ignored
+	equal(t, `<!-- svelte-ignore a11y_missing_attribute --><img src="a.png" /><img src="b.png" />`, "1:65: a11y_missing_attribute: `<img>` element should have an alt attribute")
+	// Children of the ignored node are ignored too
+	equal(t, `<!-- svelte-ignore a11y_missing_attribute --><div><img src="a.png" /></div>`, ``)
+	// Multiple codes
+	equal(t, `<!-- svelte-ignore a11y_invalid_attribute, a11y_click_events_have_key_events --><a href="#" onclick={go}>x</a>`, ``)
+	// Other codes aren't ignored
+	equal(t, `<!-- svelte-ignore a11y_unknown_role --><img src="a.png" />`, "1:41: a11y_missing_attribute: `<img>` element should have an alt attribute")
+	// Regular comments don't ignore anything
+	equal(t, `<!-- a11y_missing_attribute --><img src="a.png" />`, "1:32: a11y_missing_attribute: `<img>` element should have an alt attribute")
+}
+
+func TestRules(t *testing.T) {
+	linter := a11y.New()
+	linter.Rules[a11y.MissingAttribute] = false
+	lint(t, linter, `<img src="a.png" /><a href="#">x</a>`, "1:20: a11y_invalid_attribute: '#' is not a valid href attribute")
+}
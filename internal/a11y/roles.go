@@ -0,0 +1,129 @@
+package a11y
+
+// roles are the non-abstract WAI-ARIA 1.2, DPUB-ARIA and Graphics ARIA roles
+var roles = map[string]bool{
+	"alert":               true,
+	"alertdialog":         true,
+	"application":         true,
+	"article":             true,
+	"banner":              true,
+	"blockquote":          true,
+	"button":              true,
+	"caption":             true,
+	"cell":                true,
+	"checkbox":            true,
+	"code":                true,
+	"columnheader":        true,
+	"combobox":            true,
+	"complementary":       true,
+	"contentinfo":         true,
+	"definition":          true,
+	"deletion":            true,
+	"dialog":              true,
+	"directory":           true,
+	"document":            true,
+	"emphasis":            true,
+	"feed":                true,
+	"figure":              true,
+	"form":                true,
+	"generic":             true,
+	"grid":                true,
+	"gridcell":            true,
+	"group":               true,
+	"heading":             true,
+	"img":                 true,
+	"insertion":           true,
+	"link":                true,
+	"list":                true,
+	"listbox":             true,
+	"listitem":            true,
+	"log":                 true,
+	"main":                true,
+	"marquee":             true,
+	"math":                true,
+	"menu":                true,
+	"menubar":             true,
+	"menuitem":            true,
+	"menuitemcheckbox":    true,
+	"menuitemradio":       true,
+	"meter":               true,
+	"navigation":          true,
+	"none":                true,
+	"note":                true,
+	"option":              true,
+	"paragraph":           true,
+	"presentation":        true,
+	"progressbar":         true,
+	"radio":               true,
+	"radiogroup":          true,
+	"region":              true,
+	"row":                 true,
+	"rowgroup":            true,
+	"rowheader":           true,
+	"scrollbar":           true,
+	"search":              true,
+	"searchbox":           true,
+	"separator":           true,
+	"slider":              true,
+	"spinbutton":          true,
+	"status":              true,
+	"strong":              true,
+	"subscript":           true,
+	"superscript":         true,
+	"switch":              true,
+	"tab":                 true,
+	"table":               true,
+	"tablist":             true,
+	"tabpanel":            true,
+	"term":                true,
+	"textbox":             true,
+	"time":                true,
+	"timer":               true,
+	"toolbar":             true,
+	"tooltip":             true,
+	"tree":                true,
+	"treegrid":            true,
+	"treeitem":            true,
+	"doc-abstract":        true,
+	"doc-acknowledgments": true,
+	"doc-afterword":       true,
+	"doc-appendix":        true,
+	"doc-backlink":        true,
+	"doc-biblioentry":     true,
+	"doc-bibliography":    true,
+	"doc-biblioref":       true,
+	"doc-chapter":         true,
+	"doc-colophon":        true,
+	"doc-conclusion":      true,
+	"doc-cover":           true,
+	"doc-credit":          true,
+	"doc-credits":         true,
+	"doc-dedication":      true,
+	"doc-endnote":         true,
+	"doc-endnotes":        true,
+	"doc-epigraph":        true,
+	"doc-epilogue":        true,
+	"doc-errata":          true,
+	"doc-example":         true,
+	"doc-footnote":        true,
+	"doc-foreword":        true,
+	"doc-glossary":        true,
+	"doc-glossref":        true,
+	"doc-index":           true,
+	"doc-introduction":    true,
+	"doc-noteref":         true,
+	"doc-notice":          true,
+	"doc-pagebreak":       true,
+	"doc-pagelist":        true,
+	"doc-part":            true,
+	"doc-preface":         true,
+	"doc-prologue":        true,
+	"doc-pullquote":       true,
+	"doc-qna":             true,
+	"doc-subtitle":        true,
+	"doc-tip":             true,
+	"doc-toc":             true,
+	"graphics-document":   true,
+	"graphics-object":     true,
+	"graphics-symbol":     true,
+}
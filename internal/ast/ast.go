@@ -55,6 +55,16 @@ func (d *Document) Style() (*Style, bool) {
 	return nil, false
 }
 
+// Options returns the <svelte:options> node if it exists.
+func (d *Document) Options() (*Options, bool) {
+	for _, child := range d.Children {
+		if options, ok := child.(*Options); ok {
+			return options, true
+		}
+	}
+	return nil, false
+}
+
 // Script returns the script node if it exists.
 func (d *Document) Script() (*Script, bool) {
 	for _, child := range d.Children {
@@ -75,11 +85,35 @@ var (
 	_ Fragment = (*Component)(nil)
 	_ Fragment = (*Text)(nil)
 	_ Fragment = (*Mustache)(nil)
+	_ Fragment = (*HTML)(nil)
 	_ Fragment = (*Comment)(nil)
 	_ Fragment = (*IfBlock)(nil)
+	_ Fragment = (*EachBlock)(nil)
+	_ Fragment = (*AwaitBlock)(nil)
 )
 
+// Position in the source, starting at line 1 and column 1
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Advance returns the position after the text, used to find positions within
+// the code of a <script> or <style>
+func (p Position) Advance(text string) Position {
+	for _, r := range text {
+		if r == '\n' {
+			p.Line++
+			p.Column = 1
+			continue
+		}
+		p.Column++
+	}
+	return p
+}
+
 type Element struct {
+	Pos         Position // Position of the opening <
 	Name        string
 	Attributes  []Attribute
 	Children    []Fragment
@@ -90,6 +124,49 @@ func (e *Element) fragment() {}
 
 func (e *Element) Type() string { return "Element" }
 
+// Binding returns the element's binding for the key, like bind:value
+func (e *Element) Binding(key string) (*Binding, bool) {
+	for _, attr := range e.Attributes {
+		if binding, ok := attr.(*Binding); ok && binding.Key == key {
+			return binding, true
+		}
+	}
+	return nil, false
+}
+
+// MergedKey returns the key of a class or style attribute when the element
+// also has directives for it, so the attribute and its directives are rendered
+// together
+func (e *Element) MergedKey(attr Attribute) (string, bool) {
+	var key string
+	switch a := attr.(type) {
+	case *Class, *StyleDirective:
+		return a.GetKey(), true
+	case *Field:
+		if a.EventHandler {
+			return "", false
+		}
+		key = a.Key
+	case *AttributeShorthand:
+		key = a.Key
+	default:
+		return "", false
+	}
+	for _, attr := range e.Attributes {
+		switch attr.(type) {
+		case *Class:
+			if key == "class" {
+				return key, true
+			}
+		case *StyleDirective:
+			if key == "style" {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
 func (e *Element) print(indent string) string {
 	out := new(strings.Builder)
 	out.WriteString(indent)
@@ -116,9 +193,35 @@ func (e *Element) print(indent string) string {
 	return out.String()
 }
 
+// Options configures how the component is compiled, like
+// <svelte:options customElement="my-widget" />
+type Options struct {
+	Attributes    []Attribute
+	CustomElement string // Tag name of the custom element, if any
+}
+
+func (o *Options) fragment() {}
+
+func (o *Options) Type() string { return "Options" }
+
+func (o *Options) print(indent string) string {
+	out := new(strings.Builder)
+	out.WriteString(indent)
+	out.WriteString("<svelte:options")
+	for _, attr := range o.Attributes {
+		out.WriteString(" ")
+		out.WriteString(attr.print(" "))
+	}
+	out.WriteString(" />")
+	return out.String()
+}
+
 type Style struct {
 	Attributes  []Attribute
 	SelfClosing bool
+	Code        string   // Raw CSS, used for hashing
+	CodePos     Position // Position where the CSS starts
+	Scope       string   // Scoping class, like svelte-1mf3hor
 	StyleSheet  *css.Stylesheet
 }
 
@@ -131,6 +234,7 @@ func (e *Style) print(indent string) string {
 	out.WriteString(indent)
 	out.WriteString("<style")
 	for _, attr := range e.Attributes {
+		out.WriteString(" ")
 		out.WriteString(attr.print(" "))
 	}
 	out.WriteString(">")
@@ -148,6 +252,9 @@ func (e *Style) print(indent string) string {
 type Script struct {
 	Attributes  []Attribute
 	SelfClosing bool
+	Code        string   // Source, including any TypeScript types
+	CodePos     Position // Position where the source starts
+	Generics    string   // Type parameters, like generics="T extends Item"
 	Program     *js.AST
 }
 
@@ -160,6 +267,7 @@ func (e *Script) print(indent string) string {
 	out.WriteString(indent)
 	out.WriteString("<script")
 	for _, attr := range e.Attributes {
+		out.WriteString(" ")
 		out.WriteString(attr.print(" "))
 	}
 	out.WriteString(">")
@@ -175,12 +283,21 @@ func (e *Script) print(indent string) string {
 }
 
 type Component struct {
-	Name        string
+	Pos         Position // Position of the opening <
+	Name        string   // Imported name, or svelte:self
 	Attributes  []Attribute
 	Children    []Fragment
 	SelfClosing bool
 }
 
+// Self is the name of <svelte:self>, which renders the component it's in
+const Self = "svelte:self"
+
+// IsSelf returns true for <svelte:self>
+func (c *Component) IsSelf() bool {
+	return c.Name == Self
+}
+
 func (c *Component) fragment() {}
 
 func (c *Component) Type() string { return "Component" }
@@ -212,7 +329,8 @@ func (c *Component) print(indent string) string {
 }
 
 type Slot struct {
-	Name        string // Empty for the default slot
+	Name        string      // Empty for the default slot
+	Attributes  []Attribute // Slot props passed to the slot content
 	SelfClosing bool
 	Fallback    []Fragment
 }
@@ -231,6 +349,10 @@ func (s *Slot) print(indent string) string {
 		out.WriteString(s.Name)
 		out.WriteByte('"')
 	}
+	for _, attr := range s.Attributes {
+		out.WriteString(" ")
+		out.WriteString(attr.print(" "))
+	}
 	if s.SelfClosing {
 		out.WriteString(" />")
 		return out.String()
@@ -257,12 +379,34 @@ var (
 	_ Attribute = (*Binding)(nil)
 	_ Attribute = (*AttributeShorthand)(nil)
 	_ Attribute = (*NamedSlot)(nil)
+	_ Attribute = (*Let)(nil)
 )
 
 type Field struct {
 	Key          string
 	Values       []Value
 	EventHandler bool
+	// Directive is true for legacy on:event directives, which may have
+	// modifiers like on:click|preventDefault and forward the event to the
+	// component's parent when they have no value
+	Directive bool
+	Modifiers []string
+}
+
+// Forwards returns true for directives like on:click that pass the event on to
+// the component's parent
+func (f *Field) Forwards() bool {
+	return f.Directive && len(f.Values) == 0
+}
+
+// HasModifier returns true if the directive has the modifier
+func (f *Field) HasModifier(modifier string) bool {
+	for _, m := range f.Modifiers {
+		if m == modifier {
+			return true
+		}
+	}
+	return false
 }
 
 func (f *Field) GetKey() string {
@@ -275,9 +419,20 @@ func (f *Field) Type() string { return "Field" }
 
 func (f *Field) print(indent string) string {
 	out := new(strings.Builder)
-	out.WriteString(f.Key)
+	// Directives that can't be written as a field keep their on:event form
+	if f.Directive && (f.Forwards() || len(f.Modifiers) > 0) {
+		out.WriteString("on:" + strings.TrimPrefix(f.Key, "on"))
+		for _, modifier := range f.Modifiers {
+			out.WriteString("|" + modifier)
+		}
+	} else {
+		out.WriteString(f.Key)
+	}
+	if len(f.Values) == 0 {
+		return out.String()
+	}
 	out.WriteString("=")
-	if f.EventHandler && len(f.Values) > 0 {
+	if f.EventHandler {
 		out.WriteString(f.Values[0].print(""))
 		return out.String()
 	}
@@ -333,6 +488,32 @@ func (c *Class) print(indent string) string {
 	return out.String()
 }
 
+type StyleDirective struct {
+	Name   string
+	Values []Value
+}
+
+func (s *StyleDirective) attribute() {}
+
+func (s *StyleDirective) GetKey() string {
+	return "style"
+}
+
+func (s *StyleDirective) Type() string { return "StyleDirective" }
+
+func (s *StyleDirective) print(indent string) string {
+	out := new(strings.Builder)
+	out.WriteString("style:")
+	out.WriteString(s.Name)
+	out.WriteString("=")
+	out.WriteByte('"')
+	for _, v := range s.Values {
+		out.WriteString(v.print(""))
+	}
+	out.WriteByte('"')
+	return out.String()
+}
+
 type AttributeShorthand struct {
 	Key          string
 	EventHandler bool
@@ -365,6 +546,35 @@ func (s *NamedSlot) print(indent string) string {
 	return s.GetKey() + `="` + s.Name + `"`
 }
 
+// Let exposes a slot prop to the slot content (e.g. `let:item={alias}`)
+type Let struct {
+	Name  string
+	Alias string // Empty if the slot prop isn't renamed
+}
+
+func (l *Let) attribute() {}
+
+func (l *Let) Type() string { return "Let" }
+
+func (l *Let) GetKey() string {
+	return "let:" + l.Name
+}
+
+// Var returns the name of the variable declared for the slot content
+func (l *Let) Var() string {
+	if l.Alias != "" {
+		return l.Alias
+	}
+	return l.Name
+}
+
+func (l *Let) print(indent string) string {
+	if l.Alias == "" {
+		return l.GetKey()
+	}
+	return l.GetKey() + "={" + l.Alias + "}"
+}
+
 type Value interface {
 	Node
 	value()
@@ -376,6 +586,7 @@ var (
 )
 
 type Mustache struct {
+	Pos  Position // Position of the expression
 	Expr js.IExpr
 }
 
@@ -388,6 +599,20 @@ func (m *Mustache) print(indent string) string {
 	return "{" + m.Expr.JS() + "}"
 }
 
+// HTML is a {@html expr} tag that renders its value without escaping
+type HTML struct {
+	Pos  Position // Position of the expression
+	Expr js.IExpr
+}
+
+func (h *HTML) fragment() {}
+
+func (h *HTML) Type() string { return "HTML" }
+
+func (h *HTML) print(indent string) string {
+	return "{@html " + h.Expr.JS() + "}"
+}
+
 type Text struct {
 	Value string
 }
@@ -414,6 +639,7 @@ func (c *Comment) print(indent string) string {
 }
 
 type IfBlock struct {
+	Pos  Position // Position of the condition
 	Cond js.IExpr
 	Then []Fragment
 	Else []Fragment
@@ -446,8 +672,9 @@ func (i *IfBlock) print(indent string) string {
 }
 
 type EachBlock struct {
-	Key   *js.Var // Can be nil
-	Value *js.Var // Can be nil
+	Pos   Position // Position of the list
+	Key   *js.Var  // Can be nil
+	Value *js.Var  // Can be nil
 	List  js.IExpr
 	Body  []Fragment
 	Else  []Fragment
@@ -486,3 +713,51 @@ func (f *EachBlock) print(indent string) string {
 	out.WriteString("{/each}")
 	return out.String()
 }
+
+type AwaitBlock struct {
+	Pos     Position // Position of the promise
+	Promise js.IExpr
+	Pending []Fragment
+	Value   *js.Var // Can be nil
+	Then    []Fragment
+	Error   *js.Var // Can be nil
+	Catch   []Fragment
+}
+
+func (a *AwaitBlock) fragment() {}
+
+func (a *AwaitBlock) Type() string { return "AwaitBlock" }
+
+func (a *AwaitBlock) print(indent string) string {
+	out := new(strings.Builder)
+	out.WriteString(indent)
+	out.WriteString("{#await ")
+	out.WriteString(a.Promise.JS())
+	out.WriteString("}")
+	for _, child := range a.Pending {
+		out.WriteString(child.print(indent + "\t"))
+	}
+	out.WriteString("{:then")
+	if a.Value != nil {
+		out.WriteString(" ")
+		out.WriteString(a.Value.JS())
+	}
+	out.WriteString("}")
+	for _, child := range a.Then {
+		out.WriteString(child.print(indent + "\t"))
+	}
+	if a.Error != nil || len(a.Catch) > 0 {
+		out.WriteString("{:catch")
+		if a.Error != nil {
+			out.WriteString(" ")
+			out.WriteString(a.Error.JS())
+		}
+		out.WriteString("}")
+		for _, child := range a.Catch {
+			out.WriteString(child.print(indent + "\t"))
+		}
+	}
+	out.WriteString(indent)
+	out.WriteString("{/await}")
+	return out.String()
+}
@@ -0,0 +1,63 @@
+package ast
+
+// Visitor visits the nodes of the markup. If Visit returns a visitor w, the
+// node's children are visited with w, followed by a call to w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk the markup in depth-first order, starting with the node. The children
+// of a node are the fragments nested within it, like an element's children,
+// a slot's fallback or the branches of a block.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	for _, child := range children(node) {
+		Walk(v, child)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect the markup in depth-first order, starting with the node. If f
+// returns true, Inspect visits the node's children, followed by f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// children returns the fragments nested within the node, in source order
+func children(node Node) []Fragment {
+	switch n := node.(type) {
+	case *Document:
+		return n.Children
+	case *Element:
+		return n.Children
+	case *Component:
+		return n.Children
+	case *Slot:
+		return n.Fallback
+	case *IfBlock:
+		return concat(n.Then, n.Else)
+	case *EachBlock:
+		return concat(n.Body, n.Else)
+	case *AwaitBlock:
+		return concat(n.Pending, n.Then, n.Catch)
+	}
+	return nil
+}
+
+func concat(lists ...[]Fragment) (fragments []Fragment) {
+	for _, list := range lists {
+		fragments = append(fragments, list...)
+	}
+	return fragments
+}
@@ -0,0 +1,243 @@
+// Package check analyzes parsed components for mistakes that compile fine but
+// are likely bugs, like CSS selectors that match nothing.
+package check
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/livebud/duo/internal/a11y"
+	"github.com/livebud/duo/internal/ast"
+	"github.com/livebud/duo/internal/html"
+	"github.com/livebud/duo/internal/parser"
+	"github.com/livebud/duo/internal/resolver"
+)
+
+// Severity of a diagnostic
+type Severity string
+
+const (
+	Warning Severity = "warning"
+	Error   Severity = "error"
+)
+
+// Diagnostic is a problem found in a component. Codes follow Svelte's warning
+// codes where there's an equivalent.
+type Diagnostic struct {
+	Path     string   `json:"path"`
+	Line     int      `json:"line,omitempty"` // 0 if the position is unknown
+	Column   int      `json:"column,omitempty"`
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+}
+
+func (d *Diagnostic) String() string {
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s (%s)", d.Path, d.Severity, d.Message, d.Code)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s (%s)", d.Path, d.Line, d.Column, d.Severity, d.Message, d.Code)
+}
+
+// Document returns the diagnostics for a parsed component with every a11y
+// rule enabled
+func Document(path string, doc *ast.Document) (diagnostics []*Diagnostic) {
+	return document(a11y.New(), path, doc)
+}
+
+func document(linter *a11y.Linter, path string, doc *ast.Document) (diagnostics []*Diagnostic) {
+	diagnostics = append(diagnostics, undefinedIdentifiers(path, doc)...)
+	diagnostics = append(diagnostics, unimportedComponents(path, doc)...)
+	diagnostics = append(diagnostics, unusedVariables(path, doc)...)
+	diagnostics = append(diagnostics, Events(path, doc)...)
+	diagnostics = append(diagnostics, accessibility(linter, path, doc)...)
+	diagnostics = append(diagnostics, unusedSelectors(path, doc)...)
+	return diagnostics
+}
+
+// accessibility warns about a11y mistakes in the markup
+func accessibility(linter *a11y.Linter, path string, doc *ast.Document) (diagnostics []*Diagnostic) {
+	for _, warning := range linter.Lint(doc) {
+		diagnostics = append(diagnostics, &Diagnostic{
+			Path:     path,
+			Line:     warning.Pos.Line,
+			Column:   warning.Pos.Column,
+			Severity: Warning,
+			Code:     warning.Code,
+			Message:  warning.Message,
+		})
+	}
+	return diagnostics
+}
+
+// File parses the component and returns its diagnostics. Parse errors are
+// returned as error diagnostics.
+func File(path string, code []byte) []*Diagnostic {
+	doc, diagnostics := parse(parseFile, path, code)
+	if doc == nil {
+		return diagnostics
+	}
+	return Document(path, doc)
+}
+
+func parseFile(path string, code []byte) (*ast.Document, error) {
+	return parser.Parse(path, string(code))
+}
+
+// parse the component, returning the parse error as a diagnostic
+func parse(parseFn func(path string, code []byte) (*ast.Document, error), path string, code []byte) (*ast.Document, []*Diagnostic) {
+	doc, err := parseFn(path, code)
+	if err == nil {
+		return doc, nil
+	}
+	var placement *html.Error
+	if errors.As(err, &placement) {
+		return nil, []*Diagnostic{{
+			Path:     path,
+			Line:     placement.Pos.Line,
+			Column:   placement.Pos.Column,
+			Severity: Error,
+			Code:     placement.Code(),
+			Message:  placement.Message,
+		}}
+	}
+	return nil, []*Diagnostic{{
+		Path:     path,
+		Severity: Error,
+		Code:     "parse_error",
+		Message:  err.Error(),
+	}}
+}
+
+// Dir checks every .svelte file within the filesystem
+func Dir(fsys fs.FS) ([]*Diagnostic, error) {
+	return New(fsys).Dir()
+}
+
+// New checker for the files in fsys. Imports are resolved within fsys.
+func New(fsys fs.FS) *Checker {
+	return &Checker{
+		FS:       fsys,
+		Resolver: resolver.New(fsys),
+		Parse:    parseFile,
+		A11y:     a11y.New(),
+	}
+}
+
+// Checker checks a project's components, including that their imports resolve
+type Checker struct {
+	FS       fs.FS
+	Resolver resolver.Interface
+	// Parse the component. Override it to share a cache of parsed documents,
+	// like the dev server's.
+	Parse func(path string, code []byte) (*ast.Document, error)
+	// A11y lints the markup. Disable a rule by setting it to false in
+	// A11y.Rules.
+	A11y *a11y.Linter
+}
+
+// Dir checks every .svelte file within the filesystem, skipping hidden
+// directories and node_modules
+func (c *Checker) Dir() (diagnostics []*Diagnostic, err error) {
+	err = fs.WalkDir(c.FS, ".", func(filePath string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if de.IsDir() {
+			if filePath != "." && (strings.HasPrefix(de.Name(), ".") || de.Name() == "node_modules") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if path.Ext(filePath) != ".svelte" {
+			return nil
+		}
+		code, err := fs.ReadFile(c.FS, filePath)
+		if err != nil {
+			return err
+		}
+		diagnostics = append(diagnostics, c.File(filePath, code)...)
+		return nil
+	})
+	return diagnostics, err
+}
+
+// Paths checks the .svelte files at the paths, skipping other files and files
+// that no longer exist
+func (c *Checker) Paths(paths ...string) (diagnostics []*Diagnostic, err error) {
+	for _, filePath := range paths {
+		if path.Ext(filePath) != ".svelte" {
+			continue
+		}
+		code, err := fs.ReadFile(c.FS, filePath)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		diagnostics = append(diagnostics, c.File(filePath, code)...)
+	}
+	return diagnostics, nil
+}
+
+// File checks the component and resolves its relative imports
+func (c *Checker) File(path string, code []byte) []*Diagnostic {
+	parseFn := c.Parse
+	if parseFn == nil {
+		parseFn = parseFile
+	}
+	doc, diagnostics := parse(parseFn, path, code)
+	if doc == nil {
+		return diagnostics
+	}
+	linter := c.A11y
+	if linter == nil {
+		linter = a11y.New()
+	}
+	diagnostics = append(diagnostics, c.unresolvedImports(path, doc)...)
+	return append(diagnostics, document(linter, path, doc)...)
+}
+
+// unresolvedImports reports relative imports that can't be resolved. Package
+// and URL imports are resolved by the bundler, so they're skipped.
+func (c *Checker) unresolvedImports(path string, doc *ast.Document) (diagnostics []*Diagnostic) {
+	if doc.Scope == nil {
+		return nil
+	}
+	for _, sym := range doc.Scope.Symbols() {
+		if sym.Import == nil || !strings.HasPrefix(sym.Import.Path, ".") {
+			continue
+		}
+		if _, err := c.Resolver.Resolve(&resolver.Resolve{From: path, Path: sym.Import.Path}); err != nil {
+			diagnostic := &Diagnostic{
+				Path:     path,
+				Severity: Error,
+				Code:     "unresolved_import",
+				Message:  fmt.Sprintf("Unable to resolve %q imported as %s", sym.Import.Path, sym.Name),
+			}
+			if pos, ok := importPosition(doc, sym.Import.Path); ok {
+				diagnostic.Line, diagnostic.Column = pos.Line, pos.Column
+			}
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	return diagnostics
+}
+
+// importPosition returns the position of the quoted import path in the script
+func importPosition(doc *ast.Document, importPath string) (ast.Position, bool) {
+	script, ok := doc.Script()
+	if !ok {
+		return ast.Position{}, false
+	}
+	for _, quote := range []string{`"`, `'`} {
+		if offset := strings.Index(script.Code, quote+importPath+quote); offset >= 0 {
+			return script.CodePos.Advance(script.Code[:offset]), true
+		}
+	}
+	return ast.Position{}, false
+}
@@ -0,0 +1,193 @@
+package check_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/livebud/duo/internal/a11y"
+	"github.com/livebud/duo/internal/ast"
+	"github.com/livebud/duo/internal/check"
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+)
+
+func equal(t *testing.T, input, expected string) {
+	t.Helper()
+	t.Run(input, func(t *testing.T) {
+		t.Helper()
+		diagnostics := check.File("input.svelte", []byte(input))
+		lines := make([]string, len(diagnostics))
+		for i, diagnostic := range diagnostics {
+			lines[i] = diagnostic.String()
+		}
+		diff.TestString(t, strings.Join(lines, "\n"), expected)
+	})
+}
+
+func TestUnusedSelector(t *testing.T) {
+	equal(t, `<h1>hi</h1><style>h1 { color: red; } h2 { color: blue; }</style>`, `input.svelte:1:38: warning: Unused CSS selector "h2" (css_unused_selector)`)
+	equal(t, `<ul><li class="a">x</li></ul><style>ul .a { color: red; } ul .b { color: blue; } ol li { margin: 0; }</style>`, "input.svelte:1:59: warning: Unused CSS selector \"ul .b\" (css_unused_selector)\ninput.svelte:1:82: warning: Unused CSS selector \"ol li\" (css_unused_selector)")
+	equal(t, `<script>let done = true</script><span class:checked={done}>x</span><style>.checked { color: gray; }</style>`, ``)
+	equal(t, `<script>let cls = "b"</script><div class={cls}>x</div><style>.anything { color: gray; }</style>`, ``)
+	equal(t, `<p>x</p><style>:global(body) p { margin: 0; } :global(.x) { margin: 0; }</style>`, ``)
+	equal(t, `<script>let x = true</script>{#if x}<p>a</p>{:else}<em>b</em>{/if}<style>em { margin: 0; }</style>`, ``)
+	equal(t, "<p>x</p>\n<style>\n  /* p, h2 { } */\n  p,\n  h2 { margin: 0; }\n  @media (min-width: 1px) {\n    p > em { margin: 0; }\n  }\n</style>", "input.svelte:5:3: warning: Unused CSS selector \"h2\" (css_unused_selector)\ninput.svelte:7:5: warning: Unused CSS selector \"p > em\" (css_unused_selector)")
+}
+
+func TestUnusedVariable(t *testing.T) {
+	equal(t, `<script>let a = 1; let b = 2; const c = a + 1</script><p>{c}</p>`, `input.svelte:1:24: warning: "b" is declared but never used (unused_variable)`)
+	equal(t, `<script>function unused() {} function used() {} const x = used()</script>{x}`, `input.svelte:1:18: warning: "unused" is declared but never used (unused_variable)`)
+	equal(t, `<script>let { a, b = $bindable(0) } = $props(); export let c = 1</script>`, ``)
+	equal(t, `<script>import Item from "./Item.svelte"; import Unused from "./Unused.svelte"</script><Item />`, `input.svelte:1:50: warning: "Unused" is declared but never used (unused_variable)`)
+	equal(t, `<script>let value = $state(""); let checked = false; let title = "t"</script><input bind:value={value} /><input {checked} /><p title={title}></p>`, ``)
+	equal(t, `<script>let items = []; let show = true; let p = Promise.resolve(1)</script>{#if show}{#each items as item}{item}{/each}{/if}{#await p then v}{v}{/await}`, ``)
+	equal(t, `<script>let count = 0; function inc() { count += 1 }</script><button onclick={inc}>+</button>`, ``)
+	equal(t, "<script>\n  let count = 0\n  let counter = count + 1\n</script>", "input.svelte:3:7: warning: \"counter\" is declared but never used (unused_variable)")
+}
+
+func TestParseError(t *testing.T) {
+	is := is.New(t)
+	diagnostics := check.File("input.svelte", []byte(`<script>let = </script>`))
+	is.Equal(len(diagnostics), 1)
+	is.Equal(diagnostics[0].Severity, check.Error)
+	is.Equal(diagnostics[0].Code, "parse_error")
+}
+
+func TestDir(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		"index.svelte":              {Data: []byte(`<h1>hi</h1><style>h2 { color: red; }</style>`)},
+		"ok/page.svelte":            {Data: []byte(`<h1>hi</h1>`)},
+		"node_modules/pkg/x.svelte": {Data: []byte(`<h1>hi</h1><style>h2 { color: red; }</style>`)},
+		"README.md":                 {Data: []byte(`# readme`)},
+	}
+	diagnostics, err := check.Dir(fsys)
+	is.NoErr(err)
+	is.Equal(len(diagnostics), 1)
+	is.Equal(diagnostics[0].String(), `index.svelte:1:19: warning: Unused CSS selector "h2" (css_unused_selector)`)
+}
+
+func TestAccessibility(t *testing.T) {
+	equal(t, "<h1>hi</h1>\n<img src=\"a.png\" />", "input.svelte:2:1: warning: `<img>` element should have an alt attribute (a11y_missing_attribute)")
+	equal(t, "<!-- svelte-ignore a11y-missing-attribute -->\n<img src=\"a.png\" />", ``)
+}
+
+func TestDisableA11yRule(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		"index.svelte": {Data: []byte(`<img src="a.png" /><div onclick={() => {}}>x</div>`)},
+	}
+	checker := check.New(fsys)
+	checker.A11y.Rules[a11y.MissingAttribute] = false
+	diagnostics, err := checker.Dir()
+	is.NoErr(err)
+	is.Equal(len(diagnostics), 1)
+	is.Equal(diagnostics[0].Code, a11y.ClickEventsHaveKeyEvents)
+}
+
+func TestInvalidPlacement(t *testing.T) {
+	equal(t, "<p>\n  <div>x</div>\n</p>", "input.svelte:2:3: error: `<div>` cannot be a descendant of `<p>`. The browser will 'repair' the HTML (by moving, removing, or inserting elements) which breaks hydration against the server-rendered HTML (node_invalid_placement)")
+}
+
+func TestUndefinedIdentifier(t *testing.T) {
+	equal(t, `<p>{missing}</p>`, `input.svelte:1:5: error: "missing" is not defined (undefined_identifier)`)
+	equal(t, `<script>let a = 1; function f(b) { return a + b + c }</script><p>{f(a)}</p>`, `input.svelte:1:51: error: "c" is not defined (undefined_identifier)`)
+	equal(t, "<ul>\n  <li class={active}>a</li>\n</ul>", `input.svelte:2:14: error: "active" is not defined (undefined_identifier)`)
+	equal(t, "{#if open}\n  <p>open</p>\n{/if}", `input.svelte:1:6: error: "open" is not defined (undefined_identifier)`)
+	equal(t, `<script>const xs = [1]; const double = (x) => x * 2</script>{#each xs as item, i}{double(item) + i}{/each}`, ``)
+	equal(t, `<script>let { name } = $props(); let now = Date.now()</script><button onclick={() => console.log(name, now, window)}>x</button>`, ``)
+}
+
+func TestReactiveDeclaration(t *testing.T) {
+	equal(t, `<script>export let a = 1; $: doubled = a * 2</script><p>{doubled}</p>`, ``)
+	equal(t, `<script>export let point = {}; $: ({ x, y: [y] } = point)</script><p>{x} {y}</p>`, ``)
+	equal(t, `<script>let a = 1; $: a = b * 2</script><p>{a}</p>`, `input.svelte:1:27: error: "b" is not defined (undefined_identifier)`)
+}
+
+func TestSelf(t *testing.T) {
+	equal(t, `<script>let { node } = $props()</script><p>{node.name}</p>{#each node.children as child}<svelte:self node={child} />{/each}`, ``)
+}
+
+func TestUnimportedComponent(t *testing.T) {
+	equal(t, "<main>\n  <Card />\n</main>", "input.svelte:2:3: error: `<Card>` is not imported (unimported_component)")
+	equal(t, `<script>import Card from "./Card.svelte"</script><Card />`, ``)
+}
+
+func TestUnresolvedImport(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		"index.svelte":      {Data: []byte(`<script>import Card from "./Card.svelte"; import Nav from "./nav/Nav.svelte"</script><Card /><Nav />`)},
+		"Card.svelte":       {Data: []byte(`<p>card</p>`)},
+		"nav/Link.svelte":   {Data: []byte(`<a href="/">link</a>`)},
+		"nav/Header.svelte": {Data: []byte(`<script>import Link from "./Link.svelte"</script><Link />`)},
+	}
+	diagnostics, err := check.New(fsys).Dir()
+	is.NoErr(err)
+	is.Equal(len(diagnostics), 1)
+	is.Equal(diagnostics[0].String(), `index.svelte:1:59: error: Unable to resolve "./nav/Nav.svelte" imported as Nav (unresolved_import)`)
+}
+
+// The example app should always pass its own checks. Warnings don't fail the
+// check, so only errors are reported.
+func TestExample(t *testing.T) {
+	is := is.New(t)
+	diagnostics, err := check.New(os.DirFS("../../example/hn/view")).Dir()
+	is.NoErr(err)
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == check.Error {
+			t.Error(diagnostic)
+		}
+	}
+}
+
+func TestPaths(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		"index.svelte": {Data: []byte(`<h1>hi</h1><style>h2 { color: red; }</style>`)},
+		"about.svelte": {Data: []byte(`<h1>hi</h1><style>h2 { color: red; }</style>`)},
+	}
+	checker := check.New(fsys)
+	var parsed []string
+	parse := checker.Parse
+	checker.Parse = func(path string, code []byte) (*ast.Document, error) {
+		parsed = append(parsed, path)
+		return parse(path, code)
+	}
+	diagnostics, err := checker.Paths("index.svelte", "deleted.svelte", "README.md")
+	is.NoErr(err)
+	is.Equal(parsed, []string{"index.svelte"})
+	is.Equal(len(diagnostics), 1)
+	is.Equal(diagnostics[0].String(), `index.svelte:1:19: warning: Unused CSS selector "h2" (css_unused_selector)`)
+}
+
+func TestWrite(t *testing.T) {
+	is := is.New(t)
+	diagnostics := []*check.Diagnostic{
+		{Path: "a.svelte", Line: 2, Column: 3, Severity: check.Error, Code: "unimported_component", Message: "`<Card>` is not imported"},
+		{Path: "b,c.svelte", Severity: check.Warning, Code: "css_unused_selector", Message: "Unused CSS selector \"h2\"\n100%"},
+	}
+	out := new(strings.Builder)
+	is.NoErr(check.Write(out, check.Human, diagnostics))
+	diff.TestString(t, out.String(), "a.svelte:2:3: error: `<Card>` is not imported (unimported_component)\nb,c.svelte: warning: Unused CSS selector \"h2\"\n100% (css_unused_selector)\n1 error and 1 warning\n")
+	out.Reset()
+	is.NoErr(check.Write(out, check.GitHub, diagnostics))
+	diff.TestString(t, out.String(), "::error file=a.svelte,line=2,col=3,title=unimported_component::`<Card>` is not imported\n::warning file=b%2Cc.svelte,title=css_unused_selector::Unused CSS selector \"h2\"%0A100%25\n")
+	out.Reset()
+	is.NoErr(check.Write(out, check.JSON, diagnostics[:1]))
+	diff.TestString(t, out.String(), "[\n  {\n    \"path\": \"a.svelte\",\n    \"line\": 2,\n    \"column\": 3,\n    \"severity\": \"error\",\n    \"code\": \"unimported_component\",\n    \"message\": \"`\\u003cCard\\u003e` is not imported\"\n  }\n]\n")
+	out.Reset()
+	is.NoErr(check.Write(out, check.JSON, nil))
+	is.Equal(out.String(), "[]\n")
+	is.True(check.HasErrors(diagnostics))
+	is.True(!check.HasErrors(diagnostics[1:]))
+}
+
+func TestMisspelledEvent(t *testing.T) {
+	equal(t, "<script>function add() {}</script>\n<button onclik={add}>+</button>", "input.svelte:2:1: warning: Unknown event handler \"onclik\" on `<button>`, did you mean \"onclick\"? (unknown_event_handler)")
+	equal(t, `<script>function add() {}</script><button onMouseOvr={add}>+</button>`, "input.svelte:1:35: warning: Unknown event handler \"onMouseOvr\" on `<button>`, did you mean \"onMouseOver\"? (unknown_event_handler)")
+	equal(t, `<script>function add() {}</script><button onclick={add} on:dblclick={add} onClick={add}>+</button>`, ``)
+	equal(t, `<button onclik="add()">+</button>`, ``)
+	equal(t, `<button on:clik|once>+</button>`, "input.svelte:1:1: warning: Unknown event handler \"on:clik\" on `<button>`, did you mean \"on:click\"? (unknown_event_handler)")
+}
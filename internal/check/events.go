@@ -0,0 +1,55 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/livebud/duo/internal/ast"
+	"github.com/livebud/duo/internal/event"
+)
+
+// Events warns about event handlers that are close to a known event but don't
+// match it, like onclik. The generators report these too, since a misspelled
+// handler compiles to an attribute that's never called.
+func Events(path string, doc *ast.Document) (diagnostics []*Diagnostic) {
+	ast.Inspect(doc, func(node ast.Node) bool {
+		element, ok := node.(*ast.Element)
+		if !ok {
+			return true
+		}
+		for _, attr := range element.Attributes {
+			field, ok := attr.(*ast.Field)
+			if !ok || !field.Directive && !isExpression(field.Values) {
+				continue
+			}
+			suggestion, ok := event.Suggest(field.Key)
+			if !ok {
+				continue
+			}
+			key := field.Key
+			if field.Directive {
+				key = "on:" + strings.TrimPrefix(key, "on")
+				suggestion = "on:" + strings.TrimPrefix(suggestion, "on")
+			}
+			diagnostics = append(diagnostics, &Diagnostic{
+				Path:     path,
+				Line:     element.Pos.Line,
+				Column:   element.Pos.Column,
+				Severity: Warning,
+				Code:     "unknown_event_handler",
+				Message:  fmt.Sprintf("Unknown event handler %q on `<%s>`, did you mean %q?", key, element.Name, suggestion),
+			})
+		}
+		return true
+	})
+	return diagnostics
+}
+
+// isExpression returns true if the value is a single expression, like {fn}
+func isExpression(values []ast.Value) bool {
+	if len(values) != 1 {
+		return false
+	}
+	_, ok := values[0].(*ast.Mustache)
+	return ok
+}
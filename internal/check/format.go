@@ -0,0 +1,111 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format of the printed diagnostics
+type Format string
+
+const (
+	Human  Format = "human"
+	JSON   Format = "json"
+	GitHub Format = "github"
+)
+
+// Write the diagnostics to w in the given format
+func Write(w io.Writer, format Format, diagnostics []*Diagnostic) error {
+	switch format {
+	case Human, "":
+		return writeHuman(w, diagnostics)
+	case JSON:
+		return writeJSON(w, diagnostics)
+	case GitHub:
+		return writeGitHub(w, diagnostics)
+	default:
+		return fmt.Errorf("check: unknown format %q", format)
+	}
+}
+
+// HasErrors returns true if any of the diagnostics are errors
+func HasErrors(diagnostics []*Diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+func writeHuman(w io.Writer, diagnostics []*Diagnostic) error {
+	errors, warnings := 0, 0
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == Error {
+			errors++
+		} else {
+			warnings++
+		}
+		if _, err := fmt.Fprintln(w, diagnostic); err != nil {
+			return err
+		}
+	}
+	if len(diagnostics) == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "%s and %s\n", plural(errors, "error"), plural(warnings, "warning"))
+	return err
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, word)
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+func writeJSON(w io.Writer, diagnostics []*Diagnostic) error {
+	if diagnostics == nil {
+		diagnostics = []*Diagnostic{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(diagnostics)
+}
+
+// writeGitHub writes workflow commands that GitHub Actions turns into
+// annotations on the pull request
+func writeGitHub(w io.Writer, diagnostics []*Diagnostic) error {
+	for _, diagnostic := range diagnostics {
+		level := "warning"
+		if diagnostic.Severity == Error {
+			level = "error"
+		}
+		props := []string{"file=" + escapeProperty(diagnostic.Path)}
+		if diagnostic.Line > 0 {
+			props = append(props,
+				fmt.Sprintf("line=%d", diagnostic.Line),
+				fmt.Sprintf("col=%d", diagnostic.Column),
+			)
+		}
+		props = append(props, "title="+escapeProperty(diagnostic.Code))
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", level, strings.Join(props, ","), escapeData(diagnostic.Message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var dataEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+
+var propertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+
+func escapeData(s string) string {
+	return dataEscaper.Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return propertyEscaper.Replace(s)
+}
@@ -0,0 +1,204 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/livebud/duo/internal/ast"
+	"github.com/livebud/duo/internal/js"
+)
+
+// undefinedIdentifiers reports top-level variables that are referenced but
+// never declared, imported or provided by the browser
+func undefinedIdentifiers(path string, doc *ast.Document) (diagnostics []*Diagnostic) {
+	if doc.Scope == nil {
+		return nil
+	}
+	for _, sym := range doc.Scope.Symbols() {
+		if sym.IsDeclared() || sym.Import != nil || globals[sym.Name] {
+			continue
+		}
+		// Runes, store subscriptions and $$slots
+		if strings.HasPrefix(sym.Name, "$") {
+			continue
+		}
+		// Components are reported separately
+		if isComponentName(sym.Name) && usesComponent(doc, sym.Name) {
+			continue
+		}
+		diagnostic := &Diagnostic{
+			Path:     path,
+			Severity: Error,
+			Code:     "undefined_identifier",
+			Message:  fmt.Sprintf("%q is not defined", sym.Name),
+		}
+		if pos, ok := firstReference(doc, sym.Name); ok {
+			diagnostic.Line, diagnostic.Column = pos.Line, pos.Column
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return diagnostics
+}
+
+// firstReference returns the position of the first reference to the name in
+// the script or the markup. Positions within the markup are the positions of
+// the expressions that reference the name.
+func firstReference(doc *ast.Document, name string) (first ast.Position, found bool) {
+	reference := func(pos ast.Position) {
+		if pos.Line == 0 {
+			return
+		}
+		if !found || pos.Line < first.Line || pos.Line == first.Line && pos.Column < first.Column {
+			first, found = pos, true
+		}
+	}
+	if script, ok := doc.Script(); ok {
+		if offset, ok := identifierOffset(script.Code, name); ok {
+			reference(script.CodePos.Advance(script.Code[:offset]))
+		}
+	}
+	uses := func(expr js.INode) bool {
+		refs := references{}
+		js.Walk(refs, expr)
+		return refs[name] > 0
+	}
+	attributes := func(pos ast.Position, attrs []ast.Attribute) {
+		for _, attr := range attrs {
+			if shorthand, ok := attr.(*ast.AttributeShorthand); ok && shorthand.Key == name {
+				reference(pos)
+				continue
+			}
+			for _, value := range attributeValues(attr) {
+				if mustache, ok := value.(*ast.Mustache); ok && uses(mustache.Expr) {
+					reference(mustache.Pos)
+				}
+			}
+		}
+	}
+	ast.Inspect(doc, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.Mustache:
+			if uses(n.Expr) {
+				reference(n.Pos)
+			}
+		case *ast.HTML:
+			if uses(n.Expr) {
+				reference(n.Pos)
+			}
+		case *ast.IfBlock:
+			if uses(n.Cond) {
+				reference(n.Pos)
+			}
+		case *ast.EachBlock:
+			if uses(n.List) {
+				reference(n.Pos)
+			}
+		case *ast.AwaitBlock:
+			if uses(n.Promise) {
+				reference(n.Pos)
+			}
+		case *ast.Element:
+			attributes(n.Pos, n.Attributes)
+		case *ast.Component:
+			if n.Name == name {
+				reference(n.Pos)
+			}
+			attributes(n.Pos, n.Attributes)
+		case *ast.Slot:
+			// Slots don't have a position, but their attribute values do
+			attributes(ast.Position{}, n.Attributes)
+		}
+		return true
+	})
+	return first, found
+}
+
+// attributeValues returns the values of an attribute that can reference
+// variables
+func attributeValues(attr ast.Attribute) []ast.Value {
+	switch a := attr.(type) {
+	case *ast.Field:
+		return a.Values
+	case *ast.Binding:
+		return []ast.Value{a.Value}
+	case *ast.Class:
+		return []ast.Value{a.Value}
+	case *ast.StyleDirective:
+		return a.Values
+	}
+	return nil
+}
+
+// unimportedComponents reports components that aren't imported or declared
+func unimportedComponents(path string, doc *ast.Document) (diagnostics []*Diagnostic) {
+	ast.Inspect(doc, func(node ast.Node) bool {
+		component, ok := node.(*ast.Component)
+		if !ok || component.IsSelf() {
+			return true
+		}
+		name, _, _ := strings.Cut(component.Name, ".")
+		if doc.Scope != nil {
+			if sym, ok := doc.Scope.LookupByName(name); ok && (sym.Import != nil || sym.IsDeclared()) {
+				return true
+			}
+		}
+		diagnostics = append(diagnostics, &Diagnostic{
+			Path:     path,
+			Line:     component.Pos.Line,
+			Column:   component.Pos.Column,
+			Severity: Error,
+			Code:     "unimported_component",
+			Message:  fmt.Sprintf("`<%s>` is not imported", component.Name),
+		})
+		return true
+	})
+	return diagnostics
+}
+
+func isComponentName(name string) bool {
+	return name != "" && name[0] >= 'A' && name[0] <= 'Z'
+}
+
+func usesComponent(doc *ast.Document, name string) (found bool) {
+	ast.Inspect(doc, func(node ast.Node) bool {
+		if component, ok := node.(*ast.Component); ok && component.Name == name {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// globals are the identifiers provided by JavaScript and the browser
+var globals = map[string]bool{
+	"undefined": true, "NaN": true, "Infinity": true, "globalThis": true,
+	"Array": true, "ArrayBuffer": true, "BigInt": true, "Boolean": true,
+	"DataView": true, "Date": true, "Error": true, "EvalError": true,
+	"Float32Array": true, "Float64Array": true, "Function": true,
+	"Int8Array": true, "Int16Array": true, "Int32Array": true, "Intl": true,
+	"JSON": true, "Map": true, "Math": true, "Number": true, "Object": true,
+	"Promise": true, "Proxy": true, "RangeError": true, "ReferenceError": true,
+	"Reflect": true, "RegExp": true, "Set": true, "String": true, "Symbol": true,
+	"SyntaxError": true, "TypeError": true, "URIError": true, "Uint8Array": true,
+	"Uint8ClampedArray": true, "Uint16Array": true, "Uint32Array": true,
+	"WeakMap": true, "WeakRef": true, "WeakSet": true,
+	"decodeURI": true, "decodeURIComponent": true, "encodeURI": true,
+	"encodeURIComponent": true, "isFinite": true, "isNaN": true,
+	"parseFloat": true, "parseInt": true, "structuredClone": true,
+	"queueMicrotask": true,
+	// Browser
+	"window": true, "document": true, "navigator": true, "location": true,
+	"history": true, "console": true, "localStorage": true,
+	"sessionStorage": true, "fetch": true, "Request": true, "Response": true,
+	"Headers": true, "FormData": true, "URL": true, "URLSearchParams": true,
+	"Blob": true, "File": true, "FileReader": true, "Event": true,
+	"CustomEvent": true, "EventSource": true, "WebSocket": true,
+	"AbortController": true, "setTimeout": true, "clearTimeout": true,
+	"setInterval": true, "clearInterval": true, "requestAnimationFrame": true,
+	"cancelAnimationFrame": true, "alert": true, "confirm": true, "prompt": true,
+	"performance": true, "crypto": true, "atob": true, "btoa": true,
+	"HTMLElement": true, "Element": true, "Node": true, "customElements": true,
+	"IntersectionObserver": true, "ResizeObserver": true,
+	"MutationObserver": true, "matchMedia": true, "getComputedStyle": true,
+	"TextEncoder": true, "TextDecoder": true,
+}
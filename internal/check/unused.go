@@ -0,0 +1,330 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/livebud/duo/internal/ast"
+	"github.com/livebud/duo/internal/scope"
+	"github.com/livebud/duo/internal/style"
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// unusedSelectors warns about selectors that don't match any element
+func unusedSelectors(path string, doc *ast.Document) (diagnostics []*Diagnostic) {
+	unused := style.Unused(doc)
+	if len(unused) == 0 {
+		return nil
+	}
+	node, _ := doc.Style()
+	selectors := sourceSelectors(node.Code)
+	for _, selector := range unused {
+		diagnostic := &Diagnostic{
+			Path:     path,
+			Severity: Warning,
+			Code:     "css_unused_selector",
+			Message:  fmt.Sprintf("Unused CSS selector %q", selector.String()),
+		}
+		// The stylesheet doesn't keep positions, so find the selector in the CSS
+		if offset, ok := selectors.take(selector.String()); ok {
+			pos := node.CodePos.Advance(node.Code[:offset])
+			diagnostic.Line, diagnostic.Column = pos.Line, pos.Column
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return diagnostics
+}
+
+// selectorOffsets maps normalized selectors to their offsets in the CSS, in
+// the order they appear
+type selectorOffsets map[string][]int
+
+// take the offset of the next occurrence of the selector
+func (s selectorOffsets) take(selector string) (int, bool) {
+	key := normalizeSelector(selector)
+	offsets := s[key]
+	if len(offsets) == 0 {
+		return 0, false
+	}
+	s[key] = offsets[1:]
+	return offsets[0], true
+}
+
+// sourceSelectors finds the selectors in the CSS source. Selectors are the
+// comma-separated parts of the text before each `{`, skipping at-rules.
+func sourceSelectors(code string) selectorOffsets {
+	code = blankComments(code)
+	selectors := selectorOffsets{}
+	start := 0
+	for i := 0; i < len(code); i++ {
+		switch code[i] {
+		case '{':
+			if prelude := code[start:i]; !strings.HasPrefix(strings.TrimSpace(prelude), "@") {
+				offset := start
+				for _, part := range splitSelectors(prelude) {
+					trimmed := strings.TrimLeft(part, " \t\r\n")
+					key := normalizeSelector(trimmed)
+					selectors[key] = append(selectors[key], offset+len(part)-len(trimmed))
+					offset += len(part) + 1
+				}
+			}
+			start = i + 1
+		case '}', ';':
+			start = i + 1
+		}
+	}
+	return selectors
+}
+
+// blankComments replaces comments with spaces, keeping the offsets the same
+func blankComments(code string) string {
+	out := []byte(code)
+	for offset := 0; ; {
+		start := strings.Index(code[offset:], "/*")
+		if start < 0 {
+			return string(out)
+		}
+		start += offset
+		end := strings.Index(code[start+2:], "*/")
+		if end < 0 {
+			end = len(code)
+		} else {
+			end += start + 4
+		}
+		for i := start; i < end; i++ {
+			if out[i] != '\n' {
+				out[i] = ' '
+			}
+		}
+		offset = end
+	}
+}
+
+// splitSelectors splits a selector list on commas outside of parentheses
+func splitSelectors(list string) (parts []string) {
+	start, parens := 0, 0
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '(':
+			parens++
+		case ')':
+			parens--
+		case ',':
+			if parens == 0 {
+				parts = append(parts, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, list[start:])
+}
+
+// normalizeSelector collapses whitespace so selectors written differently
+// compare equal, like `ul>li` and `ul > li`
+func normalizeSelector(selector string) string {
+	selector = strings.Join(strings.Fields(selector), " ")
+	for _, combinator := range []string{">", "+", "~"} {
+		selector = strings.ReplaceAll(selector, " "+combinator, combinator)
+		selector = strings.ReplaceAll(selector, combinator+" ", combinator)
+	}
+	return selector
+}
+
+// unusedVariables warns about top-level script variables that are never
+// referenced in the script or the markup. Props are part of the component's
+// API, so they're never unused.
+func unusedVariables(path string, doc *ast.Document) (diagnostics []*Diagnostic) {
+	script, ok := doc.Script()
+	if !ok || script.Program == nil {
+		return nil
+	}
+	refs := references{}
+	js.Walk(refs, script.Program)
+	refs.markup(doc)
+	for _, name := range declarations(script.Program) {
+		if refs[name] > 0 {
+			continue
+		}
+		if sym, ok := doc.Scope.LookupByName(name); ok && isProp(sym) {
+			continue
+		}
+		diagnostic := &Diagnostic{
+			Path:     path,
+			Severity: Warning,
+			Code:     "unused_variable",
+			Message:  fmt.Sprintf("%q is declared but never used", name),
+		}
+		// Unused variables are never referenced, so the first occurrence of the
+		// name in the script is where it's declared
+		if offset, ok := identifierOffset(script.Code, name); ok {
+			pos := script.CodePos.Advance(script.Code[:offset])
+			diagnostic.Line, diagnostic.Column = pos.Line, pos.Column
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return diagnostics
+}
+
+// identifierOffset returns the offset of the first occurrence of the
+// identifier in the code that isn't part of a longer name or a property
+func identifierOffset(code, name string) (int, bool) {
+	for offset := 0; offset < len(code); {
+		i := strings.Index(code[offset:], name)
+		if i < 0 {
+			return 0, false
+		}
+		start, end := offset+i, offset+i+len(name)
+		if (start == 0 || !isIdentifierPart(code[start-1]) && code[start-1] != '.') &&
+			(end == len(code) || !isIdentifierPart(code[end])) {
+			return start, true
+		}
+		offset = end
+	}
+	return 0, false
+}
+
+func isIdentifierPart(b byte) bool {
+	return b == '_' || b == '$' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b >= 0x80
+}
+
+func isProp(sym *scope.Symbol) bool {
+	return sym.IsExported() || sym.Rune == scope.RuneProps || sym.Rune == scope.RuneBindable
+}
+
+// declarations returns the names declared at the top-level of the program.
+// Exported declarations are props, so they're skipped.
+func declarations(program *js.AST) (names []string) {
+	for _, stmt := range program.List {
+		switch s := stmt.(type) {
+		case *js.VarDecl:
+			for _, element := range s.List {
+				names = append(names, bindingNames(element.Binding)...)
+			}
+		case *js.FuncDecl:
+			if s.Name != nil {
+				names = append(names, string(s.Name.Data))
+			}
+		case *js.ImportStmt:
+			if s.Default != nil {
+				names = append(names, string(s.Default))
+			}
+			for _, alias := range s.List {
+				if alias.Binding != nil {
+					names = append(names, string(alias.Binding))
+				} else if alias.Name != nil {
+					names = append(names, string(alias.Name))
+				}
+			}
+		}
+	}
+	return names
+}
+
+func bindingNames(binding js.IBinding) (names []string) {
+	switch b := binding.(type) {
+	case *js.Var:
+		names = append(names, string(b.Data))
+	case *js.BindingObject:
+		for _, item := range b.List {
+			names = append(names, bindingNames(item.Value.Binding)...)
+		}
+		if b.Rest != nil {
+			names = append(names, string(b.Rest.Data))
+		}
+	case *js.BindingArray:
+		for _, element := range b.List {
+			names = append(names, bindingNames(element.Binding)...)
+		}
+		if b.Rest != nil {
+			names = append(names, bindingNames(b.Rest)...)
+		}
+	}
+	return names
+}
+
+// references counts the variables referenced by name. The bindings being
+// declared aren't references, so they're skipped.
+type references map[string]int
+
+func (r references) Enter(node js.INode) js.IVisitor {
+	switch n := node.(type) {
+	case *js.Var:
+		r[string(n.Data)]++
+	case *js.VarDecl:
+		for _, element := range n.List {
+			r.bindingDefaults(element.Binding)
+			js.Walk(r, element.Default)
+		}
+		return nil
+	case *js.FuncDecl:
+		js.Walk(r, &n.Params)
+		js.Walk(r, &n.Body)
+		return nil
+	}
+	return r
+}
+
+func (r references) Exit(js.INode) {}
+
+// bindingDefaults walks the default values within a binding pattern
+func (r references) bindingDefaults(binding js.IBinding) {
+	switch b := binding.(type) {
+	case *js.BindingObject:
+		for _, item := range b.List {
+			if item.Key != nil && item.Key.IsComputed() {
+				js.Walk(r, item.Key.Computed)
+			}
+			r.bindingDefaults(item.Value.Binding)
+			js.Walk(r, item.Value.Default)
+		}
+	case *js.BindingArray:
+		for _, element := range b.List {
+			r.bindingDefaults(element.Binding)
+			js.Walk(r, element.Default)
+		}
+	}
+}
+
+// markup counts the variables referenced in the markup
+func (r references) markup(doc *ast.Document) {
+	ast.Inspect(doc, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.Mustache:
+			js.Walk(r, n.Expr)
+		case *ast.HTML:
+			js.Walk(r, n.Expr)
+		case *ast.Element:
+			r.attributes(n.Attributes)
+		case *ast.Component:
+			r[n.Name]++
+			r.attributes(n.Attributes)
+		case *ast.Slot:
+			r.attributes(n.Attributes)
+		case *ast.IfBlock:
+			js.Walk(r, n.Cond)
+		case *ast.EachBlock:
+			js.Walk(r, n.List)
+		case *ast.AwaitBlock:
+			js.Walk(r, n.Promise)
+		}
+		return true
+	})
+}
+
+func (r references) attributes(attrs []ast.Attribute) {
+	for _, attr := range attrs {
+		if shorthand, ok := attr.(*ast.AttributeShorthand); ok {
+			r[shorthand.Key]++
+			continue
+		}
+		r.values(attributeValues(attr)...)
+	}
+}
+
+func (r references) values(values ...ast.Value) {
+	for _, value := range values {
+		if mustache, ok := value.(*ast.Mustache); ok {
+			js.Walk(r, mustache.Expr)
+		}
+	}
+}
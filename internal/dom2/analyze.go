@@ -0,0 +1,652 @@
+package dom
+
+import (
+	"github.com/livebud/duo/internal/ast"
+	duojs "github.com/livebud/duo/internal/js"
+	"github.com/livebud/duo/internal/scope"
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// kind of variable, which decides how it's read and written
+type kind uint8
+
+const (
+	normal kind = iota
+	imported
+	state        // $state
+	rawState     // $state.raw
+	derived      // $derived and $derived.by
+	prop         // destructured from $props()
+	bindableProp // destructured from $props() with $bindable
+	restProp     // rest of $props()
+	eachItem     // item of an each block
+	eachIndex    // index of an each block
+	slotProp     // slot prop declared with let:
+	awaitValue   // resolved value or error of an await block
+)
+
+// binding is a variable declared by the component
+type binding struct {
+	name       string
+	kind       kind
+	init       js.IExpr // initial value or the prop's default
+	key        string   // key within $$props for props, or the slot prop
+	slotProps  string   // param holding the slot props
+	fn         js.IExpr // function that might be hoisted as an event handler
+	reassigned bool
+	mutated    bool
+	handler    bool // used as a delegated event handler
+	escapes    bool // referenced outside of delegated event handlers
+	hoisted    bool
+	params     []string // extra params of the hoisted function
+	settled    bool     // dynamic has been decided
+	dynamic    bool     // reading the binding could change over time
+	accessor   bool     // prop exposed as an accessor on a custom element
+}
+
+// isSignal returns true if reads and writes go through the runtime
+func (b *binding) isSignal() bool {
+	switch b.kind {
+	case state, rawState:
+		return b.reassigned
+	case derived:
+		return true
+	case prop, bindableProp:
+		return b.isSource()
+	}
+	return false
+}
+
+// isSource returns true for props that are read through a getter created with
+// $.prop, rather than from $$props directly
+func (b *binding) isSource() bool {
+	return (b.kind == prop || b.kind == bindableProp) && (b.init != nil || b.reassigned || b.mutated || b.accessor)
+}
+
+// settle decides whether reading the binding could change over time, once
+// every write to it is known. State that's never written to and values derived
+// from static state are read once, rather than in an effect.
+func (c *component) settle(b *binding) bool {
+	if b.settled {
+		return b.dynamic
+	}
+	// Bindings that depend on themselves are dynamic
+	b.settled, b.dynamic = true, true
+	switch b.kind {
+	case normal:
+		b.dynamic = b.reassigned
+	case imported:
+		b.dynamic = false
+	case state:
+		// Objects are proxied, so they can change without being written to
+		b.dynamic = b.reassigned || b.mutated || !isPrimitive(b.init)
+	case rawState:
+		b.dynamic = b.reassigned
+	case derived:
+		b.dynamic = isFunction(b.init) || hasCall(b.init) || c.isDynamic(b.init)
+	}
+	return b.dynamic
+}
+
+// lookup the binding a variable refers to. Variables in the script are linked
+// to their declarations, while variables in the template are matched by name
+// against the each blocks and then the script.
+func (c *component) lookup(v *js.Var) *binding {
+	for v.Link != nil {
+		v = v.Link
+	}
+	if b, ok := c.bindings[v]; ok {
+		return b
+	}
+	if v.Decl != js.NoDecl {
+		return nil
+	}
+	name := string(v.Data)
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if b, ok := c.scopes[i][name]; ok {
+			return b
+		}
+	}
+	return c.byName[name]
+}
+
+// declare a top-level binding
+func (c *component) declare(v *js.Var, kind kind, init js.IExpr) *binding {
+	b := &binding{name: string(v.Data), kind: kind, init: init}
+	c.bindings[v] = b
+	c.byName[b.name] = b
+	c.order = append(c.order, b)
+	return b
+}
+
+// collect the top-level bindings of the script
+func (c *component) collect(program *js.AST) {
+	for _, stmt := range program.List {
+		switch s := stmt.(type) {
+		case *js.ImportStmt:
+			if s.Default != nil {
+				c.declare(id(string(s.Default)), imported, nil)
+			}
+			for _, alias := range s.List {
+				if alias.Binding != nil {
+					c.declare(id(string(alias.Binding)), imported, nil)
+				}
+			}
+		case *js.FuncDecl:
+			if s.Name != nil {
+				c.declare(s.Name, normal, nil).fn = s
+			}
+		case *js.ClassDecl:
+			if s.Name != nil {
+				c.declare(s.Name, normal, nil)
+			}
+		case *js.VarDecl:
+			c.collectVarDecl(s, false)
+		case *js.ExportStmt:
+			switch decl := s.Decl.(type) {
+			case *js.VarDecl:
+				c.collectVarDecl(decl, decl.TokenType != js.ConstToken)
+			case *js.FuncDecl:
+				if decl.Name != nil {
+					c.declare(decl.Name, normal, nil)
+				}
+			case *js.ClassDecl:
+				if decl.Name != nil {
+					c.declare(decl.Name, normal, nil)
+				}
+			}
+		}
+	}
+	// Link the bindings to the script's variables, since imports aren't
+	// declared with a *js.Var in the AST
+	for _, v := range program.Scope.Declared {
+		if b, ok := c.byName[string(v.Data)]; ok {
+			c.bindings[v] = b
+		}
+	}
+}
+
+func (c *component) collectVarDecl(decl *js.VarDecl, exported bool) {
+	for _, element := range decl.List {
+		r, _ := scope.RuneOf(element.Default)
+		switch r {
+		case scope.RuneState, scope.RuneStateRaw, scope.RuneDerived, scope.RuneDerivedBy:
+			v, ok := element.Binding.(*js.Var)
+			if !ok {
+				c.errorf("%s can't be destructured", r)
+				continue
+			}
+			k := state
+			switch r {
+			case scope.RuneStateRaw:
+				k = rawState
+			case scope.RuneDerived, scope.RuneDerivedBy:
+				k = derived
+			}
+			c.declare(v, k, firstArg(element.Default))
+		case scope.RuneProps:
+			c.collectProps(element.Binding)
+		default:
+			if exported {
+				if v, ok := element.Binding.(*js.Var); ok {
+					b := c.declare(v, prop, element.Default)
+					b.key = b.name
+					continue
+				}
+			}
+			for _, v := range bindingVars(element.Binding) {
+				b := c.declare(v, normal, nil)
+				if v == element.Binding {
+					b.init = element.Default
+					if len(decl.List) == 1 && isFunction(element.Default) {
+						b.fn = element.Default
+					}
+				}
+			}
+		}
+	}
+}
+
+// collectProps declares the props destructured from $props()
+func (c *component) collectProps(pattern js.IBinding) {
+	switch p := pattern.(type) {
+	case *js.Var:
+		c.declare(p, restProp, nil)
+	case *js.BindingObject:
+		for _, item := range p.List {
+			v, ok := item.Value.Binding.(*js.Var)
+			if !ok || item.Key == nil || item.Key.IsComputed() {
+				c.errorf("props must be destructured into variables")
+				continue
+			}
+			k, init := prop, item.Value.Default
+			if r, ok := scope.RuneOf(init); ok && r == scope.RuneBindable {
+				k, init = bindableProp, firstArg(init)
+			}
+			b := c.declare(v, k, init)
+			b.key = propertyKey(item.Key)
+		}
+		if p.Rest != nil {
+			c.declare(p.Rest, restProp, nil)
+		}
+	default:
+		c.errorf("$props() must be destructured with an object pattern")
+	}
+}
+
+// analyze the script and template, marking how bindings are used
+func (c *component) analyze(doc *ast.Document, program *js.AST) {
+	if program != nil {
+		rewriter(c.visit).block(&program.BlockStmt)
+	}
+	c.analyzeFragments(doc.Children)
+	// Custom elements can update their props through accessors
+	if c.customElement != "" {
+		c.needsContext = true
+		for _, b := range c.declaredProps() {
+			b.accessor = true
+		}
+	}
+	// Settle the bindings before the script is rewritten
+	for _, b := range c.order {
+		c.settle(b)
+	}
+	for _, b := range c.order {
+		if b.kind == prop || b.kind == bindableProp || b.kind == restProp {
+			c.needsProps = true
+		}
+	}
+	if c.needsContext {
+		c.needsProps = true
+	}
+}
+
+// visit an expression during analysis, returning nil to keep descending
+func (c *component) visit(expr js.IExpr) js.IExpr {
+	if _, ok := scope.RuneName(expr); ok {
+		c.runes = true
+	}
+	switch e := expr.(type) {
+	case *js.Var:
+		if b := c.lookup(e); b != nil {
+			if b.fn != nil {
+				b.escapes = true
+			}
+		} else if string(e.Data) == "$$props" {
+			c.needsProps = true
+		} else if string(e.Data) == "$$slots" {
+			c.needsProps = true
+			c.needsSlots = true
+		}
+	case *js.BinaryExpr:
+		if isAssignment(e.Op) {
+			c.markAssigned(e.X)
+		}
+	case *js.UnaryExpr:
+		if isUpdate(e.Op) {
+			c.markAssigned(e.X)
+		}
+	case *js.DotExpr, *js.IndexExpr:
+		if !c.isSafe(e) {
+			c.needsContext = true
+		}
+	case *js.CallExpr:
+		if r, ok := scope.RuneOf(e); ok && (r == scope.RuneEffect || r == scope.RuneEffectPre) {
+			c.needsContext = true
+		}
+	}
+	return nil
+}
+
+// isSafe returns true if a member expression doesn't read from props or
+// imports, which need the component context
+func (c *component) isSafe(expr js.IExpr) bool {
+	v, ok := rootOf(expr).(*js.Var)
+	if !ok {
+		return false
+	}
+	b := c.lookup(v)
+	if b == nil {
+		return true
+	}
+	return b.kind != imported && b.kind != prop && b.kind != bindableProp && b.kind != restProp
+}
+
+// markAssigned marks the bindings written to by an assignment or update
+func (c *component) markAssigned(target js.IExpr) {
+	switch t := target.(type) {
+	case *js.Var:
+		if b := c.lookup(t); b != nil {
+			b.reassigned = true
+		}
+	case *js.DotExpr, *js.IndexExpr:
+		if v, ok := rootOf(t).(*js.Var); ok {
+			if b := c.lookup(v); b != nil {
+				b.mutated = true
+			}
+		}
+	case *js.GroupExpr:
+		c.markAssigned(t.X)
+	case *js.ArrayExpr:
+		for _, element := range t.List {
+			c.markAssigned(element.Value)
+		}
+	case *js.ObjectExpr:
+		for _, property := range t.List {
+			c.markAssigned(property.Value)
+		}
+	case *js.BinaryExpr:
+		// Defaults within a destructuring assignment
+		if t.Op == js.EqToken {
+			c.markAssigned(t.X)
+		}
+	}
+}
+
+func (c *component) analyzeFragments(nodes []ast.Fragment) {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *ast.Mustache:
+			c.analyzeExpr(n.Expr)
+		case *ast.HTML:
+			c.analyzeExpr(n.Expr)
+		case *ast.Element:
+			c.analyzeAttributes(n.Attributes, true)
+			c.analyzeFragments(n.Children)
+		case *ast.Component:
+			c.analyzeAttributes(n.Attributes, false)
+			for _, content := range slotContents(n) {
+				c.scopes = append(c.scopes, c.slotScope(content.lets, ""))
+				c.analyzeFragments(content.nodes)
+				c.scopes = c.scopes[:len(c.scopes)-1]
+			}
+		case *ast.Slot:
+			c.needsProps = true
+			c.analyzeAttributes(n.Attributes, false)
+			c.analyzeFragments(n.Fallback)
+		case *ast.IfBlock:
+			c.analyzeExpr(n.Cond)
+			c.analyzeFragments(n.Then)
+			c.analyzeFragments(n.Else)
+		case *ast.EachBlock:
+			c.analyzeExpr(n.List)
+			// Bindings haven't settled yet, so assume the items change
+			c.scopes = append(c.scopes, c.eachScope(n, true))
+			c.analyzeFragments(n.Body)
+			c.scopes = c.scopes[:len(c.scopes)-1]
+			c.analyzeFragments(n.Else)
+		case *ast.AwaitBlock:
+			c.analyzeExpr(n.Promise)
+			c.analyzeFragments(n.Pending)
+			c.scopes = append(c.scopes, awaitScope(n.Value))
+			c.analyzeFragments(n.Then)
+			c.scopes[len(c.scopes)-1] = awaitScope(n.Error)
+			c.analyzeFragments(n.Catch)
+			c.scopes = c.scopes[:len(c.scopes)-1]
+		}
+	}
+}
+
+func (c *component) analyzeAttributes(attrs []ast.Attribute, element bool) {
+	for _, attr := range attrs {
+		switch a := attr.(type) {
+		case *ast.Field:
+			// Forwarded events are passed to the handlers in $$props.$$events
+			if a.Forwards() {
+				c.needsProps = true
+			}
+			if element && isEventHandler(a) && len(a.Modifiers) == 0 {
+				_, _, delegated := parseEvent(a.Key)
+				if v, ok := a.Values[0].(*ast.Mustache).Expr.(*js.Var); ok && delegated {
+					if b := c.lookup(v); b != nil {
+						b.handler = true
+						continue
+					}
+				}
+			}
+			c.analyzeValues(a.Values)
+		case *ast.AttributeShorthand:
+			c.analyzeExpr(id(a.Key))
+		case *ast.Binding:
+			if m, ok := a.Value.(*ast.Mustache); ok {
+				c.markAssigned(m.Expr)
+				c.analyzeExpr(m.Expr)
+			}
+		case *ast.Class:
+			c.analyzeValues([]ast.Value{a.Value})
+		case *ast.StyleDirective:
+			c.analyzeValues(a.Values)
+		}
+	}
+}
+
+func (c *component) analyzeValues(values []ast.Value) {
+	for _, value := range values {
+		if m, ok := value.(*ast.Mustache); ok {
+			c.analyzeExpr(m.Expr)
+		}
+	}
+}
+
+func (c *component) analyzeExpr(expr js.IExpr) {
+	rewriter(c.visit).expr(expr)
+}
+
+// eachScope declares the item and index of an each block. They only change
+// when the list is dynamic.
+func (c *component) eachScope(node *ast.EachBlock, dynamic bool) map[string]*binding {
+	bindings := map[string]*binding{}
+	if node.Value != nil {
+		name := string(node.Value.Data)
+		bindings[name] = &binding{name: name, kind: eachItem, settled: true, dynamic: dynamic}
+	}
+	if node.Key != nil {
+		name := string(node.Key.Data)
+		bindings[name] = &binding{name: name, kind: eachIndex, settled: true, dynamic: dynamic}
+	}
+	return bindings
+}
+
+// slotScope declares the slot props of slot content, which are read from the
+// slotProps param. Slot props are getters, so they may change.
+func (c *component) slotScope(lets []*ast.Let, slotProps string) map[string]*binding {
+	bindings := map[string]*binding{}
+	for _, let := range lets {
+		name := let.Var()
+		bindings[name] = &binding{name: name, kind: slotProp, key: let.Name, slotProps: slotProps, settled: true, dynamic: true}
+	}
+	return bindings
+}
+
+// awaitScope declares the value or error of an await block, which is a source
+// that's set when the promise settles
+func awaitScope(v *js.Var) map[string]*binding {
+	bindings := map[string]*binding{}
+	if v != nil {
+		name := string(v.Data)
+		bindings[name] = &binding{name: name, kind: awaitValue, settled: true, dynamic: true}
+	}
+	return bindings
+}
+
+// hoist decides which functions can be moved out of the component, so
+// delegated event handlers can share them instead of creating a closure per
+// element
+func (c *component) hoist() {
+	for _, b := range c.order {
+		if b.fn == nil || !b.handler || b.escapes || b.reassigned || b.kind != normal {
+			continue
+		}
+		params, ok := c.hoistable(b.fn, b)
+		if !ok {
+			continue
+		}
+		b.hoisted = true
+		b.params = params
+	}
+}
+
+// hoistable returns the component variables a function references, which are
+// passed to it as extra params when it's hoisted
+func (c *component) hoistable(fn js.IExpr, self *binding) (params []string, ok bool) {
+	var fnParams js.Params
+	var body *js.BlockStmt
+	switch f := fn.(type) {
+	case *js.FuncDecl:
+		fnParams, body = f.Params, &f.Body
+	case *js.ArrowFunc:
+		fnParams, body = f.Params, &f.Body
+	default:
+		return nil, false
+	}
+	n := len(fnParams.List)
+	if fnParams.Rest != nil {
+		n++
+	}
+	if n > 1 {
+		return nil, false
+	}
+	ok = true
+	seen := map[string]bool{}
+	visit := func(expr js.IExpr) js.IExpr {
+		v, isVar := expr.(*js.Var)
+		if !isVar {
+			return nil
+		}
+		if v.Decl == js.NoDecl && string(v.Data) == "arguments" {
+			ok = false
+			return nil
+		}
+		b := c.lookup(v)
+		if b == nil || b == self || b.kind == imported {
+			return nil
+		}
+		switch {
+		case b.kind == eachItem || b.kind == eachIndex || b.kind == slotProp || b.kind == awaitValue:
+			ok = false
+		case b.kind == normal && b.reassigned:
+			ok = false
+		}
+		name := b.name
+		if (b.kind == prop || b.kind == bindableProp) && !b.isSource() {
+			name = "$$props"
+		}
+		if !seen[name] {
+			seen[name] = true
+			params = append(params, name)
+		}
+		return nil
+	}
+	rewriter(visit).params(&fnParams)
+	rewriter(visit).block(body)
+	if params != nil && contains(params, "$$props") {
+		c.needsProps = true
+	}
+	return params, ok
+}
+
+// rootOf returns the object at the root of a member expression
+func rootOf(expr js.IExpr) js.IExpr {
+	for {
+		switch e := expr.(type) {
+		case *js.DotExpr:
+			expr = e.X
+		case *js.IndexExpr:
+			expr = e.X
+		default:
+			return expr
+		}
+	}
+}
+
+// bindingVars returns the variables declared by a binding pattern
+func bindingVars(binding js.IBinding) (vars []*js.Var) {
+	switch b := binding.(type) {
+	case *js.Var:
+		vars = append(vars, b)
+	case *js.BindingArray:
+		for _, element := range b.List {
+			vars = append(vars, bindingVars(element.Binding)...)
+		}
+		vars = append(vars, bindingVars(b.Rest)...)
+	case *js.BindingObject:
+		for _, item := range b.List {
+			vars = append(vars, bindingVars(item.Value.Binding)...)
+		}
+		if b.Rest != nil {
+			vars = append(vars, b.Rest)
+		}
+	}
+	return vars
+}
+
+func firstArg(expr js.IExpr) js.IExpr {
+	call, ok := expr.(*js.CallExpr)
+	if !ok || len(call.Args.List) == 0 {
+		return nil
+	}
+	return call.Args.List[0].Value
+}
+
+func propertyKey(name *js.PropertyName) string {
+	if name.Literal.TokenType == js.StringToken {
+		if key, err := duojs.Unquote(string(name.Literal.Data)); err == nil {
+			return key
+		}
+	}
+	return string(name.Literal.Data)
+}
+
+// isPrimitive returns true for values that can't change without being
+// reassigned, like strings and numbers
+func isPrimitive(expr js.IExpr) bool {
+	switch e := expr.(type) {
+	case nil:
+		return true
+	case *js.LiteralExpr:
+		return e.TokenType != js.RegExpToken
+	case *js.UnaryExpr:
+		return isPrimitive(e.X)
+	case *js.TemplateExpr:
+		return e.Tag == nil && len(e.List) == 0
+	case *js.Var:
+		return string(e.Data) == "undefined"
+	}
+	return false
+}
+
+func isFunction(expr js.IExpr) bool {
+	switch expr.(type) {
+	case *js.ArrowFunc, *js.FuncDecl:
+		return true
+	}
+	return false
+}
+
+func isAssignment(op js.TokenType) bool {
+	switch op {
+	case js.EqToken, js.AddEqToken, js.SubEqToken, js.MulEqToken, js.DivEqToken,
+		js.ModEqToken, js.ExpEqToken, js.LtLtEqToken, js.GtGtEqToken, js.GtGtGtEqToken,
+		js.BitAndEqToken, js.BitOrEqToken, js.BitXorEqToken, js.AndEqToken,
+		js.OrEqToken, js.NullishEqToken:
+		return true
+	}
+	return false
+}
+
+func isUpdate(op js.TokenType) bool {
+	switch op {
+	case js.IncrToken, js.DecrToken, js.PreIncrToken, js.PreDecrToken, js.PostIncrToken, js.PostDecrToken:
+		return true
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package dom
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// Helpers for building the JavaScript AST
+
+func id(name string) *js.Var {
+	return &js.Var{Data: []byte(name)}
+}
+
+// runtime returns a member of the Svelte runtime, like $.template
+func runtime(name string) js.IExpr {
+	return member(id("$"), name)
+}
+
+func member(object js.IExpr, name string) *js.DotExpr {
+	return &js.DotExpr{
+		X: object,
+		Y: js.LiteralExpr{TokenType: js.IdentifierToken, Data: []byte(name)},
+	}
+}
+
+func call(callee js.IExpr, args ...js.IExpr) *js.CallExpr {
+	list := make([]js.Arg, len(args))
+	for i, arg := range args {
+		list[i] = js.Arg{Value: arg}
+	}
+	return &js.CallExpr{X: callee, Args: js.Args{List: list}}
+}
+
+func str(value string) *js.LiteralExpr {
+	return &js.LiteralExpr{TokenType: js.StringToken, Data: []byte(quote(value))}
+}
+
+func num(n int) *js.LiteralExpr {
+	return &js.LiteralExpr{TokenType: js.DecimalToken, Data: []byte(strconv.Itoa(n))}
+}
+
+func boolean(b bool) *js.LiteralExpr {
+	if b {
+		return &js.LiteralExpr{TokenType: js.TrueToken, Data: []byte("true")}
+	}
+	return &js.LiteralExpr{TokenType: js.FalseToken, Data: []byte("false")}
+}
+
+func null() *js.LiteralExpr {
+	return &js.LiteralExpr{TokenType: js.NullToken, Data: []byte("null")}
+}
+
+// quote a string as a JavaScript string literal
+func quote(s string) string {
+	out := new(strings.Builder)
+	out.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\u2028', '\u2029':
+			out.WriteString(`\u` + strconv.FormatInt(int64(r), 16))
+		default:
+			out.WriteRune(r)
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
+
+// blockStmt returns a block statement that prints its braces
+func blockStmt(stmts ...js.IStmt) js.BlockStmt {
+	return js.BlockStmt{List: stmts, Scope: js.Scope{Parent: &js.Scope{}}}
+}
+
+func params(names ...string) js.Params {
+	list := make([]js.BindingElement, len(names))
+	for i, name := range names {
+		list[i] = js.BindingElement{Binding: id(name)}
+	}
+	return js.Params{List: list}
+}
+
+func arrow(params js.Params, body ...js.IStmt) *js.ArrowFunc {
+	return &js.ArrowFunc{Params: params, Body: blockStmt(body...)}
+}
+
+// lambda returns an arrow function returning the expression
+func lambda(params js.Params, expr js.IExpr) *js.ArrowFunc {
+	return arrow(params, &js.ReturnStmt{Value: expr})
+}
+
+// thunk returns a function that evaluates the expression. Calls without
+// arguments, like `() => count()`, are simplified to the callee.
+func thunk(expr js.IExpr) js.IExpr {
+	if c, ok := expr.(*js.CallExpr); ok && len(c.Args.List) == 0 && !c.Optional {
+		if _, ok := c.X.(*js.Var); ok {
+			return c.X
+		}
+	}
+	return lambda(js.Params{}, expr)
+}
+
+func varDecl(name string, value js.IExpr) *js.VarDecl {
+	return &js.VarDecl{
+		TokenType: js.VarToken,
+		List:      []js.BindingElement{{Binding: id(name), Default: value}},
+	}
+}
+
+func exprStmt(expr js.IExpr) *js.ExprStmt {
+	return &js.ExprStmt{Value: expr}
+}
+
+func assign(target, value js.IExpr) *js.BinaryExpr {
+	return &js.BinaryExpr{Op: js.EqToken, X: target, Y: value}
+}
+
+// group wraps expressions in parentheses where they'd otherwise bind
+// differently, since the AST prints without them
+func group(expr js.IExpr) js.IExpr {
+	switch expr.(type) {
+	case *js.BinaryExpr, *js.CondExpr, *js.ArrowFunc, *js.CommaExpr, *js.YieldExpr, *js.UnaryExpr, *js.FuncDecl, *js.ClassDecl:
+		return &js.GroupExpr{X: expr}
+	}
+	return expr
+}
+
+// templateLiteral returns a template literal interleaving the quasis with the
+// expressions. There's always one more quasi than expressions.
+func templateLiteral(quasis []string, exprs []js.IExpr) *js.TemplateExpr {
+	literal := &js.TemplateExpr{}
+	for i, expr := range exprs {
+		prefix := "}"
+		if i == 0 {
+			prefix = "`"
+		}
+		literal.List = append(literal.List, js.TemplatePart{
+			Value: []byte(prefix + escapeTemplate(quasis[i]) + "${"),
+			Expr:  expr,
+		})
+	}
+	prefix := "}"
+	if len(exprs) == 0 {
+		prefix = "`"
+	}
+	literal.Tail = []byte(prefix + escapeTemplate(quasis[len(quasis)-1]) + "`")
+	return literal
+}
+
+// escapeTemplate escapes text within a template literal
+func escapeTemplate(s string) string {
+	if !utf8.ValidString(s) {
+		s = strings.ToValidUTF8(s, "�")
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "`", "\\`")
+	return strings.ReplaceAll(s, "${", "\\${")
+}
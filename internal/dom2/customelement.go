@@ -0,0 +1,81 @@
+package dom
+
+import (
+	"github.com/livebud/duo/internal/ast"
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// declaredProps returns the bindings of the props declared by the component, in source
+// order
+func (c *component) declaredProps() (props []*binding) {
+	for _, b := range c.order {
+		if b.kind == prop || b.kind == bindableProp {
+			props = append(props, b)
+		}
+	}
+	return props
+}
+
+// pop returns the component's context, exposing the props as accessors on
+// custom elements
+func (c *component) pop() js.IStmt {
+	if c.customElement == "" {
+		return exprStmt(call(runtime("pop")))
+	}
+	accessors := &js.ObjectExpr{}
+	for _, b := range c.declaredProps() {
+		value := &js.BindingElement{Binding: id("$$value")}
+		if c.runes && b.init != nil {
+			value.Default = b.init
+		}
+		accessors.List = append(accessors.List,
+			js.Property{Value: &js.MethodDecl{Get: true, Name: *propertyName(b.key), Body: blockStmt(&js.ReturnStmt{Value: call(id(b.name))})}},
+			js.Property{Value: &js.MethodDecl{Set: true, Name: *propertyName(b.key), Params: js.Params{List: []js.BindingElement{*value}}, Body: blockStmt(
+				exprStmt(call(id(b.name), id("$$value"))),
+				exprStmt(call(runtime("flush_sync"))),
+			)}},
+		)
+	}
+	return &js.ReturnStmt{Value: call(runtime("pop"), accessors)}
+}
+
+// styles returns the declaration of the component's CSS, which custom elements
+// append to their shadow root
+func (c *component) styles(style *ast.Style) js.IStmt {
+	return &js.VarDecl{
+		TokenType: js.ConstToken,
+		List: []js.BindingElement{{Binding: id("$$css"), Default: &js.ObjectExpr{List: []js.Property{
+			{Name: propertyName("hash"), Value: str(style.Scope)},
+			{Name: propertyName("code"), Value: str(style.StyleSheet.String())},
+		}}}},
+	}
+}
+
+// defineElement registers the component as a custom element, observing an
+// attribute for each prop
+func (c *component) defineElement(name string) js.IStmt {
+	props := &js.ObjectExpr{}
+	for _, b := range c.declaredProps() {
+		definition := &js.ObjectExpr{}
+		if b.init != nil && isBoolean(b.init) {
+			definition.List = append(definition.List, js.Property{Name: propertyName("type"), Value: str("Boolean")})
+		}
+		props.List = append(props.List, js.Property{Name: propertyName(b.key), Value: definition})
+	}
+	element := call(runtime("create_custom_element"), id(name), props, &js.ArrayExpr{}, &js.ArrayExpr{}, boolean(true))
+	return exprStmt(call(member(id("customElements"), "define"), str(c.customElement), element))
+}
+
+// stylesOf returns the document's style if it has CSS rules
+func stylesOf(doc *ast.Document) (*ast.Style, bool) {
+	style, ok := doc.Style()
+	if !ok || style.StyleSheet == nil || len(style.StyleSheet.Rules) == 0 {
+		return nil, false
+	}
+	return style, true
+}
+
+func isBoolean(expr js.IExpr) bool {
+	lit, ok := expr.(*js.LiteralExpr)
+	return ok && (lit.TokenType == js.TrueToken || lit.TokenType == js.FalseToken)
+}
@@ -1,9 +1,17 @@
 package dom
 
 import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+
 	"github.com/livebud/duo/internal/ast"
-	"github.com/livebud/duo/internal/js"
+	"github.com/livebud/duo/internal/check"
+	duojs "github.com/livebud/duo/internal/js"
 	"github.com/livebud/duo/internal/parser"
+	"github.com/tdewolff/parse/v2/js"
 )
 
 func Generate(path string, code []byte) (string, error) {
@@ -11,34 +19,346 @@ func Generate(path string, code []byte) (string, error) {
 	return generator.Generate(path, code)
 }
 
+// Generator generates client components for the Svelte 5 runtime
 type Generator struct {
+	// Warn is called with problems that don't stop generation, like
+	// misspelled event handlers
+	Warn func(*check.Diagnostic)
 }
 
 func (g *Generator) Generate(path string, code []byte) (string, error) {
-	doc, err := parser.Parse(path, string(code))
+	doc, err := g.parse(path, code)
 	if err != nil {
 		return "", err
 	}
-	return Print(doc)
+	return Print(path, doc)
 }
 
-func Print(doc *ast.Document) (string, error) {
-	program, err := Transform(doc)
+func (g *Generator) parse(path string, code []byte) (*ast.Document, error) {
+	doc, err := parser.Parse(path, string(code))
+	if err != nil {
+		return nil, err
+	}
+	if g.Warn != nil {
+		for _, diagnostic := range check.Events(path, doc) {
+			g.Warn(diagnostic)
+		}
+	}
+	return doc, nil
+}
+
+func Print(path string, doc *ast.Document) (string, error) {
+	program, err := Transform(path, doc)
 	if err != nil {
 		return "", err
 	}
-	code, err := js.Format(program)
+	code, err := duojs.Format(program)
 	if err != nil {
 		return "", err
 	}
 	return code, nil
 }
 
-func Transform(doc *ast.Document) (*js.AST, error) {
+// GenerateWithSourceMap generates the client code along with a source map that
+// points its nodes and expressions back to the component
+func GenerateWithSourceMap(path string, code []byte) (string, *SourceMap, error) {
+	generator := &Generator{}
+	return generator.GenerateWithSourceMap(path, code)
+}
+
+func (g *Generator) GenerateWithSourceMap(path string, code []byte) (string, *SourceMap, error) {
+	doc, err := g.parse(path, code)
+	if err != nil {
+		return "", nil, err
+	}
+	c := newComponent()
+	c.mapping = true
+	program, err := c.transform(path, doc)
+	if err != nil {
+		return "", nil, err
+	}
+	generated, mappings := unmark(program.JS(), c.positions)
+	formatted, sourceMap, err := duojs.FormatWithSourceMap(generated, mappings)
+	if err != nil {
+		return "", nil, err
+	}
+	return formatted, &SourceMap{
+		Version:        3,
+		Sources:        []string{path},
+		SourcesContent: []string{string(code)},
+		Names:          sourceMap.Names,
+		Mappings:       sourceMap.Mappings,
+	}, nil
+}
+
+// Transform the document into a module that exports the component as a
+// function, rendering with svelte/internal/client
+func Transform(path string, doc *ast.Document) (*js.AST, error) {
+	return newComponent().transform(path, doc)
+}
+
+func newComponent() *component {
+	return &component{
+		names:    map[string]bool{},
+		bindings: map[*js.Var]*binding{},
+		byName:   map[string]*binding{},
+		groups:   map[string]string{},
+	}
+}
+
+func (c *component) transform(path string, doc *ast.Document) (*js.AST, error) {
+	var program *js.AST
+	if script, ok := doc.Script(); ok && script.Program != nil {
+		program = script.Program
+	}
+	if options, ok := doc.Options(); ok {
+		c.customElement = options.CustomElement
+	}
+	c.reserve(doc, program)
+	if program != nil {
+		c.collect(program)
+	}
+	c.analyze(doc, program)
+	c.hoist()
+	name := c.generate(componentName(path))
+	c.name = name
+	var body []js.IStmt
+	// $$slots tells which slots the parent passed content into
+	if c.needsSlots {
+		body = append(body, &js.VarDecl{
+			TokenType: js.ConstToken,
+			List:      []js.BindingElement{{Binding: id("$$slots"), Default: call(runtime("sanitize_slots"), id("$$props"))}},
+		})
+	}
+	if program != nil {
+		body = append(body, c.script(program)...)
+	}
+	body = append(body, c.fragment(doc.Children, "")...)
+	if err := errors.Join(c.errs...); err != nil {
+		return nil, err
+	}
+	body = append(c.groupDecls, body...)
+	fnParams := params("$$anchor")
+	if c.needsProps {
+		fnParams = params("$$anchor", "$$props")
+	}
+	// Custom elements render into a shadow root, so they append their own styles
+	style, hasStyle := stylesOf(doc)
+	if hasStyle && c.customElement != "" {
+		body = append([]js.IStmt{exprStmt(call(runtime("append_styles"), id("$$anchor"), id("$$css")))}, body...)
+	}
+	if c.needsContext {
+		body = append([]js.IStmt{exprStmt(call(runtime("push"), id("$$props"), boolean(c.runes)))}, body...)
+		body = append(body, c.pop())
+	}
 	var stmts []js.IStmt
+	stmts = append(stmts, &js.ImportStmt{
+		List:   []js.Alias{{Name: []byte("*"), Binding: []byte("$")}},
+		Module: []byte(`"svelte/internal/client"`),
+	})
+	stmts = append(stmts, c.imports...)
+	stmts = append(stmts, c.hoisted...)
+	stmts = append(stmts, c.templates...)
+	if hasStyle && c.customElement != "" {
+		stmts = append(stmts, c.styles(style))
+	}
+	stmts = append(stmts, &js.ExportStmt{
+		Default: true,
+		Decl: &js.FuncDecl{
+			Name:   id(name),
+			Params: fnParams,
+			Body:   blockStmt(body...),
+		},
+	})
+	if len(c.delegated) > 0 {
+		events := &js.ArrayExpr{}
+		for _, event := range c.delegated {
+			events.List = append(events.List, js.Element{Value: str(event)})
+		}
+		stmts = append(stmts, exprStmt(call(runtime("delegate"), events)))
+	}
+	if c.customElement != "" {
+		stmts = append(stmts, c.defineElement(name))
+	}
 	return &js.AST{
 		BlockStmt: js.BlockStmt{
 			List: stmts,
 		},
 	}, nil
 }
+
+// component being generated
+type component struct {
+	name          string                // name of the component's function
+	names         map[string]bool       // identifiers that are taken
+	bindings      map[*js.Var]*binding  // script variables to their bindings
+	byName        map[string]*binding   // top-level bindings by name
+	order         []*binding            // top-level bindings in source order
+	scopes        []map[string]*binding // each block and slot scopes in the template
+	runes         bool                  // uses runes
+	needsProps    bool                  // reads $$props
+	needsSlots    bool                  // reads $$slots
+	needsContext  bool                  // needs $.push and $.pop
+	imports       []js.IStmt
+	hoisted       []js.IStmt // functions moved out of the component
+	templates     []js.IStmt
+	delegated     []string          // delegated events
+	groups        map[string]string // bind:group expressions to their binding group
+	groupDecls    []js.IStmt
+	customElement string // tag name when compiling to a custom element
+	errs          []error
+
+	// Source mapping
+	mapping   bool
+	positions []ast.Position // positions of the marked expressions
+}
+
+func (c *component) errorf(format string, args ...interface{}) {
+	c.errs = append(c.errs, fmt.Errorf("dom: "+format, args...))
+}
+
+// reserve the identifiers used in the script and template, so generated
+// identifiers don't conflict with them
+func (c *component) reserve(doc *ast.Document, program *js.AST) {
+	for _, name := range []string{"$", "$$props", "$$slots", "$$anchor", "$$css", "customElements"} {
+		c.names[name] = true
+	}
+	v := &reserver{c.names}
+	if program != nil {
+		js.Walk(v, program)
+	}
+	var fragments func(nodes []ast.Fragment)
+	values := func(attrs []ast.Attribute) {
+		for _, attr := range attrs {
+			switch a := attr.(type) {
+			case *ast.Field:
+				for _, value := range a.Values {
+					if m, ok := value.(*ast.Mustache); ok {
+						js.Walk(v, m.Expr)
+					}
+				}
+			case *ast.Binding:
+				if m, ok := a.Value.(*ast.Mustache); ok {
+					js.Walk(v, m.Expr)
+				}
+			case *ast.Class:
+				if m, ok := a.Value.(*ast.Mustache); ok {
+					js.Walk(v, m.Expr)
+				}
+			case *ast.StyleDirective:
+				for _, value := range a.Values {
+					if m, ok := value.(*ast.Mustache); ok {
+						js.Walk(v, m.Expr)
+					}
+				}
+			case *ast.AttributeShorthand:
+				c.names[a.Key] = true
+			case *ast.Let:
+				c.names[a.Var()] = true
+			}
+		}
+	}
+	fragments = func(nodes []ast.Fragment) {
+		for _, node := range nodes {
+			switch n := node.(type) {
+			case *ast.Mustache:
+				js.Walk(v, n.Expr)
+			case *ast.HTML:
+				js.Walk(v, n.Expr)
+			case *ast.Element:
+				values(n.Attributes)
+				fragments(n.Children)
+			case *ast.Component:
+				values(n.Attributes)
+				fragments(n.Children)
+			case *ast.Slot:
+				values(n.Attributes)
+				fragments(n.Fallback)
+			case *ast.IfBlock:
+				js.Walk(v, n.Cond)
+				fragments(n.Then)
+				fragments(n.Else)
+			case *ast.EachBlock:
+				js.Walk(v, n.List)
+				if n.Value != nil {
+					js.Walk(v, n.Value)
+				}
+				if n.Key != nil {
+					js.Walk(v, n.Key)
+				}
+				fragments(n.Body)
+				fragments(n.Else)
+			case *ast.AwaitBlock:
+				js.Walk(v, n.Promise)
+				if n.Value != nil {
+					js.Walk(v, n.Value)
+				}
+				if n.Error != nil {
+					js.Walk(v, n.Error)
+				}
+				fragments(n.Pending)
+				fragments(n.Then)
+				fragments(n.Catch)
+			}
+		}
+	}
+	fragments(doc.Children)
+}
+
+type reserver struct {
+	names map[string]bool
+}
+
+func (r *reserver) Enter(node js.INode) js.IVisitor {
+	if v, ok := node.(*js.Var); ok {
+		r.names[string(v.Data)] = true
+	}
+	return r
+}
+
+func (r *reserver) Exit(node js.INode) {}
+
+// generate a unique identifier from the name
+func (c *component) generate(name string) string {
+	name = sanitize(name)
+	unique := name
+	for i := 1; c.names[unique]; i++ {
+		unique = fmt.Sprintf("%s_%d", name, i)
+	}
+	c.names[unique] = true
+	return unique
+}
+
+// sanitize the name into a valid identifier
+func sanitize(name string) string {
+	out := new(strings.Builder)
+	for i, r := range name {
+		if r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if i == 0 && unicode.IsDigit(r) {
+				out.WriteByte('_')
+			}
+			out.WriteRune(r)
+			continue
+		}
+		out.WriteByte('_')
+	}
+	if out.Len() == 0 {
+		return "_"
+	}
+	return out.String()
+}
+
+// componentName returns the name of the component from its path, like Input
+// for input.svelte. Index components are named after their directory.
+func componentName(filePath string) string {
+	base := path.Base(filePath)
+	name := strings.TrimSuffix(base, path.Ext(base))
+	if name == "index" {
+		if dir := path.Base(path.Dir(filePath)); dir != "." && dir != "/" {
+			name = dir
+		}
+	}
+	runes := []rune(sanitize(name))
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
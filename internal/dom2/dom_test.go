@@ -1,11 +1,15 @@
 package dom_test
 
 import (
+	"encoding/base64"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/ije/esbuild-internal/js_parser"
+	"github.com/ije/esbuild-internal/logger"
 	dom "github.com/livebud/duo/internal/dom2"
 	"github.com/livebud/duo/internal/js"
 	"github.com/matryer/is"
@@ -47,19 +51,445 @@ func Test(t *testing.T) {
 		}
 		t.Run(de.Name(), func(t *testing.T) {
 			is := is.New(t)
-			expect, err := os.ReadFile(filepath.Join(dir, de.Name(), "dom.js"))
+			expect, err := os.ReadFile(filepath.Join(dir, de.Name(), "_dom.js"))
 			if err != nil {
 				if os.IsNotExist(err) {
-					t.Skip("dom.js not found")
+					t.Skip("_dom.js not found")
 					return
 				}
 				t.Fatal(err)
 			}
 			input, err := os.ReadFile(filepath.Join(dir, de.Name(), "input.svelte"))
 			is.NoErr(err)
-			actual, err := dom.Generate(de.Name(), input)
+			actual, err := dom.Generate(filepath.Join(de.Name(), "input.svelte"), input)
 			is.NoErr(err)
 			equal(t, actual, string(expect))
 		})
 	}
 }
+
+func TestDirectives(t *testing.T) {
+	is := is.New(t)
+	actual, err := dom.Generate("badge.svelte", []byte(`<script>
+  let { kind = "info", size = 12 } = $props();
+  let active = $state(false);
+</script>
+
+<span class="badge" class:active>static</span>
+<span class="badge {kind}" class:active class:large={size > 16}>dynamic</span>
+<p style:font-size="{size}px" style:margin="0">text</p>`))
+	is.NoErr(err)
+	equal(t, actual, `import * as $ from "svelte/internal/client";
+var root = $.template(`+"`"+`<span class="badge">static</span> <span>dynamic</span> <p>text</p>`+"`"+`, 1);
+export default function Badge($$anchor, $$props) {
+	let kind = $.prop($$props, "kind", 3, "info"), size = $.prop($$props, "size", 3, 12);
+	let active = false;
+	var fragment = root();
+	var span = $.first_child(fragment);
+	$.toggle_class(span, "active", active);
+	var span_1 = $.sibling($.sibling(span, true));
+	var p = $.sibling($.sibling(span_1, true));
+	$.set_style(p, "margin", "0");
+	$.template_effect(() => {
+		$.set_class(span_1, `+"`"+`badge ${kind() ?? ""}`+"`"+`);
+		$.toggle_class(span_1, "active", active);
+		$.toggle_class(span_1, "large", size() > 16);
+		$.set_style(p, "font-size", `+"`"+`${size() ?? ""}px`+"`"+`);
+	});
+	$.append($$anchor, fragment);
+}
+`)
+}
+
+func TestEventDirectives(t *testing.T) {
+	is := is.New(t)
+	actual, err := dom.Generate("form.svelte", []byte(`<script>
+  import Button from "./Button.svelte";
+  let count = $state(0);
+  function submit() { count++ }
+</script>
+
+<form on:submit|preventDefault|once={submit}><button on:click>{count}</button></form>
+<div on:wheel|passive|capture={() => count++}></div>
+<Button on:click|once={() => count++} on:focus />`))
+	is.NoErr(err)
+	equal(t, actual, `import * as $ from "svelte/internal/client";
+import Button from "./Button.svelte";
+var root = $.template(`+"`"+`<form><button> </button></form> <div></div> <!>`+"`"+`, 1);
+export default function Form($$anchor, $$props) {
+	let count = $.source(0);
+	function submit() {
+		$.update(count);
+	}
+	var fragment = root();
+	var form = $.first_child(fragment);
+	$.event("submit", form, $.once($.preventDefault(submit)));
+	var button = $.child(form);
+	$.event("click", button, function($$arg) {
+		$.bubble_event.call(this, $$props, $$arg);
+	});
+	var text = $.child(button);
+	$.reset(button);
+	$.reset(form);
+	var div = $.sibling($.sibling(form, true));
+	$.event("wheel", div, () => {
+		return $.update(count);
+	}, true, true);
+	var node = $.sibling($.sibling(div, true));
+	Button(node, { $$events: { click: $.once(() => {
+		return $.update(count);
+	}), focus: function($$arg) {
+		$.bubble_event.call(this, $$props, $$arg);
+	} } });
+	$.template_effect(() => {
+		return $.set_text(text, $.get(count));
+	});
+	$.append($$anchor, fragment);
+}
+`)
+	_, err = dom.Generate("form.svelte", []byte(`<script>import Button from "./Button.svelte";</script><Button on:click|preventDefault />`))
+	is.True(err != nil)
+	is.Equal(err.Error(), "dom: the preventDefault modifier can't be used on components")
+}
+
+func TestStaticComponent(t *testing.T) {
+	static := []string{
+		`<h1>hello world</h1>`,
+		`<script>let name = "world"; const items = ["a", "b"]</script><h1 title={name}>hello {name}!</h1>{#each items as item}<i>{item}</i>{/each}`,
+		`<script>let open = $state(true); let count = $state(1); let doubled = $derived(count * 2)</script><p class:open>{count} x 2 = {doubled}</p>`,
+		`<script>let user = $state.raw({ name: "Jo" })</script><p style:color="red">{user.name}</p>`,
+	}
+	for _, input := range static {
+		t.Run(input, func(t *testing.T) {
+			is := is.New(t)
+			actual, err := dom.Generate("static.svelte", []byte(input))
+			is.NoErr(err)
+			is.True(!strings.Contains(actual, "template_effect"))
+			is.True(!strings.Contains(actual, "$.set_text"))
+		})
+	}
+	dynamic := []string{
+		`<script>let count = $state(0)</script><button onclick={() => count++}>{count}</button>`,
+		`<script>let todos = $state([])</script><p>{todos.length}</p>`,
+		`<script>let { name } = $props()</script><p>{name}</p>`,
+		`<script>let count = $state(0); let doubled = $derived(count * 2)</script><button onclick={() => count++}>{doubled}</button>`,
+	}
+	for _, input := range dynamic {
+		t.Run(input, func(t *testing.T) {
+			is := is.New(t)
+			actual, err := dom.Generate("dynamic.svelte", []byte(input))
+			is.NoErr(err)
+			is.True(strings.Contains(actual, "template_effect"))
+		})
+	}
+}
+
+func TestIfBlock(t *testing.T) {
+	is := is.New(t)
+	actual, err := dom.Generate("count.svelte", []byte(`<script>let count = $state(0)</script>
+<button onclick={() => count++}>+</button>
+{#if count > 1}<p>many</p>{:else if count}<p>one</p>{:else}<p>none</p>{/if}`))
+	is.NoErr(err)
+	equal(t, actual, `import * as $ from "svelte/internal/client";
+var on_click = (_, count) => {
+	return $.update(count);
+};
+var root_1 = $.template(`+"`"+`<p>many</p>`+"`"+`);
+var root_3 = $.template(`+"`"+`<p>one</p>`+"`"+`);
+var root_4 = $.template(`+"`"+`<p>none</p>`+"`"+`);
+var root = $.template(`+"`"+`<button>+</button> <!>`+"`"+`, 1);
+export default function Count($$anchor) {
+	let count = $.source(0);
+	var fragment = root();
+	var button = $.first_child(fragment);
+	button.__click = [on_click, count];
+	var node = $.sibling($.sibling(button, true));
+	$.if(node, () => $.get(count) > 1, ($$anchor) => {
+		var p = root_1();
+		$.append($$anchor, p);
+	}, ($$anchor) => {
+		var fragment_1 = $.comment();
+		var node_1 = $.first_child(fragment_1);
+		$.if(node_1, () => $.get(count), ($$anchor) => {
+			var p_1 = root_3();
+			$.append($$anchor, p_1);
+		}, ($$anchor) => {
+			var p_2 = root_4();
+			$.append($$anchor, p_2);
+		});
+		$.append($$anchor, fragment_1);
+	});
+	$.append($$anchor, fragment);
+}
+$.delegate(["click"]);
+`)
+}
+
+func TestSlot(t *testing.T) {
+	is := is.New(t)
+	actual, err := dom.Generate("card.svelte", []byte(`<script>export let item;</script>
+<div><slot name="header" {item}>fallback</slot><slot /></div>
+{#if $$slots.footer}<footer><slot name="footer" /></footer>{/if}`))
+	is.NoErr(err)
+	equal(t, actual, `import * as $ from "svelte/internal/client";
+var root_2 = $.template(`+"`"+`<footer><!></footer>`+"`"+`);
+var root = $.template(`+"`"+`<div><!><!></div> <!>`+"`"+`, 1);
+export default function Card($$anchor, $$props) {
+	const $$slots = $.sanitize_slots($$props);
+	var fragment = root();
+	var div = $.first_child(fragment);
+	var node = $.child(div);
+	$.slot(node, $$props, "header", { get item() {
+		return $$props.item;
+	} }, ($$anchor) => {
+		var text = $.text($$anchor);
+		text.nodeValue = "fallback";
+		$.append($$anchor, text);
+	});
+	var node_1 = $.sibling(node);
+	$.slot(node_1, $$props, "default", {}, null);
+	$.reset(div);
+	var node_2 = $.sibling($.sibling(div, true));
+	$.if(node_2, () => $$slots.footer, ($$anchor) => {
+		var footer = root_2();
+		var node_3 = $.child(footer);
+		$.slot(node_3, $$props, "footer", {}, null);
+		$.reset(footer);
+		$.append($$anchor, footer);
+	});
+	$.append($$anchor, fragment);
+}
+`)
+	actual, err = dom.Generate("list.svelte", []byte(`<script>
+  import Card from "./Card.svelte";
+  let { title } = $props();
+</script>
+<Card let:item>
+  <h1 slot="header" let:row={r}>{title}: {r}</h1>
+  <p>{item}</p>
+</Card>
+<Card>plain {title}</Card>`))
+	is.NoErr(err)
+	equal(t, actual, `import * as $ from "svelte/internal/client";
+import Card from "./Card.svelte";
+var root_1 = $.template(`+"`"+`<p> </p>`+"`"+`);
+var root_2 = $.template(`+"`"+`<h1 slot="header"> </h1>`+"`"+`);
+var root = $.template(`+"`"+`<!> <!>`+"`"+`, 1);
+export default function List($$anchor, $$props) {
+	var fragment = root();
+	var node = $.first_child(fragment);
+	Card(node, { $$slots: { default: ($$anchor, $$slotProps) => {
+		var p = root_1();
+		var text = $.child(p);
+		$.reset(p);
+		$.template_effect(() => $.set_text(text, $$slotProps.item));
+		$.append($$anchor, p);
+	}, header: ($$anchor, $$slotProps_1) => {
+		var h1 = root_2();
+		var text_1 = $.child(h1);
+		$.reset(h1);
+		$.template_effect(() => $.set_text(text_1, `+"`"+`${$$props.title ?? ""}: ${$$slotProps_1.row ?? ""}`+"`"+`));
+		$.append($$anchor, h1);
+	} } });
+	var node_1 = $.sibling($.sibling(node, true));
+	Card(node_1, { children: ($$anchor, $$slotProps_2) => {
+		var text_2 = $.text($$anchor);
+		$.template_effect(() => $.set_text(text_2, `+"`"+`plain ${$$props.title ?? ""}`+"`"+`));
+		$.append($$anchor, text_2);
+	}, $$slots: { default: true } });
+	$.append($$anchor, fragment);
+}
+`)
+}
+
+func TestAwait(t *testing.T) {
+	is := is.New(t)
+	actual, err := dom.Generate("user.svelte", []byte(`<script>let { load } = $props()</script>
+{#await load()}<p>loading</p>{:then user}<h1>{user.name}</h1>{:catch error}<p class="error">{error.message}</p>{/await}`))
+	is.NoErr(err)
+	equal(t, actual, `import * as $ from "svelte/internal/client";
+var root_1 = $.template(`+"`"+`<p>loading</p>`+"`"+`);
+var root_2 = $.template(`+"`"+`<h1> </h1>`+"`"+`);
+var root_3 = $.template(`+"`"+`<p class="error"> </p>`+"`"+`);
+export default function User($$anchor, $$props) {
+	var fragment = $.comment();
+	var node = $.first_child(fragment);
+	$.await(node, () => $$props.load(), ($$anchor) => {
+		var p = root_1();
+		$.append($$anchor, p);
+	}, ($$anchor, user) => {
+		var h1 = root_2();
+		var text = $.child(h1);
+		$.reset(h1);
+		$.template_effect(() => $.set_text(text, $.get(user).name));
+		$.append($$anchor, h1);
+	}, ($$anchor, error) => {
+		var p_1 = root_3();
+		var text_1 = $.child(p_1);
+		$.reset(p_1);
+		$.template_effect(() => $.set_text(text_1, $.get(error).message));
+		$.append($$anchor, p_1);
+	});
+	$.append($$anchor, fragment);
+}
+`)
+	actual, err = dom.Generate("story.svelte", []byte(`<script>let { story } = $props()</script>{#await story then s}{s}{/await}`))
+	is.NoErr(err)
+	equal(t, actual, `import * as $ from "svelte/internal/client";
+export default function Story($$anchor, $$props) {
+	var fragment = $.comment();
+	var node = $.first_child(fragment);
+	$.await(node, () => $$props.story, null, ($$anchor, s) => {
+		var text = $.text($$anchor);
+		$.template_effect(() => $.set_text(text, $.get(s)));
+		$.append($$anchor, text);
+	});
+	$.append($$anchor, fragment);
+}
+`)
+}
+
+func TestHTML(t *testing.T) {
+	is := is.New(t)
+	actual, err := dom.Generate("post.svelte", []byte(`<script>let { body } = $props()</script>
+<div>{@html body}</div>{@html "<hr>"}`))
+	is.NoErr(err)
+	equal(t, actual, `import * as $ from "svelte/internal/client";
+var root = $.template(`+"`"+`<div><!></div><!>`+"`"+`, 1);
+export default function Post($$anchor, $$props) {
+	var fragment = root();
+	var div = $.first_child(fragment);
+	var node = $.child(div);
+	$.html(node, () => $$props.body, false, false);
+	$.reset(div);
+	var node_1 = $.sibling(div);
+	$.html(node_1, () => "<hr>", false, false);
+	$.append($$anchor, fragment);
+}
+`)
+}
+
+func TestSelf(t *testing.T) {
+	is := is.New(t)
+	actual, err := dom.Generate("tree.svelte", []byte(`<script>let { node } = $props()</script>
+<p>{node.name}</p>
+{#each node.children as child}<svelte:self node={child} />{/each}`))
+	is.NoErr(err)
+	equal(t, actual, `import * as $ from "svelte/internal/client";
+var root = $.template(`+"`"+`<p> </p> <!>`+"`"+`, 1);
+export default function Tree($$anchor, $$props) {
+	$.push($$props, true);
+	var fragment = root();
+	var p = $.first_child(fragment);
+	var text = $.child(p);
+	$.reset(p);
+	var node_1 = $.sibling($.sibling(p, true));
+	$.each(node_1, 65, () => {
+		return $$props.node.children;
+	}, $.index, ($$anchor, child) => {
+		var fragment_1 = $.comment();
+		var node_2 = $.first_child(fragment_1);
+		Tree(node_2, { get node() {
+			return $.unwrap(child);
+		} });
+		$.append($$anchor, fragment_1);
+	});
+	$.template_effect(() => {
+		return $.set_text(text, $$props.node.name);
+	});
+	$.append($$anchor, fragment);
+	$.pop();
+}
+`)
+}
+
+func TestCustomElement(t *testing.T) {
+	is := is.New(t)
+	actual, err := dom.Generate("widget.svelte", []byte(`<svelte:options customElement="my-widget" />
+<script>
+  let { name = "world", open = false } = $props();
+</script>
+
+<h1 class:open>Hello {name}!</h1>
+
+<style>
+  h1 { color: red; }
+</style>
+`))
+	is.NoErr(err)
+	equal(t, actual, `
+import * as $ from "svelte/internal/client";
+var root = $.template(`+"`"+`<h1 class="svelte-w1ldfp"> </h1>`+"`"+`);
+const $$css = { hash: "svelte-w1ldfp", code: "h1.svelte-w1ldfp { color: red }" };
+export default function Widget($$anchor, $$props) {
+	$.push($$props, true);
+	$.append_styles($$anchor, $$css);
+	let name = $.prop($$props, "name", 7, "world"), open = $.prop($$props, "open", 7, false);
+	var h1 = root();
+	var text = $.child(h1);
+	$.reset(h1);
+	$.template_effect(() => {
+		$.toggle_class(h1, "open", open());
+		$.set_text(text, `+"`"+`Hello ${name() ?? ""}!`+"`"+`);
+	});
+	$.append($$anchor, h1);
+	return $.pop({
+		get name() {
+			return name();
+		},
+		set name($$value = "world") {
+			name($$value);
+			$.flush_sync();
+		},
+		get open() {
+			return open();
+		},
+		set open($$value = false) {
+			open($$value);
+			$.flush_sync();
+		}
+	});
+}
+customElements.define("my-widget", $.create_custom_element(Widget, { name: {}, open: { type: "Boolean" } }, [], [], true));
+`)
+}
+
+func TestSourceMap(t *testing.T) {
+	is := is.New(t)
+	input := "<script>\n  let { name = \"Mark\" } = $props()\n</script>\n\n<div>\n  <h1>Hello {name}!</h1>\n  <p>{name.length}</p>\n</div>\n"
+	code, sourceMap, err := dom.GenerateWithSourceMap("hello.svelte", []byte(input))
+	is.NoErr(err)
+	expected, err := dom.Generate("hello.svelte", []byte(input))
+	is.NoErr(err)
+	diff.TestString(t, code, expected)
+	is.Equal(sourceMap.Version, 3)
+	is.Equal(sourceMap.Sources, []string{"hello.svelte"})
+	is.Equal(sourceMap.SourcesContent, []string{input})
+	prefix := "data:application/json;base64,"
+	dataURL := sourceMap.DataURL()
+	is.True(strings.HasPrefix(dataURL, prefix))
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(dataURL, prefix))
+	is.NoErr(err)
+	log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+	decoded := js_parser.ParseSourceMap(log, logger.Source{Contents: string(data)})
+	is.True(decoded != nil)
+	// original finds where the generated snippet maps to in the component
+	original := func(snippet string) string {
+		t.Helper()
+		index := strings.Index(code, snippet)
+		if index < 0 {
+			t.Fatalf("unable to find %q in %s", snippet, code)
+		}
+		line := strings.Count(code[:index], "\n")
+		column := index - strings.LastIndex(code[:index], "\n") - 1
+		mapping := decoded.Find(int32(line), int32(column))
+		if mapping == nil {
+			return "unmapped"
+		}
+		return fmt.Sprintf("%d:%d", mapping.OriginalLine+1, mapping.OriginalColumn+1)
+	}
+	diff.TestString(t, original(`root()`), "5:1")
+	diff.TestString(t, original(`$.child(div)`), "6:3")
+	diff.TestString(t, original(`name() ??`), "6:14")
+	diff.TestString(t, original(`name().length`), "7:7")
+}
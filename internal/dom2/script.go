@@ -0,0 +1,491 @@
+package dom
+
+import (
+	"strings"
+
+	"github.com/livebud/duo/internal/scope"
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// Flags for $.prop
+const (
+	propIsImmutable = 1
+	propIsRunes     = 2
+	propIsUpdated   = 4
+	propIsBindable  = 8
+)
+
+// script transforms the instance script into the body of the component
+func (c *component) script(program *js.AST) (body []js.IStmt) {
+	for _, stmt := range program.List {
+		switch s := stmt.(type) {
+		case *js.ImportStmt:
+			c.imports = append(c.imports, s)
+		case *js.FuncDecl:
+			if b := c.functionBinding(s); b != nil && b.hoisted {
+				c.hoisted = append(c.hoisted, c.hoistFunction(s, b))
+				continue
+			}
+			body = append(body, c.stmt(s))
+		case *js.VarDecl:
+			if decl := c.varDecl(s); decl != nil {
+				body = append(body, decl)
+			}
+		case *js.ExportStmt:
+			switch decl := s.Decl.(type) {
+			case *js.VarDecl:
+				if decl := c.varDecl(decl); decl != nil {
+					body = append(body, decl)
+				}
+			case *js.FuncDecl, *js.ClassDecl:
+				body = append(body, c.stmt(decl.(js.IStmt)))
+			default:
+				c.errorf("unsupported export in the component script")
+			}
+		case *js.ExprStmt:
+			// $inspect is only for development
+			if r, ok := scope.RuneOf(s.Value); ok && r == scope.RuneInspect {
+				continue
+			}
+			if call, ok := s.Value.(*js.CallExpr); ok {
+				if inner, ok := call.X.(*js.DotExpr); ok {
+					if r, ok := scope.RuneOf(inner.X); ok && r == scope.RuneInspect {
+						continue
+					}
+				}
+			}
+			body = append(body, c.stmt(s))
+		default:
+			body = append(body, c.stmt(s))
+		}
+	}
+	return body
+}
+
+func (c *component) functionBinding(fn *js.FuncDecl) *binding {
+	if fn.Name == nil {
+		return nil
+	}
+	return c.lookup(fn.Name)
+}
+
+// hoistFunction moves the function to the module, passing the component
+// variables it uses as params after the event
+func (c *component) hoistFunction(fn js.IExpr, b *binding) js.IStmt {
+	var fnParams *js.Params
+	switch f := fn.(type) {
+	case *js.FuncDecl:
+		fnParams = &f.Params
+	case *js.ArrowFunc:
+		fnParams = &f.Params
+	}
+	if len(fnParams.List) == 0 && fnParams.Rest == nil {
+		fnParams.List = append(fnParams.List, js.BindingElement{Binding: id("_")})
+	} else if fnParams.Rest != nil {
+		fnParams.List = append(fnParams.List, js.BindingElement{Binding: fnParams.Rest})
+		fnParams.Rest = nil
+	}
+	for _, param := range b.params {
+		fnParams.List = append(fnParams.List, js.BindingElement{Binding: id(param)})
+	}
+	fn = c.expr(fn)
+	if decl, ok := fn.(*js.FuncDecl); ok {
+		return decl
+	}
+	return varDecl(b.name, fn)
+}
+
+// stmt transforms a statement
+func (c *component) stmt(stmt js.IStmt) js.IStmt {
+	return rewriter(c.rewrite).stmt(stmt)
+}
+
+// expr transforms an expression
+func (c *component) expr(expr js.IExpr) js.IExpr {
+	return rewriter(c.rewrite).expr(expr)
+}
+
+// varDecl transforms a variable declaration, returning nil if nothing is left
+func (c *component) varDecl(decl *js.VarDecl) *js.VarDecl {
+	var list []js.BindingElement
+	for _, element := range decl.List {
+		r, _ := scope.RuneOf(element.Default)
+		switch r {
+		case scope.RuneProps:
+			list = append(list, c.props(element.Binding)...)
+			continue
+		case scope.RuneState, scope.RuneStateRaw:
+			v, ok := element.Binding.(*js.Var)
+			if !ok {
+				continue
+			}
+			element.Default = c.state(c.lookup(v), firstArg(element.Default), r == scope.RuneState)
+		case scope.RuneDerived:
+			element.Default = call(runtime("derived"), lambda(js.Params{}, c.expr(firstArg(element.Default))))
+		case scope.RuneDerivedBy:
+			element.Default = call(runtime("derived"), c.expr(firstArg(element.Default)))
+		default:
+			if v, ok := element.Binding.(*js.Var); ok {
+				b := c.lookup(v)
+				if b != nil && b.hoisted {
+					c.hoisted = append(c.hoisted, c.hoistFunction(element.Default, b))
+					continue
+				}
+				// Exported props from legacy components
+				if b != nil && b.kind == prop {
+					list = append(list, c.props(&js.BindingObject{List: []js.BindingObjectItem{{
+						Key:   &js.PropertyName{Literal: js.LiteralExpr{TokenType: js.IdentifierToken, Data: []byte(b.key)}},
+						Value: js.BindingElement{Binding: v, Default: element.Default},
+					}}})...)
+					continue
+				}
+			}
+			rewriter(c.rewrite).bindingElement(&element)
+		}
+		list = append(list, element)
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	decl.List = list
+	return decl
+}
+
+// state returns the initial value of $state or $state.raw
+func (c *component) state(b *binding, value js.IExpr, proxy bool) js.IExpr {
+	proxied := proxy && c.shouldProxy(value)
+	if value == nil {
+		value = id("undefined")
+	} else {
+		value = c.expr(value)
+	}
+	if proxied {
+		value = call(runtime("proxy"), value)
+	}
+	if b != nil && b.reassigned {
+		return call(runtime("source"), value)
+	}
+	return value
+}
+
+// props declares the props destructured from $props()
+func (c *component) props(pattern js.IBinding) (list []js.BindingElement) {
+	switch p := pattern.(type) {
+	case *js.Var:
+		list = append(list, js.BindingElement{Binding: p, Default: c.restProps(nil)})
+	case *js.BindingObject:
+		var names []string
+		for _, item := range p.List {
+			v, ok := item.Value.Binding.(*js.Var)
+			if !ok {
+				continue
+			}
+			b := c.lookup(v)
+			names = append(names, b.key)
+			if !b.isSource() {
+				continue
+			}
+			list = append(list, js.BindingElement{Binding: v, Default: c.prop(b)})
+		}
+		if p.Rest != nil {
+			list = append(list, js.BindingElement{Binding: p.Rest, Default: c.restProps(names)})
+		}
+	}
+	return list
+}
+
+// prop returns the getter for a prop
+func (c *component) prop(b *binding) js.IExpr {
+	flags := 0
+	if c.runes {
+		flags |= propIsImmutable | propIsRunes
+	}
+	if b.reassigned || b.mutated || b.accessor {
+		flags |= propIsUpdated
+	}
+	if b.kind == bindableProp || !c.runes {
+		flags |= propIsBindable
+	}
+	args := []js.IExpr{id("$$props"), str(b.key), num(flags)}
+	if b.init != nil {
+		proxied := b.kind == bindableProp && c.shouldProxy(b.init)
+		simple := isSimple(b.init)
+		value := c.expr(b.init)
+		if proxied {
+			value = call(runtime("proxy"), value)
+		}
+		if simple && !proxied {
+			args = append(args, value)
+		} else {
+			args = append(args, thunk(value))
+		}
+	}
+	return call(runtime("prop"), args...)
+}
+
+func (c *component) restProps(names []string) js.IExpr {
+	seen := &js.ArrayExpr{}
+	for _, name := range append([]string{"$$slots", "$$events", "$$legacy"}, names...) {
+		seen.List = append(seen.List, js.Element{Value: str(name)})
+	}
+	return call(runtime("rest_props"), id("$$props"), seen)
+}
+
+// shouldProxy returns true if the value could be an object or array, which
+// are made deeply reactive
+func (c *component) shouldProxy(expr js.IExpr) bool {
+	switch e := expr.(type) {
+	case nil, *js.LiteralExpr, *js.ArrowFunc, *js.FuncDecl, *js.UnaryExpr, *js.BinaryExpr:
+		return false
+	case *js.TemplateExpr:
+		return e.Tag != nil
+	case *js.Var:
+		if string(e.Data) == "undefined" {
+			return false
+		}
+		if b := c.lookup(e); b != nil && b.init != nil && !b.reassigned && b.init != expr {
+			return c.shouldProxy(b.init)
+		}
+	}
+	return true
+}
+
+// isSimple returns true if evaluating the expression has no side effects and
+// is cheap enough to do eagerly
+func isSimple(expr js.IExpr) bool {
+	switch e := expr.(type) {
+	case *js.LiteralExpr, *js.Var, *js.ArrowFunc, *js.FuncDecl:
+		return true
+	case *js.TemplateExpr:
+		return e.Tag == nil && len(e.List) == 0
+	case *js.BinaryExpr:
+		return !isAssignment(e.Op) && isSimple(e.X) && isSimple(e.Y)
+	case *js.CondExpr:
+		return isSimple(e.Cond) && isSimple(e.X) && isSimple(e.Y)
+	case *js.GroupExpr:
+		return isSimple(e.X)
+	}
+	return false
+}
+
+// rewrite reads and writes of reactive variables into runtime calls. It
+// returns nil to keep descending into the expression.
+func (c *component) rewrite(expr js.IExpr) js.IExpr {
+	switch e := expr.(type) {
+	case *js.Var:
+		return c.read(e)
+	case *js.BinaryExpr:
+		if isAssignment(e.Op) {
+			return c.assignment(e)
+		}
+	case *js.UnaryExpr:
+		if isUpdate(e.Op) {
+			return c.update(e)
+		}
+	case *js.CallExpr:
+		if r, ok := scope.RuneOf(e); ok {
+			return c.rune(r, e)
+		}
+	}
+	return nil
+}
+
+// read a variable
+func (c *component) read(v *js.Var) js.IExpr {
+	b := c.lookup(v)
+	if b == nil {
+		return v
+	}
+	switch b.kind {
+	case state, rawState:
+		if b.reassigned {
+			return call(runtime("get"), v)
+		}
+	case derived:
+		return call(runtime("get"), v)
+	case prop, bindableProp:
+		if b.isSource() {
+			return call(v)
+		}
+		return propsMember(b.key)
+	case eachItem:
+		return call(runtime("unwrap"), v)
+	case slotProp:
+		return keyMember(b.slotProps, b.key)
+	case awaitValue:
+		return call(runtime("get"), v)
+	}
+	return v
+}
+
+// propsMember reads the key from $$props
+func propsMember(key string) js.IExpr {
+	return keyMember("$$props", key)
+}
+
+// keyMember reads the key from the object
+func keyMember(object, key string) js.IExpr {
+	if isIdentifier(key) {
+		return member(id(object), key)
+	}
+	return &js.IndexExpr{X: id(object), Y: str(key)}
+}
+
+// assignment to a reactive variable
+func (c *component) assignment(e *js.BinaryExpr) js.IExpr {
+	switch target := e.X.(type) {
+	case *js.Var:
+		b := c.lookup(target)
+		if b == nil || !b.isSignal() {
+			return nil
+		}
+		if b.kind == derived {
+			c.errorf("can't assign to derived state %q", b.name)
+			return nil
+		}
+		proxied := e.Op == js.EqToken && (b.kind == state || b.kind == bindableProp) && c.shouldProxy(e.Y)
+		value := c.expr(e.Y)
+		if e.Op != js.EqToken {
+			value = &js.BinaryExpr{Op: operatorOf(e.Op), X: c.read(target), Y: group(value)}
+		}
+		if proxied {
+			value = call(runtime("proxy"), value)
+		}
+		if b.kind == prop || b.kind == bindableProp {
+			return call(target, value)
+		}
+		return call(runtime("set"), target, value)
+	case *js.ArrayExpr, *js.ObjectExpr:
+		for _, v := range patternVars(target) {
+			if b := c.lookup(v); b != nil && b.isSignal() {
+				c.errorf("destructuring assignments to %q aren't supported yet", b.name)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// update a reactive variable with ++ or --
+func (c *component) update(e *js.UnaryExpr) js.IExpr {
+	target, ok := e.X.(*js.Var)
+	if !ok {
+		return nil
+	}
+	b := c.lookup(target)
+	if b == nil || !b.isSignal() {
+		return nil
+	}
+	if b.kind == derived {
+		c.errorf("can't assign to derived state %q", b.name)
+		return nil
+	}
+	name := "update"
+	if b.kind == prop || b.kind == bindableProp {
+		name = "update_prop"
+	}
+	if e.Op == js.PreIncrToken || e.Op == js.PreDecrToken {
+		name = strings.Replace(name, "update", "update_pre", 1)
+	}
+	args := []js.IExpr{target}
+	if e.Op == js.PreDecrToken || e.Op == js.PostDecrToken || e.Op == js.DecrToken {
+		args = append(args, &js.UnaryExpr{Op: js.NegToken, X: num(1)})
+	}
+	return call(runtime(name), args...)
+}
+
+// rune transforms runes used within expressions
+func (c *component) rune(r scope.Rune, e *js.CallExpr) js.IExpr {
+	var name string
+	switch r {
+	case scope.RuneStateSnapshot:
+		name = "snapshot"
+	case scope.RuneEffect:
+		name = "user_effect"
+	case scope.RuneEffectPre:
+		name = "user_pre_effect"
+	case scope.RuneEffectRoot:
+		name = "effect_root"
+	case scope.RuneEffectTrack:
+		name = "effect_tracking"
+	case scope.RuneInspect:
+		return id("undefined")
+	default:
+		c.errorf("%s can only be used to declare a variable", r)
+		return nil
+	}
+	rewriter(c.rewrite).args(&e.Args)
+	e.X = runtime(name)
+	return e
+}
+
+// operatorOf returns the binary operator of a compound assignment
+func operatorOf(op js.TokenType) js.TokenType {
+	switch op {
+	case js.AddEqToken:
+		return js.AddToken
+	case js.SubEqToken:
+		return js.SubToken
+	case js.MulEqToken:
+		return js.MulToken
+	case js.DivEqToken:
+		return js.DivToken
+	case js.ModEqToken:
+		return js.ModToken
+	case js.ExpEqToken:
+		return js.ExpToken
+	case js.LtLtEqToken:
+		return js.LtLtToken
+	case js.GtGtEqToken:
+		return js.GtGtToken
+	case js.GtGtGtEqToken:
+		return js.GtGtGtToken
+	case js.BitAndEqToken:
+		return js.BitAndToken
+	case js.BitOrEqToken:
+		return js.BitOrToken
+	case js.BitXorEqToken:
+		return js.BitXorToken
+	case js.AndEqToken:
+		return js.AndToken
+	case js.OrEqToken:
+		return js.OrToken
+	case js.NullishEqToken:
+		return js.NullishToken
+	}
+	return op
+}
+
+// patternVars returns the variables assigned by a destructuring assignment
+func patternVars(expr js.IExpr) (vars []*js.Var) {
+	switch e := expr.(type) {
+	case *js.Var:
+		vars = append(vars, e)
+	case *js.ArrayExpr:
+		for _, element := range e.List {
+			vars = append(vars, patternVars(element.Value)...)
+		}
+	case *js.ObjectExpr:
+		for _, property := range e.List {
+			vars = append(vars, patternVars(property.Value)...)
+		}
+	case *js.BinaryExpr:
+		if e.Op == js.EqToken {
+			vars = append(vars, patternVars(e.X)...)
+		}
+	}
+	return vars
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r == '_' || r == '$' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || i > 0 && '0' <= r && r <= '9' {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package dom
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/livebud/duo/internal/ast"
+	duojs "github.com/livebud/duo/internal/js"
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// SourceMap is a version 3 source map from the generated module back to the
+// component
+type SourceMap struct {
+	Version        int      `json:"version"`
+	Sources        []string `json:"sources"`
+	SourcesContent []string `json:"sourcesContent"`
+	Names          []string `json:"names"`
+	Mappings       string   `json:"mappings"`
+}
+
+// DataURL encodes the source map for a `//# sourceMappingURL=` comment
+func (s *SourceMap) DataURL() string {
+	data, _ := json.Marshal(s)
+	return "data:application/json;base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// marker surrounds the position IDs in the printed code. Generated strings
+// and template literals never contain it.
+const marker = '\x00'
+
+// mapped prefixes an expression with the ID of its position in the component
+type mapped struct {
+	js.IExpr
+	id int
+}
+
+func (m *mapped) JS() string {
+	return m.prefix() + m.IExpr.JS()
+}
+
+func (m *mapped) JSWriteTo(w io.Writer) (int, error) {
+	n, err := io.WriteString(w, m.prefix())
+	if err != nil {
+		return n, err
+	}
+	wn, err := m.IExpr.JSWriteTo(w)
+	return n + wn, err
+}
+
+func (m *mapped) prefix() string {
+	return string(marker) + strconv.Itoa(m.id) + string(marker)
+}
+
+// mark the expression with where it came from, when generating a source map
+func (c *component) mark(expr js.IExpr, pos ast.Position) js.IExpr {
+	if !c.mapping || pos.Line == 0 {
+		return expr
+	}
+	c.positions = append(c.positions, pos)
+	return &mapped{expr, len(c.positions) - 1}
+}
+
+// unmark strips the markers from the printed code, mapping where each one was
+// to its position in the component
+func unmark(code string, positions []ast.Position) (string, []duojs.Mapping) {
+	out := new(strings.Builder)
+	out.Grow(len(code))
+	var mappings []duojs.Mapping
+	line, column := 0, 0
+	for i := 0; i < len(code); {
+		if code[i] == marker {
+			if end := strings.IndexByte(code[i+1:], marker); end >= 0 {
+				id, err := strconv.Atoi(code[i+1 : i+1+end])
+				if err == nil && id >= 0 && id < len(positions) {
+					mappings = append(mappings, duojs.Mapping{
+						GeneratedLine:   line,
+						GeneratedColumn: column,
+						OriginalLine:    positions[id].Line - 1,
+						OriginalColumn:  positions[id].Column - 1,
+					})
+					i += end + 2
+					continue
+				}
+			}
+		}
+		r, size := utf8.DecodeRuneInString(code[i:])
+		out.WriteString(code[i : i+size])
+		i += size
+		// Columns count UTF-16 code units
+		switch {
+		case r == '\n':
+			line++
+			column = 0
+		case r >= 0x10000:
+			column += 2
+		default:
+			column++
+		}
+	}
+	return out.String(), mappings
+}
@@ -0,0 +1,1185 @@
+package dom
+
+import (
+	"html"
+	"strings"
+
+	"github.com/livebud/duo/internal/ast"
+	"github.com/livebud/duo/internal/event"
+	duojs "github.com/livebud/duo/internal/js"
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// Flags for $.template
+const templateFragment = 1
+
+// Flags for $.each
+const (
+	eachItemReactive = 1
+	eachIsStrictEq   = 64
+)
+
+// block is a group of nodes cloned from the same template, like the
+// component's nodes or the body of an each block
+type block struct {
+	template strings.Builder
+	init     []js.IStmt // runs once after the nodes are created
+	update   []js.IStmt // runs in $.template_effect when state changes
+	after    []js.IStmt // runs after the update effect, like bindings
+}
+
+// fragment returns the statements that render the nodes at $$anchor
+func (c *component) fragment(nodes []ast.Fragment, parent string) (body []js.IStmt) {
+	nodes = clean(nodes, parent)
+	if len(nodes) == 0 {
+		return nil
+	}
+	templateName := c.generate("root")
+	b := new(block)
+	var nodeID string
+	switch {
+	case len(nodes) == 1 && isElement(nodes[0]):
+		el := nodes[0].(*ast.Element)
+		nodeID = c.generate(el.Name)
+		c.element(b, el, nodeID)
+		c.addTemplate(templateName, b.template.String(), 0)
+		body = append(body, varDecl(nodeID, c.mark(call(id(templateName)), el.Pos)))
+	case isText(nodes):
+		nodeID = c.generate("text")
+		body = append(body, varDecl(nodeID, call(runtime("text"), id("$$anchor"))))
+		c.text(b, nodeID, nodes)
+	default:
+		nodeID = c.generate("fragment")
+		c.children(b, nodes, func(text bool) js.IExpr {
+			if text {
+				return call(runtime("first_child"), id(nodeID), boolean(true))
+			}
+			return call(runtime("first_child"), id(nodeID))
+		})
+		if template := b.template.String(); template == "<!>" {
+			body = append(body, varDecl(nodeID, call(runtime("comment"))))
+		} else {
+			c.addTemplate(templateName, template, templateFragment)
+			body = append(body, varDecl(nodeID, call(id(templateName))))
+		}
+	}
+	body = append(body, b.init...)
+	if len(b.update) > 0 {
+		body = append(body, templateEffect(b.update))
+	}
+	body = append(body, b.after...)
+	return append(body, exprStmt(call(runtime("append"), id("$$anchor"), id(nodeID))))
+}
+
+// addTemplate declares a template at the top of the module
+func (c *component) addTemplate(name, template string, flags int) {
+	args := []js.IExpr{templateLiteral([]string{template}, nil)}
+	if flags != 0 {
+		args = append(args, num(flags))
+	}
+	c.templates = append(c.templates, varDecl(name, call(runtime("template"), args...)))
+}
+
+// templateEffect updates the nodes when the state they read changes
+func templateEffect(stmts []js.IStmt) js.IStmt {
+	if len(stmts) == 1 {
+		if stmt, ok := stmts[0].(*js.ExprStmt); ok {
+			return exprStmt(call(runtime("template_effect"), lambda(js.Params{}, stmt.Value)))
+		}
+	}
+	return exprStmt(call(runtime("template_effect"), arrow(js.Params{}, stmts...)))
+}
+
+// children adds the nodes to the block's template and declares variables for
+// the nodes that need code. next returns the expression that gets the next
+// node, given whether that node is text.
+func (c *component) children(b *block, nodes []ast.Fragment, next func(text bool) js.IExpr) {
+	var sequence []ast.Fragment
+	flush := func() {
+		if len(sequence) == 0 {
+			return
+		}
+		nodes := sequence
+		sequence = nil
+		if text, ok := staticText(nodes); ok {
+			b.template.WriteString(text)
+			prev := next
+			next = func(bool) js.IExpr {
+				return call(runtime("sibling"), prev(true))
+			}
+			return
+		}
+		// Text with expressions is a space in the template that's filled in
+		b.template.WriteString(" ")
+		textID := c.nodeID(b, next(true), "text", ast.Position{})
+		c.text(b, textID, nodes)
+		next = siblingOf(textID)
+	}
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *ast.Text, *ast.Mustache:
+			sequence = append(sequence, n)
+		default:
+			flush()
+			name, pos := "node", ast.Position{}
+			switch n := n.(type) {
+			case *ast.Element:
+				name, pos = n.Name, n.Pos
+			case *ast.Component:
+				pos = n.Pos
+			}
+			nodeID := c.nodeID(b, next(false), name, pos)
+			next = siblingOf(nodeID)
+			c.node(b, n, nodeID)
+		}
+	}
+	flush()
+}
+
+// nodeID declares a variable for the node, unless it already has one
+func (c *component) nodeID(b *block, expr js.IExpr, name string, pos ast.Position) string {
+	if v, ok := expr.(*js.Var); ok {
+		return string(v.Data)
+	}
+	nodeID := c.generate(name)
+	b.init = append(b.init, varDecl(nodeID, c.mark(expr, pos)))
+	return nodeID
+}
+
+func siblingOf(nodeID string) func(text bool) js.IExpr {
+	return func(text bool) js.IExpr {
+		if text {
+			return call(runtime("sibling"), id(nodeID), boolean(true))
+		}
+		return call(runtime("sibling"), id(nodeID))
+	}
+}
+
+func (c *component) node(b *block, node ast.Fragment, nodeID string) {
+	switch n := node.(type) {
+	case *ast.Element:
+		c.element(b, n, nodeID)
+	case *ast.IfBlock:
+		c.ifBlock(b, n, nodeID)
+	case *ast.EachBlock:
+		c.each(b, n, nodeID)
+	case *ast.Component:
+		c.child(b, n, nodeID)
+	case *ast.Slot:
+		c.slot(b, n, nodeID)
+	case *ast.AwaitBlock:
+		c.await(b, n, nodeID)
+	case *ast.HTML:
+		c.html(b, n, nodeID)
+	default:
+		c.errorf("unable to generate %T", node)
+	}
+}
+
+// text sets the value of a text node from a sequence of text and expressions
+func (c *component) text(b *block, textID string, nodes []ast.Fragment) {
+	var exprs []js.IExpr
+	for _, node := range nodes {
+		if m, ok := node.(*ast.Mustache); ok {
+			exprs = append(exprs, m.Expr)
+		}
+	}
+	calls, dynamic := hasCall(exprs...), c.isDynamic(exprs...)
+	value := c.textValue(nodes)
+	switch {
+	case calls:
+		b.init = append(b.init, templateEffect([]js.IStmt{exprStmt(call(runtime("set_text"), id(textID), value))}))
+	case dynamic:
+		b.update = append(b.update, exprStmt(call(runtime("set_text"), id(textID), value)))
+	default:
+		b.init = append(b.init, exprStmt(assign(member(id(textID), "nodeValue"), value)))
+	}
+}
+
+// textValue returns the value of text and expressions. A single expression is
+// used as is, otherwise the parts are joined in a template literal.
+func (c *component) textValue(nodes []ast.Fragment) js.IExpr {
+	if len(nodes) == 1 {
+		switch n := nodes[0].(type) {
+		case *ast.Mustache:
+			return c.mark(c.expr(n.Expr), n.Pos)
+		case *ast.Text:
+			return str(html.UnescapeString(n.Value))
+		}
+	}
+	quasis := []string{""}
+	var exprs []js.IExpr
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *ast.Text:
+			quasis[len(quasis)-1] += html.UnescapeString(n.Value)
+		case *ast.Mustache:
+			if literal, ok := n.Expr.(*js.LiteralExpr); ok && literal.TokenType == js.DecimalToken {
+				quasis[len(quasis)-1] += string(literal.Data)
+				continue
+			} else if ok && literal.TokenType == js.StringToken {
+				if s, err := duojs.Unquote(string(literal.Data)); err == nil {
+					quasis[len(quasis)-1] += s
+					continue
+				}
+			}
+			exprs = append(exprs, &js.BinaryExpr{Op: js.NullishToken, X: c.mark(group(c.expr(n.Expr)), n.Pos), Y: str("")})
+			quasis = append(quasis, "")
+		}
+	}
+	return templateLiteral(quasis, exprs)
+}
+
+// element adds the element to the template and generates code for its
+// attributes and children
+func (c *component) element(b *block, el *ast.Element, nodeID string) {
+	b.template.WriteString("<" + el.Name)
+	if el.Name == "input" && needsInputDefaults(el) {
+		b.init = append(b.init, exprStmt(call(runtime("remove_input_defaults"), id(nodeID))))
+	}
+	_, grouped := el.Binding("group")
+	merged := map[string][]ast.Value{} // class and style attributes with directives
+	var classes, styles []effect
+	var bindings []*ast.Binding
+	for _, attr := range el.Attributes {
+		switch a := attr.(type) {
+		case *ast.Field:
+			if a.Forwards() || len(a.Modifiers) > 0 {
+				c.eventDirective(b, nodeID, a)
+				continue
+			}
+			if isEventHandler(a) {
+				c.event(b, nodeID, a.Key, a.Values[0].(*ast.Mustache).Expr)
+				continue
+			}
+			if grouped && a.Key == "value" {
+				c.groupValue(b, nodeID, a.Values)
+				continue
+			}
+			if key := strings.ToLower(a.Key); hasDirective(el, key) {
+				merged[key] = a.Values
+				continue
+			}
+			c.attribute(b, nodeID, a.Key, a.Values)
+		case *ast.AttributeShorthand:
+			if strings.HasPrefix(a.Key, "on") {
+				c.event(b, nodeID, a.Key, id(a.Key))
+				continue
+			}
+			values := []ast.Value{&ast.Mustache{Expr: id(a.Key)}}
+			if grouped && a.Key == "value" {
+				c.groupValue(b, nodeID, values)
+				continue
+			}
+			if hasDirective(el, a.Key) {
+				merged[a.Key] = values
+				continue
+			}
+			c.attribute(b, nodeID, a.Key, values)
+		case *ast.Class:
+			var expr js.IExpr = id(a.Name)
+			if m, ok := a.Value.(*ast.Mustache); ok {
+				expr = m.Expr
+			}
+			calls, dynamic := hasCall(expr), c.isDynamic(expr)
+			classes = append(classes, effect{
+				stmt:    exprStmt(call(runtime("toggle_class"), id(nodeID), str(a.Name), c.expr(expr))),
+				calls:   calls,
+				dynamic: dynamic,
+			})
+		case *ast.StyleDirective:
+			value, calls, dynamic := c.attributeValue(a.Values)
+			styles = append(styles, effect{
+				stmt:    exprStmt(call(runtime("set_style"), id(nodeID), str(a.Name), value)),
+				calls:   calls,
+				dynamic: dynamic,
+			})
+		case *ast.Binding:
+			bindings = append(bindings, a)
+		case *ast.NamedSlot:
+			b.template.WriteString(` slot="` + escapeAttribute(a.Name) + `"`)
+		case *ast.Let:
+			// Slot props are declared by the parent component
+		default:
+			c.errorf("unable to generate attribute %T", attr)
+		}
+	}
+	c.directives(b, nodeID, "class", merged["class"], classes)
+	c.directives(b, nodeID, "style", merged["style"], styles)
+	for _, binding := range bindings {
+		c.bind(b, nodeID, binding)
+	}
+	b.template.WriteString(">")
+	if voidElements[el.Name] {
+		return
+	}
+	accessed := false
+	c.children(b, clean(el.Children, el.Name), func(bool) js.IExpr {
+		accessed = true
+		return call(runtime("child"), id(nodeID))
+	})
+	if accessed {
+		b.init = append(b.init, exprStmt(call(runtime("reset"), id(nodeID))))
+	}
+	b.template.WriteString("</" + el.Name + ">")
+}
+
+// attribute adds static attributes to the template and sets dynamic ones
+func (c *component) attribute(b *block, nodeID, key string, values []ast.Value) {
+	if text, ok := staticValue(values); ok {
+		if len(values) == 0 {
+			b.template.WriteString(" " + key)
+			return
+		}
+		b.template.WriteString(" " + key + `="` + escapeAttribute(text) + `"`)
+		return
+	}
+	value, calls, dynamic := c.attributeValue(values)
+	name := strings.ToLower(key)
+	var stmt js.IStmt
+	switch {
+	case name == "class":
+		stmt = exprStmt(call(runtime("set_class"), id(nodeID), value))
+	case domProperties[name]:
+		property := name
+		if alias, ok := attributeAliases[name]; ok {
+			property = alias
+		}
+		stmt = exprStmt(assign(member(id(nodeID), property), value))
+	default:
+		stmt = exprStmt(call(runtime("set_attribute"), id(nodeID), str(key), value))
+	}
+	addEffect(b, stmt, calls, dynamic)
+}
+
+// attributeValue returns the value of an attribute, whether it calls functions
+// and whether it reads state that can change
+func (c *component) attributeValue(values []ast.Value) (value js.IExpr, calls, dynamic bool) {
+	var exprs []js.IExpr
+	fragments := make([]ast.Fragment, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case *ast.Mustache:
+			exprs = append(exprs, v.Expr)
+			fragments[i] = v
+		case *ast.Text:
+			fragments[i] = v
+		}
+	}
+	if len(values) == 0 {
+		return boolean(true), false, false
+	} else if text, ok := staticValue(values); ok {
+		return str(text), false, false
+	}
+	// Check before the expressions are rewritten
+	calls, dynamic = hasCall(exprs...), c.isDynamic(exprs...)
+	return c.textValue(fragments), calls, dynamic
+}
+
+// addEffect adds a statement that sets part of a node. Statements that read
+// state run in the template effect, while calls get their own effect.
+func addEffect(b *block, stmt js.IStmt, calls, dynamic bool) {
+	addEffects(b, []js.IStmt{stmt}, calls, dynamic)
+}
+
+// addEffects adds statements that need to run together
+func addEffects(b *block, stmts []js.IStmt, calls, dynamic bool) {
+	switch {
+	case calls:
+		b.init = append(b.init, templateEffect(stmts))
+	case dynamic:
+		b.update = append(b.update, stmts...)
+	default:
+		b.init = append(b.init, stmts...)
+	}
+}
+
+// effect is a statement that may need to run when state changes
+type effect struct {
+	stmt    js.IStmt
+	calls   bool
+	dynamic bool
+}
+
+// directives sets class: or style: directives along with the attribute they
+// merge into. Setting a dynamic attribute overwrites the directives, so they're
+// reapplied in the same effect.
+func (c *component) directives(b *block, nodeID, key string, values []ast.Value, effects []effect) {
+	if _, ok := staticValue(values); ok {
+		if values != nil {
+			c.attribute(b, nodeID, key, values)
+		}
+		for _, effect := range effects {
+			addEffect(b, effect.stmt, effect.calls, effect.dynamic)
+		}
+		return
+	}
+	value, calls, dynamic := c.attributeValue(values)
+	stmt := exprStmt(call(runtime("set_attribute"), id(nodeID), str(key), value))
+	if key == "class" {
+		stmt = exprStmt(call(runtime("set_class"), id(nodeID), value))
+	}
+	stmts := []js.IStmt{stmt}
+	for _, effect := range effects {
+		stmts = append(stmts, effect.stmt)
+		calls = calls || effect.calls
+		dynamic = dynamic || effect.dynamic
+	}
+	addEffects(b, stmts, calls, dynamic)
+}
+
+// hasDirective returns true if the element has class: or style: directives for
+// the key
+func hasDirective(el *ast.Element, key string) bool {
+	for _, attr := range el.Attributes {
+		switch attr.(type) {
+		case *ast.Class:
+			if key == "class" {
+				return true
+			}
+		case *ast.StyleDirective:
+			if key == "style" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// groupValue sets the value of an input bound to a group. The original value
+// is kept in __value, so groups can hold numbers and objects.
+func (c *component) groupValue(b *block, nodeID string, values []ast.Value) {
+	value, calls, dynamic := c.attributeValue(values)
+	stmt := exprStmt(assign(member(id(nodeID), "value"), &js.CondExpr{
+		Cond: &js.BinaryExpr{
+			Op: js.EqEqToken,
+			X:  null(),
+			Y:  &js.GroupExpr{X: assign(member(id(nodeID), "__value"), value)},
+		},
+		X: str(""),
+		Y: value,
+	}))
+	addEffect(b, stmt, calls, dynamic)
+}
+
+// event attaches an event handler. Delegated events are handled by a single
+// listener on the root, which calls the handler stored on the element.
+func (c *component) event(b *block, nodeID, key string, expr js.IExpr) {
+	name, capture, delegated := parseEvent(key)
+	if delegated {
+		if !contains(c.delegated, name) {
+			c.delegated = append(c.delegated, name)
+		}
+		handler := c.delegatedHandler(name, expr)
+		b.init = append(b.init, exprStmt(assign(member(id(nodeID), "__"+name), handler)))
+		return
+	}
+	args := []js.IExpr{str(name), id(nodeID), c.handler(expr)}
+	if capture {
+		args = append(args, boolean(true))
+	}
+	b.init = append(b.init, exprStmt(call(runtime("event"), args...)))
+}
+
+// eventDirective attaches a legacy on:event directive that has modifiers or
+// forwards the event. These are attached to the element rather than delegated,
+// so modifiers like stopPropagation and capture behave like they did in
+// Svelte 4.
+func (c *component) eventDirective(b *block, nodeID string, field *ast.Field) {
+	handler, ok := c.directiveHandler(field)
+	if !ok {
+		return
+	}
+	for _, modifier := range handlerModifiers {
+		if field.HasModifier(modifier) {
+			handler = call(runtime(modifier), handler)
+		}
+	}
+	args := []js.IExpr{str(strings.TrimPrefix(field.Key, "on")), id(nodeID), handler}
+	capture := field.HasModifier("capture")
+	switch {
+	case field.HasModifier("passive"):
+		args = append(args, boolean(capture), boolean(true))
+	case field.HasModifier("nonpassive"):
+		args = append(args, boolean(capture), boolean(false))
+	case capture:
+		args = append(args, boolean(true))
+	}
+	b.init = append(b.init, exprStmt(call(runtime("event"), args...)))
+}
+
+// handlerModifiers wrap the handler, in the order they're applied
+var handlerModifiers = []string{"stopPropagation", "stopImmediatePropagation", "preventDefault", "self", "trusted", "once"}
+
+// directiveHandler returns the handler of an on:event directive. Directives
+// without a value bubble the event up to the handlers the parent passed in
+// $$events.
+func (c *component) directiveHandler(field *ast.Field) (js.IExpr, bool) {
+	if field.Forwards() {
+		bubble := call(member(runtime("bubble_event"), "call"), &js.LiteralExpr{TokenType: js.ThisToken, Data: []byte("this")}, id("$$props"), id("$$arg"))
+		return &js.FuncDecl{Params: params("$$arg"), Body: blockStmt(exprStmt(bubble))}, true
+	}
+	m, ok := field.Values[0].(*ast.Mustache)
+	if !ok || len(field.Values) != 1 {
+		c.errorf("on:%s must be an expression", strings.TrimPrefix(field.Key, "on"))
+		return nil, false
+	}
+	return c.handler(m.Expr), true
+}
+
+// delegatedHandler returns the handler stored on the element. Handlers that
+// only use the component's variables are hoisted out of the component and
+// stored with those variables as [handler, ...variables].
+func (c *component) delegatedHandler(name string, expr js.IExpr) js.IExpr {
+	if v, ok := expr.(*js.Var); ok {
+		if b := c.lookup(v); b != nil && b.hoisted {
+			return hoistedHandler(v, b.params)
+		}
+	}
+	if isFunction(expr) {
+		if params, ok := c.hoistable(expr, nil); ok {
+			handler := c.generate("on_" + name)
+			c.hoisted = append(c.hoisted, c.hoistFunction(expr, &binding{name: handler, params: params}))
+			return hoistedHandler(id(handler), params)
+		}
+	}
+	return c.handler(expr)
+}
+
+func hoistedHandler(handler *js.Var, params []string) js.IExpr {
+	if len(params) == 0 {
+		return handler
+	}
+	list := &js.ArrayExpr{List: []js.Element{{Value: handler}}}
+	for _, param := range params {
+		list.List = append(list.List, js.Element{Value: id(param)})
+	}
+	return list
+}
+
+// handler returns the event handler. Expressions that could change or aren't
+// functions are called through a wrapper.
+func (c *component) handler(expr js.IExpr) js.IExpr {
+	switch e := expr.(type) {
+	case *js.ArrowFunc, *js.FuncDecl:
+		return c.expr(e)
+	case *js.Var:
+		if b := c.lookup(e); b == nil || (b.kind == normal || b.kind == imported) && !b.reassigned {
+			return e
+		}
+	}
+	apply := &js.CallExpr{
+		X: &js.DotExpr{
+			X:        group(c.expr(expr)),
+			Y:        js.LiteralExpr{TokenType: js.IdentifierToken, Data: []byte("apply")},
+			Optional: true,
+		},
+		Args: js.Args{List: []js.Arg{
+			{Value: &js.LiteralExpr{TokenType: js.ThisToken, Data: []byte("this")}},
+			{Value: id("$$args")},
+		}},
+	}
+	return &js.FuncDecl{
+		Params: js.Params{Rest: id("$$args")},
+		Body:   blockStmt(exprStmt(apply)),
+	}
+}
+
+// bind keeps the element's property and the variable in sync
+func (c *component) bind(b *block, nodeID string, binding *ast.Binding) {
+	m, ok := binding.Value.(*ast.Mustache)
+	if !ok {
+		c.errorf("bind:%s must be bound to a variable", binding.Key)
+		return
+	}
+	getter, setter := c.accessors(m.Expr)
+	update := lambda(params("$$value"), setter)
+	switch binding.Key {
+	case "value":
+		b.after = append(b.after, exprStmt(call(runtime("bind_value"), id(nodeID), thunk(getter), update)))
+	case "checked":
+		b.after = append(b.after, exprStmt(call(runtime("bind_checked"), id(nodeID), thunk(getter), update)))
+	case "group":
+		group := c.bindingGroup(m.Expr)
+		b.after = append(b.after, exprStmt(call(runtime("bind_group"), id(group), &js.ArrayExpr{}, id(nodeID), thunk(getter), update)))
+	case "this":
+		b.after = append(b.after, exprStmt(call(runtime("bind_this"), id(nodeID), update, thunk(getter))))
+	default:
+		c.errorf("bind:%s isn't supported yet", binding.Key)
+	}
+}
+
+// accessors returns the expressions that read and write a bound variable,
+// where the written value is $$value
+func (c *component) accessors(expr js.IExpr) (getter, setter js.IExpr) {
+	value := id("$$value")
+	if v, ok := expr.(*js.Var); ok && c.lookup(v) != nil && c.lookup(v).isSignal() {
+		if b := c.lookup(v); b.kind == prop || b.kind == bindableProp {
+			return c.read(v), call(v, value)
+		}
+		return c.read(v), call(runtime("set"), v, value)
+	}
+	target := c.expr(expr)
+	return target, assign(target, value)
+}
+
+// bindingGroup returns the array shared by the inputs bound to the same group
+func (c *component) bindingGroup(expr js.IExpr) string {
+	key := expr.JS()
+	if name, ok := c.groups[key]; ok {
+		return name
+	}
+	name := c.generate("binding_group")
+	c.groups[key] = name
+	c.groupDecls = append(c.groupDecls, &js.VarDecl{
+		TokenType: js.ConstToken,
+		List:      []js.BindingElement{{Binding: id(name), Default: &js.ArrayExpr{}}},
+	})
+	return name
+}
+
+// child renders a component at the anchor. Props that read state are passed
+// as getters, so the component sees the latest value, and bound props also
+// get a setter.
+func (c *component) child(b *block, node *ast.Component, nodeID string) {
+	b.template.WriteString("<!>")
+	props := &js.ObjectExpr{}
+	events := &js.ObjectExpr{}
+	var this js.IExpr
+	for _, attr := range node.Attributes {
+		switch a := attr.(type) {
+		case *ast.Field:
+			if a.Directive {
+				c.componentEvent(events, a)
+				continue
+			}
+			value, calls, dynamic := c.attributeValue(a.Values)
+			props.List = append(props.List, componentProp(a.Key, value, calls || dynamic))
+		case *ast.AttributeShorthand:
+			expr := id(a.Key)
+			dynamic := c.isDynamic(expr)
+			props.List = append(props.List, componentProp(a.Key, c.expr(expr), dynamic))
+		case *ast.Binding:
+			m, ok := a.Value.(*ast.Mustache)
+			if !ok {
+				c.errorf("bind:%s must be bound to a variable", a.Key)
+				continue
+			}
+			if a.Key == "this" {
+				this = m.Expr
+				continue
+			}
+			getter, setter := c.accessors(m.Expr)
+			props.List = append(props.List,
+				js.Property{Value: &js.MethodDecl{Get: true, Name: *propertyName(a.Key), Body: blockStmt(&js.ReturnStmt{Value: getter})}},
+				js.Property{Value: &js.MethodDecl{Set: true, Name: *propertyName(a.Key), Params: params("$$value"), Body: blockStmt(exprStmt(setter))}},
+			)
+		case *ast.Let:
+			// Slot props of the default slot
+		default:
+			c.errorf("%T isn't supported on components yet", attr)
+		}
+	}
+	if len(events.List) > 0 {
+		props.List = append(props.List, js.Property{Name: propertyName("$$events"), Value: events})
+	}
+	c.slotProps(props, node)
+	callee := node.Name
+	if node.IsSelf() {
+		callee = c.name
+	}
+	var render js.IExpr = call(id(callee), id(nodeID), props)
+	if this != nil {
+		getter, setter := c.accessors(this)
+		render = call(runtime("bind_this"), render, lambda(params("$$value"), setter), thunk(getter))
+	}
+	b.init = append(b.init, exprStmt(render))
+}
+
+// slotProps passes the component's children into the slots they're for. The
+// default slot is passed as children, like a snippet, unless it declares slot
+// props, which children can't receive.
+func (c *component) slotProps(props *js.ObjectExpr, node *ast.Component) {
+	slots := &js.ObjectExpr{}
+	for _, content := range slotContents(node) {
+		if len(clean(content.nodes, "")) == 0 {
+			continue
+		}
+		param := c.generate("$$slotProps")
+		c.scopes = append(c.scopes, c.slotScope(content.lets, param))
+		fn := arrow(params("$$anchor", param), c.fragment(content.nodes, "")...)
+		c.scopes = c.scopes[:len(c.scopes)-1]
+		if content.name == "default" && len(content.lets) == 0 {
+			props.List = append(props.List, js.Property{Name: propertyName("children"), Value: fn})
+			slots.List = append(slots.List, js.Property{Name: propertyName("default"), Value: boolean(true)})
+			continue
+		}
+		slots.List = append(slots.List, js.Property{Name: propertyName(content.name), Value: fn})
+	}
+	if len(slots.List) > 0 {
+		props.List = append(props.List, js.Property{Name: propertyName("$$slots"), Value: slots})
+	}
+}
+
+// slotContent is what a component passes into one of its child's slots
+type slotContent struct {
+	name  string
+	lets  []*ast.Let
+	nodes []ast.Fragment
+}
+
+// slotContents sorts the component's children into the slots they're passed
+// into. Elements with a slot attribute go into that slot, along with their
+// let: directives, and everything else goes into the default slot.
+func slotContents(node *ast.Component) []*slotContent {
+	contents := []*slotContent{{name: "default", lets: letsOf(node.Attributes)}}
+	named := map[string]*slotContent{}
+	for _, child := range node.Children {
+		el, ok := child.(*ast.Element)
+		if !ok {
+			contents[0].nodes = append(contents[0].nodes, child)
+			continue
+		}
+		name, ok := slotOf(el)
+		if !ok || name == "default" {
+			contents[0].nodes = append(contents[0].nodes, child)
+			contents[0].lets = append(contents[0].lets, letsOf(el.Attributes)...)
+			continue
+		}
+		content, ok := named[name]
+		if !ok {
+			content = &slotContent{name: name}
+			named[name] = content
+			contents = append(contents, content)
+		}
+		content.lets = append(content.lets, letsOf(el.Attributes)...)
+		content.nodes = append(content.nodes, el)
+	}
+	return contents
+}
+
+func letsOf(attrs []ast.Attribute) (lets []*ast.Let) {
+	for _, attr := range attrs {
+		if let, ok := attr.(*ast.Let); ok {
+			lets = append(lets, let)
+		}
+	}
+	return lets
+}
+
+// componentEvent adds the handler of an on:event directive on a component to
+// its $$events, where the component's createEventDispatcher and forwarded
+// events look for them. Only the once modifier applies to component events.
+func (c *component) componentEvent(events *js.ObjectExpr, field *ast.Field) {
+	for _, modifier := range field.Modifiers {
+		if modifier != "once" {
+			c.errorf("the %s modifier can't be used on components", modifier)
+			return
+		}
+	}
+	handler, ok := c.directiveHandler(field)
+	if !ok {
+		return
+	}
+	if field.HasModifier("once") {
+		handler = call(runtime("once"), handler)
+	}
+	events.List = append(events.List, js.Property{Name: propertyName(strings.TrimPrefix(field.Key, "on")), Value: handler})
+}
+
+// componentProp returns the property passing the value to a component
+func componentProp(key string, value js.IExpr, dynamic bool) js.Property {
+	if !dynamic {
+		return js.Property{Name: propertyName(key), Value: value}
+	}
+	return js.Property{Value: &js.MethodDecl{Get: true, Name: *propertyName(key), Body: blockStmt(&js.ReturnStmt{Value: value})}}
+}
+
+func propertyName(key string) *js.PropertyName {
+	if isIdentifier(key) {
+		return &js.PropertyName{Literal: js.LiteralExpr{TokenType: js.IdentifierToken, Data: []byte(key)}}
+	}
+	return &js.PropertyName{Literal: js.LiteralExpr{TokenType: js.StringToken, Data: []byte(quote(key))}}
+}
+
+// slotOf returns the slot an element is passed into
+func slotOf(el *ast.Element) (string, bool) {
+	for _, attr := range el.Attributes {
+		if slot, ok := attr.(*ast.NamedSlot); ok {
+			return slot.Name, true
+		}
+	}
+	return "", false
+}
+
+// each renders the body for every item in the list
+func (c *component) each(b *block, node *ast.EachBlock, nodeID string) {
+	b.template.WriteString("<!>")
+	dynamic := hasCall(node.List) || c.isDynamic(node.List)
+	collection := thunk(c.expr(node.List))
+	flags := eachItemReactive
+	if c.runes {
+		flags |= eachIsStrictEq
+	}
+	itemName := "$$item"
+	if node.Value != nil {
+		itemName = string(node.Value.Data)
+	}
+	names := []string{"$$anchor", itemName}
+	if node.Key != nil {
+		names = append(names, string(node.Key.Data))
+	}
+	c.scopes = append(c.scopes, c.eachScope(node, dynamic))
+	body := c.fragment(node.Body, "")
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	args := []js.IExpr{id(nodeID), num(flags), collection, runtime("index"), arrow(params(names...), body...)}
+	if len(node.Else) > 0 {
+		args = append(args, arrow(params("$$anchor"), c.fragment(node.Else, "")...))
+	}
+	b.init = append(b.init, exprStmt(call(runtime("each"), args...)))
+}
+
+// ifBlock renders the consequent while the condition holds and the alternate
+// otherwise
+func (c *component) ifBlock(b *block, node *ast.IfBlock, nodeID string) {
+	b.template.WriteString("<!>")
+	args := []js.IExpr{id(nodeID), thunk(c.expr(node.Cond)), arrow(params("$$anchor"), c.fragment(node.Then, "")...)}
+	if len(clean(node.Else, "")) > 0 {
+		args = append(args, arrow(params("$$anchor"), c.fragment(node.Else, "")...))
+	}
+	b.init = append(b.init, exprStmt(call(runtime("if"), args...)))
+}
+
+// slot renders the content the parent passed into the slot, or the fallback
+// when there isn't any. Slot props that can change are passed as getters.
+func (c *component) slot(b *block, node *ast.Slot, nodeID string) {
+	b.template.WriteString("<!>")
+	name := node.Name
+	if name == "" {
+		name = "default"
+	}
+	props := &js.ObjectExpr{}
+	for _, attr := range node.Attributes {
+		switch a := attr.(type) {
+		case *ast.Field:
+			value, calls, dynamic := c.attributeValue(a.Values)
+			props.List = append(props.List, componentProp(a.Key, value, calls || dynamic))
+		case *ast.AttributeShorthand:
+			expr := id(a.Key)
+			props.List = append(props.List, componentProp(a.Key, c.expr(expr), c.isDynamic(expr)))
+		default:
+			c.errorf("%T isn't supported on slots", attr)
+		}
+	}
+	var fallback js.IExpr = null()
+	if len(clean(node.Fallback, "")) > 0 {
+		fallback = arrow(params("$$anchor"), c.fragment(node.Fallback, "")...)
+	}
+	b.init = append(b.init, exprStmt(call(runtime("slot"), id(nodeID), id("$$props"), str(name), props, fallback)))
+}
+
+// await renders the pending content until the promise settles, then the
+// content for its value or error
+func (c *component) await(b *block, node *ast.AwaitBlock, nodeID string) {
+	b.template.WriteString("<!>")
+	args := []js.IExpr{id(nodeID), thunk(c.expr(node.Promise)), null(), null()}
+	if len(clean(node.Pending, "")) > 0 {
+		args[2] = arrow(params("$$anchor"), c.fragment(node.Pending, "")...)
+	}
+	// settled renders the content, given the value or error it reads
+	settled := func(v *js.Var, nodes []ast.Fragment) js.IExpr {
+		names := []string{"$$anchor"}
+		if v != nil {
+			names = append(names, string(v.Data))
+		}
+		c.scopes = append(c.scopes, awaitScope(v))
+		body := c.fragment(nodes, "")
+		c.scopes = c.scopes[:len(c.scopes)-1]
+		return arrow(params(names...), body...)
+	}
+	if len(clean(node.Then, "")) > 0 {
+		args[3] = settled(node.Value, node.Then)
+	}
+	if len(clean(node.Catch, "")) > 0 {
+		args = append(args, settled(node.Error, node.Catch))
+	}
+	b.init = append(b.init, exprStmt(call(runtime("await"), args...)))
+}
+
+// html renders the value as HTML, replacing the nodes when it changes
+func (c *component) html(b *block, node *ast.HTML, nodeID string) {
+	b.template.WriteString("<!>")
+	value := c.mark(c.expr(node.Expr), node.Pos)
+	b.init = append(b.init, exprStmt(call(runtime("html"), id(nodeID), thunk(value), boolean(false), boolean(false))))
+}
+
+// isDynamic returns true if the expressions read state that can change
+func (c *component) isDynamic(exprs ...js.IExpr) bool {
+	dynamic := false
+	visit := func(expr js.IExpr) js.IExpr {
+		if v, ok := expr.(*js.Var); ok {
+			if b := c.lookup(v); b != nil && c.settle(b) || b == nil && string(v.Data) == "$$props" {
+				dynamic = true
+			}
+		}
+		return nil
+	}
+	for _, expr := range exprs {
+		rewriter(visit).expr(expr)
+	}
+	return dynamic
+}
+
+// hasCall returns true if the expressions call functions, which could read
+// state we don't know about
+func hasCall(exprs ...js.IExpr) bool {
+	found := false
+	visit := func(expr js.IExpr) js.IExpr {
+		if _, ok := expr.(*js.CallExpr); ok {
+			found = true
+		}
+		return nil
+	}
+	for _, expr := range exprs {
+		rewriter(visit).expr(expr)
+	}
+	return found
+}
+
+// clean removes nodes that aren't rendered and collapses whitespace the way
+// browsers would render it
+func clean(nodes []ast.Fragment, parent string) []ast.Fragment {
+	var regular []ast.Fragment
+	for _, node := range nodes {
+		switch node.(type) {
+		case *ast.Comment, *ast.Script, *ast.Style, *ast.Options:
+			continue
+		}
+		regular = append(regular, node)
+	}
+	if parent == "pre" || parent == "textarea" {
+		return regular
+	}
+	for len(regular) > 0 && isWhitespace(regular[0]) {
+		regular = regular[1:]
+	}
+	for len(regular) > 0 && isWhitespace(regular[len(regular)-1]) {
+		regular = regular[:len(regular)-1]
+	}
+	removable := removableWhitespace[parent]
+	var trimmed []ast.Fragment
+	for i, node := range regular {
+		text, ok := node.(*ast.Text)
+		if !ok {
+			trimmed = append(trimmed, node)
+			continue
+		}
+		value := text.Value
+		if i == 0 {
+			value = strings.TrimLeft(value, whitespace)
+		} else if _, ok := regular[i-1].(*ast.Mustache); !ok {
+			prev, isText := regular[i-1].(*ast.Text)
+			if isText && strings.TrimRight(prev.Value, whitespace) != prev.Value {
+				value = strings.TrimLeft(value, whitespace)
+			} else {
+				value = collapseLeft(value)
+			}
+		}
+		if i == len(regular)-1 {
+			value = strings.TrimRight(value, whitespace)
+		} else if _, ok := regular[i+1].(*ast.Mustache); !ok {
+			value = collapseRight(value)
+		}
+		if value == "" || value == " " && removable {
+			continue
+		}
+		trimmed = append(trimmed, &ast.Text{Value: value})
+	}
+	return trimmed
+}
+
+const whitespace = " \t\r\n"
+
+func isWhitespace(node ast.Fragment) bool {
+	text, ok := node.(*ast.Text)
+	return ok && strings.Trim(text.Value, whitespace) == ""
+}
+
+func collapseLeft(s string) string {
+	if trimmed := strings.TrimLeft(s, whitespace); trimmed != s {
+		return " " + trimmed
+	}
+	return s
+}
+
+func collapseRight(s string) string {
+	if trimmed := strings.TrimRight(s, whitespace); trimmed != s {
+		return trimmed + " "
+	}
+	return s
+}
+
+func isElement(node ast.Fragment) bool {
+	_, ok := node.(*ast.Element)
+	return ok
+}
+
+// isText returns true if the nodes are all text and expressions
+func isText(nodes []ast.Fragment) bool {
+	for _, node := range nodes {
+		switch node.(type) {
+		case *ast.Text, *ast.Mustache:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// staticText returns the text if there are no expressions
+func staticText(nodes []ast.Fragment) (string, bool) {
+	text := ""
+	for _, node := range nodes {
+		t, ok := node.(*ast.Text)
+		if !ok {
+			return "", false
+		}
+		text += t.Value
+	}
+	return text, true
+}
+
+func staticValue(values []ast.Value) (string, bool) {
+	text := ""
+	for _, value := range values {
+		t, ok := value.(*ast.Text)
+		if !ok {
+			return "", false
+		}
+		text += t.Value
+	}
+	return text, true
+}
+
+func escapeAttribute(s string) string {
+	return strings.ReplaceAll(s, `"`, "&quot;")
+}
+
+// isEventHandler returns true for attributes like onclick={...}
+func isEventHandler(field *ast.Field) bool {
+	if !strings.HasPrefix(field.Key, "on") || len(field.Values) != 1 {
+		return false
+	}
+	_, ok := field.Values[0].(*ast.Mustache)
+	return ok
+}
+
+// parseEvent returns the event an attribute like onclick or onClick listens
+// to, whether it listens during the capture phase and whether it's delegated
+func parseEvent(key string) (name string, capture, delegated bool) {
+	if handler, capture, ok := event.Parse(key); ok {
+		return handler.Name(), capture, !capture && handler.Delegated()
+	}
+	// Custom events
+	name = strings.ToLower(strings.TrimPrefix(key, "on"))
+	if strings.HasSuffix(name, "capture") && name != "gotpointercapture" && name != "lostpointercapture" {
+		return strings.TrimSuffix(name, "capture"), true, false
+	}
+	return name, false, false
+}
+
+// needsInputDefaults returns true if the input's value or checked state is set
+// by code, so the defaults from the template shouldn't be reset on a form reset
+func needsInputDefaults(el *ast.Element) bool {
+	for _, attr := range el.Attributes {
+		switch a := attr.(type) {
+		case *ast.Binding:
+			if a.Key == "value" || a.Key == "checked" || a.Key == "group" {
+				return true
+			}
+		case *ast.Field:
+			if a.Key == "value" || a.Key == "checked" {
+				if _, ok := staticValue(a.Values); !ok {
+					return true
+				}
+			}
+		case *ast.AttributeShorthand:
+			if a.Key == "value" || a.Key == "checked" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var voidElements = map[string]bool{
+	"area":    true,
+	"base":    true,
+	"br":      true,
+	"col":     true,
+	"command": true,
+	"embed":   true,
+	"hr":      true,
+	"img":     true,
+	"input":   true,
+	"keygen":  true,
+	"link":    true,
+	"meta":    true,
+	"param":   true,
+	"source":  true,
+	"track":   true,
+	"wbr":     true,
+}
+
+// Whitespace between these elements' children isn't rendered
+var removableWhitespace = map[string]bool{
+	"select":   true,
+	"tr":       true,
+	"table":    true,
+	"tbody":    true,
+	"thead":    true,
+	"tfoot":    true,
+	"colgroup": true,
+	"datalist": true,
+}
+
+// Attributes that are set as DOM properties
+var domProperties = map[string]bool{
+	"allowfullscreen": true,
+	"async":           true,
+	"autofocus":       true,
+	"autoplay":        true,
+	"checked":         true,
+	"controls":        true,
+	"default":         true,
+	"disabled":        true,
+	"formnovalidate":  true,
+	"hidden":          true,
+	"indeterminate":   true,
+	"inert":           true,
+	"ismap":           true,
+	"loop":            true,
+	"multiple":        true,
+	"muted":           true,
+	"nomodule":        true,
+	"novalidate":      true,
+	"open":            true,
+	"playsinline":     true,
+	"readonly":        true,
+	"required":        true,
+	"reversed":        true,
+	"seamless":        true,
+	"selected":        true,
+	"value":           true,
+	"volume":          true,
+	"webkitdirectory": true,
+}
+
+// Properties with different names than their attributes
+var attributeAliases = map[string]string{
+	"formnovalidate": "formNoValidate",
+	"ismap":          "isMap",
+	"nomodule":       "noModule",
+	"playsinline":    "playsInline",
+	"readonly":       "readOnly",
+}
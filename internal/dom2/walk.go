@@ -0,0 +1,227 @@
+package dom
+
+import (
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// rewriter replaces expressions in source order. It returns nil to keep the
+// expression, in which case the expression's children are rewritten instead.
+type rewriter func(expr js.IExpr) js.IExpr
+
+func (fn rewriter) expr(expr js.IExpr) js.IExpr {
+	if expr == nil {
+		return nil
+	}
+	if replaced := fn(expr); replaced != nil {
+		return replaced
+	}
+	switch n := expr.(type) {
+	case *js.GroupExpr:
+		n.X = fn.expr(n.X)
+	case *js.ArrayExpr:
+		for i := range n.List {
+			n.List[i].Value = fn.expr(n.List[i].Value)
+		}
+	case *js.ObjectExpr:
+		for i := range n.List {
+			property := &n.List[i]
+			if property.Name != nil && property.Name.Computed != nil {
+				property.Name.Computed = fn.expr(property.Name.Computed)
+			}
+			property.Value = fn.expr(property.Value)
+			property.Init = fn.expr(property.Init)
+		}
+	case *js.TemplateExpr:
+		n.Tag = fn.expr(n.Tag)
+		for i := range n.List {
+			n.List[i].Expr = fn.expr(n.List[i].Expr)
+		}
+	case *js.IndexExpr:
+		n.X = fn.expr(n.X)
+		n.Y = fn.expr(n.Y)
+	case *js.DotExpr:
+		n.X = fn.expr(n.X)
+	case *js.NewExpr:
+		n.X = fn.expr(n.X)
+		if n.Args != nil {
+			fn.args(n.Args)
+		}
+	case *js.CallExpr:
+		n.X = fn.expr(n.X)
+		fn.args(&n.Args)
+	case *js.UnaryExpr:
+		n.X = fn.expr(n.X)
+	case *js.BinaryExpr:
+		n.X = fn.expr(n.X)
+		n.Y = fn.expr(n.Y)
+	case *js.CondExpr:
+		n.Cond = fn.expr(n.Cond)
+		n.X = fn.expr(n.X)
+		n.Y = fn.expr(n.Y)
+	case *js.YieldExpr:
+		n.X = fn.expr(n.X)
+	case *js.CommaExpr:
+		for i := range n.List {
+			n.List[i] = fn.expr(n.List[i])
+		}
+	case *js.ArrowFunc:
+		fn.params(&n.Params)
+		fn.block(&n.Body)
+	case *js.FuncDecl:
+		fn.params(&n.Params)
+		fn.block(&n.Body)
+	case *js.MethodDecl:
+		fn.params(&n.Params)
+		fn.block(&n.Body)
+	case *js.ClassDecl:
+		fn.class(n)
+	case *js.VarDecl:
+		fn.varDecl(n)
+	}
+	return expr
+}
+
+func (fn rewriter) args(args *js.Args) {
+	for i := range args.List {
+		args.List[i].Value = fn.expr(args.List[i].Value)
+	}
+}
+
+func (fn rewriter) params(params *js.Params) {
+	for i := range params.List {
+		fn.bindingElement(&params.List[i])
+	}
+	fn.binding(params.Rest)
+}
+
+func (fn rewriter) varDecl(decl *js.VarDecl) {
+	for i := range decl.List {
+		fn.bindingElement(&decl.List[i])
+	}
+}
+
+func (fn rewriter) bindingElement(element *js.BindingElement) {
+	fn.binding(element.Binding)
+	element.Default = fn.expr(element.Default)
+}
+
+// binding rewrites the defaults within a binding pattern, leaving the
+// declared variables alone
+func (fn rewriter) binding(binding js.IBinding) {
+	switch b := binding.(type) {
+	case *js.BindingArray:
+		for i := range b.List {
+			fn.bindingElement(&b.List[i])
+		}
+		fn.binding(b.Rest)
+	case *js.BindingObject:
+		for i := range b.List {
+			item := &b.List[i]
+			if item.Key != nil && item.Key.Computed != nil {
+				item.Key.Computed = fn.expr(item.Key.Computed)
+			}
+			fn.bindingElement(&item.Value)
+		}
+	}
+}
+
+func (fn rewriter) class(class *js.ClassDecl) {
+	class.Extends = fn.expr(class.Extends)
+	for i := range class.List {
+		element := &class.List[i]
+		switch {
+		case element.StaticBlock != nil:
+			fn.block(element.StaticBlock)
+		case element.Method != nil:
+			fn.params(&element.Method.Params)
+			fn.block(&element.Method.Body)
+		default:
+			if element.Field.Name.Computed != nil {
+				element.Field.Name.Computed = fn.expr(element.Field.Name.Computed)
+			}
+			element.Field.Init = fn.expr(element.Field.Init)
+		}
+	}
+}
+
+func (fn rewriter) block(block *js.BlockStmt) {
+	for i := range block.List {
+		block.List[i] = fn.stmt(block.List[i])
+	}
+}
+
+func (fn rewriter) stmt(stmt js.IStmt) js.IStmt {
+	switch s := stmt.(type) {
+	case *js.BlockStmt:
+		fn.block(s)
+	case *js.ExprStmt:
+		s.Value = fn.expr(s.Value)
+	case *js.VarDecl:
+		fn.varDecl(s)
+	case *js.IfStmt:
+		s.Cond = fn.expr(s.Cond)
+		s.Body = fn.stmt(s.Body)
+		if s.Else != nil {
+			s.Else = fn.stmt(s.Else)
+		}
+	case *js.DoWhileStmt:
+		s.Body = fn.stmt(s.Body)
+		s.Cond = fn.expr(s.Cond)
+	case *js.WhileStmt:
+		s.Cond = fn.expr(s.Cond)
+		s.Body = fn.stmt(s.Body)
+	case *js.ForStmt:
+		s.Init = fn.expr(s.Init)
+		s.Cond = fn.expr(s.Cond)
+		s.Post = fn.expr(s.Post)
+		if s.Body != nil {
+			fn.block(s.Body)
+		}
+	case *js.ForInStmt:
+		s.Init = fn.expr(s.Init)
+		s.Value = fn.expr(s.Value)
+		if s.Body != nil {
+			fn.block(s.Body)
+		}
+	case *js.ForOfStmt:
+		s.Init = fn.expr(s.Init)
+		s.Value = fn.expr(s.Value)
+		if s.Body != nil {
+			fn.block(s.Body)
+		}
+	case *js.SwitchStmt:
+		s.Init = fn.expr(s.Init)
+		for i := range s.List {
+			clause := &s.List[i]
+			clause.Cond = fn.expr(clause.Cond)
+			for j := range clause.List {
+				clause.List[j] = fn.stmt(clause.List[j])
+			}
+		}
+	case *js.ReturnStmt:
+		s.Value = fn.expr(s.Value)
+	case *js.LabelledStmt:
+		s.Value = fn.stmt(s.Value)
+	case *js.ThrowStmt:
+		s.Value = fn.expr(s.Value)
+	case *js.TryStmt:
+		if s.Body != nil {
+			fn.block(s.Body)
+		}
+		fn.binding(s.Binding)
+		if s.Catch != nil {
+			fn.block(s.Catch)
+		}
+		if s.Finally != nil {
+			fn.block(s.Finally)
+		}
+	case *js.FuncDecl:
+		fn.params(&s.Params)
+		fn.block(&s.Body)
+	case *js.ClassDecl:
+		fn.class(s)
+	case *js.ExportStmt:
+		s.Decl = fn.expr(s.Decl)
+	}
+	return stmt
+}
@@ -24,6 +24,7 @@ var (
 	PlatformNeutral = api.PlatformNeutral
 	PlatformBrowser = api.PlatformBrowser
 	LoaderTSX       = api.LoaderTSX
+	SourceMapInline = api.SourceMapInline
 )
 
 func BuildOne(options BuildOptions) (File, error) {
@@ -4,19 +4,32 @@ import (
 	"fmt"
 	"io/fs"
 	"path/filepath"
+	"strings"
 
 	"github.com/evanw/esbuild/pkg/api"
-	"github.com/livebud/duo/internal/dom"
+	"github.com/livebud/duo/internal/check"
+	dom "github.com/livebud/duo/internal/dom2"
 )
 
 const namespace = "svelte"
 const filter = `\.svelte$`
 
-// Svelte plugin for compiling Svelte components
+// SvelteRuntime is where imports of svelte, like the svelte/internal/client
+// that components import, are loaded from. It's pinned to the version of the
+// compiler in package.json, which the generated components match.
+const SvelteRuntime = "https://esm.sh/svelte@5.0.0-next.216"
+
+// Svelte plugin for compiling Svelte components. The Svelte runtime is loaded
+// through the HTTP plugin.
 func Svelte(fsys fs.FS) api.Plugin {
 	return api.Plugin{
 		Name: "svelte",
 		Setup: func(epb api.PluginBuild) {
+			epb.OnResolve(api.OnResolveOptions{Filter: `^svelte(/.*)?$`}, func(args api.OnResolveArgs) (result api.OnResolveResult, err error) {
+				result.Path = SvelteRuntime + strings.TrimPrefix(args.Path, "svelte")
+				result.Namespace = httpNamespace
+				return result, nil
+			})
 			epb.OnResolve(api.OnResolveOptions{Filter: filter}, func(args api.OnResolveArgs) (result api.OnResolveResult, err error) {
 				result.Path = args.Path
 				result.Namespace = namespace
@@ -27,16 +40,36 @@ func Svelte(fsys fs.FS) api.Plugin {
 				if err != nil {
 					return api.OnLoadResult{}, fmt.Errorf("reading file: %w", err)
 				}
-				clientCode, err := dom.Generate(args.Path, code)
+				// Warnings are reported by esbuild alongside its own
+				var warnings []api.Message
+				generator := &dom.Generator{
+					Warn: func(diagnostic *check.Diagnostic) {
+						warnings = append(warnings, warning(args.Path, diagnostic))
+					},
+				}
+				clientCode, sourceMap, err := generator.GenerateWithSourceMap(args.Path, code)
 				if err != nil {
 					return api.OnLoadResult{}, fmt.Errorf("generating client: %w", err)
 				}
+				// Point esbuild's source maps back to the component
+				clientCode += "//# sourceMappingURL=" + sourceMap.DataURL() + "\n"
 				return api.OnLoadResult{
 					Contents:   &clientCode,
 					Loader:     api.LoaderJS,
 					ResolveDir: filepath.Dir(args.Path),
+					Warnings:   warnings,
 				}, nil
 			})
 		},
 	}
 }
+
+// warning converts a diagnostic into an esbuild message
+func warning(path string, diagnostic *check.Diagnostic) api.Message {
+	message := api.Message{Text: diagnostic.Message}
+	if diagnostic.Line > 0 {
+		// esbuild's columns start at 0
+		message.Location = &api.Location{File: path, Line: diagnostic.Line, Column: diagnostic.Column - 1}
+	}
+	return message
+}
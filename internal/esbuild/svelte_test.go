@@ -0,0 +1,114 @@
+package esbuild_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/livebud/duo/internal/esbuild"
+	"github.com/matryer/is"
+)
+
+func TestSvelteSourceMap(t *testing.T) {
+	is := is.New(t)
+	component := "<script>\n  export let name = \"Mark\"\n</script>\n\n<h1>Hello {name}!</h1>\n"
+	fsys := fstest.MapFS{
+		"index.svelte": &fstest.MapFile{Data: []byte(component)},
+	}
+	runtime := &runtime{}
+	file, err := esbuild.BuildOne(esbuild.BuildOptions{
+		AbsWorkingDir: t.TempDir(),
+		EntryPoints:   []string{"./index.svelte"},
+		Plugins: []esbuild.Plugin{
+			esbuild.HTTP(&http.Client{Transport: runtime}),
+			esbuild.Svelte(fsys),
+		},
+		Format:    esbuild.FormatESModule,
+		Platform:  esbuild.PlatformBrowser,
+		Sourcemap: esbuild.SourceMapInline,
+		Bundle:    true,
+	})
+	is.NoErr(err)
+	code := string(file.Contents)
+	prefix := "//# sourceMappingURL=data:application/json;base64,"
+	index := strings.LastIndex(code, prefix)
+	is.True(index >= 0)
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(code[index+len(prefix):]))
+	is.NoErr(err)
+	var sourceMap struct {
+		Sources        []string `json:"sources"`
+		SourcesContent []string `json:"sourcesContent"`
+		Mappings       string   `json:"mappings"`
+	}
+	is.NoErr(json.Unmarshal(data, &sourceMap))
+	// The component is compiled against the pinned runtime
+	is.Equal(runtime.requests, []string{esbuild.SvelteRuntime + "/internal/client"})
+	is.Equal(len(sourceMap.Sources), 2)
+	is.True(strings.HasSuffix(sourceMap.Sources[1], "index.svelte"))
+	is.Equal(sourceMap.SourcesContent[1], component)
+	is.True(sourceMap.Mappings != "")
+}
+
+// runtime serves a stub of the Svelte runtime
+type runtime struct {
+	requests []string
+}
+
+func (r *runtime) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.requests = append(r.requests, req.URL.String())
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader("export function template(html) { return () => html }\n")),
+		Request:    req,
+	}, nil
+}
+
+func TestSveltePrunesEffects(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		"story.svelte": &fstest.MapFile{Data: []byte(`<script>let { title } = $props(); let count = $state(1); const label = $derived(count * 2)</script><h1>{label}</h1><p>{title}</p>`)},
+	}
+	file, err := esbuild.BuildOne(esbuild.BuildOptions{
+		AbsWorkingDir: t.TempDir(),
+		EntryPoints:   []string{"./story.svelte"},
+		Plugins: []esbuild.Plugin{
+			esbuild.Svelte(fsys),
+		},
+		Format:   esbuild.FormatESModule,
+		Platform: esbuild.PlatformBrowser,
+	})
+	is.NoErr(err)
+	code := string(file.Contents)
+	// State that's never written to is read once
+	is.True(strings.Contains(code, "let count = 1;"))
+	is.True(strings.Contains(code, "text.nodeValue = $.get(label);"))
+	// Only props can change, so they're the only values in an effect
+	is.Equal(strings.Count(code, "$.template_effect("), 1)
+	is.True(strings.Contains(code, "$.set_text(text_1, $$props.title)"))
+}
+
+func TestSvelteWarnings(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		"index.svelte": &fstest.MapFile{Data: []byte("<script>function add() {}</script>\n<button onclik={add}>+</button>")},
+	}
+	result := api.Build(api.BuildOptions{
+		AbsWorkingDir: t.TempDir(),
+		EntryPoints:   []string{"./index.svelte"},
+		Plugins: []esbuild.Plugin{
+			esbuild.Svelte(fsys),
+		},
+		Format: esbuild.FormatESModule,
+	})
+	is.Equal(len(result.Errors), 0)
+	is.Equal(len(result.Warnings), 1)
+	is.Equal(result.Warnings[0].Text, "Unknown event handler \"onclik\" on `<button>`, did you mean \"onclick\"?")
+	is.Equal(result.Warnings[0].Location.File, "./index.svelte")
+	is.Equal(result.Warnings[0].Location.Line, 2)
+	is.Equal(result.Warnings[0].Location.Column, 0)
+}
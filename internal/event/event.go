@@ -1,5 +1,7 @@
 package event
 
+import "strings"
+
 type Key uint8
 
 // Based on:
@@ -133,12 +135,133 @@ const (
 	Resize
 )
 
-// Is an event handler
-func Is(key string) bool {
-	_, ok := To[key]
+// Is an event handler, like onClick, onclick or on:click
+func Is(attr string) bool {
+	_, _, ok := Parse(attr)
 	return ok
 }
 
+// Parse an event handler attribute, like the React-style onClick, the Svelte 5
+// onclick or the legacy on:click directive. Handlers ending in capture, like
+// onclickcapture, listen during the capture phase.
+func Parse(attr string) (key Key, capture bool, ok bool) {
+	if key, ok := To[attr]; ok {
+		return key, false, true
+	}
+	name := strings.ToLower(attr)
+	if !strings.HasPrefix(name, "on") {
+		return 0, false, false
+	}
+	name = strings.TrimPrefix(name[2:], ":")
+	if key, ok := byName[name]; ok {
+		return key, false, true
+	}
+	if key, ok := byName[strings.TrimSuffix(name, "capture")]; ok {
+		return key, true, true
+	}
+	return 0, false, false
+}
+
+// Name of the DOM event, like click
+func (k Key) Name() string {
+	return strings.ToLower(strings.TrimPrefix(From[k], "on"))
+}
+
+// String returns the React-style handler name, like onClick
+func (k Key) String() string {
+	return From[k]
+}
+
+// Delegated events bubble, so they can be handled by a single listener on the
+// root instead of a listener per element
+func (k Key) Delegated() bool {
+	return delegated[k]
+}
+
+// Suggest the handler that was probably meant for a misspelled handler like
+// onclik. The suggestion is in the same style as the attribute.
+func Suggest(attr string) (string, bool) {
+	if Is(attr) || len(attr) < 3 || !strings.EqualFold(attr[:2], "on") {
+		return "", false
+	}
+	name := strings.ToLower(strings.TrimPrefix(attr[2:], ":"))
+	best, distance := Key(0), 3
+	for key, handler := range From {
+		if d := levenshtein(name, strings.ToLower(handler[2:])); d < distance || d == distance && handler < From[best] {
+			best, distance = key, d
+		}
+	}
+	if distance > 2 {
+		return "", false
+	}
+	switch {
+	case strings.HasPrefix(attr, "on:"):
+		return "on:" + best.Name(), true
+	case attr == strings.ToLower(attr):
+		return "on" + best.Name(), true
+	default:
+		return best.String(), true
+	}
+}
+
+// levenshtein returns the number of edits to turn a into b
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// byName maps DOM event names like click to their key
+var byName = func() map[string]Key {
+	names := make(map[string]Key, len(From))
+	for key := range From {
+		names[key.Name()] = key
+	}
+	return names
+}()
+
+// Based on:
+// https://github.com/sveltejs/svelte/blob/svelte%405.0.0-next.216/packages/svelte/src/utils.js
+var delegated = map[Key]bool{
+	BeforeInput: true,
+	Click:       true,
+	Change:      true,
+	DblClick:    true,
+	ContextMenu: true,
+	FocusIn:     true,
+	FocusOut:    true,
+	Input:       true,
+	KeyDown:     true,
+	KeyUp:       true,
+	MouseDown:   true,
+	MouseMove:   true,
+	MouseOut:    true,
+	MouseOver:   true,
+	MouseUp:     true,
+	PointerDown: true,
+	PointerMove: true,
+	PointerOut:  true,
+	PointerOver: true,
+	PointerUp:   true,
+	TouchEnd:    true,
+	TouchMove:   true,
+	TouchStart:  true,
+}
+
 // To key from string
 var To = map[string]Key{
 	"onLoad":                  Load,
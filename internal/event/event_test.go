@@ -0,0 +1,55 @@
+package event_test
+
+import (
+	"testing"
+
+	"github.com/livebud/duo/internal/event"
+	"github.com/matryer/is"
+)
+
+func TestParse(t *testing.T) {
+	is := is.New(t)
+	for _, attr := range []string{"onClick", "onclick", "on:click"} {
+		key, capture, ok := event.Parse(attr)
+		is.True(ok)
+		is.True(!capture)
+		is.Equal(key, event.Click)
+	}
+	key, capture, ok := event.Parse("onclickcapture")
+	is.True(ok)
+	is.True(capture)
+	is.Equal(key, event.Click)
+	key, _, ok = event.Parse("ondblclick")
+	is.True(ok)
+	is.Equal(key.Name(), "dblclick")
+	is.Equal(key.String(), "onDblClick")
+	_, _, ok = event.Parse("onclik")
+	is.True(!ok)
+	_, _, ok = event.Parse("class")
+	is.True(!ok)
+}
+
+func TestDelegated(t *testing.T) {
+	is := is.New(t)
+	is.True(event.Click.Delegated())
+	is.True(event.Input.Delegated())
+	is.True(!event.MouseEnter.Delegated())
+	is.True(!event.Scroll.Delegated())
+}
+
+func TestSuggest(t *testing.T) {
+	is := is.New(t)
+	suggestion, ok := event.Suggest("onclik")
+	is.True(ok)
+	is.Equal(suggestion, "onclick")
+	suggestion, ok = event.Suggest("onMouseOvr")
+	is.True(ok)
+	is.Equal(suggestion, "onMouseOver")
+	suggestion, ok = event.Suggest("on:keydonw")
+	is.True(ok)
+	is.Equal(suggestion, "on:keydown")
+	_, ok = event.Suggest("onclick")
+	is.True(!ok)
+	_, ok = event.Suggest("online")
+	is.True(!ok)
+}
@@ -0,0 +1,413 @@
+package gogen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	duojs "github.com/livebud/duo/internal/js"
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// expr is a translated Go expression and its type. Values whose types aren't
+// known ahead of time are "any" and go through the render runtime.
+type expr struct {
+	code string
+	typ  string
+}
+
+func (g *generator) expr(node js.IExpr) (*expr, error) {
+	switch n := node.(type) {
+	case *js.LiteralExpr:
+		return g.literal(n)
+	case *js.Var:
+		return g.variable(string(n.Data))
+	case *js.GroupExpr:
+		x, err := g.expr(n.X)
+		if err != nil {
+			return nil, err
+		}
+		return &expr{"(" + x.code + ")", x.typ}, nil
+	case *js.DotExpr:
+		x, err := g.expr(n.X)
+		if err != nil {
+			return nil, err
+		}
+		return g.member(x, n.Y.String())
+	case *js.IndexExpr:
+		x, err := g.expr(n.X)
+		if err != nil {
+			return nil, err
+		}
+		index, err := g.expr(n.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &expr{"render.Index(" + x.code + ", " + index.code + ")", "any"}, nil
+	case *js.BinaryExpr:
+		return g.binary(n)
+	case *js.UnaryExpr:
+		return g.unary(n)
+	case *js.CondExpr:
+		cond, err := g.expr(n.Cond)
+		if err != nil {
+			return nil, err
+		}
+		x, err := g.expr(n.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := g.expr(n.Y)
+		if err != nil {
+			return nil, err
+		}
+		typ := unify(x, y)
+		return &expr{generic("render.Cond", typ, truthy(cond)+", ", x, y), typ}, nil
+	case *js.TemplateExpr:
+		return g.template(n)
+	case *js.ArrayExpr:
+		return g.array(n)
+	case *js.ObjectExpr:
+		return g.object(n)
+	case *js.CallExpr:
+		return g.call(n)
+	case *js.ArrowFunc, *js.FuncDecl:
+		return &expr{"nil", "func"}, nil
+	default:
+		return nil, g.errorf("unable to generate Go for %s", node.JS())
+	}
+}
+
+func (g *generator) literal(n *js.LiteralExpr) (*expr, error) {
+	switch n.TokenType {
+	case js.StringToken:
+		s, err := duojs.Unquote(string(n.Data))
+		if err != nil {
+			return nil, g.errorf("invalid string %s: %w", n.Data, err)
+		}
+		return &expr{strconv.Quote(s), "string"}, nil
+	case js.DecimalToken, js.HexadecimalToken, js.OctalToken, js.BinaryToken:
+		number := string(n.Data)
+		if isFloat(number) {
+			return &expr{number, "float64"}, nil
+		}
+		return &expr{number, "int"}, nil
+	case js.TrueToken:
+		return &expr{"true", "bool"}, nil
+	case js.FalseToken:
+		return &expr{"false", "bool"}, nil
+	case js.NullToken:
+		return &expr{"nil", "any"}, nil
+	case js.IdentifierToken:
+		return g.variable(string(n.Data))
+	default:
+		return nil, g.errorf("unable to generate Go for %s", n.Data)
+	}
+}
+
+func (g *generator) variable(name string) (*expr, error) {
+	if name == "undefined" {
+		return &expr{"nil", "any"}, nil
+	}
+	v, ok := g.scope.lookup(name)
+	if !ok {
+		return nil, g.errorf("%s is not defined", name)
+	} else if v.browser {
+		// Functions like event handlers are skipped on the server
+		if v.fn {
+			return &expr{"nil", "func"}, nil
+		}
+		return nil, g.errorf("%s is only available in the browser", name)
+	}
+	return &expr{v.ident, v.typ}, nil
+}
+
+// member accesses the fields of typed props directly. Members of values whose
+// types aren't known go through the runtime.
+func (g *generator) member(x *expr, name string) (*expr, error) {
+	if name == "length" && (x.typ == "string" || strings.HasPrefix(x.typ, "[]")) {
+		return &expr{"render.Len(" + x.code + ")", "int"}, nil
+	}
+	if g.types.isStruct(x.typ) {
+		field, ok := g.types.field(x.typ, name)
+		if !ok {
+			return nil, g.errorf("%s has no field %q", strings.TrimPrefix(x.typ, "*"), name)
+		}
+		return &expr{x.code + "." + field.goName, field.typ}, nil
+	}
+	return &expr{"render.Member(" + x.code + ", " + strconv.Quote(name) + ")", "any"}, nil
+}
+
+func (g *generator) binary(n *js.BinaryExpr) (*expr, error) {
+	x, err := g.expr(n.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := g.expr(n.Y)
+	if err != nil {
+		return nil, err
+	}
+	switch n.Op {
+	case js.AddToken:
+		switch {
+		case x.typ == "string" || y.typ == "string":
+			return &expr{toString(x) + " + " + toString(y), "string"}, nil
+		case isNumber(x.typ) && isNumber(y.typ):
+			typ := unify(x, y)
+			return &expr{toNumber(x, typ) + " + " + toNumber(y, typ), typ}, nil
+		default:
+			return &expr{"render.Add(" + x.code + ", " + y.code + ")", "any"}, nil
+		}
+	case js.SubToken, js.MulToken:
+		typ := "float64"
+		if isNumber(x.typ) && isNumber(y.typ) {
+			typ = unify(x, y)
+		}
+		return &expr{toNumber(x, typ) + " " + n.Op.String() + " " + toNumber(y, typ), typ}, nil
+	case js.DivToken:
+		// Division always returns a float in JavaScript
+		return &expr{toNumber(x, "float64") + " / " + toNumber(y, "float64"), "float64"}, nil
+	case js.ModToken:
+		if x.typ == "int" && y.typ == "int" {
+			return &expr{x.code + " % " + y.code, "int"}, nil
+		}
+		return &expr{"render.Mod(" + x.code + ", " + y.code + ")", "float64"}, nil
+	case js.LtToken, js.LtEqToken, js.GtToken, js.GtEqToken:
+		op := n.Op.String()
+		switch {
+		case x.typ == "string" && y.typ == "string":
+			return &expr{x.code + " " + op + " " + y.code, "bool"}, nil
+		case isNumber(x.typ) && isNumber(y.typ):
+			typ := unify(x, y)
+			return &expr{toNumber(x, typ) + " " + op + " " + toNumber(y, typ), "bool"}, nil
+		default:
+			return &expr{"render.Compare(" + x.code + ", " + y.code + ") " + op + " 0", "bool"}, nil
+		}
+	case js.EqEqToken, js.EqEqEqToken, js.NotEqToken, js.NotEqEqToken:
+		equal := n.Op == js.EqEqToken || n.Op == js.EqEqEqToken
+		switch {
+		case x.typ == y.typ && isPrimitive(x.typ):
+			if equal {
+				return &expr{x.code + " == " + y.code, "bool"}, nil
+			}
+			return &expr{x.code + " != " + y.code, "bool"}, nil
+		case isNumber(x.typ) && isNumber(y.typ):
+			op := " == "
+			if !equal {
+				op = " != "
+			}
+			return &expr{toNumber(x, "float64") + op + toNumber(y, "float64"), "bool"}, nil
+		default:
+			code := "render.Equal(" + x.code + ", " + y.code + ")"
+			if !equal {
+				code = "!" + code
+			}
+			return &expr{code, "bool"}, nil
+		}
+	case js.AndToken, js.OrToken, js.NullishToken:
+		fn := map[js.TokenType]string{
+			js.AndToken:     "render.And",
+			js.OrToken:      "render.Or",
+			js.NullishToken: "render.Coalesce",
+		}[n.Op]
+		typ := unify(x, y)
+		return &expr{generic(fn, typ, "", x, y), typ}, nil
+	default:
+		return nil, g.errorf("unable to generate Go for the %s operator", n.Op)
+	}
+}
+
+func (g *generator) unary(n *js.UnaryExpr) (*expr, error) {
+	x, err := g.expr(n.X)
+	if err != nil {
+		return nil, err
+	}
+	switch n.Op {
+	case js.NotToken:
+		return &expr{"!" + truthy(x), "bool"}, nil
+	case js.NegToken:
+		if isNumber(x.typ) {
+			return &expr{"-" + x.code, x.typ}, nil
+		}
+		return &expr{"-render.Float(" + x.code + ")", "float64"}, nil
+	case js.PosToken:
+		if isNumber(x.typ) {
+			return x, nil
+		}
+		return &expr{"render.Float(" + x.code + ")", "float64"}, nil
+	default:
+		return nil, g.errorf("unable to generate Go for the %s operator", n.Op)
+	}
+}
+
+func (g *generator) template(n *js.TemplateExpr) (*expr, error) {
+	if n.Tag != nil {
+		return nil, g.errorf("tagged templates aren't supported")
+	}
+	parts := []string{}
+	for _, part := range n.List {
+		text := strings.TrimSuffix(string(part.Value[1:]), "${")
+		if text != "" {
+			parts = append(parts, strconv.Quote(text))
+		}
+		value, err := g.expr(part.Expr)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, toString(value))
+	}
+	if tail := strings.TrimSuffix(string(n.Tail[1:]), "`"); tail != "" || len(parts) == 0 {
+		parts = append(parts, strconv.Quote(tail))
+	}
+	return &expr{strings.Join(parts, " + "), "string"}, nil
+}
+
+func (g *generator) array(n *js.ArrayExpr) (*expr, error) {
+	items := make([]*expr, len(n.List))
+	elem := ""
+	for i, item := range n.List {
+		if item.Spread || item.Value == nil {
+			return nil, g.errorf("array spreads and holes aren't supported")
+		}
+		value, err := g.expr(item.Value)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = value
+		if elem == "" || elem == value.typ {
+			elem = value.typ
+		} else {
+			elem = "any"
+		}
+	}
+	if !isPrimitive(elem) {
+		elem = "any"
+	}
+	codes := make([]string, len(items))
+	for i, item := range items {
+		codes[i] = item.code
+	}
+	typ := "[]" + elem
+	return &expr{typ + "{" + strings.Join(codes, ", ") + "}", typ}, nil
+}
+
+func (g *generator) object(n *js.ObjectExpr) (*expr, error) {
+	entries := make([]string, len(n.List))
+	for i, property := range n.List {
+		if property.Spread || property.Name == nil || property.Name.IsComputed() {
+			return nil, g.errorf("object spreads and computed keys aren't supported")
+		}
+		key := string(property.Name.Literal.Data)
+		if property.Name.Literal.TokenType == js.StringToken {
+			unquoted, err := duojs.Unquote(key)
+			if err != nil {
+				return nil, err
+			}
+			key = unquoted
+		}
+		value, err := g.expr(property.Value)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = strconv.Quote(key) + ": " + value.code
+	}
+	return &expr{"map[string]any{" + strings.Join(entries, ", ") + "}", "map[string]any"}, nil
+}
+
+func (g *generator) call(n *js.CallExpr) (*expr, error) {
+	args := make([]*expr, len(n.Args.List))
+	for i, arg := range n.Args.List {
+		if arg.Rest {
+			return nil, g.errorf("spread arguments aren't supported")
+		}
+		value, err := g.expr(arg.Value)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	if v, ok := n.X.(*js.Var); ok && len(args) == 1 {
+		if _, declared := g.scope.lookup(string(v.Data)); !declared {
+			switch string(v.Data) {
+			case "String":
+				return &expr{toString(args[0]), "string"}, nil
+			case "Number":
+				return &expr{toNumber(args[0], "float64"), "float64"}, nil
+			case "Boolean":
+				return &expr{truthy(args[0]), "bool"}, nil
+			}
+		}
+	}
+	return nil, g.errorf("unable to call %s in Go", n.X.JS())
+}
+
+// convert a value to the Go type, failing if the types are incompatible
+func (g *generator) convert(value *expr, typ string) (*expr, error) {
+	switch {
+	case value.typ == typ || typ == "any":
+		return value, nil
+	case value.typ == "any":
+		return &expr{"render.As[" + typ + "](" + value.code + ")", typ}, nil
+	case isNumber(value.typ) && isNumber(typ):
+		return &expr{toNumber(value, typ), typ}, nil
+	case value.code == "nil":
+		return &expr{"*new(" + typ + ")", typ}, nil
+	case strings.HasPrefix(typ, "[]") && value.typ == "[]any" && strings.HasSuffix(value.code, "{}"):
+		// Empty arrays can be any kind of slice
+		return &expr{typ + "{}", typ}, nil
+	default:
+		return nil, fmt.Errorf("cannot use %s (%s) as %s", value.code, value.typ, typ)
+	}
+}
+
+// unify returns a type that can hold both values
+func unify(x, y *expr) string {
+	switch {
+	case x.typ == y.typ:
+		return x.typ
+	case isNumber(x.typ) && isNumber(y.typ):
+		return "float64"
+	default:
+		return "any"
+	}
+}
+
+// generic calls a generic function, instantiating it when the type can't be
+// inferred from the arguments
+func generic(fn, typ, prefix string, x, y *expr) string {
+	switch {
+	case x.typ == y.typ:
+		return fn + "(" + prefix + x.code + ", " + y.code + ")"
+	case typ == "float64":
+		return fn + "(" + prefix + toNumber(x, typ) + ", " + toNumber(y, typ) + ")"
+	default:
+		return fn + "[" + typ + "](" + prefix + x.code + ", " + y.code + ")"
+	}
+}
+
+func truthy(x *expr) string {
+	if x.typ == "bool" {
+		return x.code
+	}
+	return "render.Truthy(" + x.code + ")"
+}
+
+func toString(x *expr) string {
+	if x.typ == "string" {
+		return x.code
+	}
+	return "render.String(" + x.code + ")"
+}
+
+func toNumber(x *expr, typ string) string {
+	switch {
+	case x.typ == typ:
+		return x.code
+	case x.typ == "int" && typ == "float64":
+		return "float64(" + x.code + ")"
+	case typ == "int":
+		return "int(render.Float(" + x.code + "))"
+	default:
+		return "render.Float(" + x.code + ")"
+	}
+}
@@ -0,0 +1,1123 @@
+// Package gogen compiles .svelte files into Go render functions. Each component
+// gets a typed props struct and a function that writes directly to an
+// io.Writer, so templates are type-checked by `go build` and render without
+// parsing at runtime.
+package gogen
+
+import (
+	"fmt"
+	"go/format"
+	"html"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/livebud/duo/internal/ast"
+	"github.com/livebud/duo/internal/check"
+	duojs "github.com/livebud/duo/internal/js"
+	"github.com/livebud/duo/internal/parser"
+	"github.com/livebud/duo/internal/props"
+	"github.com/livebud/duo/internal/scope"
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// New Go code generator for the package
+func New(pkg string) *Generator {
+	return &Generator{Package: pkg}
+}
+
+type Generator struct {
+	Package string
+	// Warn is called with problems that don't stop generation, like
+	// misspelled event handlers
+	Warn func(*check.Diagnostic)
+}
+
+// File is a generated Go file
+type File struct {
+	Path string
+	Code []byte
+}
+
+// Generate Go code for each .svelte file in the root of fsys. Components can
+// only import other components within the same directory.
+func (g *Generator) Generate(fsys fs.FS) ([]*File, error) {
+	paths, err := fs.Glob(fsys, "*.svelte")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	docs := make([]*ast.Document, len(paths))
+	// View and NewView are reserved for the output of Props
+	types := newTypes("View", "NewView")
+	for i, path := range paths {
+		code, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := parser.Parse(path, string(code))
+		if err != nil {
+			return nil, err
+		}
+		docs[i] = doc
+		g.warn(check.Events(path, doc))
+		// Component functions and their props are reserved
+		name := componentName(path)
+		types.reserve(name)
+		types.reserve(name + "Props")
+	}
+	components := map[string]*component{}
+	for i, path := range paths {
+		component, err := load(path, docs[i], types)
+		if err != nil {
+			return nil, err
+		}
+		components[path] = component
+	}
+	files := make([]*File, len(paths))
+	for i, path := range paths {
+		code, err := g.generate(components[path], components, types)
+		if err != nil {
+			return nil, err
+		}
+		files[i] = &File{path + ".go", code}
+	}
+	return files, nil
+}
+
+func (g *Generator) warn(diagnostics []*check.Diagnostic) {
+	if g.Warn == nil {
+		return
+	}
+	for _, diagnostic := range diagnostics {
+		g.Warn(diagnostic)
+	}
+}
+
+// component is a parsed .svelte file
+type component struct {
+	path    string
+	name    string
+	doc     *ast.Document
+	props   []*prop
+	locals  []*js.BindingElement
+	imports map[string]string // variable name to component path
+	browser map[string]bool   // browser-only variables and whether they're functions
+	slots   bool
+	fields  []*field // Go fields of the props, typed by the schema
+}
+
+// prop is a component property and its Go field
+type prop struct {
+	*field
+	value js.IExpr // default value, can be nil
+}
+
+// optional returns true if the field points to the value, so that a nil field
+// falls back to the default
+func (p *prop) optional() bool {
+	return p.value != nil && strings.HasPrefix(p.typ, "*")
+}
+
+// valueType is the type of the prop within the component
+func (p *prop) valueType() string {
+	if p.optional() {
+		return strings.TrimPrefix(p.typ, "*")
+	}
+	return p.typ
+}
+
+func (c *component) prop(name string) (*prop, bool) {
+	for _, prop := range c.props {
+		if prop.name == name {
+			return prop, true
+		}
+	}
+	return nil, false
+}
+
+// load the props, locals and imports from the component's script. The props
+// are typed by their schema.
+func load(filePath string, doc *ast.Document, types *types) (*component, error) {
+	c := &component{
+		path:    filePath,
+		name:    componentName(filePath),
+		doc:     doc,
+		imports: map[string]string{},
+		browser: map[string]bool{},
+		slots:   hasSlot(doc.Children),
+	}
+	script, ok := doc.Script()
+	if !ok || script.Program == nil {
+		return c, nil
+	}
+	types.owner = filePath
+	c.fields = types.fields(c.name, props.Extract(filePath, doc).Props)
+	for _, stmt := range script.Program.List {
+		switch s := stmt.(type) {
+		case *js.ImportStmt:
+			module, err := duojs.Unquote(string(s.Module))
+			if err != nil {
+				return nil, err
+			}
+			if path.Ext(module) != ".svelte" || s.Default == nil {
+				for _, alias := range s.List {
+					c.browser[string(alias.Binding)] = true
+				}
+				if s.Default != nil {
+					c.browser[string(s.Default)] = true
+				}
+				continue
+			}
+			importPath := path.Clean(module)
+			if strings.Contains(importPath, "/") {
+				return nil, fmt.Errorf("gogen: %s imports %s, but components must be in the same directory", filePath, module)
+			}
+			c.imports[string(s.Default)] = importPath
+		case *js.ExportStmt:
+			decl, ok := s.Decl.(*js.VarDecl)
+			if !ok {
+				continue
+			}
+			for _, element := range decl.List {
+				v, ok := element.Binding.(*js.Var)
+				if !ok {
+					return nil, fmt.Errorf("gogen: %s has an unsupported export %s", filePath, element.Binding.JS())
+				}
+				c.addProp(string(v.Data), element.Default)
+			}
+		case *js.VarDecl:
+			for i := range s.List {
+				element := &s.List[i]
+				if r, ok := scope.RuneOf(element.Default); ok && r == scope.RuneProps {
+					if err := c.addProps(element.Binding); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				c.locals = append(c.locals, element)
+			}
+		case *js.FuncDecl:
+			if s.Name != nil {
+				c.browser[string(s.Name.Data)] = true
+			}
+		}
+	}
+	return c, nil
+}
+
+func (c *component) addProp(name string, value js.IExpr) {
+	for _, field := range c.fields {
+		if field.name == name {
+			c.props = append(c.props, &prop{field, value})
+			return
+		}
+	}
+}
+
+// addProps adds props destructured from $props()
+func (c *component) addProps(binding js.IBinding) error {
+	object, ok := binding.(*js.BindingObject)
+	if !ok {
+		return fmt.Errorf("gogen: %s must destructure $props()", c.path)
+	}
+	if object.Rest != nil {
+		return fmt.Errorf("gogen: %s can't use rest props", c.path)
+	}
+	for _, item := range object.List {
+		v, ok := item.Value.Binding.(*js.Var)
+		if !ok || item.Key == nil {
+			return fmt.Errorf("gogen: %s has an unsupported prop %s", c.path, item.Value.Binding.JS())
+		}
+		value := item.Value.Default
+		if r, ok := scope.RuneOf(value); ok && r == scope.RuneBindable {
+			value = firstArg(value.(*js.CallExpr))
+		}
+		c.addProp(string(v.Data), value)
+	}
+	return nil
+}
+
+func hasSlot(fragments []ast.Fragment) bool {
+	for _, fragment := range fragments {
+		switch f := fragment.(type) {
+		case *ast.Slot:
+			return true
+		case *ast.Element:
+			if hasSlot(f.Children) {
+				return true
+			}
+		case *ast.Component:
+			if hasSlot(f.Children) {
+				return true
+			}
+		case *ast.IfBlock:
+			if hasSlot(f.Then) || hasSlot(f.Else) {
+				return true
+			}
+		case *ast.EachBlock:
+			if hasSlot(f.Body) || hasSlot(f.Else) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func firstArg(call *js.CallExpr) js.IExpr {
+	if len(call.Args.List) == 0 {
+		return nil
+	}
+	return call.Args.List[0].Value
+}
+
+// componentName turns a file path like "story-list.svelte" into "StoryList"
+func componentName(filePath string) string {
+	return fieldName(strings.TrimSuffix(path.Base(filePath), path.Ext(filePath)))
+}
+
+// fieldName turns an identifier like "num_comments" or a path like
+// "posts/show" into "NumComments" or "PostsShow", spelling initialisms like
+// "userId" as "UserID"
+func fieldName(name string) string {
+	out := new(strings.Builder)
+	word := new(strings.Builder)
+	flush := func() {
+		if upper := strings.ToUpper(word.String()); initialisms[upper] {
+			out.WriteString(upper)
+		} else {
+			out.WriteString(word.String())
+		}
+		word.Reset()
+	}
+	var prev rune
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			prev = 0
+			continue
+		}
+		// Words also start at a capital after a lowercase letter, like "userId"
+		if unicode.IsUpper(r) && unicode.IsLower(prev) {
+			flush()
+		}
+		if word.Len() == 0 {
+			r = unicode.ToUpper(r)
+		}
+		word.WriteRune(r)
+		prev = r
+	}
+	flush()
+	if out.Len() == 0 || !unicode.IsLetter([]rune(out.String())[0]) {
+		return "X" + out.String()
+	}
+	return out.String()
+}
+
+// initialisms that Go spells in all caps, like ID in "UserID"
+var initialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true,
+	"EOF": true, "GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true,
+	"IP": true, "JSON": true, "LHS": true, "QPS": true, "RAM": true, "RHS": true,
+	"RPC": true, "SLA": true, "SMTP": true, "SQL": true, "SSH": true, "TCP": true,
+	"TLS": true, "TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true,
+	"URI": true, "URL": true, "UTF8": true, "VM": true, "XML": true, "XMPP": true,
+	"XSRF": true, "XSS": true,
+}
+
+// reserved identifiers that can't be used as local variables
+var reserved = map[string]bool{
+	"w": true, "out": true, "props": true, "render": true, "io": true, "err": true,
+	"break": true, "case": true, "chan": true, "const": true, "continue": true,
+	"default": true, "defer": true, "else": true, "fallthrough": true, "for": true,
+	"func": true, "go": true, "goto": true, "if": true, "import": true,
+	"interface": true, "map": true, "package": true, "range": true, "return": true,
+	"select": true, "struct": true, "switch": true, "type": true, "var": true,
+	"any": true, "bool": true, "string": true, "int": true, "float64": true,
+	"len": true, "nil": true, "true": true, "false": true, "slot": true,
+}
+
+// localName turns a JS identifier into a Go identifier that won't conflict
+// with the generated code
+func localName(name string) string {
+	name = strings.ReplaceAll(name, "$", "_")
+	if reserved[name] {
+		return name + "_"
+	}
+	return name
+}
+
+func isFloat(number string) bool {
+	return strings.ContainsAny(number, ".eE") && !strings.HasPrefix(number, "0x")
+}
+
+func isPrimitive(typ string) bool {
+	switch typ {
+	case "string", "int", "float64", "bool":
+		return true
+	default:
+		return false
+	}
+}
+
+func isNumber(typ string) bool {
+	return typ == "int" || typ == "float64"
+}
+
+// generate the Go code for a component
+func (g *Generator) generate(c *component, components map[string]*component, types *types) ([]byte, error) {
+	gen := &generator{
+		component:  c,
+		components: components,
+		types:      types,
+		out:        new(strings.Builder),
+		scope:      &variables{vars: map[string]*variable{}},
+	}
+	for name, fn := range c.browser {
+		gen.scope.vars[name] = &variable{browser: true, fn: fn}
+	}
+	file := new(strings.Builder)
+	file.WriteString("// Code generated by duo. DO NOT EDIT.\n\n")
+	file.WriteString("package " + g.Package + "\n\n")
+	file.WriteString("import (\n\t\"io\"\n\n\t\"github.com/livebud/duo/render\"\n)\n\n")
+	// Props struct
+	file.WriteString(fmt.Sprintf("// %sProps are the props for %s. Nil fields fall back to the defaults\n// declared in the component.\n", c.name, c.path))
+	file.WriteString(fmt.Sprintf("type %sProps struct {\n", c.name))
+	fields := make([]*field, len(c.props))
+	for i, prop := range c.props {
+		fields[i] = prop.field
+	}
+	file.WriteString(types.body(fields))
+	if c.slots {
+		file.WriteString("\tSlots map[string]func(w io.Writer) error `json:\"-\"`\n")
+	}
+	file.WriteString("}\n\n")
+	// Render function
+	file.WriteString(fmt.Sprintf("// %s renders %s\n", c.name, c.path))
+	file.WriteString(fmt.Sprintf("func %s(w io.Writer, props *%sProps) error {\n", c.name, c.name))
+	file.WriteString(fmt.Sprintf("if props == nil {\nprops = &%sProps{}\n}\n", c.name))
+	file.WriteString("out := render.NewWriter(w)\n")
+	if err := gen.generateProps(); err != nil {
+		return nil, err
+	}
+	if err := gen.generateLocals(); err != nil {
+		return nil, err
+	}
+	if err := gen.generateFragments(c.doc.Children...); err != nil {
+		return nil, err
+	}
+	gen.flush()
+	file.WriteString(gen.out.String())
+	file.WriteString("return out.Err()\n}\n")
+	// Types declared for this component's props
+	for _, decl := range types.decls {
+		if decl.owner == c.path {
+			file.WriteString("\n" + decl.code)
+		}
+	}
+	code, err := format.Source([]byte(file.String()))
+	if err != nil {
+		return nil, fmt.Errorf("gogen: unable to format %s: %w", c.path, err)
+	}
+	return code, nil
+}
+
+// variables in scope while generating
+type variables struct {
+	parent *variables
+	vars   map[string]*variable
+}
+
+type variable struct {
+	ident   string
+	typ     string
+	browser bool // only available in the browser
+	fn      bool // a function, like an event handler
+}
+
+func (v *variables) lookup(name string) (*variable, bool) {
+	if variable, ok := v.vars[name]; ok {
+		return variable, true
+	}
+	if v.parent != nil {
+		return v.parent.lookup(name)
+	}
+	return nil, false
+}
+
+type generator struct {
+	component  *component
+	components map[string]*component
+	types      *types
+	out        *strings.Builder
+	text       strings.Builder // static text that hasn't been written yet
+	scope      *variables
+}
+
+func (g *generator) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("gogen: %s: "+format, append([]interface{}{g.component.path}, args...)...)
+}
+
+// write static text, merging it with adjacent static text
+func (g *generator) write(text string) {
+	g.text.WriteString(text)
+}
+
+// flush the static text out
+func (g *generator) flush() {
+	if g.text.Len() == 0 {
+		return
+	}
+	g.out.WriteString("out.WriteString(" + strconv.Quote(g.text.String()) + ")\n")
+	g.text.Reset()
+}
+
+// code writes a Go statement
+func (g *generator) code(format string, args ...interface{}) {
+	g.flush()
+	fmt.Fprintf(g.out, format+"\n", args...)
+}
+
+func (g *generator) declare(name, typ string) string {
+	ident := localName(name)
+	g.scope.vars[name] = &variable{ident: ident, typ: typ}
+	return ident
+}
+
+func (g *generator) pushScope() {
+	g.scope = &variables{parent: g.scope, vars: map[string]*variable{}}
+}
+
+func (g *generator) popScope() {
+	g.scope = g.scope.parent
+}
+
+// generateProps declares the props as variables. Props with a default are
+// either pointers that fall back to the default when nil, or nil-able types
+// like slices.
+func (g *generator) generateProps() error {
+	for _, prop := range g.component.props {
+		typ := prop.valueType()
+		ident := g.declare(prop.name, typ)
+		if prop.value == nil || isZeroDefault(prop.value) {
+			g.code("%s := props.%s", ident, prop.goName)
+			g.code("_ = %s", ident)
+			continue
+		}
+		value, err := g.expr(prop.value)
+		if err != nil {
+			return err
+		}
+		value, err = g.convert(value, typ)
+		if err != nil {
+			return err
+		}
+		if prop.optional() {
+			g.code("%s := %s", ident, value.code)
+			g.code("if props.%s != nil {\n%s = *props.%s\n}", prop.goName, ident, prop.goName)
+		} else {
+			g.code("%s := props.%s", ident, prop.goName)
+			g.code("if %s == nil {\n%s = %s\n}", ident, ident, value.code)
+		}
+		g.code("_ = %s", ident)
+	}
+	return nil
+}
+
+// generateLocals declares the script variables that can run on the server.
+// Everything else, like functions, only exists in the browser.
+func (g *generator) generateLocals() error {
+	for _, local := range g.component.locals {
+		v, ok := local.Binding.(*js.Var)
+		if !ok {
+			continue
+		}
+		name := string(v.Data)
+		value := local.Default
+		if r, ok := scope.RuneOf(value); ok {
+			switch r {
+			case scope.RuneState, scope.RuneStateRaw, scope.RuneDerived:
+				value = firstArg(value.(*js.CallExpr))
+			default:
+				g.scope.vars[name] = &variable{browser: true}
+				continue
+			}
+		}
+		if value == nil {
+			g.scope.vars[name] = &variable{browser: true}
+			continue
+		}
+		expr, err := g.expr(value)
+		if err != nil || expr.typ == "func" {
+			g.scope.vars[name] = &variable{browser: true, fn: err == nil}
+			continue
+		}
+		ident := g.declare(name, expr.typ)
+		g.code("%s := %s", ident, expr.code)
+		g.code("_ = %s", ident)
+	}
+	return nil
+}
+
+func (g *generator) generateFragments(fragments ...ast.Fragment) error {
+	for _, fragment := range fragments {
+		if err := g.generateFragment(fragment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) generateFragment(node ast.Fragment) error {
+	switch n := node.(type) {
+	case *ast.Text:
+		g.write(n.Value)
+		return nil
+	case *ast.Mustache:
+		return g.generateMustache(n)
+	case *ast.HTML:
+		return g.generateHTML(n)
+	case *ast.Element:
+		return g.generateElement(n)
+	case *ast.IfBlock:
+		return g.generateIfBlock(n)
+	case *ast.EachBlock:
+		return g.generateEachBlock(n)
+	case *ast.Component:
+		return g.generateComponent(n)
+	case *ast.Slot:
+		return g.generateSlot(n)
+	case *ast.Style:
+		if n.StyleSheet != nil && len(n.StyleSheet.Rules) > 0 {
+			g.code("out.Style(%q, %q)", n.Scope, n.StyleSheet.String())
+		}
+		return nil
+	case *ast.Script, *ast.Comment, *ast.Options:
+		return nil
+	default:
+		return g.errorf("unable to generate %T", n)
+	}
+}
+
+func (g *generator) generateMustache(node *ast.Mustache) error {
+	value, err := g.expr(node.Expr)
+	if err != nil {
+		return err
+	}
+	g.print(value)
+	return nil
+}
+
+// generateHTML writes the value of {@html expr} without escaping it
+func (g *generator) generateHTML(node *ast.HTML) error {
+	value, err := g.expr(node.Expr)
+	if err != nil {
+		return err
+	}
+	switch value.typ {
+	case "func":
+	case "string":
+		g.code("out.WriteString(%s)", value.code)
+	default:
+		g.code("out.WriteString(render.String(%s))", value.code)
+	}
+	return nil
+}
+
+// print writes the value as escaped text
+func (g *generator) print(value *expr) {
+	switch value.typ {
+	case "func":
+	case "string":
+		g.code("out.Text(%s)", value.code)
+	default:
+		g.code("out.Print(%s)", value.code)
+	}
+}
+
+func (g *generator) generateElement(node *ast.Element) error {
+	g.write("<" + node.Name)
+	merged := map[string]bool{}
+	for _, attr := range node.Attributes {
+		// Class and style directives are merged into a single attribute
+		if key, ok := node.MergedKey(attr); ok {
+			if merged[key] {
+				continue
+			}
+			merged[key] = true
+			if err := g.generateMerged(node, key); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := g.generateAttribute(node, attr); err != nil {
+			return err
+		}
+	}
+	if node.SelfClosing {
+		g.write("/>")
+		return nil
+	}
+	g.write(">")
+	// Textareas render their bound value as their content
+	if binding, ok := node.Binding("value"); ok && node.Name == "textarea" {
+		value, err := g.values([]ast.Value{binding.Value})
+		if err != nil {
+			return err
+		}
+		g.print(value)
+	}
+	if err := g.generateFragments(node.Children...); err != nil {
+		return err
+	}
+	g.write("</" + node.Name + ">")
+	return nil
+}
+
+func (g *generator) generateAttribute(el *ast.Element, node ast.Attribute) error {
+	switch a := node.(type) {
+	case *ast.Field:
+		if a.EventHandler {
+			return nil
+		}
+		return g.generateField(a)
+	case *ast.Binding:
+		return g.generateBinding(el, a)
+	case *ast.AttributeShorthand:
+		if a.EventHandler {
+			return nil
+		}
+		value, err := g.variable(a.Key)
+		if err != nil {
+			return err
+		}
+		g.attr(a.Key, value)
+		return nil
+	case *ast.NamedSlot, *ast.Let:
+		// Only used to distribute slot content
+		return nil
+	default:
+		return g.errorf("unable to generate attribute %T", a)
+	}
+}
+
+// generateBinding renders the initial state of a two-way binding
+func (g *generator) generateBinding(el *ast.Element, node *ast.Binding) error {
+	switch {
+	case node.Key == "this":
+		// Elements are only bound in the browser
+		return nil
+	case node.Key == "value" && el.Name == "textarea":
+		// Rendered as the content instead
+		return nil
+	}
+	value, err := g.values([]ast.Value{node.Value})
+	if err != nil {
+		return err
+	}
+	if node.Key != "group" {
+		g.attr(node.Key, value)
+		return nil
+	}
+	// Check the input if its value is selected by the group
+	inputValue := &expr{"nil", "any"}
+	for _, attr := range el.Attributes {
+		var err error
+		switch a := attr.(type) {
+		case *ast.Field:
+			if a.Key == "value" {
+				inputValue, err = g.values(a.Values)
+			}
+		case *ast.AttributeShorthand:
+			if a.Key == "value" {
+				inputValue, err = g.variable(a.Key)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	g.attr("checked", &expr{fmt.Sprintf("render.Grouped(%s, %s)", value.code, inputValue.code), "bool"})
+	return nil
+}
+
+// generateMerged renders the class or style attribute along with its
+// directives, like `class="item" class:active={active}`
+func (g *generator) generateMerged(el *ast.Element, key string) error {
+	var parts []string
+	for _, attr := range el.Attributes {
+		switch a := attr.(type) {
+		case *ast.Field:
+			if a.Key != key {
+				continue
+			}
+			value, err := g.values(a.Values)
+			if err != nil {
+				return err
+			}
+			parts = append(parts, toString(value))
+		case *ast.AttributeShorthand:
+			if a.Key != key {
+				continue
+			}
+			value, err := g.variable(a.Key)
+			if err != nil {
+				return err
+			}
+			parts = append(parts, toString(value))
+		case *ast.Class:
+			if key != "class" {
+				continue
+			}
+			value, err := g.values([]ast.Value{a.Value})
+			if err != nil {
+				return err
+			}
+			parts = append(parts, fmt.Sprintf("render.Cond(%s, %q, \"\")", truthy(value), a.Name))
+		case *ast.StyleDirective:
+			if key != "style" {
+				continue
+			}
+			value, err := g.values(a.Values)
+			if err != nil {
+				return err
+			}
+			parts = append(parts, fmt.Sprintf("render.Declaration(%q, %s)", a.Name, value.code))
+		}
+	}
+	fn := "render.Class"
+	if key == "style" {
+		fn = "render.Style"
+	}
+	g.code("out.Attr(%q, %s(%s))", key, fn, strings.Join(parts, ", "))
+	return nil
+}
+
+func (g *generator) generateField(node *ast.Field) error {
+	if len(node.Values) == 0 {
+		g.write(" " + node.Key)
+		return nil
+	}
+	// Static attributes are written as they were authored
+	if isStatic(node.Values) {
+		g.write(" " + node.Key + `="`)
+		for _, value := range node.Values {
+			g.write(value.(*ast.Text).Value)
+		}
+		g.write(`"`)
+		return nil
+	}
+	value, err := g.values(node.Values)
+	if err != nil {
+		return err
+	}
+	g.attr(node.Key, value)
+	return nil
+}
+
+func isStatic(values []ast.Value) bool {
+	for _, value := range values {
+		if _, ok := value.(*ast.Text); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// values converts attribute values into a single expression
+func (g *generator) values(values []ast.Value) (*expr, error) {
+	if len(values) == 0 {
+		return &expr{"true", "bool"}, nil
+	}
+	// Quoted values end with an empty text value
+	if text, ok := values[len(values)-1].(*ast.Text); ok && text.Value == "" && len(values) > 1 {
+		values = values[:len(values)-1]
+	}
+	parts := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case *ast.Text:
+			// Static text is escaped again along with the dynamic values
+			parts[i] = strconv.Quote(html.UnescapeString(v.Value))
+		case *ast.Mustache:
+			x, err := g.expr(v.Expr)
+			if err != nil {
+				return nil, err
+			} else if len(values) == 1 {
+				return x, nil
+			}
+			parts[i] = toString(x)
+		default:
+			return nil, g.errorf("unable to generate attribute value %T", v)
+		}
+	}
+	return &expr{strings.Join(parts, " + "), "string"}, nil
+}
+
+// attr writes the attribute with an escaped value
+func (g *generator) attr(key string, value *expr) {
+	switch value.typ {
+	case "func":
+		// Functions only make sense in the browser
+	default:
+		g.code("out.Attr(%q, %s)", key, value.code)
+	}
+}
+
+func (g *generator) generateIfBlock(node *ast.IfBlock) error {
+	cond, err := g.expr(node.Cond)
+	if err != nil {
+		return err
+	}
+	g.code("if %s {", truthy(cond))
+	if err := g.generateFragments(node.Then...); err != nil {
+		return err
+	}
+	if len(node.Else) > 0 {
+		g.code("} else {")
+		if err := g.generateFragments(node.Else...); err != nil {
+			return err
+		}
+	}
+	g.code("}")
+	return nil
+}
+
+func (g *generator) generateEachBlock(node *ast.EachBlock) error {
+	list, err := g.expr(node.List)
+	if err != nil {
+		return err
+	}
+	elem := "any"
+	rangeOver := "render.Each(" + list.code + ")"
+	if strings.HasPrefix(list.typ, "[]") {
+		elem = strings.TrimPrefix(list.typ, "[]")
+		rangeOver = list.code
+	}
+	if len(node.Else) > 0 {
+		g.code("if render.Len(%s) == 0 {", list.code)
+		if err := g.generateFragments(node.Else...); err != nil {
+			return err
+		}
+		g.code("}")
+	}
+	g.pushScope()
+	defer g.popScope()
+	index, value := "_", "_"
+	if node.Key != nil {
+		index = g.declare(string(node.Key.Data), "int")
+	}
+	if node.Value != nil {
+		value = g.declare(string(node.Value.Data), elem)
+	}
+	g.code("for %s, %s := range %s {", index, value, rangeOver)
+	if index != "_" {
+		g.code("_ = %s", index)
+	}
+	if value != "_" {
+		g.code("_ = %s", value)
+	}
+	if err := g.generateFragments(node.Body...); err != nil {
+		return err
+	}
+	g.code("}")
+	return nil
+}
+
+func (g *generator) generateComponent(node *ast.Component) error {
+	callee, err := g.callee(node)
+	if err != nil {
+		return err
+	}
+	fields := []string{}
+	for _, attr := range node.Attributes {
+		key, value, err := g.componentProp(attr)
+		if err != nil {
+			return err
+		} else if value == nil {
+			continue
+		}
+		prop, ok := callee.prop(key)
+		if !ok {
+			return g.errorf("<%s> has no prop %q", node.Name, key)
+		}
+		code, ok, err := g.propValue(prop, value)
+		if err != nil {
+			return g.errorf("<%s> prop %q: %w", node.Name, key, err)
+		} else if !ok {
+			continue
+		}
+		fields = append(fields, prop.goName+": "+code+",")
+	}
+	slots, err := g.generateSlots(node)
+	if err != nil {
+		return err
+	}
+	if len(slots) > 0 {
+		if !callee.slots {
+			return g.errorf("<%s> doesn't have any slots", node.Name)
+		}
+		fields = append(fields, "Slots: map[string]func(w io.Writer) error{\n"+strings.Join(slots, "\n")+"\n},")
+	}
+	g.code("if err := %s(out, &%sProps{\n%s\n}); err != nil {\nreturn err\n}", callee.name, callee.name, strings.Join(fields, "\n"))
+	return nil
+}
+
+// callee returns the component rendered by the node. <svelte:self> renders the
+// component being generated.
+func (g *generator) callee(node *ast.Component) (*component, error) {
+	if node.IsSelf() {
+		return g.component, nil
+	}
+	importPath, ok := g.component.imports[node.Name]
+	if !ok {
+		return nil, g.errorf("component %s not imported", node.Name)
+	}
+	callee, ok := g.components[importPath]
+	if !ok {
+		return nil, g.errorf("component %s not found", importPath)
+	}
+	return callee, nil
+}
+
+// propValue converts the value passed to a component's prop. Values passed to
+// pointer fields are referenced, while undefined values are left out so the
+// component falls back to its default.
+func (g *generator) propValue(prop *prop, value *expr) (string, bool, error) {
+	if value.typ == prop.typ || !strings.HasPrefix(prop.typ, "*") {
+		converted, err := g.convert(value, prop.typ)
+		if err != nil {
+			return "", false, err
+		}
+		return converted.code, true, nil
+	}
+	if value.code == "nil" {
+		return "", false, nil
+	}
+	converted, err := g.convert(value, strings.TrimPrefix(prop.typ, "*"))
+	if err != nil {
+		return "", false, err
+	}
+	return "render.Ptr(" + converted.code + ")", true, nil
+}
+
+// componentProp returns the prop's name and value, or nil if the attribute
+// isn't a prop
+func (g *generator) componentProp(node ast.Attribute) (string, *expr, error) {
+	switch a := node.(type) {
+	case *ast.Field:
+		if a.EventHandler {
+			return "", nil, nil
+		}
+		value, err := g.values(a.Values)
+		if err != nil {
+			return "", nil, err
+		}
+		return a.Key, value, nil
+	case *ast.AttributeShorthand:
+		if a.EventHandler {
+			return "", nil, nil
+		}
+		value, err := g.variable(a.Key)
+		if err != nil {
+			return "", nil, err
+		}
+		return a.Key, value, nil
+	case *ast.Binding:
+		// Components are only bound in the browser
+		if a.Key == "this" {
+			return "", nil, nil
+		}
+		value, err := g.values([]ast.Value{a.Value})
+		if err != nil {
+			return "", nil, err
+		}
+		return a.Key, value, nil
+	case *ast.Let:
+		return "", nil, g.errorf("slot props aren't supported yet")
+	default:
+		return "", nil, g.errorf("unable to generate component attribute %T", a)
+	}
+}
+
+// generateSlots generates a render function for each slot passed to the
+// component
+func (g *generator) generateSlots(node *ast.Component) ([]string, error) {
+	names := []string{}
+	contents := map[string][]ast.Fragment{}
+	for _, child := range node.Children {
+		name := slotName(child)
+		if _, ok := contents[name]; !ok {
+			names = append(names, name)
+		}
+		contents[name] = append(contents[name], child)
+	}
+	slots := []string{}
+	for _, name := range names {
+		if isEmpty(contents[name]) {
+			continue
+		}
+		// Generate the slot's content into its own function
+		g.flush()
+		out := g.out
+		g.out = new(strings.Builder)
+		if err := g.generateFragments(contents[name]...); err != nil {
+			return nil, err
+		}
+		g.flush()
+		body := g.out.String()
+		g.out = out
+		slots = append(slots, fmt.Sprintf("%q: func(w io.Writer) error {\nout := render.NewWriter(w)\n%sreturn out.Err()\n},", name, body))
+	}
+	return slots, nil
+}
+
+func (g *generator) generateSlot(node *ast.Slot) error {
+	if len(node.Attributes) > 0 {
+		return g.errorf("slot props aren't supported yet")
+	}
+	name := node.Name
+	if name == "" {
+		name = "default"
+	}
+	g.code("if slot := props.Slots[%q]; slot != nil {\nif err := slot(out); err != nil {\nreturn err\n}", name)
+	if len(node.Fallback) > 0 {
+		g.code("} else {")
+		if err := g.generateFragments(node.Fallback...); err != nil {
+			return err
+		}
+	}
+	g.code("}")
+	return nil
+}
+
+// slotName returns the slot a component's child should be rendered into
+func slotName(fragment ast.Fragment) string {
+	var attrs []ast.Attribute
+	switch f := fragment.(type) {
+	case *ast.Element:
+		attrs = f.Attributes
+	case *ast.Component:
+		attrs = f.Attributes
+	}
+	for _, attr := range attrs {
+		if slot, ok := attr.(*ast.NamedSlot); ok {
+			return slot.Name
+		}
+	}
+	return "default"
+}
+
+// isEmpty returns true if the slot content is only whitespace
+func isEmpty(fragments []ast.Fragment) bool {
+	for _, fragment := range fragments {
+		text, ok := fragment.(*ast.Text)
+		if !ok || strings.TrimSpace(text.Value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,268 @@
+package gogen_test
+
+import (
+	goast "go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/livebud/duo/internal/check"
+	"github.com/livebud/duo/internal/gogen"
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+)
+
+// Run `go generate ./internal/gogen/view` to update the generated view
+func TestGenerateView(t *testing.T) {
+	is := is.New(t)
+	files, err := gogen.New("view").Generate(os.DirFS("view"))
+	is.NoErr(err)
+	is.Equal(len(files), 9)
+	for _, file := range files {
+		expected, err := os.ReadFile(filepath.Join("view", file.Path))
+		is.NoErr(err)
+		diff.TestString(t, string(file.Code), string(expected))
+	}
+}
+
+func generateError(t *testing.T, name string, files map[string]string, expected string) {
+	t.Helper()
+	t.Run(name, func(t *testing.T) {
+		t.Helper()
+		fsys := fstest.MapFS{}
+		for path, code := range files {
+			fsys[path] = &fstest.MapFile{Data: []byte(code)}
+		}
+		_, err := gogen.New("view").Generate(fsys)
+		if err == nil {
+			t.Fatalf("expected an error")
+		}
+		diff.TestString(t, err.Error(), expected)
+	})
+}
+
+func TestGenerateErrors(t *testing.T) {
+	generateError(t, "undefined", map[string]string{
+		"index.svelte": `<h1>{title}</h1>`,
+	}, `gogen: index.svelte: title is not defined`)
+	generateError(t, "call", map[string]string{
+		"index.svelte": `<script>import format from "timeago.js"; export let date = ""</script><p>{format(date)}</p>`,
+	}, `gogen: index.svelte: unable to call format in Go`)
+	generateError(t, "browser", map[string]string{
+		"index.svelte": `<script>let el = document.body</script><p>{el}</p>`,
+	}, `gogen: index.svelte: el is only available in the browser`)
+	generateError(t, "prop type", map[string]string{
+		"index.svelte":   `<script>import Counter from "./Counter.svelte"</script><Counter count="five" />`,
+		"Counter.svelte": `<script>export let count = 0</script><p>{count}</p>`,
+	}, `gogen: index.svelte: <Counter> prop "count": cannot use "five" (string) as float64`)
+	generateError(t, "unknown prop", map[string]string{
+		"index.svelte":   `<script>import Counter from "./Counter.svelte"</script><Counter total={1} />`,
+		"Counter.svelte": `<script>export let count = 0</script><p>{count}</p>`,
+	}, `gogen: index.svelte: <Counter> has no prop "total"`)
+	generateError(t, "other directory", map[string]string{
+		"index.svelte": `<script>import Counter from "../Counter.svelte"</script><Counter />`,
+	}, `gogen: index.svelte imports ../Counter.svelte, but components must be in the same directory`)
+	generateError(t, "field", map[string]string{
+		"index.svelte": `<script lang="ts">export let story: { title: string }</script><h1>{story.name}</h1>`,
+	}, `gogen: index.svelte: IndexStory has no field "name"`)
+	generateError(t, "await", map[string]string{
+		"index.svelte": `<script>export let story</script>{#await story then s}{s}{/await}`,
+	}, `gogen: index.svelte: unable to generate *ast.AwaitBlock`)
+}
+
+func TestGenerateUnicode(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		"index.svelte": &fstest.MapFile{Data: []byte(`<script>let label = "café ❌ 😀"; const escaped = 'caf\xE9 \u{1F600}'</script><p title={label}>{escaped}</p>`)},
+	}
+	files, err := gogen.New("view").Generate(fsys)
+	is.NoErr(err)
+	code := string(files[0].Code)
+	is.True(strings.Contains(code, `label := "café ❌ 😀"`))
+	is.True(strings.Contains(code, `escaped := "café 😀"`))
+}
+
+func TestGenerateSelf(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		"tree.svelte": &fstest.MapFile{Data: []byte(`<script>export let name = ""; export let depth = 0</script><li>{name}{#if depth < 2}<ul><svelte:self {name} depth={depth + 1} /></ul>{/if}</li>`)},
+	}
+	files, err := gogen.New("view").Generate(fsys)
+	is.NoErr(err)
+	is.Equal(len(files), 1)
+	is.True(strings.Contains(string(files[0].Code), "if err := Tree(out, &TreeProps{\n\t\t\tName:  name,\n\t\t\tDepth: depth + float64(1),\n\t\t}); err != nil {"))
+}
+
+func TestGenerateWarnings(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		"index.svelte": &fstest.MapFile{Data: []byte(`<script>function add() {}</script><button onclik={add}>+</button>`)},
+	}
+	generator := gogen.New("view")
+	var warnings []string
+	generator.Warn = func(diagnostic *check.Diagnostic) {
+		warnings = append(warnings, diagnostic.String())
+	}
+	files, err := generator.Generate(fsys)
+	is.NoErr(err)
+	is.Equal(len(files), 1)
+	is.Equal(warnings, []string{"index.svelte:1:35: warning: Unknown event handler \"onclik\" on `<button>`, did you mean \"onclick\"? (unknown_event_handler)"})
+}
+
+func TestProps(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		"index.svelte": {Data: []byte(`<script>export let stories = []; export let page = 1</script>{#each stories as story}{story.title}{/each}`)},
+		"posts/show.svelte": {Data: []byte(`<script lang="ts">
+			interface Story { title: string; url?: string; tags: string[] }
+			interface Props { story: Story; related: Story[]; author: { name: string } | null; meta: Record<string, number>; onclick?: () => void }
+			let { story, related, author, meta, onclick }: Props = $props()
+		</script><h1>{story.title}</h1>`)},
+		"Story.svelte":          {Data: []byte(`<script lang="ts">let { story, active = $bindable(false) }: { story: { id: number }; active?: boolean } = $props()</script>{story.id}`)},
+		"node_modules/x.svelte": {Data: []byte(`<script>export let x = 1</script>`)},
+	}
+	file, err := gogen.New("view").Props(fsys)
+	is.NoErr(err)
+	is.Equal(file.Path, "props_gen.go")
+	diff.TestString(t, string(file.Code), `// Code generated by duo. DO NOT EDIT.
+
+package view
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/livebud/duo"
+)
+
+// View renders the components with typed props
+type View struct {
+	view *duo.View
+}
+
+// NewView wraps the view with typed render methods
+func NewView(view *duo.View) *View {
+	return &View{view}
+}
+
+// ViewStoryProps are the props for Story.svelte. Nil fields fall back to the defaults
+// declared in the component.
+type ViewStoryProps struct {
+	Story  ViewStoryStory `+"`"+`json:"story"`+"`"+`
+	Active bool           `+"`"+`json:"active,omitempty"`+"`"+`
+}
+
+// RenderStory renders Story.svelte
+func (v *View) RenderStory(w http.ResponseWriter, props *ViewStoryProps) {
+	v.view.Render(w, "Story.svelte", props)
+}
+
+// RenderStoryContext renders Story.svelte, stopping when the context is cancelled
+func (v *View) RenderStoryContext(ctx context.Context, w http.ResponseWriter, props *ViewStoryProps) {
+	v.view.RenderContext(ctx, w, "Story.svelte", props)
+}
+
+// ViewIndexProps are the props for index.svelte. Nil fields fall back to the defaults
+// declared in the component.
+type ViewIndexProps struct {
+	Stories any      `+"`"+`json:"stories,omitempty"`+"`"+`
+	Page    *float64 `+"`"+`json:"page,omitempty"`+"`"+`
+}
+
+// RenderIndex renders index.svelte
+func (v *View) RenderIndex(w http.ResponseWriter, props *ViewIndexProps) {
+	v.view.Render(w, "index.svelte", props)
+}
+
+// RenderIndexContext renders index.svelte, stopping when the context is cancelled
+func (v *View) RenderIndexContext(ctx context.Context, w http.ResponseWriter, props *ViewIndexProps) {
+	v.view.RenderContext(ctx, w, "index.svelte", props)
+}
+
+// ViewPostsShowProps are the props for posts/show.svelte. Nil fields fall back to the defaults
+// declared in the component.
+type ViewPostsShowProps struct {
+	Story   ViewStory            `+"`"+`json:"story"`+"`"+`
+	Related []ViewStory          `+"`"+`json:"related"`+"`"+`
+	Author  *ViewPostsShowAuthor `+"`"+`json:"author"`+"`"+`
+	Meta    map[string]float64   `+"`"+`json:"meta"`+"`"+`
+	Onclick any                  `+"`"+`json:"onclick,omitempty"`+"`"+`
+}
+
+// RenderPostsShow renders posts/show.svelte
+func (v *View) RenderPostsShow(w http.ResponseWriter, props *ViewPostsShowProps) {
+	v.view.Render(w, "posts/show.svelte", props)
+}
+
+// RenderPostsShowContext renders posts/show.svelte, stopping when the context is cancelled
+func (v *View) RenderPostsShowContext(ctx context.Context, w http.ResponseWriter, props *ViewPostsShowProps) {
+	v.view.RenderContext(ctx, w, "posts/show.svelte", props)
+}
+
+type ViewStoryStory struct {
+	ID float64 `+"`"+`json:"id"`+"`"+`
+}
+
+type ViewStory struct {
+	Title string   `+"`"+`json:"title"`+"`"+`
+	URL   string   `+"`"+`json:"url,omitempty"`+"`"+`
+	Tags  []string `+"`"+`json:"tags"`+"`"+`
+}
+
+type ViewPostsShowAuthor struct {
+	Name string `+"`"+`json:"name"`+"`"+`
+}
+`)
+}
+
+func TestPropsWithGenerate(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		"Story.svelte":  {Data: []byte(`<script lang="ts">interface Story { id: number; url: string }; export let story: Story</script><a href={story.url}>{story.id}</a>`)},
+		"Author.svelte": {Data: []byte(`<script lang="ts">export let story: { userId: number }</script>{story.userId}`)},
+	}
+	files, err := gogen.New("view").Generate(fsys)
+	is.NoErr(err)
+	file, err := gogen.New("view").Props(fsys)
+	is.NoErr(err)
+	// The files are in the same package, so their declarations can't collide
+	decls := map[string]string{}
+	fset := token.NewFileSet()
+	for _, file := range append(files, file) {
+		f, err := parser.ParseFile(fset, file.Path, file.Code, 0)
+		is.NoErr(err)
+		for _, decl := range f.Decls {
+			var names []string
+			switch decl := decl.(type) {
+			case *goast.FuncDecl:
+				if decl.Recv != nil {
+					continue
+				}
+				names = append(names, decl.Name.Name)
+			case *goast.GenDecl:
+				for _, spec := range decl.Specs {
+					if spec, ok := spec.(*goast.TypeSpec); ok {
+						names = append(names, spec.Name.Name)
+					}
+				}
+			}
+			for _, name := range names {
+				if other, ok := decls[name]; ok {
+					t.Fatalf("%s is declared in %s and %s", name, other, file.Path)
+				}
+				decls[name] = file.Path
+			}
+		}
+	}
+	is.Equal(decls["ViewStory"], "props_gen.go")
+	is.Equal(decls["Story"], "Story.svelte.go")
+	// The View type can't be shared with a component
+	fsys["View.svelte"] = &fstest.MapFile{Data: []byte(`<h1>view</h1>`)}
+	_, err = gogen.New("view").Props(fsys)
+	is.True(err != nil)
+	is.Equal(err.Error(), "gogen: View.svelte collides with the generated View")
+}
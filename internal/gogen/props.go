@@ -0,0 +1,92 @@
+package gogen
+
+import (
+	"fmt"
+	"go/format"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/livebud/duo/internal/parser"
+	"github.com/livebud/duo/internal/props"
+)
+
+// Props generates a Go struct for the props of each component in fsys, along
+// with a View that renders the components through duo.View with those props.
+// Unlike Generate, the components still render at runtime, so they can be
+// nested in directories and use everything the server-side renderer supports.
+func (g *Generator) Props(fsys fs.FS) (*File, error) {
+	var paths []string
+	err := fs.WalkDir(fsys, ".", func(filePath string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if de.IsDir() {
+			if filePath != "." && (strings.HasPrefix(de.Name(), ".") || de.Name() == "node_modules") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if path.Ext(filePath) == ".svelte" {
+			paths = append(paths, filePath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	// Types are prefixed with View, so they don't collide with the output of
+	// Generate in the same package
+	types := newTypes("View", "NewView")
+	types.prefix = "View"
+	schemas := make([]*props.Schema, len(paths))
+	names := make([]string, len(paths))
+	for i, filePath := range paths {
+		code, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := parser.Parse(filePath, string(code))
+		if err != nil {
+			return nil, err
+		}
+		schemas[i] = props.Extract(filePath, doc)
+		names[i] = fieldName(strings.TrimSuffix(filePath, path.Ext(filePath)))
+		if name := componentName(filePath); name == "View" || name == "NewView" {
+			return nil, fmt.Errorf("gogen: %s collides with the generated %s", filePath, name)
+		}
+		types.reserve("View" + names[i] + "Props")
+		// Component functions and their props from Generate are reserved too
+		types.reserve(componentName(filePath))
+		types.reserve(componentName(filePath) + "Props")
+	}
+	file := new(strings.Builder)
+	file.WriteString("// Code generated by duo. DO NOT EDIT.\n\n")
+	file.WriteString("package " + g.Package + "\n\n")
+	file.WriteString("import (\n\t\"context\"\n\t\"net/http\"\n\n\t\"github.com/livebud/duo\"\n)\n\n")
+	file.WriteString("// View renders the components with typed props\n")
+	file.WriteString("type View struct {\n\tview *duo.View\n}\n\n")
+	file.WriteString("// NewView wraps the view with typed render methods\n")
+	file.WriteString("func NewView(view *duo.View) *View {\n\treturn &View{view}\n}\n\n")
+	for i, schema := range schemas {
+		name := names[i]
+		file.WriteString(fmt.Sprintf("// View%sProps are the props for %s. Nil fields fall back to the defaults\n// declared in the component.\n", name, schema.Path))
+		file.WriteString(fmt.Sprintf("type View%sProps struct {\n%s}\n\n", name, types.body(types.fields(name, schema.Props))))
+		file.WriteString(fmt.Sprintf("// Render%s renders %s\n", name, schema.Path))
+		file.WriteString(fmt.Sprintf("func (v *View) Render%s(w http.ResponseWriter, props *View%sProps) {\n", name, name))
+		file.WriteString(fmt.Sprintf("\tv.view.Render(w, %q, props)\n}\n\n", schema.Path))
+		file.WriteString(fmt.Sprintf("// Render%sContext renders %s, stopping when the context is cancelled\n", name, schema.Path))
+		file.WriteString(fmt.Sprintf("func (v *View) Render%sContext(ctx context.Context, w http.ResponseWriter, props *View%sProps) {\n", name, name))
+		file.WriteString(fmt.Sprintf("\tv.view.RenderContext(ctx, w, %q, props)\n}\n\n", schema.Path))
+	}
+	for _, decl := range types.decls {
+		file.WriteString(decl.code)
+	}
+	code, err := format.Source([]byte(file.String()))
+	if err != nil {
+		return nil, fmt.Errorf("gogen: unable to format props: %w", err)
+	}
+	return &File{"props_gen.go", code}, nil
+}
@@ -0,0 +1,206 @@
+package gogen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/livebud/duo/internal/props"
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// types maps the prop schemas to Go types. Both Props and Generate go through
+// it, so a component's props have the same Go types either way.
+type types struct {
+	names   map[string]string   // declared type names to their struct fields
+	structs map[string][]*field // declared struct types to their fields
+	decls   []*decl
+	owner   string // path of the component being declared
+	prefix  string // prefix of the declared type names
+}
+
+// field of a props struct or a declared struct type
+type field struct {
+	name     string // name in the component
+	goName   string
+	typ      string
+	optional bool
+}
+
+// decl is a struct type declared for a component's props
+type decl struct {
+	owner string // path of the component that first needed it
+	code  string
+}
+
+func newTypes(reserved ...string) *types {
+	t := &types{
+		names:   map[string]string{},
+		structs: map[string][]*field{},
+	}
+	for _, name := range reserved {
+		t.names[name] = ""
+	}
+	return t
+}
+
+// reserve a type name so it isn't used for a struct
+func (t *types) reserve(name string) {
+	t.names[name] = ""
+}
+
+// fields returns the Go fields for the props, declaring the struct types they
+// need along the way
+func (t *types) fields(owner string, fields []*props.Prop) []*field {
+	out := make([]*field, len(fields))
+	for i, prop := range fields {
+		name := fieldName(prop.Name)
+		out[i] = &field{
+			name:     prop.Name,
+			goName:   name,
+			typ:      fieldType(prop, t.goType(owner+name, prop.Type)),
+			optional: prop.Optional,
+		}
+	}
+	return out
+}
+
+// body of the struct with the fields
+func (t *types) body(fields []*field) string {
+	out := new(strings.Builder)
+	for _, field := range fields {
+		tag := field.name
+		if field.optional {
+			tag += ",omitempty"
+		}
+		out.WriteString(fmt.Sprintf("\t%s %s `json:%q`\n", field.goName, field.typ, tag))
+	}
+	return out.String()
+}
+
+// goType returns the Go type for the prop type, declaring structs for objects
+// named after their TypeScript type or the owner
+func (t *types) goType(owner string, typ *props.Type) string {
+	switch typ.Kind {
+	case props.String:
+		return "string"
+	case props.Number:
+		return "float64"
+	case props.Boolean:
+		return "bool"
+	case props.Array:
+		if typ.Elem == nil {
+			return "any"
+		}
+		elem := t.goType(owner+"Item", typ.Elem)
+		// Slices like []*Story aren't assignable to []any
+		if elem == "any" {
+			return "any"
+		}
+		return "[]" + elem
+	case props.Object:
+		if len(typ.Fields) > 0 {
+			name := owner
+			if typ.Name != "" {
+				name = fieldName(typ.Name)
+			}
+			return t.declare(name, owner, typ.Fields)
+		}
+		if typ.Elem != nil {
+			return "map[string]" + t.goType(owner+"Value", typ.Elem)
+		}
+		return "any"
+	default:
+		return "any"
+	}
+}
+
+// declare a struct type, reusing a type with the same name and fields. Names
+// already taken by a different type are prefixed with the owner.
+func (t *types) declare(name, owner string, fields []*props.Prop) string {
+	goFields := t.fields(name, fields)
+	body := t.body(goFields)
+	candidates := []string{t.prefix + name}
+	if owner != name {
+		candidates = append(candidates, t.prefix+owner+name)
+	}
+	for i := 2; ; i++ {
+		for _, candidate := range candidates {
+			existing, ok := t.names[candidate]
+			if ok && existing == body {
+				return candidate
+			}
+			if !ok {
+				t.names[candidate] = body
+				t.structs[candidate] = goFields
+				t.decls = append(t.decls, &decl{
+					owner: t.owner,
+					code:  fmt.Sprintf("type %s struct {\n%s}\n\n", candidate, body),
+				})
+				return candidate
+			}
+		}
+		candidates = []string{fmt.Sprintf("%s%s%d", t.prefix, name, i)}
+	}
+}
+
+// field returns the field of a declared struct type
+func (t *types) field(typ, name string) (*field, bool) {
+	fields, ok := t.structs[strings.TrimPrefix(typ, "*")]
+	if !ok {
+		return nil, false
+	}
+	for _, field := range fields {
+		if field.name == name {
+			return field, true
+		}
+	}
+	return nil, false
+}
+
+// isStruct returns true if the type is a declared struct
+func (t *types) isStruct(typ string) bool {
+	_, ok := t.structs[strings.TrimPrefix(typ, "*")]
+	return ok
+}
+
+// fieldType returns the Go type of a prop or an object field. Values with a
+// default other than the zero value are pointers, so passing the zero value
+// isn't mistaken for leaving the prop out. Required values that can be null are
+// pointers too.
+func fieldType(prop *props.Prop, typ string) string {
+	if isNilable(typ) {
+		return typ
+	}
+	if prop.Default != nil && !isZeroDefault(prop.Default) || prop.Type.Nullable && !prop.Optional {
+		return "*" + typ
+	}
+	return typ
+}
+
+// isZeroDefault returns true if the default is the same as the Go zero value
+func isZeroDefault(value js.IExpr) bool {
+	switch v := value.(type) {
+	case *js.LiteralExpr:
+		switch v.TokenType {
+		case js.StringToken:
+			return len(v.Data) == 2
+		case js.DecimalToken:
+			return strings.Trim(string(v.Data), "0.") == ""
+		case js.FalseToken, js.NullToken:
+			return true
+		}
+	case *js.TemplateExpr:
+		return v.Tag == nil && len(v.List) == 0 && string(v.Tail) == "``"
+	case *js.Var:
+		return string(v.Data) == "undefined"
+	case *js.ObjectExpr:
+		return len(v.List) == 0
+	case *js.ArrayExpr:
+		return len(v.List) == 0
+	}
+	return false
+}
+
+func isNilable(typ string) bool {
+	return typ == "any" || strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || strings.HasPrefix(typ, "*")
+}
@@ -0,0 +1,54 @@
+// Code generated by duo. DO NOT EDIT.
+
+package view
+
+import (
+	"io"
+
+	"github.com/livebud/duo/render"
+)
+
+// BadgeProps are the props for Badge.svelte. Nil fields fall back to the defaults
+// declared in the component.
+type BadgeProps struct {
+	Label  string   `json:"label,omitempty"`
+	Kind   *string  `json:"kind,omitempty"`
+	Active bool     `json:"active,omitempty"`
+	Color  any      `json:"color,omitempty"`
+	Size   *float64 `json:"size,omitempty"`
+	Icon   string   `json:"icon,omitempty"`
+}
+
+// Badge renders Badge.svelte
+func Badge(w io.Writer, props *BadgeProps) error {
+	if props == nil {
+		props = &BadgeProps{}
+	}
+	out := render.NewWriter(w)
+	label := props.Label
+	_ = label
+	kind := "info"
+	if props.Kind != nil {
+		kind = *props.Kind
+	}
+	_ = kind
+	active := props.Active
+	_ = active
+	color := props.Color
+	_ = color
+	size := float64(12)
+	if props.Size != nil {
+		size = *props.Size
+	}
+	_ = size
+	icon := props.Icon
+	_ = icon
+	out.WriteString("\n\n<span")
+	out.Attr("class", render.Class("badge "+kind, render.Cond(active, "active", ""), render.Cond(size > float64(16), "large", "")))
+	out.Attr("style", render.Style(render.Declaration("color", color), render.Declaration("font-size", render.String(size)+"px")))
+	out.WriteString(">")
+	out.WriteString(icon)
+	out.Text(label)
+	out.WriteString("</span>\n")
+	return out.Err()
+}
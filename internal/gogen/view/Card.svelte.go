@@ -0,0 +1,64 @@
+// Code generated by duo. DO NOT EDIT.
+
+package view
+
+import (
+	"io"
+
+	"github.com/livebud/duo/render"
+)
+
+// CardProps are the props for Card.svelte. Nil fields fall back to the defaults
+// declared in the component.
+type CardProps struct {
+	Title *string                            `json:"title,omitempty"`
+	Width *float64                           `json:"width,omitempty"`
+	Slots map[string]func(w io.Writer) error `json:"-"`
+}
+
+// Card renders Card.svelte
+func Card(w io.Writer, props *CardProps) error {
+	if props == nil {
+		props = &CardProps{}
+	}
+	out := render.NewWriter(w)
+	title := "Untitled"
+	if props.Title != nil {
+		title = *props.Title
+	}
+	_ = title
+	width := 1.5
+	if props.Width != nil {
+		width = *props.Width
+	}
+	_ = width
+	out.WriteString("\n\n<article")
+	out.Attr("style", "width: "+render.String(width*float64(2))+"rem")
+	out.Attr("data-empty", !render.Truthy(title))
+	out.WriteString(">\n  <header>")
+	if slot := props.Slots["header"]; slot != nil {
+		if err := slot(out); err != nil {
+			return err
+		}
+	} else {
+		out.WriteString("<h2>")
+		out.Text(render.Cond(render.Len(title) > 3, title, "short: "+title))
+		out.WriteString("</h2>")
+	}
+	out.WriteString("</header>\n  ")
+	if slot := props.Slots["default"]; slot != nil {
+		if err := slot(out); err != nil {
+			return err
+		}
+	}
+	out.WriteString("\n  <footer>")
+	if slot := props.Slots["footer"]; slot != nil {
+		if err := slot(out); err != nil {
+			return err
+		}
+	} else {
+		out.WriteString("no footer")
+	}
+	out.WriteString("</footer>\n</article>\n")
+	return out.Err()
+}
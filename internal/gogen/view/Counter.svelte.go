@@ -0,0 +1,34 @@
+// Code generated by duo. DO NOT EDIT.
+
+package view
+
+import (
+	"io"
+
+	"github.com/livebud/duo/render"
+)
+
+// CounterProps are the props for Counter.svelte. Nil fields fall back to the defaults
+// declared in the component.
+type CounterProps struct {
+	Count *float64 `json:"count,omitempty"`
+}
+
+// Counter renders Counter.svelte
+func Counter(w io.Writer, props *CounterProps) error {
+	if props == nil {
+		props = &CounterProps{}
+	}
+	out := render.NewWriter(w)
+	count := float64(10)
+	if props.Count != nil {
+		count = *props.Count
+	}
+	_ = count
+	out.WriteString("\n\n<button>\n  Clicked ")
+	out.Print(render.Or(count, float64(0)))
+	out.WriteString("\n  ")
+	out.Text(render.Cond(count == float64(1), "time", "times"))
+	out.WriteString("\n</button>\n")
+	return out.Err()
+}
@@ -0,0 +1,30 @@
+// Code generated by duo. DO NOT EDIT.
+
+package view
+
+import (
+	"io"
+
+	"github.com/livebud/duo/render"
+)
+
+// GreetingProps are the props for Greeting.svelte. Nil fields fall back to the defaults
+// declared in the component.
+type GreetingProps struct {
+}
+
+// Greeting renders Greeting.svelte
+func Greeting(w io.Writer, props *GreetingProps) error {
+	if props == nil {
+		props = &GreetingProps{}
+	}
+	out := render.NewWriter(w)
+	greeting := "hello"
+	_ = greeting
+	out.WriteString("\n\n<h1")
+	out.Attr("name", "hello-"+greeting+"-cool")
+	out.WriteString(">")
+	out.Text(greeting)
+	out.WriteString("</h1>\n")
+	return out.Err()
+}
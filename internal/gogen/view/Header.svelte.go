@@ -0,0 +1,26 @@
+// Code generated by duo. DO NOT EDIT.
+
+package view
+
+import (
+	"io"
+
+	"github.com/livebud/duo/render"
+)
+
+// HeaderProps are the props for Header.svelte. Nil fields fall back to the defaults
+// declared in the component.
+type HeaderProps struct {
+}
+
+// Header renders Header.svelte
+func Header(w io.Writer, props *HeaderProps) error {
+	if props == nil {
+		props = &HeaderProps{}
+	}
+	out := render.NewWriter(w)
+	out.WriteString("<div class=\"list svelte-h3luwf\">\n  <img src=\"https://news.ycombinator.com/y18.gif\" alt=\"Hacker News\" class=\"svelte-h3luwf\"/>\n  <a href=\"/\" class=\"svelte-h3luwf\"><h1 class=\"svelte-h3luwf\">Hacker News</h1></a>\n  <div class=\"links svelte-h3luwf\">\n    <a href=\"/newest\" class=\"svelte-h3luwf\">Newest</a>\n    <a href=\"/askhn\" class=\"svelte-h3luwf\">Ask HN</a>\n    <a href=\"/showhn\" class=\"svelte-h3luwf\">Show HN</a>\n  </div>\n</div>\n\n")
+	out.Style("svelte-h3luwf", ".list.svelte-h3luwf { display: flex; align-items: center; padding: 10px }\na.svelte-h3luwf { text-decoration: none; color: inherit }\nimg.svelte-h3luwf { display: block; height: 20px; width: 20px }\nh1.svelte-h3luwf { margin-left: 10px; font-weight: 600; font-size: 1rem }\nh1.svelte-h3luwf:hover { text-decoration: none }\n.links.svelte-h3luwf { margin-left: 10px; display: flex; align-items: center }\n.links.svelte-h3luwf > a.svelte-h3luwf { margin-left: 10px; font-size: 75% }\n.links.svelte-h3luwf > a.svelte-h3luwf:hover { text-decoration: underline }")
+	out.WriteString("\n")
+	return out.Err()
+}
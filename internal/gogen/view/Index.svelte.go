@@ -0,0 +1,57 @@
+// Code generated by duo. DO NOT EDIT.
+
+package view
+
+import (
+	"io"
+
+	"github.com/livebud/duo/render"
+)
+
+// IndexProps are the props for Index.svelte. Nil fields fall back to the defaults
+// declared in the component.
+type IndexProps struct {
+	Stories []Item `json:"stories,omitempty"`
+}
+
+// Index renders Index.svelte
+func Index(w io.Writer, props *IndexProps) error {
+	if props == nil {
+		props = &IndexProps{}
+	}
+	out := render.NewWriter(w)
+	stories := props.Stories
+	_ = stories
+	out.WriteString("\n\n<main>\n  ")
+	if err := Header(out, &HeaderProps{}); err != nil {
+		return err
+	}
+	out.WriteString("\n\n  <div>\n    ")
+	for i, story := range stories {
+		_ = i
+		_ = story
+		out.WriteString("\n      ")
+		if err := Story(out, &StoryProps{
+			Story: story,
+		}); err != nil {
+			return err
+		}
+		out.WriteString("\n      ")
+		if i%2 == 1 {
+			out.WriteString("<hr/>")
+		}
+		out.WriteString("\n    ")
+	}
+	out.WriteString("\n  </div>\n</main>\n")
+	return out.Err()
+}
+
+type Item struct {
+	ID          float64 `json:"ID"`
+	Title       string  `json:"Title"`
+	URL         string  `json:"URL,omitempty"`
+	Points      float64 `json:"Points"`
+	Author      string  `json:"Author"`
+	CreatedAt   string  `json:"CreatedAt,omitempty"`
+	NumComments float64 `json:"NumComments"`
+}
@@ -0,0 +1,76 @@
+// Code generated by duo. DO NOT EDIT.
+
+package view
+
+import (
+	"io"
+
+	"github.com/livebud/duo/render"
+)
+
+// PageProps are the props for Page.svelte. Nil fields fall back to the defaults
+// declared in the component.
+type PageProps struct {
+	Name  any      `json:"name"`
+	Count float64  `json:"count,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// Page renders Page.svelte
+func Page(w io.Writer, props *PageProps) error {
+	if props == nil {
+		props = &PageProps{}
+	}
+	out := render.NewWriter(w)
+	name := props.Name
+	_ = name
+	count := props.Count
+	_ = count
+	tags := props.Tags
+	if tags == nil {
+		tags = []string{"a", "b"}
+	}
+	_ = tags
+	greeting := "hi " + render.String(name)
+	_ = greeting
+	out.WriteString("\n\n")
+	if err := Card(out, &CardProps{
+		Title: render.Ptr(render.As[string](name)),
+		Width: render.Ptr(count),
+		Slots: map[string]func(w io.Writer) error{
+			"default": func(w io.Writer) error {
+				out := render.NewWriter(w)
+				out.WriteString("\n  \n  ")
+				for _, tag := range tags {
+					_ = tag
+					out.WriteString("<span>")
+					out.Text(tag)
+					out.WriteString("</span>")
+				}
+				out.WriteString("\n  <p>")
+				out.Print(count / float64(2))
+				out.WriteString(" ")
+				out.Print(render.And[any](count > float64(1), "many"))
+				out.WriteString(" ")
+				out.Print(render.Coalesce[any](name, "anonymous"))
+				out.WriteString("</p>\n")
+				return out.Err()
+			},
+			"header": func(w io.Writer) error {
+				out := render.NewWriter(w)
+				out.WriteString("<h1>")
+				out.Text(greeting)
+				out.WriteString("!</h1>")
+				return out.Err()
+			},
+		},
+	}); err != nil {
+		return err
+	}
+	out.WriteString("\n")
+	if err := Card(out, &CardProps{}); err != nil {
+		return err
+	}
+	out.WriteString("\n")
+	return out.Err()
+}
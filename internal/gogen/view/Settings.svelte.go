@@ -0,0 +1,54 @@
+// Code generated by duo. DO NOT EDIT.
+
+package view
+
+import (
+	"io"
+
+	"github.com/livebud/duo/render"
+)
+
+// SettingsProps are the props for Settings.svelte. Nil fields fall back to the defaults
+// declared in the component.
+type SettingsProps struct {
+	Name       string  `json:"name,omitempty"`
+	Bio        string  `json:"bio,omitempty"`
+	Subscribed bool    `json:"subscribed,omitempty"`
+	Flavor     *string `json:"flavor,omitempty"`
+	Toppings   any     `json:"toppings,omitempty"`
+}
+
+// Settings renders Settings.svelte
+func Settings(w io.Writer, props *SettingsProps) error {
+	if props == nil {
+		props = &SettingsProps{}
+	}
+	out := render.NewWriter(w)
+	name := props.Name
+	_ = name
+	bio := props.Bio
+	_ = bio
+	subscribed := props.Subscribed
+	_ = subscribed
+	flavor := "mint"
+	if props.Flavor != nil {
+		flavor = *props.Flavor
+	}
+	_ = flavor
+	toppings := props.Toppings
+	_ = toppings
+	out.WriteString("\n\n<form>\n  <input")
+	out.Attr("value", name)
+	out.WriteString("/>\n  <textarea>")
+	out.Text(bio)
+	out.WriteString("</textarea>\n  <input type=\"checkbox\"")
+	out.Attr("checked", subscribed)
+	out.WriteString("/>\n  <input type=\"radio\"")
+	out.Attr("checked", render.Grouped(flavor, "mint"))
+	out.WriteString(" value=\"mint\"/>\n  <input type=\"radio\"")
+	out.Attr("checked", render.Grouped(flavor, "lemon"))
+	out.WriteString(" value=\"lemon\"/>\n  <input type=\"checkbox\"")
+	out.Attr("checked", render.Grouped(toppings, "nuts"))
+	out.WriteString(" value=\"nuts\"/>\n</form>\n")
+	return out.Err()
+}
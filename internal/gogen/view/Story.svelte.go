@@ -0,0 +1,51 @@
+// Code generated by duo. DO NOT EDIT.
+
+package view
+
+import (
+	"io"
+
+	"github.com/livebud/duo/render"
+)
+
+// StoryProps are the props for Story.svelte. Nil fields fall back to the defaults
+// declared in the component.
+type StoryProps struct {
+	Story Item `json:"story"`
+}
+
+// Story renders Story.svelte
+func Story(w io.Writer, props *StoryProps) error {
+	if props == nil {
+		props = &StoryProps{}
+	}
+	out := render.NewWriter(w)
+	story := props.Story
+	_ = story
+	out.WriteString("\n\n<div class=\"story svelte-1tbs0et\">\n  <div>\n    <a class=\"title svelte-1tbs0et\"")
+	out.Attr("href", render.Or(story.URL, "/"+render.String(story.ID)))
+	out.WriteString(">")
+	out.Text(story.Title)
+	out.WriteString("</a>\n    ")
+	if render.Truthy(story.URL) {
+		out.WriteString("\n      <a class=\"url svelte-1tbs0et\"")
+		out.Attr("href", story.URL)
+		out.WriteString(">(")
+		out.Text(story.URL)
+		out.WriteString(")</a>\n    ")
+	}
+	out.WriteString("\n  </div>\n  <div class=\"meta svelte-1tbs0et\">\n    ")
+	out.Print(story.Points)
+	out.WriteString(" points by ")
+	out.Text(story.Author)
+	out.WriteString(" • ")
+	out.Text(story.CreatedAt)
+	out.WriteString(" •\n    <a")
+	out.Attr("href", "/"+render.String(story.ID))
+	out.WriteString(" class=\"svelte-1tbs0et\">")
+	out.Print(story.NumComments)
+	out.WriteString(" comments</a>\n  </div>\n</div>\n\n")
+	out.Style("svelte-1tbs0et", ".story.svelte-1tbs0et { padding: 10px; font-size: 14px }\na.svelte-1tbs0et { text-decoration: none; color: inherit }\na.svelte-1tbs0et[href]:hover { text-decoration: underline }\n.title.svelte-1tbs0et { font-weight: 500 }\n.url.svelte-1tbs0et, .meta.svelte-1tbs0et { color: gray }")
+	out.WriteString("\n")
+	return out.Err()
+}
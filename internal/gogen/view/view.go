@@ -0,0 +1,4 @@
+// Package view is compiled from .svelte files to check the generated Go code.
+package view
+
+//go:generate go run ../../../cmd/duo generate
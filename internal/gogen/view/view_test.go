@@ -0,0 +1,145 @@
+package view_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/livebud/duo/internal/gogen/view"
+	"github.com/livebud/duo/internal/resolver"
+	"github.com/livebud/duo/internal/ssr"
+	"github.com/livebud/duo/render"
+	"github.com/matthewmueller/diff"
+)
+
+type Map = map[string]interface{}
+
+// equal checks the generated code renders the same as the server renderer. An
+// empty expectation only compares against the server renderer.
+func equal(t *testing.T, path string, props interface{}, render func(*strings.Builder) error, expected string) {
+	t.Helper()
+	t.Run(path, func(t *testing.T) {
+		t.Helper()
+		actual := new(strings.Builder)
+		if err := render(actual); err != nil {
+			t.Fatal(err)
+		}
+		if expected != "" {
+			diff.TestString(t, actual.String(), expected)
+		}
+		renderer := ssr.New(resolver.New(os.DirFS(".")))
+		interpreted := new(strings.Builder)
+		if err := renderer.Render(interpreted, path, props); err != nil {
+			t.Fatal(err)
+		}
+		diff.TestString(t, actual.String(), interpreted.String())
+	})
+}
+
+func TestCounter(t *testing.T) {
+	equal(t, "Counter.svelte", Map{}, func(w *strings.Builder) error {
+		return view.Counter(w, nil)
+	}, "\n\n<button>\n  Clicked 10\n  times\n</button>\n")
+	equal(t, "Counter.svelte", Map{"count": 1}, func(w *strings.Builder) error {
+		return view.Counter(w, &view.CounterProps{Count: render.Ptr(1.0)})
+	}, "\n\n<button>\n  Clicked 1\n  time\n</button>\n")
+	// Zero is passed through rather than replaced by the default
+	equal(t, "Counter.svelte", Map{"count": 0}, func(w *strings.Builder) error {
+		return view.Counter(w, &view.CounterProps{Count: render.Ptr(0.0)})
+	}, "\n\n<button>\n  Clicked 0\n  times\n</button>\n")
+}
+
+func TestGreeting(t *testing.T) {
+	equal(t, "Greeting.svelte", Map{}, func(w *strings.Builder) error {
+		return view.Greeting(w, nil)
+	}, "\n\n<h1 name=\"hello-hello-cool\">hello</h1>\n")
+}
+
+func TestIndex(t *testing.T) {
+	stories := []view.Item{
+		{ID: 1, Title: "Duo", URL: "https://github.com/livebud/duo", Points: 10, Author: "anki", NumComments: 2},
+		{ID: 2, Title: "Ask HN", Points: 3, Author: "matt"},
+	}
+	expected := `<div class="story svelte-1tbs0et">
+  <div>
+    <a class="title svelte-1tbs0et" href="https://github.com/livebud/duo">Duo</a>
+    
+      <a class="url svelte-1tbs0et" href="https://github.com/livebud/duo">(https://github.com/livebud/duo)</a>
+    
+  </div>`
+	t.Run("stories", func(t *testing.T) {
+		actual := new(strings.Builder)
+		if err := view.Index(actual, &view.IndexProps{Stories: stories}); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(actual.String(), expected) {
+			t.Fatalf("unexpected story in %s", actual)
+		}
+		if !strings.Contains(actual.String(), `<a class="title svelte-1tbs0et" href="/2">Ask HN</a>`) {
+			t.Fatalf("unexpected story in %s", actual)
+		}
+		if strings.Count(actual.String(), "<hr/>") != 1 {
+			t.Fatalf("expected a single <hr/> in %s", actual)
+		}
+	})
+	equal(t, "Index.svelte", Map{"stories": stories}, func(w *strings.Builder) error {
+		return view.Index(w, &view.IndexProps{Stories: stories})
+	}, "")
+}
+
+func TestEscape(t *testing.T) {
+	story := view.Item{ID: 1, Title: `<script>alert("hi")</script>`, URL: `/x" onclick="alert(1)`, Author: "Tom & Jerry"}
+	equal(t, "Story.svelte", Map{"story": story}, func(w *strings.Builder) error {
+		return view.Story(w, &view.StoryProps{Story: story})
+	}, `
+
+<div class="story svelte-1tbs0et">
+  <div>
+    <a class="title svelte-1tbs0et" href="/x&quot; onclick=&quot;alert(1)">&lt;script>alert("hi")&lt;/script></a>
+    
+      <a class="url svelte-1tbs0et" href="/x&quot; onclick=&quot;alert(1)">(/x" onclick="alert(1))</a>
+    
+  </div>
+  <div class="meta svelte-1tbs0et">
+    0 points by Tom &amp; Jerry •  •
+    <a href="/1" class="svelte-1tbs0et">0 comments</a>
+  </div>
+</div>
+
+<style>.story.svelte-1tbs0et { padding: 10px; font-size: 14px }
+a.svelte-1tbs0et { text-decoration: none; color: inherit }
+a.svelte-1tbs0et[href]:hover { text-decoration: underline }
+.title.svelte-1tbs0et { font-weight: 500 }
+.url.svelte-1tbs0et, .meta.svelte-1tbs0et { color: gray }</style>
+`)
+	equal(t, "Badge.svelte", Map{"label": "<b>", "kind": `"x"`}, func(w *strings.Builder) error {
+		return view.Badge(w, &view.BadgeProps{Label: "<b>", Kind: render.Ptr(`"x"`)})
+	}, "\n\n<span class=\"badge &quot;x&quot;\" style=\"font-size: 12px;\">&lt;b></span>\n")
+}
+
+func TestSlots(t *testing.T) {
+	equal(t, "Page.svelte", Map{"name": "anki", "count": 3}, func(w *strings.Builder) error {
+		return view.Page(w, &view.PageProps{Name: "anki", Count: 3})
+	}, "")
+}
+
+func TestSettings(t *testing.T) {
+	equal(t, "Settings.svelte", Map{}, func(w *strings.Builder) error {
+		return view.Settings(w, nil)
+	}, "\n\n<form>\n  <input value=\"\"/>\n  <textarea></textarea>\n  <input type=\"checkbox\"/>\n  <input type=\"radio\" checked value=\"mint\"/>\n  <input type=\"radio\" value=\"lemon\"/>\n  <input type=\"checkbox\" value=\"nuts\"/>\n</form>\n")
+	equal(t, "Settings.svelte", Map{"name": "Jo", "bio": "Hi", "subscribed": true, "flavor": "lemon", "toppings": []interface{}{"nuts"}}, func(w *strings.Builder) error {
+		return view.Settings(w, &view.SettingsProps{Name: "Jo", Bio: "Hi", Subscribed: true, Flavor: render.Ptr("lemon"), Toppings: []any{"nuts"}})
+	}, "\n\n<form>\n  <input value=\"Jo\"/>\n  <textarea>Hi</textarea>\n  <input type=\"checkbox\" checked/>\n  <input type=\"radio\" value=\"mint\"/>\n  <input type=\"radio\" checked value=\"lemon\"/>\n  <input type=\"checkbox\" checked value=\"nuts\"/>\n</form>\n")
+}
+
+func TestBadge(t *testing.T) {
+	equal(t, "Badge.svelte", Map{"label": "new"}, func(w *strings.Builder) error {
+		return view.Badge(w, &view.BadgeProps{Label: "new"})
+	}, "\n\n<span class=\"badge info\" style=\"font-size: 12px;\">new</span>\n")
+	equal(t, "Badge.svelte", Map{"label": "hot", "kind": "warn", "active": true, "color": "red", "size": 20}, func(w *strings.Builder) error {
+		return view.Badge(w, &view.BadgeProps{Label: "hot", Kind: render.Ptr("warn"), Active: true, Color: "red", Size: render.Ptr(20.0)})
+	}, "\n\n<span class=\"badge warn active large\" style=\"color: red; font-size: 20px;\">hot</span>\n")
+	equal(t, "Badge.svelte", Map{"label": "new", "icon": "<i>★</i>"}, func(w *strings.Builder) error {
+		return view.Badge(w, &view.BadgeProps{Label: "new", Icon: "<i>★</i>"})
+	}, "")
+}
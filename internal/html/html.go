@@ -0,0 +1,176 @@
+// Package html validates markup against the HTML nesting rules. Browsers
+// silently restructure invalid markup like `<p><div></div></p>`, so the DOM no
+// longer matches the server-rendered tree the client expects to hydrate.
+package html
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/livebud/duo/internal/ast"
+)
+
+// Error is an element placed where the browser would move it
+type Error struct {
+	Pos     ast.Position
+	Parent  string
+	Child   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d:%d)", e.Message, e.Pos.Line, e.Pos.Column)
+}
+
+// Code of the error, matching Svelte
+func (e *Error) Code() string {
+	return "node_invalid_placement"
+}
+
+// rule disallows elements within an element
+type rule struct {
+	// Elements that can't be a direct child
+	direct []string
+	// Elements that can't be a descendant
+	descendant []string
+	// Elements that allow the descendants again, like <dl> within <dd>
+	resetBy []string
+}
+
+var headings = []string{"h1", "h2", "h3", "h4", "h5", "h6"}
+
+// Based on the rules in Svelte's html-tree-validation
+var disallowed = map[string]rule{
+	"li": {direct: []string{"li"}},
+	"dt": {descendant: []string{"dt", "dd"}, resetBy: []string{"dl"}},
+	"dd": {descendant: []string{"dt", "dd"}, resetBy: []string{"dl"}},
+	"p": {descendant: append([]string{
+		"address", "article", "aside", "blockquote", "div", "dl", "fieldset",
+		"footer", "form", "header", "hgroup", "hr", "main", "menu", "nav", "ol",
+		"p", "pre", "section", "table", "ul",
+	}, headings...)},
+	"rt":       {descendant: []string{"rt", "rp"}},
+	"rp":       {descendant: []string{"rt", "rp"}},
+	"optgroup": {descendant: []string{"optgroup"}},
+	"option":   {descendant: []string{"option", "optgroup"}},
+	"thead":    {direct: []string{"tbody", "tfoot"}},
+	"tbody":    {direct: []string{"tbody", "thead"}},
+	"tfoot":    {direct: []string{"tbody"}},
+	"tr":       {direct: []string{"tr", "tbody"}},
+	"td":       {direct: []string{"td", "th", "tr"}},
+	"th":       {direct: []string{"td", "th", "tr"}},
+	"form":     {descendant: []string{"form"}},
+	"a":        {descendant: []string{"a"}},
+	"button":   {descendant: []string{"button"}},
+	"h1":       {descendant: headings},
+	"h2":       {descendant: headings},
+	"h3":       {descendant: headings},
+	"h4":       {descendant: headings},
+	"h5":       {descendant: headings},
+	"h6":       {descendant: headings},
+}
+
+// onlyWithin are elements that can only be a direct child of these parents
+var onlyWithin = map[string][]string{
+	"tr":       {"thead", "tbody", "tfoot"},
+	"thead":    {"table"},
+	"tbody":    {"table"},
+	"tfoot":    {"table"},
+	"caption":  {"table"},
+	"colgroup": {"table"},
+	"td":       {"tr"},
+	"th":       {"tr"},
+	"col":      {"colgroup"},
+}
+
+// Validate the document's elements, returning the misplaced elements in
+// source order. Slot content isn't checked against the elements outside the
+// component, since it's rendered wherever the component puts it.
+func Validate(doc *ast.Document) (errors []*Error) {
+	v := &validator{errors: &errors}
+	ast.Walk(v, doc)
+	return errors
+}
+
+// validator checks the elements within its ancestors
+type validator struct {
+	errors    *[]*Error
+	ancestors []string
+}
+
+func (v *validator) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.Element:
+		name := strings.ToLower(n.Name)
+		if err := validate(n, name, v.ancestors); err != nil {
+			*v.errors = append(*v.errors, err)
+		}
+		return &validator{v.errors, append(v.ancestors[:len(v.ancestors):len(v.ancestors)], name)}
+	case *ast.Component:
+		return &validator{v.errors, nil}
+	case nil:
+		return nil
+	}
+	return v
+}
+
+// validate the element's placement within its ancestors
+func validate(el *ast.Element, name string, ancestors []string) *Error {
+	if len(ancestors) == 0 {
+		return nil
+	}
+	parent := ancestors[len(ancestors)-1]
+	if parents, ok := onlyWithin[name]; ok && !contains(parents, parent) {
+		return childError(el, parent, name)
+	}
+	if rule, ok := disallowed[parent]; ok && contains(rule.direct, name) {
+		return childError(el, parent, name)
+	}
+	// Walk up the ancestors looking for an element that disallows this one
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		ancestor := ancestors[i]
+		rule, ok := disallowed[ancestor]
+		if !ok {
+			continue
+		}
+		if contains(rule.descendant, name) {
+			// Reset by an element between the ancestor and this element
+			if !containsAny(rule.resetBy, ancestors[i+1:]) {
+				return &Error{
+					Pos:     el.Pos,
+					Parent:  ancestor,
+					Child:   name,
+					Message: fmt.Sprintf("`<%s>` cannot be a descendant of `<%s>`. The browser will 'repair' the HTML (by moving, removing, or inserting elements) which breaks hydration against the server-rendered HTML", name, ancestor),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func childError(el *ast.Element, parent, name string) *Error {
+	return &Error{
+		Pos:     el.Pos,
+		Parent:  parent,
+		Child:   name,
+		Message: fmt.Sprintf("`<%s>` cannot be a child of `<%s>`. The browser will 'repair' the HTML (by moving, removing, or inserting elements) which breaks hydration against the server-rendered HTML", name, parent),
+	}
+}
+
+func contains(list []string, name string) bool {
+	for _, item := range list {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(list []string, names []string) bool {
+	for _, name := range names {
+		if contains(list, name) {
+			return true
+		}
+	}
+	return false
+}
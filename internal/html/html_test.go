@@ -0,0 +1,85 @@
+package html_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/livebud/duo/internal/html"
+	"github.com/livebud/duo/internal/parser"
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+)
+
+// equal checks the parse result, which fails on the first misplaced element
+func equal(t *testing.T, input, expected string) {
+	t.Helper()
+	t.Run(input, func(t *testing.T) {
+		t.Helper()
+		actual := ""
+		if _, err := parser.Parse("input.svelte", input); err != nil {
+			actual = err.Error()
+		}
+		diff.TestString(t, actual, expected)
+	})
+}
+
+func invalid(t *testing.T, input, child, relation, parent string) {
+	t.Helper()
+	t.Run(input, func(t *testing.T) {
+		t.Helper()
+		_, err := parser.Parse("input.svelte", input)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		prefix := "`<" + child + ">` cannot be a " + relation + " of `<" + parent + ">`"
+		if !strings.Contains(err.Error(), prefix) {
+			t.Fatalf("expected %q to contain %q", err.Error(), prefix)
+		}
+	})
+}
+
+func TestDescendant(t *testing.T) {
+	invalid(t, `<p><div></div></p>`, "div", "descendant", "p")
+	invalid(t, `<p><span><h2>hi</h2></span></p>`, "h2", "descendant", "p")
+	invalid(t, `<a href="/"><span><a href="/x">x</a></span></a>`, "a", "descendant", "a")
+	invalid(t, `<button><button>x</button></button>`, "button", "descendant", "button")
+	invalid(t, `<form><div><form></form></div></form>`, "form", "descendant", "form")
+	invalid(t, `<h1><h2>x</h2></h1>`, "h2", "descendant", "h1")
+	invalid(t, `<dl><dt><dd>x</dd></dt></dl>`, "dd", "descendant", "dt")
+	// Blocks don't change the tree
+	invalid(t, `<p>{#if x}<div></div>{/if}</p>`, "div", "descendant", "p")
+	invalid(t, `<a href="/">{#each xs as x}<a href={x}>x</a>{/each}</a>`, "a", "descendant", "a")
+}
+
+func TestChild(t *testing.T) {
+	invalid(t, `<li><li>x</li></li>`, "li", "child", "li")
+	invalid(t, `<table><tr><td>x</td></tr></table>`, "tr", "child", "table")
+	invalid(t, `<div><td>x</td></div>`, "td", "child", "div")
+	invalid(t, `<tr><tr></tr></tr>`, "tr", "child", "tr")
+}
+
+func TestValid(t *testing.T) {
+	equal(t, `<p><span>x</span><a href="/">y</a></p>`, ``)
+	equal(t, `<li><ul><li>x</li></ul></li>`, ``)
+	equal(t, `<table><thead><tr><th>x</th></tr></thead><tbody><tr><td>y</td></tr></tbody></table>`, ``)
+	equal(t, `<dl><dd><dl><dt>x</dt></dl></dd></dl>`, ``)
+	equal(t, `<div><p>x</p><div><p>y</p></div></div>`, ``)
+	// Root elements could be rendered anywhere
+	equal(t, `<tr><td>x</td></tr>`, ``)
+	// Slot content is rendered wherever the component puts it
+	equal(t, `<script>import Card from "./Card.svelte"</script><p><Card><div>x</div></Card></p>`, ``)
+}
+
+func TestError(t *testing.T) {
+	is := is.New(t)
+	_, err := parser.Parse("input.svelte", "<main>\n  <p>\n    <div></div>\n  </p>\n</main>")
+	is.True(err != nil)
+	var placement *html.Error
+	is.True(errors.As(err, &placement))
+	is.Equal(placement.Pos.Line, 3)
+	is.Equal(placement.Pos.Column, 5)
+	is.Equal(placement.Parent, "p")
+	is.Equal(placement.Child, "div")
+	is.Equal(placement.Code(), "node_invalid_placement")
+}
@@ -1,56 +1,63 @@
 package js
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
 
-	esbuild "github.com/evanw/esbuild/pkg/api"
 	"github.com/ije/esbuild-internal/ast"
 	"github.com/ije/esbuild-internal/config"
+	"github.com/ije/esbuild-internal/js_ast"
 	"github.com/ije/esbuild-internal/js_parser"
 	"github.com/ije/esbuild-internal/js_printer"
 	"github.com/ije/esbuild-internal/logger"
 	"github.com/ije/esbuild-internal/renamer"
+	"github.com/ije/esbuild-internal/sourcemap"
 	"github.com/ije/esbuild-internal/test"
 	"github.com/tdewolff/parse/v2"
 	"github.com/tdewolff/parse/v2/js"
 )
 
 type (
-	IExpr          = js.IExpr
-	BlockStmt      = js.BlockStmt
-	IStmt          = js.IStmt
-	ExprStmt       = js.ExprStmt
-	ExportStmt     = js.ExportStmt
-	ImportStmt     = js.ImportStmt
-	FuncDecl       = js.FuncDecl
-	VarDecl        = js.VarDecl
-	BindingElement = js.BindingElement
-	IBinding       = js.IBinding
-	BinaryExpr     = js.BinaryExpr
-	IfStmt         = js.IfStmt
-	Var            = js.Var
-	CondExpr       = js.CondExpr
-	ArrowFunc      = js.ArrowFunc
-	UnaryExpr      = js.UnaryExpr
-	GroupExpr      = js.GroupExpr
-	CallExpr       = js.CallExpr
-	ReturnStmt     = js.ReturnStmt
-	LiteralExpr    = js.LiteralExpr
-	ArrayExpr      = js.ArrayExpr
-	AST            = js.AST
-	ObjectExpr     = js.ObjectExpr
-	Property       = js.Property
-	Arg            = js.Arg
-	Element        = js.Element
-	PropertyName   = js.PropertyName
-	Args           = js.Args
-	DotExpr        = js.DotExpr
-	Params         = js.Params
-	Scope          = js.Scope
-	INode          = js.INode
-	IVisitor       = js.IVisitor
+	IExpr             = js.IExpr
+	BlockStmt         = js.BlockStmt
+	IStmt             = js.IStmt
+	ExprStmt          = js.ExprStmt
+	LabelledStmt      = js.LabelledStmt
+	ExportStmt        = js.ExportStmt
+	ImportStmt        = js.ImportStmt
+	FuncDecl          = js.FuncDecl
+	VarDecl           = js.VarDecl
+	BindingElement    = js.BindingElement
+	BindingObject     = js.BindingObject
+	BindingArray      = js.BindingArray
+	BindingObjectItem = js.BindingObjectItem
+	IBinding          = js.IBinding
+	BinaryExpr        = js.BinaryExpr
+	IfStmt            = js.IfStmt
+	Var               = js.Var
+	CondExpr          = js.CondExpr
+	ArrowFunc         = js.ArrowFunc
+	UnaryExpr         = js.UnaryExpr
+	GroupExpr         = js.GroupExpr
+	CallExpr          = js.CallExpr
+	ReturnStmt        = js.ReturnStmt
+	LiteralExpr       = js.LiteralExpr
+	ArrayExpr         = js.ArrayExpr
+	AST               = js.AST
+	ObjectExpr        = js.ObjectExpr
+	Property          = js.Property
+	Arg               = js.Arg
+	Element           = js.Element
+	PropertyName      = js.PropertyName
+	Args              = js.Args
+	DotExpr           = js.DotExpr
+	IndexExpr         = js.IndexExpr
+	Params            = js.Params
+	Scope             = js.Scope
+	INode             = js.INode
+	IVisitor          = js.IVisitor
 )
 
 var (
@@ -61,14 +68,72 @@ var (
 	AddToken        = js.AddToken
 	EqToken         = js.EqToken
 	OrToken         = js.OrToken
+	EqEqToken       = js.EqEqToken
+	EqEqEqToken     = js.EqEqEqToken
+	NotEqEqToken    = js.NotEqEqToken
+	CommaToken      = js.CommaToken
+	NullToken       = js.NullToken
 	IdentifierToken = js.IdentifierToken
 )
 
-// Parse a script using esbuild's internal packages, which are about 40x faster
-// than using esbuild's public API
-func esbuildInternalParse(script string) (string, error) {
+// Parse a script into the AST the rest of duo works with. JavaScript is parsed
+// once, so offsets in the AST point into the script. Scripts that aren't valid
+// JavaScript are parsed with ParseTS, in case they're TypeScript without
+// lang="ts".
+func Parse(script string) (*js.AST, error) {
+	ast, err := parseJS(script)
+	if err != nil {
+		return ParseTS(script)
+	}
+	return ast, nil
+}
+
+// ParseTS parses a TypeScript script, stripping the types. esbuild's internal
+// parser strips the types, which is about 40x faster than esbuild's public
+// API, then the JavaScript it prints is parsed. Errors point into
+// the script, but offsets in the AST point into the printed JavaScript.
+func ParseTS(script string) (*js.AST, error) {
+	code, err := strip(script, 0)
+	if err != nil {
+		return nil, err
+	}
+	return parseJS(code)
+}
+
+func parseJS(code string) (*js.AST, error) {
+	return js.Parse(parse.NewInputString(code), js.Options{})
+}
+
+// ParseTSExpr parses an expression that may contain TypeScript, like
+// `value as string` or `fn<T>(x)`, stripping the types
+func ParseTSExpr(contents string) (js.IExpr, error) {
+	// Declare a variable with the expression, so strings aren't treated as
+	// directives and trailing line comments don't swallow the semicolon
+	const prefix = "let $$expr = ("
+	code, err := strip(prefix+contents+"\n);", len(prefix))
+	if err != nil {
+		return nil, err
+	}
+	ast, err := js.Parse(parse.NewInputString(code), js.Options{})
+	if err != nil {
+		return nil, err
+	}
+	if len(ast.List) != 1 {
+		return nil, fmt.Errorf("expected one statement, got %d", len(ast.List))
+	}
+	decl, ok := ast.List[0].(*js.VarDecl)
+	if !ok || len(decl.List) != 1 {
+		return nil, fmt.Errorf("expected an expression, got %s", contents)
+	}
+	return decl.List[0].Default, nil
+}
+
+// strip the types from TypeScript code. Imports are kept as written, since
+// the components they import aren't known to be types. Errors are positioned
+// within the code, less the offset of any wrapper on the first line.
+func strip(code string, offset int) (string, error) {
 	log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
-	tree, ok := js_parser.Parse(log, test.SourceForTest(script), js_parser.OptionsFromConfig(&config.Options{
+	tree, ok := js_parser.Parse(log, test.SourceForTest(code), js_parser.OptionsFromConfig(&config.Options{
 		TS: config.TSOptions{
 			Parse: true,
 			Config: config.TSConfig{
@@ -76,61 +141,41 @@ func esbuildInternalParse(script string) (string, error) {
 			},
 		},
 	}))
-	msgs := log.Done()
-	text := ""
-	for _, msg := range msgs {
-		text += msg.String(logger.OutputOptions{}, logger.TerminalInfo{})
-	}
 	if !ok {
-		return "", errors.New(text)
+		return "", parseError(log.Done(), offset)
 	}
+	return string(printAST(tree, js_printer.Options{}).JS), nil
+}
+
+// printAST prints the esbuild AST, keeping the code as written
+func printAST(tree js_ast.AST, options js_printer.Options) js_printer.PrintResult {
 	symbols := ast.NewSymbolMap(1)
 	symbols.SymbolsForSource[0] = tree.Symbols
 	r := renamer.NewNoOpRenamer(symbols)
-	js := js_printer.Print(tree, symbols, r, js_printer.Options{
-		OutputFormat: config.FormatPreserve,
-		ASCIIOnly:    true,
-	}).JS
-	return string(js), nil
+	options.OutputFormat = config.FormatPreserve
+	return js_printer.Print(tree, symbols, r, options)
 }
 
-// Parse a script
-func ParseTS(script string) (*js.AST, error) {
-	// We use esbuild to parse the JavaScript because it supports Typescript
-	// obviously, this is a bit of a hack, but it works for now
-	result := esbuild.Transform(script, esbuild.TransformOptions{
-		Sourcefile:  "script.js",
-		Loader:      esbuild.LoaderTS,
-		TsconfigRaw: `{ "compilerOptions": { "verbatimModuleSyntax": true } }`,
-	})
-	if len(result.Errors) > 0 {
-		var errs []error
-		for _, err := range result.Errors {
-			errs = append(errs, errors.New(err.Text))
+// parseError joins the errors logged by esbuild, prefixed with their line and
+// column
+func parseError(msgs []logger.Msg, offset int) error {
+	var errs []error
+	for _, msg := range msgs {
+		if msg.Kind != logger.Error {
+			continue
 		}
-		return nil, errors.Join(errs...)
-	}
-	// Re-parse the code using the tdewolff/parser
-	ast, err := js.Parse(parse.NewInputBytes(result.Code), js.Options{})
-	if err != nil {
-		return nil, err
-	}
-	return ast, nil
-}
-
-// Parse a script
-func Parse(script string) (*js.AST, error) {
-	// TODO: remove, this is redundant
-	code, err := esbuildInternalParse(script)
-	if err != nil {
-		return nil, err
-	}
-	// Re-parse the code using the tdewolff/parser
-	ast, err := js.Parse(parse.NewInputString(code), js.Options{})
-	if err != nil {
-		return nil, err
+		loc := msg.Data.Location
+		if loc == nil {
+			errs = append(errs, errors.New(msg.Data.Text))
+			continue
+		}
+		column := loc.Column + 1
+		if loc.Line == 1 {
+			column -= offset
+		}
+		errs = append(errs, fmt.Errorf("%d:%d: %s", loc.Line, column, msg.Data.Text))
 	}
-	return ast, nil
+	return errors.Join(errs...)
 }
 
 // ParseExpr parses a JavaScript expression
@@ -157,23 +202,74 @@ func Print(ast js.INode) string {
 	return strings.TrimSpace(ast.JS())
 }
 
+// Format prints the node, formatted by esbuild's printer
 func Format(node js.INode) (string, error) {
-	log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
-	tree, ok := js_parser.Parse(log, test.SourceForTest(node.JS()), js_parser.OptionsFromConfig(&config.Options{}))
-	msgs := log.Done()
-	text := ""
-	for _, msg := range msgs {
-		text += msg.String(logger.OutputOptions{}, logger.TerminalInfo{})
+	code, _, err := format(node.JS(), nil)
+	return code, err
+}
+
+// Mapping from a position in the code to its position in the original source.
+// Lines and columns start at zero and columns count UTF-16 code units.
+type Mapping struct {
+	GeneratedLine   int
+	GeneratedColumn int
+	OriginalLine    int
+	OriginalColumn  int
+}
+
+// SourceMap contains the "mappings" and "names" fields of a source map
+type SourceMap struct {
+	Mappings string
+	Names    []string
+}
+
+// FormatWithSourceMap formats the code like Format, carrying the mappings from
+// the code to its original source through to the formatted code. Mappings must
+// be sorted by their generated position.
+func FormatWithSourceMap(code string, mappings []Mapping) (string, *SourceMap, error) {
+	input := &sourcemap.SourceMap{
+		Sources:        []string{""},
+		SourcesContent: []sourcemap.SourceContent{{}},
 	}
+	for _, m := range mappings {
+		input.Mappings = append(input.Mappings, sourcemap.Mapping{
+			GeneratedLine:   int32(m.GeneratedLine),
+			GeneratedColumn: int32(m.GeneratedColumn),
+			OriginalLine:    int32(m.OriginalLine),
+			OriginalColumn:  int32(m.OriginalColumn),
+		})
+	}
+	return format(code, input)
+}
+
+func format(code string, input *sourcemap.SourceMap) (string, *SourceMap, error) {
+	log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+	tree, ok := js_parser.Parse(log, test.SourceForTest(code), js_parser.OptionsFromConfig(&config.Options{}))
 	if !ok {
-		return "", errors.New(text)
+		return "", nil, parseError(log.Done(), 0)
 	}
-	symbols := ast.NewSymbolMap(1)
-	symbols.SymbolsForSource[0] = tree.Symbols
-	r := renamer.NewNoOpRenamer(symbols)
-	js := js_printer.Print(tree, symbols, r, js_printer.Options{
-		OutputFormat: config.FormatPreserve,
-		ASCIIOnly:    true,
-	}).JS
-	return string(js), nil
+	// Generated code is served to browsers, so it's kept to ASCII
+	options := js_printer.Options{ASCIIOnly: true}
+	if input != nil {
+		options.InputSourceMap = input
+		options.AddSourceMappings = true
+		options.SourceMap = config.SourceMapExternalWithoutComment
+		options.LineOffsetTables = sourcemap.GenerateLineOffsetTables(code, int32(strings.Count(code, "\n")))
+	}
+	result := printAST(tree, options)
+	if input == nil {
+		return string(result.JS), nil, nil
+	}
+	sourceMap := &SourceMap{
+		Mappings: string(result.SourceMapChunk.Buffer.Data),
+		Names:    []string{},
+	}
+	for _, quoted := range result.SourceMapChunk.QuotedNames {
+		var name string
+		if err := json.Unmarshal(quoted, &name); err != nil {
+			return "", nil, fmt.Errorf("unable to unquote name %s: %w", quoted, err)
+		}
+		sourceMap.Names = append(sourceMap.Names, name)
+	}
+	return string(result.JS), sourceMap, nil
 }
@@ -29,12 +29,13 @@ func equalJS(t *testing.T, path, input, expected string) {
 }
 
 func TestSampleJS(t *testing.T) {
-	equalJS(t, "", `const a = 'hello';`, `const a = "hello";`)
+	equalJS(t, "", `const a = 'hello';`, `const a = 'hello';`)
 	equalJS(t, "", `const a: string = 'hello';`, `const a = "hello";`)
 	equalJS(t, "", `export let props: Props = []`, `export let props = [];`)
-	equalJS(t, "", `import Sub from './04-sub.html';`, `import Sub from "./04-sub.html";`)
+	equalJS(t, "", `import Sub from './04-sub.html';`, `import Sub from './04-sub.html';`)
 	equalJS(t, "", `import type Sub from './04-sub.html';`, ``)
-	equalJS(t, "", `import { Sub } from './04-sub.html';`, `import { Sub } from "./04-sub.html";`)
+	equalJS(t, "", `import { Sub } from './04-sub.html';`, `import { Sub } from './04-sub.html';`)
+	equalJS(t, "", `const a: string = "café 😀";`, `const a = "café 😀";`)
 }
 
 func TestParse(t *testing.T) {
@@ -43,30 +44,86 @@ func TestParse(t *testing.T) {
 		t.Fatal(err)
 	}
 	actual := js.Print(ast)
-	ast2, err := js.ParseTS("export let props: Props = []")
-	if err != nil {
-		t.Fatal(err)
-	}
-	actual2 := js.Print(ast2)
 	expect := `export let props = [];`
 	if actual != expect {
 		t.Fatalf("expected %s, got %s", expect, actual)
 	}
-	if actual != actual2 {
-		t.Fatalf("expected %s, got %s", actual, actual2)
+}
+
+func TestParseError(t *testing.T) {
+	_, err := js.Parse("let a = 1\nlet b: = 2")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	diff.TestString(t, err.Error(), `2:8: Unexpected "="`)
+	_, err = js.ParseTSExpr("value as")
+	if err == nil {
+		t.Fatal("expected an error")
 	}
+	diff.TestString(t, err.Error(), `2:1: Unexpected ")"`)
+}
+
+func TestParseTSExpr(t *testing.T) {
+	expr, err := js.ParseTSExpr("fn<string>(value as string)!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff.TestString(t, js.Print(expr), `fn(value)`)
 }
 
 func BenchmarkParseJS(b *testing.B) {
-	// run the Fib function b.N times
 	for n := 0; n < b.N; n++ {
-		js.Parse("export let props: Props = []")
+		js.Parse("export let props = []")
 	}
 }
 
 func BenchmarkParseTS(b *testing.B) {
-	// run the Fib function b.N times
 	for n := 0; n < b.N; n++ {
 		js.ParseTS("export let props: Props = []")
 	}
 }
+
+func BenchmarkParseExpr(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		js.ParseExpr("count + 1")
+	}
+}
+
+func BenchmarkParseTSExpr(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		js.ParseTSExpr("count as number + 1")
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	tests := []struct {
+		literal string
+		expect  string
+	}{
+		{`"hello"`, "hello"},
+		{`'it\'s'`, "it's"},
+		{`"café ❌ 😀"`, "café ❌ 😀"},
+		{`"caf\xE9"`, "café"},
+		{`"❌ \u{1F600}"`, "❌ 😀"},
+		{`"😀"`, "😀"},
+		{`"\uD83D"`, "�"},
+		{`"a\b\f\v\0z"`, "a\b\f\v\x00z"},
+		{`"\n\t\r\\\"\q"`, "\n\t\r\\\"q"},
+		{"\"line \\\ncontinued \\\r\nagain\"", "line continued again"},
+		{"\"sep\\\u2028arator\"", "separator"},
+	}
+	for _, test := range tests {
+		t.Run(test.literal, func(t *testing.T) {
+			actual, err := js.Unquote(test.literal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			diff.TestString(t, actual, test.expect)
+		})
+	}
+	for _, literal := range []string{`hello`, `"\x4"`, `"\u{}"`, `"\u{110000}"`, `"\01"`, `"a\"`} {
+		if _, err := js.Unquote(literal); err == nil {
+			t.Fatalf("expected an error unquoting %s", literal)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package js
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Unquote decodes a JavaScript string literal like 'caf\xE9' or "\u{1F600}"
+// into UTF-8. Lone surrogates, which can't be encoded in UTF-8, become the
+// replacement character.
+func Unquote(literal string) (string, error) {
+	if len(literal) < 2 {
+		return "", fmt.Errorf("js: invalid string literal %s", literal)
+	}
+	quote := literal[0]
+	if (quote != '"' && quote != '\'') || literal[len(literal)-1] != quote {
+		return "", fmt.Errorf("js: invalid string literal %s", literal)
+	}
+	s := literal[1 : len(literal)-1]
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	out := new(strings.Builder)
+	var surrogate rune // high surrogate waiting for its low surrogate
+	flush := func() {
+		if surrogate != 0 {
+			out.WriteRune(utf8.RuneError)
+			surrogate = 0
+		}
+	}
+	for i := 0; i < len(s); {
+		if s[i] != '\\' {
+			flush()
+			end := strings.IndexByte(s[i:], '\\')
+			if end < 0 {
+				end = len(s) - i
+			}
+			out.WriteString(s[i : i+end])
+			i += end
+			continue
+		}
+		i++
+		if i == len(s) {
+			return "", fmt.Errorf("js: invalid escape at the end of %s", literal)
+		}
+		c := s[i]
+		i++
+		var r rune
+		switch c {
+		case 'n':
+			r = '\n'
+		case 't':
+			r = '\t'
+		case 'r':
+			r = '\r'
+		case 'b':
+			r = '\b'
+		case 'f':
+			r = '\f'
+		case 'v':
+			r = '\v'
+		case '0':
+			if i < len(s) && s[i] >= '0' && s[i] <= '9' {
+				return "", fmt.Errorf("js: octal escapes aren't supported in %s", literal)
+			}
+			r = 0
+		case 'x':
+			n, err := hex(s, i, 2)
+			if err != nil {
+				return "", fmt.Errorf("js: invalid \\x escape in %s", literal)
+			}
+			r, i = rune(n), i+2
+		case 'u':
+			if i < len(s) && s[i] == '{' {
+				end := strings.IndexByte(s[i:], '}')
+				if end < 2 {
+					return "", fmt.Errorf("js: invalid \\u{...} escape in %s", literal)
+				}
+				n, err := hex(s, i+1, end-1)
+				if err != nil || n > utf8.MaxRune {
+					return "", fmt.Errorf("js: invalid \\u{...} escape in %s", literal)
+				}
+				r, i = rune(n), i+end+1
+				break
+			}
+			n, err := hex(s, i, 4)
+			if err != nil {
+				return "", fmt.Errorf("js: invalid \\u escape in %s", literal)
+			}
+			r, i = rune(n), i+4
+		case '\r':
+			// Line continuations, including \r\n, aren't part of the string
+			if i < len(s) && s[i] == '\n' {
+				i++
+			}
+			continue
+		case '\n':
+			continue
+		default:
+			if c < utf8.RuneSelf {
+				r = rune(c)
+				break
+			}
+			// Escaped line or paragraph separators are line continuations,
+			// other characters escape to themselves
+			r, size := utf8.DecodeRuneInString(s[i-1:])
+			i += size - 1
+			if r == '\u2028' || r == '\u2029' {
+				continue
+			}
+			flush()
+			out.WriteRune(r)
+			continue
+		}
+		switch {
+		case utf16.IsSurrogate(r) && r < 0xDC00:
+			flush()
+			surrogate = r
+		case utf16.IsSurrogate(r) && surrogate != 0:
+			out.WriteRune(utf16.DecodeRune(surrogate, r))
+			surrogate = 0
+		default:
+			flush()
+			out.WriteRune(r)
+		}
+	}
+	flush()
+	return out.String(), nil
+}
+
+// hex parses the n hex digits at s[i:]
+func hex(s string, i, n int) (uint64, error) {
+	if i+n > len(s) {
+		return 0, strconv.ErrSyntax
+	}
+	digits := s[i : i+n]
+	if strings.ContainsAny(digits, "+-_") {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.ParseUint(digits, 16, 32)
+}
@@ -2,6 +2,7 @@ package lexer
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -55,6 +56,25 @@ type Lexer struct {
 
 	inScript bool
 	inStyle  bool
+
+	lines []int // Offsets where each line starts, computed lazily
+}
+
+// Position returns the line and column of the offset, both starting at 1.
+// Columns count runes, not bytes.
+func (l *Lexer) Position(offset int) (line, column int) {
+	if l.lines == nil {
+		l.lines = []int{0}
+		for i := 0; i < len(l.input); i++ {
+			if l.input[i] == '\n' {
+				l.lines = append(l.lines, i+1)
+			}
+		}
+	}
+	offset = min(max(offset, 0), len(l.input))
+	line = sort.Search(len(l.lines), func(i int) bool { return l.lines[i] > offset })
+	column = utf8.RuneCountInString(l.input[l.lines[line-1]:offset]) + 1
+	return line, column
 }
 
 func (l *Lexer) nextToken() token.Token {
@@ -273,7 +293,13 @@ func startOpenTagState(l *Lexer) token.Type {
 				case isLowerNumeric(l.cp):
 					l.step()
 				case isDash(l.cp):
-					tokenType = token.DashIdentifier
+					if tokenType != token.ColonIdentifier {
+						tokenType = token.DashIdentifier
+					}
+					l.step()
+				case l.cp == ':' && tokenType == token.Identifier:
+					// Special elements like svelte:options
+					tokenType = token.ColonIdentifier
 					l.step()
 				default:
 					break tagName
@@ -335,6 +361,10 @@ func middleTagState(l *Lexer) (t token.Type) {
 		case l.cp == ':':
 			l.step()
 			return token.Colon
+		case l.cp == '|':
+			// Event modifiers like on:click|preventDefault
+			l.step()
+			return token.Pipe
 		case l.cp == '>':
 			l.step()
 			l.popState()
@@ -397,7 +427,13 @@ func startCloseTagState(l *Lexer) token.Type {
 				case isLowerNumeric(l.cp):
 					l.step()
 				case isDash(l.cp):
-					tokenType = token.DashIdentifier
+					if tokenType != token.ColonIdentifier {
+						tokenType = token.DashIdentifier
+					}
+					l.step()
+				case l.cp == ':' && tokenType == token.Identifier:
+					// Special elements like svelte:options
+					tokenType = token.ColonIdentifier
 					l.step()
 				default:
 					break tagName
@@ -555,6 +591,11 @@ func openBlockState(l *Lexer) token.Type {
 			l.popState()
 			l.pushState(slashBlockState)
 			return token.Slash
+		case l.cp == '@':
+			l.step()
+			l.popState()
+			l.pushState(atBlockState)
+			return token.At
 		default:
 			l.popState()
 			l.pushState(exprState)
@@ -579,6 +620,11 @@ func hashBlockState(l *Lexer) token.Type {
 			l.popState()
 			l.pushState(exprState)
 			return token.If
+		case l.accept('a', 'w', 'a', 'i', 't') && isSpace(l.cp):
+			l.step()
+			l.popState()
+			l.pushState(awaitState)
+			return token.Await
 		case isSpace(l.cp):
 			l.step()
 			for isSpace(l.cp) {
@@ -593,6 +639,23 @@ func hashBlockState(l *Lexer) token.Type {
 	}
 }
 
+// atBlockState lexes the tag name of {@html expr}
+func atBlockState(l *Lexer) token.Type {
+	switch {
+	case l.cp == eof:
+		l.popState()
+		return l.unexpected()
+	case l.accept('h', 't', 'm', 'l') && isSpace(l.cp):
+		l.step()
+		l.popState()
+		l.pushState(exprState)
+		return token.HTML
+	default:
+		l.popState()
+		return l.unexpected()
+	}
+}
+
 func colonBlockState(l *Lexer) token.Type {
 	for {
 		switch {
@@ -610,6 +673,14 @@ func colonBlockState(l *Lexer) token.Type {
 				return token.ElseIf
 			}
 			return token.Else
+		case l.accept('t', 'h', 'e', 'n'):
+			l.popState()
+			l.pushState(awaitState)
+			return token.Then
+		case l.accept('c', 'a', 't', 'c', 'h'):
+			l.popState()
+			l.pushState(awaitState)
+			return token.Catch
 		case isSpace(l.cp):
 			l.step()
 			for isSpace(l.cp) {
@@ -638,6 +709,8 @@ func slashBlockState(l *Lexer) token.Type {
 			return token.Each
 		case l.accept('i', 'f'):
 			return token.If
+		case l.accept('a', 'w', 'a', 'i', 't'):
+			return token.Await
 		case isSpace(l.cp):
 			l.step()
 			for isSpace(l.cp) {
@@ -694,6 +767,73 @@ func exprState(l *Lexer) token.Type {
 	}
 }
 
+// awaitState lexes the inside of {#await promise then value}, {:then value}
+// and {:catch error}
+func awaitState(l *Lexer) token.Type {
+	for {
+		switch {
+		case l.cp == eof:
+			l.popState()
+			return l.unexpected()
+		case isSpace(l.cp):
+			l.step()
+			for isSpace(l.cp) {
+				l.step()
+			}
+			l.ignore()
+			continue
+		case l.cp == '}':
+			l.step()
+			l.popState()
+			return token.RightBrace
+		case l.isKeyword("then"):
+			l.accept('t', 'h', 'e', 'n')
+			return token.Then
+		case l.isKeyword("catch"):
+			l.accept('c', 'a', 't', 'c', 'h')
+			return token.Catch
+		default:
+			// Read the expression up until the closing brace or a then or catch
+			// keyword
+			depth := 0
+			for {
+				switch {
+				case l.cp == eof:
+					return l.unexpected()
+				case l.cp == '{':
+					depth++
+				case l.cp == '}':
+					if depth == 0 {
+						return token.Expr
+					}
+					depth--
+				case depth == 0 && isSpace(l.cp):
+					next := l.peak(6)
+					if strings.HasPrefix(next, "then") && isKeywordEnd(next[4:]) ||
+						strings.HasPrefix(next, "catch") && isKeywordEnd(next[5:]) {
+						return token.Expr
+					}
+				}
+				l.step()
+			}
+		}
+	}
+}
+
+// isKeyword checks if the upcoming input is the keyword followed by a space or
+// a closing brace
+func (l *Lexer) isKeyword(keyword string) bool {
+	next := l.peak(len(keyword))
+	if len(next) != len(keyword) {
+		return false
+	}
+	return string(l.cp)+next[:len(next)-1] == keyword && isKeywordEnd(next[len(next)-1:])
+}
+
+func isKeywordEnd(rest string) bool {
+	return rest == "" || rest[0] == '}' || isSpace(rune(rest[0]))
+}
+
 func doctypeState(l *Lexer) token.Type {
 	for {
 		switch {
@@ -747,11 +887,6 @@ func eachState(l *Lexer) token.Type {
 			l.step()
 			l.pushState(eachAsState)
 			return token.As
-		case isIdentifierHead(l.cp):
-			for isAlphaNumeric(l.cp) {
-				l.step()
-			}
-			return token.Expr
 		case l.cp == ',':
 			l.step()
 			return token.Comma
@@ -760,8 +895,35 @@ func eachState(l *Lexer) token.Type {
 			l.popState()
 			return token.RightBrace
 		default:
-			l.popState()
-			return l.unexpected()
+			// Read the expression up until the as keyword, a comma or the closing
+			// brace
+			depth := 0
+			for {
+				switch {
+				case l.cp == eof:
+					l.popState()
+					return l.unexpected()
+				case l.cp == '{' || l.cp == '(' || l.cp == '[':
+					depth++
+				case l.cp == ')' || l.cp == ']':
+					depth--
+				case l.cp == '}':
+					if depth == 0 {
+						return token.Expr
+					}
+					depth--
+				case depth == 0 && l.cp == ',':
+					return token.Expr
+				case depth == 0 && isSpace(l.cp):
+					// Leave trailing whitespace out of the expression
+					rest := strings.TrimLeftFunc(l.input[l.next:], isSpace)
+					if strings.HasPrefix(rest, "as") && isKeywordEnd(rest[2:]) ||
+						strings.HasPrefix(rest, ",") || strings.HasPrefix(rest, "}") {
+						return token.Expr
+					}
+				}
+				l.step()
+			}
 		}
 	}
 }
@@ -113,6 +113,12 @@ func TestExpression(t *testing.T) {
 	equal(t, "i expr", "{i}", `{ expr:"i" }`)
 }
 
+func TestRawHTML(t *testing.T) {
+	equal(t, "", "<div>{@html comment.text}</div>", `< identifier:"div" > { @ html:"html " expr:"comment.text" } </ identifier:"div" >`)
+	equal(t, "", "{@html  post.body }", `{ @ html:"html " expr:" post.body " }`)
+	equal(t, "", "{@debug x}", `{ @ error:"lexer: unexpected token 'd'" text:"ebug x}"`)
+}
+
 func TestDoctype(t *testing.T) {
 	equal(t, "", "<!doctype html>", `<!doctype identifier:"html" >`)
 	equal(t, "", "<!doctype html/>", `<!doctype identifier:"html" />`)
@@ -163,6 +169,9 @@ func TestEachLoop(t *testing.T) {
 	equal(t, "", "{#each   items  as      item  ,  i   }  \n  {  i  }:{  item  }\n{ / each  }", `{ # each:"each " expr:"items" as:"as " expr:"     item  " , expr:"i" } text:"  \n  " { expr:"i  " } text:":" { expr:"item  " } text:"\n" { / each }`)
 	equal(t, "", "{#each items as 3}{3}{/each}", `{ # each:"each " expr:"items" as:"as " expr:"3" } { expr:"3" } { / each }`)
 	equal(t, "", "{#each items}{outer}{/each}", `{ # each:"each " expr:"items" } { expr:"outer" } { / each }`)
+	equal(t, "", "{#each story.children as comment}{comment}{/each}", `{ # each:"each " expr:"story.children" as:"as " expr:"comment" } { expr:"comment" } { / each }`)
+	equal(t, "", "{#each items.filter((item) => item.done) as item, i}{i}{/each}", `{ # each:"each " expr:"items.filter((item) => item.done)" as:"as " expr:"item" , expr:"i" } { expr:"i" } { / each }`)
+	equal(t, "", "{#each assets as asset}{asset}{/each}", `{ # each:"each " expr:"assets" as:"as " expr:"asset" } { expr:"asset" } { / each }`)
 	equal(t, "", "{#each   items  }{outer}{/each}", `{ # each:"each " expr:"items" } { expr:"outer" } { / each }`)
 }
 
@@ -191,6 +200,11 @@ func TestSlot(t *testing.T) {
 	equal(t, "", "<span slot=\"name\">fallback</span>", `< identifier:"span" slot = quote:"\"" text:"name" quote:"\"" > text:"fallback" </ identifier:"span" >`)
 }
 
+func TestSpecialElement(t *testing.T) {
+	equal(t, "", `<svelte:options customElement="my-widget" />`, `< colon_identifier:"svelte:options" identifier:"customElement" = quote:"\"" text:"my-widget" quote:"\"" />`)
+	equal(t, "", `<svelte:options></svelte:options>`, `< colon_identifier:"svelte:options" > </ colon_identifier:"svelte:options" >`)
+}
+
 func TestTypeDefinition(t *testing.T) {
 	equal(t, "", `<script>const a = 'hello';</script>`, `< script > text:"const a = 'hello';" </ script >`)
 	equal(t, "", `<script>const a: string = 'hello';</script>`, `< script > text:"const a: string = 'hello';" </ script >`)
@@ -204,4 +218,14 @@ func TestColonAttr(t *testing.T) {
 	equal(t, "", `<input type="text" bind:value={name} />`, `< identifier:"input" identifier:"type" = quote:"\"" text quote:"\"" identifier:"bind" : identifier:"value" = { expr:"name" } />`)
 	equal(t, "", `<input bind:value={todo.newItem} type="text" placeholder="new todo item.." />`, `< identifier:"input" identifier:"bind" : identifier:"value" = { expr:"todo.newItem" } identifier:"type" = quote:"\"" text quote:"\"" identifier:"placeholder" = quote:"\"" text:"new todo item.." quote:"\"" />`)
 	equal(t, "", `<span class:checked={item.status}>{item.text}</span>`, `< identifier:"span" identifier:"class" : identifier:"checked" = { expr:"item.status" } > { expr:"item.text" } </ identifier:"span" >`)
+	equal(t, "", `<button on:click|preventDefault|once={submit}>`, `< identifier:"button" identifier:"on" : identifier:"click" | identifier:"preventDefault" | identifier:"once" = { expr:"submit" } >`)
+	equal(t, "", `<button on:click>`, `< identifier:"button" identifier:"on" : identifier:"click" >`)
+}
+
+func TestAwaitBlock(t *testing.T) {
+	equal(t, "", "{#await p}loading{:then v}{v}{:catch err}{err}{/await}", `{ # await:"await " expr:"p" } text:"loading" { : then expr:"v" } { expr:"v" } { : catch expr:"err" } { expr:"err" } { / await }`)
+	equal(t, "", "{#await fetch({a: 1}) then value}{value}{/await}", `{ # await:"await " expr:"fetch({a: 1})" then expr:"value" } { expr:"value" } { / await }`)
+	equal(t, "", "{#await p catch e}x{/await}", `{ # await:"await " expr:"p" catch expr:"e" } text:"x" { / await }`)
+	equal(t, "", "{#await p}{:then}ok{/await}", `{ # await:"await " expr:"p" } { : then } text:"ok" { / await }`)
+	equal(t, "", "{#await thenable}{/await}", `{ # await:"await " expr:"thenable" } { / await }`)
 }
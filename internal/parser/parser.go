@@ -2,12 +2,16 @@ package parser
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/livebud/duo/internal/ast"
 	"github.com/livebud/duo/internal/event"
+	"github.com/livebud/duo/internal/html"
 	"github.com/livebud/duo/internal/js"
 	"github.com/livebud/duo/internal/lexer"
 	"github.com/livebud/duo/internal/scope"
+	"github.com/livebud/duo/internal/style"
 	"github.com/livebud/duo/internal/token"
 	"github.com/matthewmueller/css"
 )
@@ -15,9 +19,14 @@ import (
 func Parse(path, input string) (*ast.Document, error) {
 	l := lexer.New(input)
 	p := New(path, l)
+	// Markup before the script may contain TypeScript too
+	p.ts = tsScript.MatchString(input)
 	return p.Parse()
 }
 
+// tsScript matches a script with TypeScript, like <script lang="ts">
+var tsScript = regexp.MustCompile(`<script\b[^>]*\blang=["']?(ts|typescript)\b`)
+
 func Print(path, input string) string {
 	doc, err := Parse(path, input)
 	if err != nil {
@@ -27,13 +36,14 @@ func Print(path, input string) string {
 }
 
 func New(path string, l *lexer.Lexer) *Parser {
-	return &Parser{path, l, scope.New()}
+	return &Parser{path: path, l: l, sc: scope.New()}
 }
 
 type Parser struct {
 	path string
 	l    *lexer.Lexer
 	sc   *scope.Scope
+	ts   bool // Expressions may contain TypeScript
 }
 
 func (p *Parser) Parse() (*ast.Document, error) {
@@ -61,6 +71,14 @@ func (p *Parser) parseDocument() (*ast.Document, error) {
 		}
 		doc.Children = append(doc.Children, child)
 	}
+	// Catch markup the browser would restructure
+	if errs := html.Validate(doc); len(errs) > 0 {
+		return nil, fmt.Errorf("parser: %s: %w", p.path, errs[0])
+	}
+	// Scope the component's styles
+	if err := style.Scope(p.path, doc); err != nil {
+		return nil, err
+	}
 	return doc, nil
 }
 
@@ -76,6 +94,8 @@ func (p *Parser) parseFragment() (ast.Fragment, error) {
 		switch {
 		case p.Accept(token.Hash):
 			return p.parseBlock()
+		case p.Accept(token.At):
+			return p.parseHTML()
 		default:
 			return p.parseMustache()
 		}
@@ -104,6 +124,8 @@ func (p *Parser) parseTag() (ast.Fragment, error) {
 		return p.parseScript()
 	case p.Accept(token.Slot):
 		return p.parseSlot()
+	case p.Accept(token.ColonIdentifier):
+		return p.parseSpecialElement()
 	default:
 		return nil, p.unexpected("tag")
 	}
@@ -111,6 +133,7 @@ func (p *Parser) parseTag() (ast.Fragment, error) {
 
 func (p *Parser) parseElement() (*ast.Element, error) {
 	node := &ast.Element{
+		Pos:  p.position(p.l.Token.Start - 1),
 		Name: p.Text(),
 	}
 
@@ -153,6 +176,7 @@ func (p *Parser) parseElement() (*ast.Element, error) {
 
 func (p *Parser) parseComponent() (*ast.Component, error) {
 	node := &ast.Component{
+		Pos:  p.position(p.l.Token.Start - 1),
 		Name: p.Text(),
 	}
 
@@ -181,7 +205,11 @@ func (p *Parser) parseComponent() (*ast.Component, error) {
 	}
 
 	// Closing tag
-	if err := p.Expect(token.PascalIdentifier); err != nil {
+	closing := token.PascalIdentifier
+	if node.IsSelf() {
+		closing = token.ColonIdentifier
+	}
+	if err := p.Expect(closing); err != nil {
 		return nil, err
 	} else if p.Text() != node.Name {
 		return nil, p.errorf("expected closing tag %s, got %s", node.Name, p.Text())
@@ -203,6 +231,12 @@ func (p *Parser) parseAttribute() (ast.Attribute, error) {
 				return p.parseBind()
 			case "class":
 				return p.parseClass()
+			case "style":
+				return p.parseStyleDirective()
+			case "let":
+				return p.parseLet()
+			case "on":
+				return p.parseEventDirective()
 			default:
 				return nil, p.unexpected("colon attribute")
 			}
@@ -223,8 +257,14 @@ func (p *Parser) parseBind() (*ast.Binding, error) {
 		return nil, err
 	}
 	node.Key = p.Text()
-	if err := p.Expect(token.Equal); err != nil {
-		return nil, err
+	// Shorthand for bind:name={name}
+	if !p.Accept(token.Equal) {
+		value, err := p.parseDirectiveShorthand("bind", node.Key)
+		if err != nil {
+			return nil, err
+		}
+		node.Value = value
+		return node, nil
 	}
 	if err := p.Expect(token.LeftBrace); err != nil {
 		return nil, err
@@ -243,8 +283,14 @@ func (p *Parser) parseClass() (*ast.Class, error) {
 		return nil, err
 	}
 	node.Name = p.Text()
-	if err := p.Expect(token.Equal); err != nil {
-		return nil, err
+	// Shorthand for class:name={name}
+	if !p.Accept(token.Equal) {
+		value, err := p.parseDirectiveShorthand("class", node.Name)
+		if err != nil {
+			return nil, err
+		}
+		node.Value = value
+		return node, nil
 	}
 	if err := p.Expect(token.LeftBrace); err != nil {
 		return nil, err
@@ -257,14 +303,138 @@ func (p *Parser) parseClass() (*ast.Class, error) {
 	return node, nil
 }
 
-func (p *Parser) parseField() (*ast.Field, error) {
+func (p *Parser) parseStyleDirective() (*ast.StyleDirective, error) {
+	node := &ast.StyleDirective{}
+	if err := p.Expect(token.Identifier); err != nil {
+		return nil, err
+	}
+	node.Name = p.Text()
+	// Shorthand for style:name={name}
+	if !p.Accept(token.Equal) {
+		value, err := p.parseDirectiveShorthand("style", node.Name)
+		if err != nil {
+			return nil, err
+		}
+		node.Values = []ast.Value{value}
+		return node, nil
+	}
+	values, err := p.parseAttributeValues()
+	if err != nil {
+		return nil, err
+	}
+	node.Values = values
+	return node, nil
+}
+
+// parseDirectiveShorthand turns the name of a directive like class:active into
+// the variable it reads
+func (p *Parser) parseDirectiveShorthand(directive, name string) (*ast.Mustache, error) {
+	expr, err := js.ParseExpr(name)
+	if err != nil {
+		return nil, p.errorf("%s:%s shorthand must be a variable name", directive, name)
+	}
+	v, ok := expr.(*js.Var)
+	if !ok {
+		return nil, p.errorf("%s:%s shorthand must be a variable name", directive, name)
+	}
+	if err := walk(p.sc, v); err != nil {
+		return nil, fmt.Errorf("parser: error walking: %w", err)
+	}
+	return &ast.Mustache{Expr: v}, nil
+}
+
+func (p *Parser) parseLet() (*ast.Let, error) {
+	node := &ast.Let{}
+	if err := p.Expect(token.Identifier); err != nil {
+		return nil, err
+	}
+	node.Name = p.Text()
+	if p.Accept(token.Equal) {
+		if err := p.Expect(token.LeftBrace, token.Expr); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseExpr(p.Text())
+		if err != nil {
+			return nil, err
+		}
+		alias, err := p.exprToVar(expr)
+		if err != nil {
+			return nil, err
+		}
+		node.Alias = string(alias.Data)
+		if err := p.Expect(token.RightBrace); err != nil {
+			return nil, err
+		}
+	}
+	// Slot props are declared for the slot content
+	p.sc.IsDeclaration = true
+	p.sc.Use(node.Var())
+	p.sc.IsDeclaration = false
+	return node, nil
+}
+
+// parseEventDirective parses the legacy on:click={handler} directive into the
+// equivalent onclick={handler} field. Directives may have modifiers like
+// on:click|preventDefault, and forward the event when they have no value.
+func (p *Parser) parseEventDirective() (*ast.Field, error) {
+	if err := p.Expect(token.Identifier); err != nil {
+		return nil, err
+	}
 	field := &ast.Field{
-		Key:          p.Text(),
-		EventHandler: event.Is(p.Text()),
+		Key:          "on" + p.Text(),
+		EventHandler: true,
+		Directive:    true,
 	}
-	if err := p.Expect(token.Equal); err != nil {
+	for p.Accept(token.Pipe) {
+		if err := p.Expect(token.Identifier); err != nil {
+			return nil, err
+		}
+		modifier := p.Text()
+		if !eventModifiers[modifier] {
+			return nil, p.errorf("unknown event modifier %q on %s", modifier, field.Key)
+		}
+		field.Modifiers = append(field.Modifiers, modifier)
+	}
+	if field.HasModifier("passive") && field.HasModifier("preventDefault") {
+		return nil, p.errorf("the passive and preventDefault modifiers can't be used together on %s", field.Key)
+	}
+	// Forward the event
+	if !p.Accept(token.Equal) {
+		return field, nil
+	}
+	value, err := p.parseEventValue()
+	if err != nil {
 		return nil, err
 	}
+	field.Values = append(field.Values, value)
+	return field, nil
+}
+
+// eventModifiers are the modifiers allowed on on:event directives
+var eventModifiers = map[string]bool{
+	"preventDefault":           true,
+	"stopPropagation":          true,
+	"stopImmediatePropagation": true,
+	"capture":                  true,
+	"once":                     true,
+	"passive":                  true,
+	"nonpassive":               true,
+	"self":                     true,
+	"trusted":                  true,
+}
+
+func (p *Parser) parseField() (*ast.Field, error) {
+	field := &ast.Field{
+		Key: p.Text(),
+	}
+	// Boolean attributes like <select multiple>
+	if !p.Accept(token.Equal) {
+		return field, nil
+	}
+	// React-style handlers are always expressions, while handlers like onclick
+	// may also be inline scripts
+	_, reactStyle := event.To[field.Key]
+	field.EventHandler = reactStyle || event.Is(field.Key) && p.Is(token.LeftBrace)
 	if field.EventHandler {
 		value, err := p.parseEventValue()
 		if err != nil {
@@ -283,6 +453,10 @@ func (p *Parser) parseField() (*ast.Field, error) {
 
 func (p *Parser) parseAttributeValues() (values []ast.Value, err error) {
 	for !p.Is(token.GreaterThan, token.SlashGreaterThan, token.Identifier) {
+		// Unquoted values end at whitespace, e.g. `a={b} {c}`
+		if len(values) > 0 && p.l.Peak(1).Start != p.l.Token.Start+len(p.l.Token.Text) {
+			return values, nil
+		}
 		switch {
 		case p.Accept(token.Quote):
 			return p.parseAttributeStringValues()
@@ -342,6 +516,24 @@ func (p *Parser) parseMustache() (*ast.Mustache, error) {
 	if err := p.Expect(token.Expr); err != nil {
 		return nil, err
 	}
+	node.Pos = p.position(p.l.Token.Start)
+	expr, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	node.Expr = expr
+	if err := p.Expect(token.RightBrace); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func (p *Parser) parseHTML() (*ast.HTML, error) {
+	node := new(ast.HTML)
+	if err := p.Expect(token.HTML, token.Expr); err != nil {
+		return nil, err
+	}
+	node.Pos = p.position(p.l.Token.Start)
 	expr, err := p.parseExpression()
 	if err != nil {
 		return nil, err
@@ -359,6 +551,8 @@ func (p *Parser) parseBlock() (ast.Fragment, error) {
 		return p.parseIfBlock()
 	case p.Accept(token.Each):
 		return p.parseEachBlock()
+	case p.Accept(token.Await):
+		return p.parseAwaitBlock()
 	default:
 		return nil, p.unexpected("block")
 	}
@@ -391,6 +585,7 @@ func (p *Parser) parseIfBlock() (*ast.IfBlock, error) {
 	if err := p.Expect(token.Expr); err != nil {
 		return nil, err
 	}
+	node.Pos = p.position(p.l.Token.Start)
 	expr, err := p.parseExpression()
 	if err != nil {
 		return nil, err
@@ -435,6 +630,7 @@ func (p *Parser) parseElseIfBlock() (*ast.IfBlock, error) {
 	if err := p.Expect(token.Expr); err != nil {
 		return nil, err
 	}
+	node.Pos = p.position(p.l.Token.Start)
 	expr, err := p.parseExpression()
 	if err != nil {
 		return nil, err
@@ -489,6 +685,7 @@ func (p *Parser) parseEachBlock() (*ast.EachBlock, error) {
 	if err := p.Expect(token.Expr); err != nil {
 		return nil, err
 	}
+	node.Pos = p.position(p.l.Token.Start)
 	left, err := p.parseExpression()
 	if err != nil {
 		return nil, err
@@ -509,6 +706,7 @@ func (p *Parser) parseEachBlock() (*ast.EachBlock, error) {
 			return nil, err
 		}
 		node.Value = value
+		p.declare(string(value.Data))
 
 		// handle key
 		if p.Accept(token.Comma) {
@@ -524,6 +722,7 @@ func (p *Parser) parseEachBlock() (*ast.EachBlock, error) {
 				return nil, err
 			}
 			node.Key = key
+			p.declare(string(key.Data))
 		}
 	}
 
@@ -553,6 +752,92 @@ func (p *Parser) parseEachBlock() (*ast.EachBlock, error) {
 	return node, nil
 }
 
+func (p *Parser) parseAwaitBlock() (*ast.AwaitBlock, error) {
+	node := new(ast.AwaitBlock)
+	if err := p.Expect(token.Expr); err != nil {
+		return nil, err
+	}
+	node.Pos = p.position(p.l.Token.Start)
+	promise, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	node.Promise = promise
+
+	// The section of the await block we're currently parsing
+	section := &node.Pending
+	switch {
+	case p.Accept(token.Then):
+		if node.Value, err = p.parseAwaitVar(); err != nil {
+			return nil, err
+		}
+		section = &node.Then
+	case p.Accept(token.Catch):
+		if node.Error, err = p.parseAwaitVar(); err != nil {
+			return nil, err
+		}
+		section = &node.Catch
+	}
+	if err := p.Expect(token.RightBrace); err != nil {
+		return nil, err
+	}
+
+	// Parse the body
+	for !p.Accept(token.LeftBrace, token.Slash) {
+		switch {
+		case p.Accept(token.EOF):
+			return nil, p.errorf("unclosed await block")
+		case p.Accept(token.LeftBrace, token.Colon, token.Then):
+			if node.Value, err = p.parseAwaitVar(); err != nil {
+				return nil, err
+			}
+			if err := p.Expect(token.RightBrace); err != nil {
+				return nil, err
+			}
+			section = &node.Then
+		case p.Accept(token.LeftBrace, token.Colon, token.Catch):
+			if node.Error, err = p.parseAwaitVar(); err != nil {
+				return nil, err
+			}
+			if err := p.Expect(token.RightBrace); err != nil {
+				return nil, err
+			}
+			section = &node.Catch
+		default:
+			fragment, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			*section = append(*section, fragment)
+		}
+	}
+
+	// Closing block
+	if err := p.Expect(token.Await, token.RightBrace); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// parseAwaitVar parses the optional variable after then or catch
+func (p *Parser) parseAwaitVar() (*js.Var, error) {
+	if !p.Accept(token.Expr) {
+		return nil, nil
+	}
+	expr, err := p.parseExpr(p.Text())
+	if err != nil {
+		return nil, err
+	}
+	v, err := p.exprToVar(expr)
+	if err != nil {
+		return nil, err
+	}
+	p.sc.IsDeclaration = true
+	p.sc.Use(string(v.Data))
+	p.sc.IsDeclaration = false
+	return v, nil
+}
+
 // Checks that the next token is one of the given types
 func (p *Parser) Is(types ...token.Type) bool {
 	token := p.l.Peak(1)
@@ -589,7 +874,7 @@ func (p *Parser) Expect(tokens ...token.Type) error {
 	for i, tok := range tokens {
 		peaked := p.l.Peak(i + 1)
 		if peaked.Type == token.Error {
-			return p.errorf(peaked.Text)
+			return p.errorf("%s", peaked.Text)
 		} else if peaked.Type != tok {
 			return p.errorf("expected %s, got %s", tok, peaked.Type)
 		}
@@ -606,6 +891,19 @@ func (p *Parser) Type() token.Type {
 }
 
 // Text of the current token
+// declare a variable that's scoped to a block, like an {#each} item
+func (p *Parser) declare(name string) {
+	p.sc.IsDeclaration = true
+	p.sc.Use(name)
+	p.sc.IsDeclaration = false
+}
+
+// position of the offset in the source
+func (p *Parser) position(offset int) ast.Position {
+	line, column := p.l.Position(offset)
+	return ast.Position{Line: line, Column: column}
+}
+
 func (p *Parser) Text() string {
 	return p.l.Token.Text
 }
@@ -650,11 +948,23 @@ func (p *Parser) parseNamedSlot() (*ast.NamedSlot, error) {
 	return node, nil
 }
 
+// parseExpr parses an expression, stripping TypeScript types when the
+// component's script is TypeScript
+func (p *Parser) parseExpr(code string) (js.IExpr, error) {
+	if p.ts {
+		return js.ParseTSExpr(code)
+	}
+	return js.ParseExpr(code)
+}
+
 func (p *Parser) parseExpression() (js.IExpr, error) {
-	expr, err := js.ParseExpr(p.l.Token.Text)
+	expr, err := p.parseExpr(p.l.Token.Text)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkRunes(expr); err != nil {
+		return nil, p.errorf("%s", err)
+	}
 	// Walk the expression to update scope
 	if err := walk(p.sc, expr); err != nil {
 		return nil, fmt.Errorf("parser: error walking: %w", err)
@@ -671,6 +981,22 @@ func (p *Parser) parseScript() (*ast.Script, error) {
 		if err != nil {
 			return nil, err
 		}
+		if field, ok := attr.(*ast.Field); ok {
+			switch field.Key {
+			case "lang":
+				lang, err := p.staticValue(field)
+				if err != nil {
+					return nil, err
+				}
+				p.ts = p.ts || lang == "ts" || lang == "typescript"
+			case "generics":
+				generics, err := p.staticValue(field)
+				if err != nil {
+					return nil, err
+				}
+				node.Generics = generics
+			}
+		}
 		node.Attributes = append(node.Attributes, attr)
 	}
 	if p.Accept(token.SlashGreaterThan) {
@@ -687,6 +1013,7 @@ func (p *Parser) parseScript() (*ast.Script, error) {
 	}
 
 	jsCode := p.Text()
+	node.CodePos = p.position(p.l.Token.Start)
 
 	// Closing tag
 	if err := p.Expect(token.LessThanSlash); err != nil {
@@ -699,7 +1026,11 @@ func (p *Parser) parseScript() (*ast.Script, error) {
 		return nil, err
 	}
 	// Parse the program
-	program, err := js.ParseTS(jsCode)
+	parse := js.Parse
+	if p.ts {
+		parse = js.ParseTS
+	}
+	program, err := parse(jsCode)
 	if err != nil {
 		return nil, err
 	}
@@ -707,7 +1038,7 @@ func (p *Parser) parseScript() (*ast.Script, error) {
 	if err := walk(p.sc, program); err != nil {
 		return nil, fmt.Errorf("parser: error walking: %w", err)
 	}
-	fmt.Println("scope", p.sc)
+	node.Code = jsCode
 	node.Program = program
 	return node, nil
 }
@@ -737,6 +1068,7 @@ func (p *Parser) parseStyle() (*ast.Style, error) {
 	}
 
 	cssCode := p.Text()
+	node.CodePos = p.position(p.l.Token.Start)
 
 	// Closing tag
 	if err := p.Expect(token.LessThanSlash); err != nil {
@@ -753,6 +1085,7 @@ func (p *Parser) parseStyle() (*ast.Style, error) {
 	if err != nil {
 		return nil, err
 	}
+	node.Code = cssCode
 	node.StyleSheet = stylesheet
 	return node, nil
 }
@@ -760,23 +1093,23 @@ func (p *Parser) parseStyle() (*ast.Style, error) {
 func (p *Parser) parseSlot() (*ast.Slot, error) {
 	node := &ast.Slot{}
 
-	if p.Accept(token.Identifier) {
-		if p.Text() != "name" {
-			return nil, p.errorf("expected slot name, got %s", p.Text())
-		}
-		if err := p.Expect(token.Equal); err != nil {
-			return nil, err
-		}
-		if err := p.Expect(token.Quote); err != nil {
-			return nil, err
-		}
-		if err := p.Expect(token.Text); err != nil {
+	// Handle the slot name and slot props
+	for !p.Check(token.GreaterThan) && !p.Check(token.SlashGreaterThan) {
+		attr, err := p.parseAttribute()
+		if err != nil {
 			return nil, err
 		}
-		node.Name = p.Text()
-		if err := p.Expect(token.Quote); err != nil {
-			return nil, err
+		if field, ok := attr.(*ast.Field); ok && field.Key == "name" {
+			for _, value := range field.Values {
+				text, ok := value.(*ast.Text)
+				if !ok {
+					return nil, p.errorf("expected a static slot name")
+				}
+				node.Name += text.Value
+			}
+			continue
 		}
+		node.Attributes = append(node.Attributes, attr)
 	}
 	if p.Accept(token.SlashGreaterThan) {
 		node.SelfClosing = true
@@ -804,6 +1137,88 @@ func (p *Parser) parseSlot() (*ast.Slot, error) {
 	return node, nil
 }
 
+// parseSpecialElement parses the svelte: elements
+func (p *Parser) parseSpecialElement() (ast.Fragment, error) {
+	switch name := p.Text(); name {
+	case "svelte:options":
+		return p.parseOptions()
+	case ast.Self:
+		return p.parseComponent()
+	default:
+		return nil, p.errorf("unsupported element <%s>", name)
+	}
+}
+
+func (p *Parser) parseOptions() (*ast.Options, error) {
+	node := &ast.Options{}
+	for !p.Check(token.GreaterThan) && !p.Check(token.SlashGreaterThan) {
+		attr, err := p.parseAttribute()
+		if err != nil {
+			return nil, err
+		}
+		if field, ok := attr.(*ast.Field); ok && field.Key == "customElement" {
+			for _, value := range field.Values {
+				text, ok := value.(*ast.Text)
+				if !ok {
+					return nil, p.errorf("expected a static custom element tag name")
+				}
+				node.CustomElement += text.Value
+			}
+			if !isCustomElementName(node.CustomElement) {
+				return nil, p.errorf("custom element tag name %q must be lowercase and contain a hyphen", node.CustomElement)
+			}
+		}
+		node.Attributes = append(node.Attributes, attr)
+	}
+	if p.Accept(token.SlashGreaterThan) {
+		return node, nil
+	}
+	if err := p.Expect(token.GreaterThan); err != nil {
+		return nil, err
+	}
+	if !p.Accept(token.LessThanSlash) {
+		return nil, p.errorf("<svelte:options> can't have children")
+	}
+	if err := p.Expect(token.ColonIdentifier); err != nil {
+		return nil, err
+	} else if p.Text() != "svelte:options" {
+		return nil, p.errorf("expected closing tag svelte:options, got %s", p.Text())
+	}
+	if err := p.Expect(token.GreaterThan); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// staticValue returns the text of an attribute without expressions
+func (p *Parser) staticValue(field *ast.Field) (string, error) {
+	value := ""
+	for _, v := range field.Values {
+		text, ok := v.(*ast.Text)
+		if !ok {
+			return "", p.errorf("expected a static %s attribute", field.Key)
+		}
+		value += text.Value
+	}
+	return value, nil
+}
+
+// isCustomElementName returns true for valid custom element names, like
+// my-widget
+func isCustomElementName(name string) bool {
+	if name == "" || name[0] < 'a' || name[0] > 'z' || !strings.Contains(name, "-") {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.', r == '_', r >= 0x80:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 func (p *Parser) parseComment() (*ast.Comment, error) {
 	return &ast.Comment{
 		Value: p.Text(),
@@ -115,7 +115,7 @@ func Test(t *testing.T) {
 	equal(t, "attribute", `<hr {id} />`, `<hr {id} />`)
 	equal(t, "attribute", `<h1 name="">{greeting}</h1>`, `<h1 name="">{greeting}</h1>`)
 	equal(t, "attributes", `<h1 name=""></h1>`, `<h1 name=""></h1>`)
-	equal(t, "attribute", `<button onclick={addToList} disabled={newItem === ""}>Add</button>`, `<button onclick="{addToList}" disabled="{newItem === ""}">Add</button>`)
+	equal(t, "attribute", `<button onclick={addToList} disabled={newItem === ""}>Add</button>`, `<button onclick={addToList} disabled="{newItem === ""}">Add</button>`)
 }
 
 func TestEventHandler(t *testing.T) {
@@ -127,6 +127,17 @@ func TestEventHandler(t *testing.T) {
 	equal(t, "", "<button onMouseOut={() => count++}>+</button>", `<button onMouseOut={() => { return count++; }}>+</button>`)
 	equal(t, "", "<button onClick={increment} onDragStart={() => count++}>+</button>", `<button onClick={increment} onDragStart={() => { return count++; }}>+</button>`)
 	equal(t, "", "<button {onClick} {onDragStart}>+</button>", `<button {onClick} {onDragStart}>+</button>`)
+	equal(t, "", "<button onclick={increment}>+</button>", `<button onclick={increment}>+</button>`)
+	equal(t, "", "<button on:click={increment}>+</button>", `<button onclick={increment}>+</button>`)
+	equal(t, "", "<button on:dblclick={() => count++}>+</button>", `<button ondblclick={() => { return count++; }}>+</button>`)
+	equal(t, "", `<button onclick="alert('hi')">+</button>`, `<button onclick="alert('hi')">+</button>`)
+	equal(t, "", "<button on:click|preventDefault={increment}>+</button>", `<button on:click|preventDefault={increment}>+</button>`)
+	equal(t, "", "<button on:click|stopPropagation|once={() => count++}>+</button>", `<button on:click|stopPropagation|once={() => { return count++; }}>+</button>`)
+	equal(t, "", "<button on:click>+</button>", `<button on:click>+</button>`)
+	equal(t, "", "<button on:click|self>+</button>", `<button on:click|self>+</button>`)
+	equal(t, "", "<Button on:click />", `<Button on:click />`)
+	equal(t, "", "<button on:click|prevent={increment}>+</button>", `parser: <button on:click|prevent={increment}>+</button>: unknown event modifier "prevent" on onclick`)
+	equal(t, "", "<div on:wheel|passive|preventDefault={scroll}></div>", `parser: <div on:wheel|passive|preventDefault={scroll}></div>: the passive and preventDefault modifiers can't be used together on onwheel`)
 }
 
 func TestFile(t *testing.T) {
@@ -153,6 +164,51 @@ func TestScope(t *testing.T) {
 	`)
 }
 
+func TestScopeRunes(t *testing.T) {
+	equalScope(t, "../../testdata/02-greeting/input.svelte", `
+		"greeting" declared mutable rune=$state
+		"setInterval"
+	`)
+	equalScope(t, "../../testdata/03-counter/input.svelte", `
+		"count" declared mutable rune=$props
+		"increment" declared
+	`)
+	equalScope(t, "../../testdata/04-todo/input.svelte", `
+		"todoList" declared mutable rune=$bindable
+		"newItem" declared mutable rune=$state
+		"addToList" declared
+		"removeFromList" declared
+		"item" declared
+		"index" declared
+	`)
+}
+
+func TestScopeImports(t *testing.T) {
+	equalScope(t, "../../example/hn/view/Comment.svelte", `
+		"timeago" import="timeago.js" name=format
+		"comment" declared exported mutable
+		"show" declared mutable
+		"toggle" declared
+	`)
+}
+
+func TestSelf(t *testing.T) {
+	equal(t, "", "<svelte:self node={child} />", `<svelte:self node="{child}" />`)
+	equal(t, "", "{#if open}<svelte:self {node}>child</svelte:self>{/if}", `{#if open}<svelte:self {node}>child</svelte:self>{/if}`)
+	equal(t, "", "<svelte:self></Self>", `parser: <svelte:self></Self>: expected colon_identifier, got pascal_identifier`)
+}
+
+func TestRawHTML(t *testing.T) {
+	equal(t, "", "<div>{@html comment.text}</div>", `<div>{@html comment.text}</div>`)
+	equal(t, "", "<div>{@html}</div>", `parser: <div>{@html}</div>: lexer: unexpected token '}'`)
+}
+
+func TestRunesInMarkup(t *testing.T) {
+	equal(t, "", `<p>{$state(1)}</p>`, `parser: <p>{$state(1)}</p>: $state(...) can only be used in the script`)
+	equal(t, "", `{#if $derived.by(() => x)}x{/if}`, `parser: {#if $derived.by(() => x)}x{/if}: $derived.by(...) can only be used in the script`)
+	equal(t, "", `<p>{$state.snapshot(items)}</p>`, `<p>{$state.snapshot(items)}</p>`)
+}
+
 func TestIfStatement(t *testing.T) {
 	equal(t, "", "{#if x}{x}{/if}", `{#if x}{x}{/if}`)
 	equal(t, "", "{#if x}\n{x}\n{/if}", `{#if x}{x}{/if}`)
@@ -183,6 +239,8 @@ func TestEachLoop(t *testing.T) {
 	equal(t, "", "{#each items as 3}{3}{/each}", `parser: {#each items as 3}{3}{/each}: expected an identifier, got *js.LiteralExpr`)
 	equal(t, "", "{#each items}{outer}{/each}", `{#each items}{outer}{/each}`)
 	equal(t, "", "{#each   items  }{outer}{/each}", `{#each items}{outer}{/each}`)
+	equal(t, "", "{#each story.children as comment}{comment.text}{/each}", `{#each story.children as comment}{comment.text}{/each}`)
+	equal(t, "", "{#each items.filter((item) => item.done) as item, i}{i}{/each}", `{#each items.filter((item) => { return item.done; }) as item, i}{i}{/each}`)
 	// TODO: handle parsing destructured objects
 	// equal(t, "", "{#each cats as { id, name }, i}{id}:{name}{/each}", ``)
 }
@@ -200,7 +258,7 @@ func TestComponent(t *testing.T) {
 	equal(t, "", `<script>import Component from "./component.duo";</script><Component/>`, `<script>import Component from "./component.duo"; </script><Component />`)
 	equal(t, "", `<script>import Component from "./component.duo";</script><Component a={b}/>`, `<script>import Component from "./component.duo"; </script><Component a="{b}" />`)
 	equal(t, "", `<script>import A from "./a.duo"; import B from "./b.duo";</script><A/><B/>`, `<script>import A from "./a.duo"; import B from "./b.duo"; </script><A /><B />`)
-	equal(t, "", `<script>import A from "./a.duo"; import B from "./b.duo"; import C from './c.duo';</script><A/><B/>`, `<script>import A from "./a.duo"; import B from "./b.duo"; import C from "./c.duo"; </script><A /><B />`)
+	equal(t, "", `<script>import A from "./a.duo"; import B from "./b.duo"; import C from './c.duo';</script><A/><B/>`, `<script>import A from "./a.duo"; import B from "./b.duo"; import C from './c.duo'; </script><A /><B />`)
 }
 
 func TestSlot(t *testing.T) {
@@ -209,6 +267,10 @@ func TestSlot(t *testing.T) {
 	equal(t, "", "<slot  >fallback</slot>", `<slot>fallback</slot>`)
 	equal(t, "", "<slot name=\"value\" ><span>1</span><span>2</span></slot>", `<slot name="value"><span>1</span><span>2</span></slot>`)
 	equal(t, "", "<span slot=\"name\">fallback</span>", `<span slot="name">fallback</span>`)
+	equal(t, "", "<slot name=\"row\" item={item} {index} />", `<slot name="row" item="{item}" {index} />`)
+	equal(t, "", "<slot name={name} />", `parser: <slot name={name} />: expected a static slot name`)
+	equal(t, "", "<List let:item>{item}</List>", `<List let:item>{item}</List>`)
+	equal(t, "", "<span slot=\"row\" let:item={row}>{row}</span>", `<span slot="row" let:item={row}>{row}</span>`)
 }
 
 func TestScript(t *testing.T) {
@@ -216,6 +278,15 @@ func TestScript(t *testing.T) {
 	equal(t, "", "<script>let posts: Post[] = [];</script>", `<script>let posts = []; </script>`)
 }
 
+func TestTypeScriptMarkup(t *testing.T) {
+	equal(t, "", `<script lang="ts">let value: unknown;</script><h1 title={value as string}>{format<string>(value)}{value!}</h1>`, `<script lang="ts">let value; </script><h1 title="{value}">{format(value)}{value}</h1>`)
+	equal(t, "", `<p>{user satisfies User}</p><script lang="ts">let user: User;</script>`, `<p>{user}</p><script lang="ts">let user; </script>`)
+	equal(t, "", `{#await load() then items as Item[]}{items.length}{/await}<script lang="ts"></script>`, `{#await load()}{:then items}{items.length}{/await}<script lang="ts"></script>`)
+	equal(t, "", `<script lang="ts" generics="T extends Array<string>">let { items }: { items: T } = $props();</script>`, `<script lang="ts" generics="T extends Array<string>">let {items} = $props(); </script>`)
+	// Without TypeScript, angle brackets are comparisons
+	equal(t, "", `<script>let a = 1;</script><p>{a < b > (c)}</p>`, `<script>let a = 1; </script><p>{a < b > (c)}</p>`)
+}
+
 func TestComment(t *testing.T) {
 	equal(t, "", "<!-- this is a comment -->\n<h2>hello world</h2>", "<!-- this is a comment --><h2>hello world</h2>")
 }
@@ -223,12 +294,61 @@ func TestComment(t *testing.T) {
 func TestBind(t *testing.T) {
 	equal(t, "", `<input type="text" bind:value={name} />`, `<input type="text" bind:value={name} />`)
 	equal(t, "", `<input bind:value={todo.newItem} type="text" placeholder="new todo item.." />`, `<input bind:value={todo.newItem} type="text" placeholder="new todo item.." />`)
+	equal(t, "", `<input type="checkbox" bind:checked />`, `<input type="checkbox" bind:checked={checked} />`)
+	equal(t, "", `<select multiple bind:value={flavors}></select>`, `<select multiple bind:value={flavors}></select>`)
 }
 
 func TestClass(t *testing.T) {
 	equal(t, "", `<span class:checked={item.status}>{item.text}</span>`, `<span class:checked={item.status}>{item.text}</span>`)
+	equal(t, "", `<span class="item" class:active>{item.text}</span>`, `<span class="item" class:active={active}>{item.text}</span>`)
+	equal(t, "", `<span class:is-active>x</span>`, `parser: <span class:is-active>x</span>: class:is-active shorthand must be a variable name`)
+}
+
+func TestStyleDirective(t *testing.T) {
+	equal(t, "", `<span style:color={color} style:background-color="red" style:width="{width}px">x</span>`, `<span style:color="{color}" style:background-color="red" style:width="{width}px">x</span>`)
+	equal(t, "", `<span style:color>x</span>`, `<span style:color="{color}">x</span>`)
+	equal(t, "", `<span style:background-color>x</span>`, `parser: <span style:background-color>x</span>: style:background-color shorthand must be a variable name`)
 }
 
 func TestStyle(t *testing.T) {
-	equal(t, "", `<span>{item.text}</span><style>span { background-color: blue; }</style>`, `<span>{item.text}</span><style>span { background-color: blue }</style>`)
+	equal(t, "", `<span>{item.text}</span><style>span { background-color: blue; }</style>`, `<span class="svelte-14dblqe">{item.text}</span><style>span.svelte-14dblqe { background-color: blue }</style>`)
+}
+
+func TestOptions(t *testing.T) {
+	equal(t, "", `<svelte:options customElement="my-widget" /><h1>hi</h1>`, `<svelte:options customElement="my-widget" /><h1>hi</h1>`)
+	equal(t, "", `<svelte:options customElement="my-widget"></svelte:options>`, `<svelte:options customElement="my-widget" />`)
+	equal(t, "", `<svelte:options customElement="widget" />`, `parser: <svelte:options customElement="widget" />: custom element tag name "widget" must be lowercase and contain a hyphen`)
+	equal(t, "", `<svelte:options customElement="My-Widget" />`, `parser: <svelte:options customElement="My-Widget" />: custom element tag name "My-Widget" must be lowercase and contain a hyphen`)
+	equal(t, "", `<svelte:options customElement={tag} />`, `parser: <svelte:options customElement={tag} />: expected a static custom element tag name`)
+	equal(t, "", `<svelte:window />`, `parser: <svelte:window />: unsupported element <svelte:window>`)
+}
+
+func TestAwaitBlock(t *testing.T) {
+	equal(t, "", "{#await p}loading{:then v}{v}{:catch err}{err}{/await}", `{#await p}loading{:then v}{v}{:catch err}{err}{/await}`)
+	equal(t, "", "{#await fetch(url) then value}<p>{value}</p>{/await}", `{#await fetch(url)}{:then value}<p>{value}</p>{/await}`)
+	equal(t, "", "{#await p catch e}{e}{/await}", `{#await p}{:then}{:catch e}{e}{/await}`)
+	equal(t, "", "{#await p}{:then}ok{/await}", `{#await p}{:then}ok{/await}`)
+	equal(t, "", "{#await p}loading", `parser: {#await p}loading: unclosed await block`)
+}
+
+// BenchmarkParse parses each component in the testdata corpus
+func BenchmarkParse(b *testing.B) {
+	paths, err := filepath.Glob(filepath.Join("..", "..", "testdata", "*", "input.svelte"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, path := range paths {
+		input, err := os.ReadFile(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(filepath.Base(filepath.Dir(path)), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := parser.Parse(path, string(input)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
 }
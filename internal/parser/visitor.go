@@ -14,12 +14,68 @@ func walk(sc *scope.Scope, node js.INode) error {
 	return v.err
 }
 
+// checkRunes returns an error if a template expression calls a rune that only
+// works within the script, like $state(...)
+func checkRunes(node js.INode) error {
+	v := &runeChecker{}
+	js.Walk(v, node)
+	return v.err
+}
+
+type runeChecker struct {
+	err error
+}
+
+func (v *runeChecker) Enter(node js.INode) js.IVisitor {
+	if v.err != nil {
+		return nil
+	}
+	if call, ok := node.(*js.CallExpr); !ok {
+		return v
+	} else if r, ok := scope.RuneOf(call); ok && r.ScriptOnly() {
+		v.err = fmt.Errorf("%s(...) can only be used in the script", r)
+		return nil
+	}
+	return v
+}
+
+func (v *runeChecker) Exit(js.INode) {}
+
 type visitor struct {
 	sc  *scope.Scope
 	err error
 }
 
 func (v *visitor) Enter(node js.INode) js.IVisitor {
+	// Variable declarations are walked separately to distinguish the bindings
+	// being declared from the expressions that initialize them
+	if decl, ok := node.(*js.VarDecl); ok {
+		if err := v.walkVarDecl(decl); err != nil {
+			v.err = err
+		}
+		return nil
+	}
+	// Functions are walked separately to declare their name and params
+	if fn, ok := node.(*js.FuncDecl); ok {
+		if err := v.walkFunc(fn.Name, &fn.Params, &fn.Body); err != nil {
+			v.err = err
+		}
+		return nil
+	}
+	if fn, ok := node.(*js.ArrowFunc); ok {
+		if err := v.walkFunc(nil, &fn.Params, &fn.Body); err != nil {
+			v.err = err
+		}
+		return nil
+	}
+	// Legacy reactive statements like `$: doubled = count * 2` declare the
+	// variables they assign to
+	if stmt, ok := node.(*js.LabelledStmt); ok && string(stmt.Label) == "$" {
+		if err := v.walkReactive(stmt); err != nil {
+			v.err = err
+		}
+		return nil
+	}
 	if err := v.enter(node); err != nil {
 		v.err = err
 		return nil
@@ -40,10 +96,6 @@ func (v *visitor) enter(n js.INode) error {
 		return v.enterImportStmt(n)
 	case *js.ExportStmt:
 		return v.enterExportStmt(n)
-	case *js.VarDecl:
-		return v.enterVarDecl(n)
-	case *js.FuncDecl:
-		return v.enterFuncDecl(n)
 	case *js.Var:
 		return v.enterVar(n)
 	default:
@@ -55,10 +107,6 @@ func (v *visitor) exit(n js.INode) error {
 	switch n := n.(type) {
 	case *js.ExportStmt:
 		return v.exitExportStmt(n)
-	case *js.VarDecl:
-		return v.exitVarDecl(n)
-	case *js.FuncDecl:
-		return v.exitFuncDecl(n)
 	default:
 		return nil
 	}
@@ -73,8 +121,19 @@ func (v *visitor) enterImportStmt(node *js.ImportStmt) error {
 			Default: true,
 		}
 	}
-	if len(node.List) > 0 {
-		return fmt.Errorf("parser: walk imported aliases not implemented yet")
+	// Named imports like { format as timeago } and namespace imports like
+	// * as path
+	for _, alias := range node.List {
+		name := string(alias.Binding)
+		if alias.Name != nil {
+			name = string(alias.Name)
+		}
+		sym := v.sc.Use(string(alias.Binding))
+		sym.Import = &scope.Import{
+			Path:    importPath,
+			Default: name == "default",
+			Name:    name,
+		}
 	}
 	return nil
 }
@@ -89,36 +148,190 @@ func (v *visitor) exitExportStmt(_ *js.ExportStmt) error {
 	return nil
 }
 
-func (v *visitor) enterVarDecl(node *js.VarDecl) error {
-	v.sc.IsDeclaration = true
-	if node.TokenType == js.VarToken || node.TokenType == js.LetToken {
-		v.sc.IsMutable = true
+func (v *visitor) walkVarDecl(node *js.VarDecl) error {
+	mutable := node.TokenType == js.VarToken || node.TokenType == js.LetToken
+	for _, element := range node.List {
+		r, _ := scope.RuneOf(element.Default)
+		if err := v.declareBinding(element.Binding, mutable, r); err != nil {
+			return err
+		}
+		if err := v.walk(element.Default); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (v *visitor) exitVarDecl(node *js.VarDecl) error {
-	v.sc.IsDeclaration = false
-	if node.TokenType == js.VarToken || node.TokenType == js.LetToken {
-		v.sc.IsMutable = false
+// declareBinding declares the variables within a binding pattern, recording
+// the rune that initialized them, like $state.
+func (v *visitor) declareBinding(binding js.IBinding, mutable bool, r scope.Rune) error {
+	switch b := binding.(type) {
+	case *js.Var:
+		isDeclaration, isMutable := v.sc.IsDeclaration, v.sc.IsMutable
+		v.sc.IsDeclaration = true
+		v.sc.IsMutable = isMutable || mutable
+		sym := v.sc.Use(string(b.Data))
+		v.sc.IsDeclaration, v.sc.IsMutable = isDeclaration, isMutable
+		if r != "" {
+			sym.Rune = r
+		}
+		return nil
+	case *js.BindingObject:
+		for _, item := range b.List {
+			if item.Key != nil && item.Key.IsComputed() {
+				if err := v.walk(item.Key.Computed); err != nil {
+					return err
+				}
+			}
+			if err := v.declareBindingElement(item.Value, mutable, r); err != nil {
+				return err
+			}
+		}
+		if b.Rest != nil {
+			return v.declareBinding(b.Rest, mutable, r)
+		}
+		return nil
+	case *js.BindingArray:
+		for _, element := range b.List {
+			if err := v.declareBindingElement(element, mutable, r); err != nil {
+				return err
+			}
+		}
+		if b.Rest != nil {
+			return v.declareBinding(b.Rest, mutable, r)
+		}
+		return nil
+	default:
+		return fmt.Errorf("parser: unexpected binding %T", binding)
 	}
-	return nil
+}
+
+func (v *visitor) declareBindingElement(element js.BindingElement, mutable bool, r scope.Rune) error {
+	if element.Binding == nil {
+		return nil
+	}
+	// Destructured props with a $bindable default are bindable props
+	if r == scope.RuneProps {
+		if bindable, ok := scope.RuneOf(element.Default); ok && bindable == scope.RuneBindable {
+			r = bindable
+		}
+	}
+	if err := v.declareBinding(element.Binding, mutable, r); err != nil {
+		return err
+	}
+	return v.walk(element.Default)
+}
+
+// walkReactive declares the variables assigned by a top-level reactive
+// statement that aren't declared already, then walks the statement
+func (v *visitor) walkReactive(stmt *js.LabelledStmt) error {
+	if v.sc.Parent() == nil {
+		for _, target := range assignedVars(stmt.Value) {
+			if sym, ok := v.sc.LookupByName(string(target.Data)); ok && sym.IsDeclared() {
+				continue
+			}
+			if err := v.declareBinding(target, true, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return v.walk(stmt.Value)
+}
+
+// assignedVars returns the variables assigned by an expression statement like
+// `a = 1` or `({ a, b } = obj)`
+func assignedVars(stmt js.IStmt) []*js.Var {
+	expr, ok := stmt.(*js.ExprStmt)
+	if !ok {
+		return nil
+	}
+	value := expr.Value
+	if group, ok := value.(*js.GroupExpr); ok {
+		value = group.X
+	}
+	assign, ok := value.(*js.BinaryExpr)
+	if !ok || assign.Op != js.EqToken {
+		return nil
+	}
+	return patternVars(assign.X)
+}
+
+// patternVars returns the variables within an assignment target
+func patternVars(expr js.IExpr) (vars []*js.Var) {
+	switch e := expr.(type) {
+	case *js.Var:
+		vars = append(vars, e)
+	case *js.ArrayExpr:
+		for _, element := range e.List {
+			vars = append(vars, patternVars(element.Value)...)
+		}
+	case *js.ObjectExpr:
+		for _, property := range e.List {
+			vars = append(vars, patternVars(property.Value)...)
+		}
+	case *js.BinaryExpr:
+		// Defaults like [a = 1]
+		if e.Op == js.EqToken {
+			vars = append(vars, patternVars(e.X)...)
+		}
+	}
+	return vars
+}
+
+// walk a child node with the same visitor
+func (v *visitor) walk(node js.INode) error {
+	if node == nil || v.err != nil {
+		return v.err
+	}
+	js.Walk(v, node)
+	return v.err
 }
 
 func (v *visitor) enterVar(node *js.Var) error {
 	name := string(node.Data)
+	// Runes are compiler instructions, not variables
+	if scope.IsRune(name) {
+		return nil
+	}
 	v.sc.Use(name)
 	return nil
 }
 
-func (v *visitor) enterFuncDecl(_ *js.FuncDecl) error {
-	v.sc.IsDeclaration = true
-	v.sc = v.sc.New()
-	return nil
-}
-
-func (v *visitor) exitFuncDecl(_ *js.FuncDecl) error {
-	v.sc.IsDeclaration = false
-	v.sc = v.sc.Parent()
+// walkFunc declares the function's name in the current scope, then walks the
+// params and body in a new scope
+func (v *visitor) walkFunc(name *js.Var, params *js.Params, body *js.BlockStmt) error {
+	if name != nil {
+		if err := v.declareBinding(name, false, ""); err != nil {
+			return err
+		}
+	}
+	parent := v.sc
+	child := parent.New()
+	// Exports don't apply to the function's body
+	child.IsExported = false
+	v.sc = child
+	defer func() { v.sc = parent }()
+	for _, param := range params.List {
+		if err := v.declareBindingElement(param, true, ""); err != nil {
+			return err
+		}
+	}
+	if params.Rest != nil {
+		if err := v.declareBinding(params.Rest, true, ""); err != nil {
+			return err
+		}
+	}
+	if err := v.walk(body); err != nil {
+		return err
+	}
+	// Variables the function doesn't declare are references to the outer scope
+	isExported, isDeclaration, isMutable := parent.IsExported, parent.IsDeclaration, parent.IsMutable
+	parent.IsExported, parent.IsDeclaration, parent.IsMutable = false, false, false
+	for _, sym := range child.Symbols() {
+		if !sym.IsDeclared() {
+			parent.Use(sym.Name)
+		}
+	}
+	parent.IsExported, parent.IsDeclaration, parent.IsMutable = isExported, isDeclaration, isMutable
 	return nil
 }
@@ -0,0 +1,290 @@
+// Package props extracts the props a component declares with `export let` or
+// `$props()`, so the data rendered from Go can be checked against them.
+// TypeScript types are used when they're present. Otherwise the types are
+// inferred from the default values.
+package props
+
+import (
+	"github.com/livebud/duo/internal/ast"
+	duojs "github.com/livebud/duo/internal/js"
+	"github.com/livebud/duo/internal/scope"
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// Kind of value a prop accepts
+type Kind string
+
+const (
+	Any      Kind = "any"
+	String   Kind = "string"
+	Number   Kind = "number"
+	Boolean  Kind = "boolean"
+	Array    Kind = "array"
+	Object   Kind = "object"
+	Function Kind = "function"
+)
+
+// Type of a prop
+type Type struct {
+	Kind     Kind
+	Name     string  // TypeScript name, like Story, if any
+	Elem     *Type   // Array elements or the values of a Record
+	Fields   []*Prop // Object fields, nil if unknown
+	Nullable bool    // Accepts null or undefined
+
+	null bool // null or undefined within a union
+}
+
+func (t *Type) String() string {
+	switch {
+	case t.Name != "":
+		return t.Name
+	case t.Kind == Array && t.Elem != nil && t.Elem.Kind != Any:
+		return t.Elem.String() + "[]"
+	default:
+		return string(t.Kind)
+	}
+}
+
+// Field returns the object's field by name
+func (t *Type) Field(name string) (*Prop, bool) {
+	for _, field := range t.Fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return nil, false
+}
+
+// Prop is a component prop or the field of an object type
+type Prop struct {
+	Name     string
+	Type     *Type
+	Optional bool     // Has a default value or is marked optional
+	Bindable bool     // Declared with $bindable()
+	Default  js.IExpr // Default value, nil if there isn't one
+}
+
+// Generic is a type parameter declared with `<script generics="...">`
+type Generic struct {
+	Name       string
+	Constraint *Type // The type it extends, any if unconstrained
+}
+
+// Schema of a component's props
+type Schema struct {
+	Path     string
+	Props    []*Prop
+	Rest     bool // Accepts other props, like `let { a, ...rest } = $props()`
+	Generics []*Generic
+}
+
+// Prop returns the prop by name
+func (s *Schema) Prop(name string) (*Prop, bool) {
+	for _, prop := range s.Props {
+		if prop.Name == name {
+			return prop, true
+		}
+	}
+	return nil, false
+}
+
+// Extract the schema from the component's script
+func Extract(path string, doc *ast.Document) *Schema {
+	schema := &Schema{Path: path}
+	script, ok := doc.Script()
+	if !ok || script.Program == nil {
+		return schema
+	}
+	decls := declare(script.Code, script.Generics)
+	for _, param := range decls.params {
+		schema.Generics = append(schema.Generics, &Generic{
+			Name:       param.name,
+			Constraint: decls.resolve(param.constraint),
+		})
+	}
+	for _, stmt := range script.Program.List {
+		switch s := stmt.(type) {
+		case *js.ExportStmt:
+			decl, ok := s.Decl.(*js.VarDecl)
+			if !ok {
+				continue
+			}
+			for _, element := range decl.List {
+				v, ok := element.Binding.(*js.Var)
+				if !ok {
+					continue
+				}
+				name := string(v.Data)
+				typ, ok := decls.vars[name]
+				if ok {
+					typ = decls.resolve(typ)
+				} else {
+					typ = infer(element.Default)
+				}
+				schema.add(&Prop{
+					Name:     name,
+					Type:     typ,
+					Optional: element.Default != nil,
+					Default:  element.Default,
+				})
+			}
+		case *js.VarDecl:
+			for _, element := range s.List {
+				if r, ok := scope.RuneOf(element.Default); ok && r == scope.RuneProps {
+					schema.destructure(element.Binding, decls.resolve(decls.props))
+				}
+			}
+		}
+	}
+	return schema
+}
+
+func (s *Schema) add(prop *Prop) {
+	if _, ok := s.Prop(prop.Name); ok {
+		return
+	}
+	s.Props = append(s.Props, prop)
+}
+
+// destructure adds the props destructured from $props(), typed by the
+// annotation if there is one
+func (s *Schema) destructure(binding js.IBinding, typ *Type) {
+	object, ok := binding.(*js.BindingObject)
+	if !ok {
+		// All the props are in a single variable like `let props = $props()`
+		s.Rest = typ == nil || typ.Fields == nil
+	} else {
+		for _, item := range object.List {
+			name, ok := keyName(item)
+			if !ok {
+				continue
+			}
+			prop := &Prop{Name: name}
+			value := item.Value.Default
+			if r, ok := scope.RuneOf(value); ok && r == scope.RuneBindable {
+				prop.Bindable = true
+				prop.Optional = true
+				value = firstArg(value.(*js.CallExpr))
+			}
+			prop.Optional = prop.Optional || value != nil
+			prop.Default = value
+			if field, ok := typeField(typ, name); ok {
+				prop.Type = field.Type
+				prop.Optional = prop.Optional || field.Optional
+			} else {
+				prop.Type = infer(value)
+			}
+			s.add(prop)
+		}
+		s.Rest = object.Rest != nil && (typ == nil || typ.Fields == nil)
+	}
+	if typ == nil {
+		return
+	}
+	// Declared props that aren't destructured are still accepted
+	for _, field := range typ.Fields {
+		s.add(field)
+	}
+	// Index signatures accept any other props
+	if typ.Elem != nil {
+		s.Rest = true
+	}
+}
+
+func typeField(typ *Type, name string) (*Prop, bool) {
+	if typ == nil {
+		return nil, false
+	}
+	return typ.Field(name)
+}
+
+// keyName returns the prop name of a destructured item
+func keyName(item js.BindingObjectItem) (string, bool) {
+	if item.Key != nil && !item.Key.IsComputed() && item.Key.IsSet() {
+		name := string(item.Key.Literal.Data)
+		if unquoted, err := duojs.Unquote(name); err == nil {
+			return unquoted, true
+		}
+		return name, true
+	}
+	if v, ok := item.Value.Binding.(*js.Var); ok {
+		return string(v.Data), true
+	}
+	return "", false
+}
+
+func firstArg(call *js.CallExpr) js.IExpr {
+	if len(call.Args.List) == 0 {
+		return nil
+	}
+	return call.Args.List[0].Value
+}
+
+// infer the type from a default value
+func infer(value js.IExpr) *Type {
+	switch v := value.(type) {
+	case *js.LiteralExpr:
+		switch v.TokenType {
+		case js.StringToken:
+			return &Type{Kind: String}
+		case js.TrueToken, js.FalseToken:
+			return &Type{Kind: Boolean}
+		case js.DecimalToken, js.BinaryToken, js.OctalToken, js.HexadecimalToken, js.BigIntToken:
+			return &Type{Kind: Number}
+		case js.NullToken:
+			return &Type{Kind: Any, Nullable: true}
+		}
+	case *js.TemplateExpr:
+		if v.Tag == nil {
+			return &Type{Kind: String}
+		}
+	case *js.UnaryExpr:
+		switch v.Op {
+		case js.NegToken, js.PosToken:
+			return infer(v.X)
+		case js.NotToken:
+			return &Type{Kind: Boolean}
+		}
+	case *js.GroupExpr:
+		return infer(v.X)
+	case *js.ArrayExpr:
+		var elems []*Type
+		for _, item := range v.List {
+			if item.Spread {
+				return &Type{Kind: Array, Elem: &Type{Kind: Any}}
+			}
+			elems = append(elems, infer(item.Value))
+		}
+		elem := union(elems)
+		if len(elems) == 0 || elem.Nullable {
+			elem = &Type{Kind: Any}
+		}
+		return &Type{Kind: Array, Elem: elem}
+	case *js.ObjectExpr:
+		// The fields of a default object are examples, so they're optional
+		typ := &Type{Kind: Object, Fields: []*Prop{}}
+		for _, property := range v.List {
+			if property.Spread || property.Name == nil || property.Name.IsComputed() {
+				continue
+			}
+			name := string(property.Name.Literal.Data)
+			if unquoted, err := duojs.Unquote(name); err == nil {
+				name = unquoted
+			}
+			typ.Fields = append(typ.Fields, &Prop{
+				Name:     name,
+				Type:     infer(property.Value),
+				Optional: true,
+			})
+		}
+		return typ
+	case *js.ArrowFunc, *js.FuncDecl:
+		return &Type{Kind: Function}
+	case *js.CallExpr:
+		if r, ok := scope.RuneOf(v); ok && r == scope.RuneBindable {
+			return infer(firstArg(v))
+		}
+	}
+	return &Type{Kind: Any}
+}
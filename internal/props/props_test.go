@@ -0,0 +1,233 @@
+package props_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livebud/duo/internal/parser"
+	"github.com/livebud/duo/internal/props"
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+)
+
+func extract(t *testing.T, input string) *props.Schema {
+	t.Helper()
+	doc, err := parser.Parse("input.svelte", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return props.Extract("input.svelte", doc)
+}
+
+// equal prints the schema, one prop per line
+func equal(t *testing.T, input, expected string) {
+	t.Helper()
+	t.Run(input, func(t *testing.T) {
+		t.Helper()
+		schema := extract(t, input)
+		lines := make([]string, len(schema.Props))
+		for i, prop := range schema.Props {
+			lines[i] = format(prop)
+		}
+		if schema.Rest {
+			lines = append(lines, "...rest")
+		}
+		diff.TestString(t, strings.Join(lines, "\n"), expected)
+	})
+}
+
+func format(prop *props.Prop) string {
+	s := prop.Name
+	if prop.Optional {
+		s += "?"
+	}
+	s += ": " + formatType(prop.Type)
+	if prop.Bindable {
+		s += " bindable"
+	}
+	return s
+}
+
+func formatType(typ *props.Type) string {
+	s := typ.String()
+	if typ.Kind == props.Object && typ.Fields != nil {
+		fields := make([]string, len(typ.Fields))
+		for i, field := range typ.Fields {
+			fields[i] = format(field)
+		}
+		s += "{" + strings.Join(fields, ", ") + "}"
+	}
+	if typ.Nullable {
+		s += " | null"
+	}
+	return s
+}
+
+func TestExportLet(t *testing.T) {
+	equal(t, `<script>export let name; export let count = 0; export let items = []; export let story = {}; let local = 1</script>`, "name: any\ncount?: number\nitems?: array\nstory?: object{}")
+	equal(t, `<script>export let title = "hi"; export let on = true; export let tags = ["a", "b"]</script>`, "title?: string\non?: boolean\ntags?: string[]")
+	equal(t, `<script lang="ts">export let name: string; export let count: number | undefined = 0</script>`, "name: string\ncount?: number | null")
+	equal(t, `<script>export let story = { title: "", points: 0 }</script>`, "story?: object{title?: string, points?: number}")
+}
+
+func TestRunes(t *testing.T) {
+	equal(t, `<script>let { name, count = 0, value = $bindable("") } = $props()</script>`, "name: any\ncount?: number\nvalue?: string bindable")
+	equal(t, `<script>let { a, ...rest } = $props()</script>`, "a: any\n...rest")
+	equal(t, `<script>let props = $props()</script>`, "...rest")
+	equal(t, `<script>let { "aria-label": label } = $props()</script>`, "aria-label: any")
+}
+
+func TestTypeScript(t *testing.T) {
+	equal(t, `<script lang="ts">
+		interface Props {
+			name: string
+			count?: number
+			tags: string[]
+			story: Story
+			onclick: (e: MouseEvent) => void
+			render(): string
+			items: Array<{ id: number }>
+			nested: Array<Array<string>>
+			kind: 'a' | 'b'
+			owner: User | null
+			meta: Record<string, number>
+			created: Date
+		}
+		type Story = { title: string; url?: string }
+		let { name, count = 1, ...rest }: Props = $props()
+		function f(a: number): number { let x: string = "" ; return a }
+	</script>`, strings.Join([]string{
+		"name: string",
+		"count?: number",
+		"tags: string[]",
+		"story: Story{title: string, url?: string}",
+		"onclick: function",
+		"render: function",
+		"items: object[]",
+		"nested: string[][]",
+		"kind: string",
+		"owner: User | null",
+		"meta: object",
+		"created: Date",
+	}, "\n"))
+	equal(t, `<script lang="ts">let { a, b = 2 }: { a: boolean; b?: number } = $props()</script>`, "a: boolean\nb?: number")
+	equal(t, `<script lang="ts">let { a }: { a: string; [key: string]: unknown } = $props()</script>`, "a: string\n...rest")
+}
+
+func TestGenerics(t *testing.T) {
+	equal(t, `<script lang="ts" generics="T extends Item, K extends keyof T">
+		interface Item { id: number }
+		let { items, key, selected, onselect }: { items: T[]; key: K; selected: T | null; onselect: (item: T) => void } = $props()
+	</script>`, strings.Join([]string{
+		"items: Item[]",
+		"key: string",
+		"selected: Item{id: number} | null",
+		"onselect: function",
+	}, "\n"))
+	equal(t, `<script lang="ts" generics="T">export let value: T;</script>`, "value: any")
+	equal(t, `<script lang="ts" generics="T extends string = 'a', U = number">let { a, b }: { a: T; b?: U[] } = $props()</script>`, "a: string\nb?: array")
+	is := is.New(t)
+	schema := extract(t, `<script lang="ts" generics="T extends Item, U">interface Item { id: string }; let { item }: { item: T } = $props()</script>`)
+	is.Equal(len(schema.Generics), 2)
+	is.Equal(schema.Generics[0].Name, "T")
+	is.Equal(formatType(schema.Generics[0].Constraint), "Item{id: string}")
+	is.Equal(schema.Generics[1].Name, "U")
+	is.Equal(formatType(schema.Generics[1].Constraint), "any")
+}
+
+type Story struct {
+	Title     string
+	URL       string
+	Points    int
+	CreatedAt time.Time
+}
+
+func TestCheck(t *testing.T) {
+	is := is.New(t)
+	schema := extract(t, `<script lang="ts">
+		interface Props {
+			stories: { title: string; points: number }[]
+			page?: number
+			user: { name: string } | null
+		}
+		let { stories, page = 1, user }: Props = $props()
+	</script>`)
+	is.NoErr(schema.Check(reflect.TypeOf(struct {
+		Stories []*Story
+		Page    int
+		User    *struct{ Name string }
+	}{})))
+	// Optional props can be left out and fields can be renamed with json tags
+	is.NoErr(schema.Check(reflect.TypeOf(struct {
+		List []Story `json:"stories"`
+		User any
+	}{})))
+	is.NoErr(schema.Check(reflect.TypeOf(map[string]any{})))
+	err := schema.Check(reflect.TypeOf(struct {
+		Stories []struct{ Title int }
+		Extra   string
+		secret  string
+	}{}))
+	is.True(err != nil)
+	diff.TestString(t, err.Error(), strings.Join([]string{
+		`props: input.svelte: prop "stories[].title" should be a string, not int`,
+		`props: input.svelte: missing prop "stories[].points"`,
+		`props: input.svelte: missing prop "user"`,
+		`props: input.svelte: unknown prop "extra" from field Extra`,
+	}, "\n"))
+	err = schema.Check(reflect.TypeOf(struct {
+		Stories string
+		Page    bool
+		User    string
+	}{}))
+	is.True(err != nil)
+	diff.TestString(t, err.Error(), strings.Join([]string{
+		`props: input.svelte: prop "stories" should be an array, not string`,
+		`props: input.svelte: prop "page" should be a number, not bool`,
+		`props: input.svelte: prop "user" should be an object, not string`,
+	}, "\n"))
+	err = schema.Check(reflect.TypeOf(""))
+	is.True(err != nil)
+	is.Equal(err.Error(), "props: input.svelte: expected a map or struct, got string")
+}
+
+func TestValidate(t *testing.T) {
+	is := is.New(t)
+	schema := extract(t, `<script>
+		export let story = { title: "", points: 0 }
+		export let count
+		export let label = "x"
+	</script>`)
+	is.NoErr(schema.Validate(reflect.ValueOf(map[string]any{
+		"story": &Story{Title: "a", Points: 1, CreatedAt: time.Now()},
+		"count": 3,
+	})))
+	err := schema.Validate(reflect.ValueOf(map[string]any{
+		"story": map[string]any{"title": 1},
+		"label": 2,
+		"other": true,
+	}))
+	is.True(err != nil)
+	diff.TestString(t, err.Error(), strings.Join([]string{
+		`props: input.svelte: prop "story.title" should be a string, not int`,
+		`props: input.svelte: missing prop "count"`,
+		`props: input.svelte: prop "label" should be a string, not int`,
+		`props: input.svelte: unknown prop "other"`,
+	}, "\n"))
+	err = schema.Validate(reflect.ValueOf(nil))
+	is.True(err != nil)
+	is.Equal(err.Error(), `props: input.svelte: missing prop "count"`)
+	// Structs are validated by their values
+	err = schema.Validate(reflect.ValueOf(struct {
+		Story *Story
+		Count any
+	}{Story: &Story{}}))
+	is.True(err != nil)
+	is.Equal(err.Error(), `props: input.svelte: missing prop "count"`)
+	is.NoErr(schema.Validate(reflect.ValueOf(struct {
+		Story *Story
+		Count any
+	}{Count: 1})))
+}
@@ -0,0 +1,477 @@
+package props
+
+import (
+	"strings"
+
+	"github.com/livebud/duo/internal/scope"
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// token is a lexed TypeScript token without whitespace or comments
+type token struct {
+	tt   js.TokenType
+	text string
+}
+
+// lex the TypeScript source. Types are stripped before the script is parsed,
+// so they're read from the tokens instead.
+func lex(code string) (tokens []token) {
+	l := js.NewLexer(parse.NewInputString(code))
+	for {
+		tt, data := l.Next()
+		switch tt {
+		case js.ErrorToken:
+			return tokens
+		case js.WhitespaceToken, js.LineTerminatorToken, js.CommentToken, js.CommentLineTerminatorToken:
+			continue
+		case js.GtGtToken, js.GtGtGtToken:
+			// Closes nested generics like Array<Array<string>>
+			for range data {
+				tokens = append(tokens, token{js.GtToken, ">"})
+			}
+			continue
+		}
+		tokens = append(tokens, token{tt, string(data)})
+	}
+}
+
+// declarations are the types read from a component's script
+type declarations struct {
+	named  map[string]*Type // interfaces and type aliases
+	vars   map[string]*Type // annotated variables, like `export let name: string`
+	props  *Type            // the annotation on `let { ... }: Props = $props()`
+	params []*param         // type parameters from the generics attribute
+}
+
+// param is a type parameter, like `T extends Item`
+type param struct {
+	name       string
+	constraint *Type
+}
+
+// declare reads the type parameters, top-level type declarations and
+// annotations
+func declare(code, generics string) *declarations {
+	d := &declarations{
+		named: map[string]*Type{},
+		vars:  map[string]*Type{},
+	}
+	d.generics(&reader{tokens: lex(generics)})
+	r := &reader{tokens: lex(code)}
+	for !r.done() {
+		switch {
+		case r.is(js.InterfaceToken) && r.peek(1).tt == js.IdentifierToken:
+			r.next()
+			name := r.next().text
+			// Ignore type parameters and extended interfaces
+			for !r.done() && !r.is(js.OpenBraceToken) {
+				r.next()
+			}
+			d.named[name] = r.object()
+		case r.isWord("type") && r.peek(1).tt == js.IdentifierToken && r.peek(2).tt == js.EqToken:
+			r.next()
+			name := r.next().text
+			r.next()
+			d.named[name] = r.union()
+		case r.is(js.LetToken) || r.is(js.ConstToken) || r.is(js.VarToken):
+			r.next()
+			d.variable(r)
+		case r.is(js.OpenBraceToken) || r.is(js.OpenParenToken) || r.is(js.OpenBracketToken):
+			// Only top-level declarations are props
+			r.skipGroup()
+		default:
+			r.next()
+		}
+	}
+	return d
+}
+
+// generics reads type parameters, like `T extends Item, K extends keyof T`.
+// Defaults are ignored, since the props can be any type within the constraint.
+func (d *declarations) generics(r *reader) {
+	for !r.done() {
+		if r.is(js.ConstToken) {
+			r.next()
+		}
+		if !r.is(js.IdentifierToken) {
+			r.next()
+			continue
+		}
+		p := &param{name: r.next().text, constraint: &Type{Kind: Any}}
+		if r.accept(js.ExtendsToken) {
+			p.constraint = r.union()
+		}
+		d.params = append(d.params, p)
+		// Skip the rest of the parameter, like its default
+		for !r.done() && !r.accept(js.CommaToken) {
+			if _, ok := closers[r.peek(0).tt]; ok {
+				r.skipGroup()
+				continue
+			}
+			r.next()
+		}
+	}
+}
+
+// param returns the type parameter by name
+func (d *declarations) param(name string) (*param, bool) {
+	for _, p := range d.params {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// variable reads the annotation of a declared variable
+func (d *declarations) variable(r *reader) {
+	if r.is(js.IdentifierToken) {
+		name := r.next().text
+		if r.accept(js.ColonToken) {
+			d.vars[name] = r.union()
+		}
+		return
+	}
+	if !r.is(js.OpenBraceToken) {
+		return
+	}
+	r.skipGroup()
+	if !r.accept(js.ColonToken) {
+		return
+	}
+	typ := r.union()
+	if r.accept(js.EqToken) && r.isWord(string(scope.RuneProps)) {
+		d.props = typ
+	}
+}
+
+// resolve named types, leaving unknown names as any. Type parameters resolve
+// to their constraint.
+func (d *declarations) resolve(typ *Type) *Type {
+	return d.resolveSeen(typ, map[string]bool{})
+}
+
+func (d *declarations) resolveSeen(typ *Type, seen map[string]bool) *Type {
+	if typ == nil {
+		return nil
+	}
+	if typ.Kind == Any && typ.Name != "" && !seen[typ.Name] {
+		if p, ok := d.param(typ.Name); ok {
+			seen[typ.Name] = true
+			resolved := *d.resolveSeen(p.constraint, seen)
+			resolved.Nullable = resolved.Nullable || typ.Nullable
+			delete(seen, typ.Name)
+			return &resolved
+		}
+		named, ok := d.named[typ.Name]
+		if !ok {
+			return typ
+		}
+		seen[typ.Name] = true
+		resolved := *d.resolveSeen(named, seen)
+		resolved.Name = typ.Name
+		resolved.Nullable = resolved.Nullable || typ.Nullable
+		delete(seen, typ.Name)
+		return &resolved
+	}
+	resolved := *typ
+	resolved.Elem = d.resolveSeen(typ.Elem, seen)
+	if typ.Fields != nil {
+		resolved.Fields = make([]*Prop, len(typ.Fields))
+		for i, field := range typ.Fields {
+			f := *field
+			f.Type = d.resolveSeen(field.Type, seen)
+			resolved.Fields[i] = &f
+		}
+	}
+	return &resolved
+}
+
+// reader reads types from the tokens
+type reader struct {
+	tokens []token
+	pos    int
+}
+
+func (r *reader) done() bool {
+	return r.pos >= len(r.tokens)
+}
+
+func (r *reader) peek(n int) token {
+	if r.pos+n >= len(r.tokens) {
+		return token{tt: js.ErrorToken}
+	}
+	return r.tokens[r.pos+n]
+}
+
+func (r *reader) next() token {
+	t := r.peek(0)
+	r.pos++
+	return t
+}
+
+func (r *reader) is(tt js.TokenType) bool {
+	return r.peek(0).tt == tt
+}
+
+func (r *reader) isWord(word string) bool {
+	t := r.peek(0)
+	return t.tt == js.IdentifierToken && t.text == word
+}
+
+func (r *reader) accept(tt js.TokenType) bool {
+	if r.is(tt) {
+		r.pos++
+		return true
+	}
+	return false
+}
+
+var closers = map[js.TokenType]js.TokenType{
+	js.OpenBraceToken:   js.CloseBraceToken,
+	js.OpenParenToken:   js.CloseParenToken,
+	js.OpenBracketToken: js.CloseBracketToken,
+	js.LtToken:          js.GtToken,
+}
+
+// skipGroup skips past the bracketed group starting at the current token.
+// Angle brackets are only matched within type parameters, since they're
+// comparisons elsewhere.
+func (r *reader) skipGroup() {
+	open := r.next().tt
+	angles := open == js.LtToken
+	stack := []js.TokenType{closers[open]}
+	for !r.done() && len(stack) > 0 {
+		t := r.next()
+		if closer, ok := closers[t.tt]; ok && (t.tt != js.LtToken || angles) {
+			stack = append(stack, closer)
+		} else if t.tt == stack[len(stack)-1] {
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+// union reads a type, combining the members of a union
+func (r *reader) union() *Type {
+	r.accept(js.BitOrToken)
+	var members []*Type
+	for {
+		members = append(members, r.array())
+		if !r.accept(js.BitOrToken) {
+			break
+		}
+	}
+	// Intersections are treated as the first type
+	for r.accept(js.BitAndToken) {
+		r.array()
+	}
+	return union(members)
+}
+
+// union of the members. Nullable members make the type nullable, and members
+// of different kinds can be anything.
+func union(members []*Type) *Type {
+	var result *Type
+	nullable := false
+	for _, member := range members {
+		if member.null {
+			nullable = true
+			continue
+		}
+		if result == nil {
+			result = member
+			continue
+		}
+		if result.Kind != member.Kind || result.Name != member.Name {
+			result = &Type{Kind: Any}
+		}
+	}
+	if result == nil {
+		return &Type{Kind: Any, Nullable: true}
+	}
+	if nullable {
+		t := *result
+		t.Nullable = true
+		return &t
+	}
+	return result
+}
+
+// array reads a type with optional [] suffixes
+func (r *reader) array() *Type {
+	typ := r.primary()
+	for r.is(js.OpenBracketToken) && r.peek(1).tt == js.CloseBracketToken {
+		r.pos += 2
+		typ = &Type{Kind: Array, Elem: typ}
+	}
+	return typ
+}
+
+func (r *reader) primary() *Type {
+	t := r.peek(0)
+	switch t.tt {
+	case js.OpenBraceToken:
+		return r.object()
+	case js.OpenBracketToken:
+		// Tuples are arrays of anything
+		r.skipGroup()
+		return &Type{Kind: Array, Elem: &Type{Kind: Any}}
+	case js.OpenParenToken, js.LtToken:
+		// Function types, like (e: Event) => void, or a parenthesized type
+		start := r.pos
+		r.skipGroup()
+		if t.tt == js.LtToken && r.is(js.OpenParenToken) {
+			r.skipGroup()
+		}
+		if r.accept(js.ArrowToken) {
+			r.union()
+			return &Type{Kind: Function}
+		}
+		if t.tt == js.LtToken {
+			return &Type{Kind: Any}
+		}
+		end := r.pos
+		r.pos = start + 1
+		typ := r.union()
+		r.pos = end
+		return typ
+	case js.StringToken, js.TemplateToken:
+		r.next()
+		return &Type{Kind: String}
+	case js.DecimalToken, js.BinaryToken, js.OctalToken, js.HexadecimalToken, js.BigIntToken:
+		r.next()
+		return &Type{Kind: Number}
+	case js.SubToken:
+		// Negative number literals
+		r.next()
+		return r.primary()
+	case js.TrueToken, js.FalseToken:
+		r.next()
+		return &Type{Kind: Boolean}
+	case js.NullToken, js.VoidToken:
+		r.next()
+		return &Type{Kind: Any, null: true}
+	case js.TypeofToken:
+		r.next()
+		r.reference()
+		return &Type{Kind: Any}
+	case js.IdentifierToken:
+		if t.text == "keyof" {
+			r.next()
+			r.primary()
+			return &Type{Kind: String}
+		}
+		return r.named()
+	case js.NewToken:
+		r.next()
+		return r.primary()
+	}
+	r.next()
+	return &Type{Kind: Any}
+}
+
+// reference reads a dotted name, like App.Locals
+func (r *reader) reference() string {
+	name := r.next().text
+	for r.is(js.DotToken) && r.peek(1).tt == js.IdentifierToken {
+		r.next()
+		name += "." + r.next().text
+	}
+	return name
+}
+
+// named reads a type reference, including its type arguments
+func (r *reader) named() *Type {
+	name := r.reference()
+	var args []*Type
+	if r.accept(js.LtToken) {
+		for !r.done() && !r.accept(js.GtToken) {
+			args = append(args, r.union())
+			r.accept(js.CommaToken)
+		}
+	}
+	arg := func(i int) *Type {
+		if i < len(args) {
+			return args[i]
+		}
+		return &Type{Kind: Any}
+	}
+	switch name {
+	case "string":
+		return &Type{Kind: String}
+	case "number", "bigint":
+		return &Type{Kind: Number}
+	case "boolean":
+		return &Type{Kind: Boolean}
+	case "undefined", "never":
+		return &Type{Kind: Any, null: true}
+	case "any", "unknown", "object":
+		return &Type{Kind: Any}
+	case "Function", "Snippet":
+		return &Type{Kind: Function}
+	case "Array", "ReadonlyArray", "Set":
+		return &Type{Kind: Array, Elem: arg(0)}
+	case "Record", "Map":
+		return &Type{Kind: Object, Elem: arg(1)}
+	case "Partial", "Readonly", "Required", "NonNullable":
+		return arg(0)
+	}
+	// Resolved later, since they can be declared after they're used
+	return &Type{Kind: Any, Name: name}
+}
+
+// object reads an object type's members
+func (r *reader) object() *Type {
+	typ := &Type{Kind: Object, Fields: []*Prop{}}
+	if !r.accept(js.OpenBraceToken) {
+		return &Type{Kind: Any}
+	}
+	for !r.done() && !r.accept(js.CloseBraceToken) {
+		if r.isWord("readonly") && isName(r.peek(1)) {
+			r.next()
+		}
+		t := r.peek(0)
+		switch {
+		case t.tt == js.OpenBracketToken:
+			// Index signatures, like [key: string]: number
+			r.skipGroup()
+			if r.accept(js.ColonToken) {
+				typ.Elem = r.union()
+			}
+		case t.tt == js.StringToken || isName(t):
+			r.next()
+			field := &Prop{Name: strings.Trim(t.text, `"'`)}
+			field.Optional = r.accept(js.QuestionToken)
+			if r.is(js.OpenParenToken) || r.is(js.LtToken) {
+				// Method signatures
+				r.skipGroup()
+				if r.is(js.OpenParenToken) {
+					r.skipGroup()
+				}
+				if r.accept(js.ColonToken) {
+					r.union()
+				}
+				field.Type = &Type{Kind: Function}
+			} else if r.accept(js.ColonToken) {
+				field.Type = r.union()
+			} else {
+				field.Type = &Type{Kind: Any}
+			}
+			typ.Fields = append(typ.Fields, field)
+		default:
+			r.next()
+		}
+		for r.accept(js.SemicolonToken) || r.accept(js.CommaToken) {
+		}
+	}
+	return typ
+}
+
+// isName is true for identifiers and keywords, which can both be member names
+func isName(t token) bool {
+	if t.tt == js.IdentifierToken {
+		return true
+	}
+	return t.text != "" && (t.text[0] >= 'a' && t.text[0] <= 'z')
+}
@@ -0,0 +1,324 @@
+package props
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Validate the props passed to the component, either a map with string keys
+// or a struct. Struct fields match props by their Go name, json tag or
+// case-insensitively by name.
+func (s *Schema) Validate(v reflect.Value) error {
+	c := &checker{path: s.Path}
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.Invalid:
+		for _, prop := range s.Props {
+			c.value(prop.Name, prop, v)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("props: %s: expected props with string keys, got %s", s.Path, v.Type())
+		}
+		for _, prop := range s.Props {
+			c.value(prop.Name, prop, v.MapIndex(reflect.ValueOf(prop.Name).Convert(v.Type().Key())))
+		}
+		if !s.Rest {
+			for _, key := range v.MapKeys() {
+				if _, ok := s.Prop(key.String()); !ok {
+					c.errorf("unknown prop %q", key.String())
+				}
+			}
+		}
+	case reflect.Struct:
+		for _, prop := range s.Props {
+			c.value(prop.Name, prop, Field(v, prop.Name))
+		}
+		if !s.Rest {
+			c.unknown(v.Type(), s.Props)
+		}
+	default:
+		return fmt.Errorf("props: %s: expected a map or struct, got %s", s.Path, v.Type())
+	}
+	return c.err()
+}
+
+// Check that values of the Go type satisfy the component's props. The type
+// must be a struct or a map with string keys.
+func (s *Schema) Check(t reflect.Type) error {
+	c := &checker{path: s.Path}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return fmt.Errorf("props: %s: expected props with string keys, got %s", s.Path, t)
+		}
+		for _, prop := range s.Props {
+			c.typeOf(prop.Name, prop.Type, t.Elem())
+		}
+	case reflect.Struct:
+		for _, prop := range s.Props {
+			field, ok := fieldOf(t, prop.Name)
+			if !ok {
+				if !prop.Optional {
+					c.errorf("missing prop %q", prop.Name)
+				}
+				continue
+			}
+			c.typeOf(prop.Name, prop.Type, field.Type)
+		}
+		if !s.Rest {
+			c.unknown(t, s.Props)
+		}
+	default:
+		return fmt.Errorf("props: %s: expected a map or struct, got %s", s.Path, t)
+	}
+	return c.err()
+}
+
+// Field returns the struct field for the prop, or an invalid value
+func Field(v reflect.Value, name string) reflect.Value {
+	field, ok := fieldOf(v.Type(), name)
+	if !ok {
+		return reflect.Value{}
+	}
+	return v.FieldByIndex(field.Index)
+}
+
+// fieldOf finds the struct field for the prop by its Go name, then by its json
+// tag, then case-insensitively by name like encoding/json
+func fieldOf(t reflect.Type, name string) (reflect.StructField, bool) {
+	fields := reflect.VisibleFields(t)
+	var tagged, folded reflect.StructField
+	hasTagged, hasFolded := false, false
+	for _, field := range fields {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		tag, ok := jsonName(field)
+		switch {
+		case tag == "-":
+			continue
+		case field.Name == name:
+			return field, true
+		case ok:
+			if tag == name && !hasTagged {
+				tagged, hasTagged = field, true
+			}
+		case strings.EqualFold(field.Name, name) && !hasFolded:
+			folded, hasFolded = field, true
+		}
+	}
+	if hasTagged {
+		return tagged, true
+	}
+	return folded, hasFolded
+}
+
+// jsonName returns the name in the field's json tag
+func jsonName(field reflect.StructField) (string, bool) {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name, name != ""
+}
+
+type checker struct {
+	path string
+	errs []error
+}
+
+func (c *checker) errorf(format string, args ...interface{}) {
+	c.errs = append(c.errs, fmt.Errorf("props: %s: %s", c.path, fmt.Sprintf(format, args...)))
+}
+
+func (c *checker) err() error {
+	return errors.Join(c.errs...)
+}
+
+// unknown reports struct fields that aren't props
+func (c *checker) unknown(t reflect.Type, props []*Prop) {
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		if tag, _ := jsonName(field); tag == "-" {
+			continue
+		}
+		matched := false
+		for _, prop := range props {
+			if f, ok := fieldOf(t, prop.Name); ok && f.Name == field.Name {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			c.errorf("unknown prop %q from field %s", fieldProp(field), field.Name)
+		}
+	}
+}
+
+// fieldProp is the prop name the field would have
+func fieldProp(field reflect.StructField) string {
+	if name, ok := jsonName(field); ok {
+		return name
+	}
+	return strings.ToLower(field.Name[:1]) + field.Name[1:]
+}
+
+// value checks the value passed for the prop
+func (c *checker) value(name string, prop *Prop, v reflect.Value) {
+	v = indirect(v)
+	if !v.IsValid() {
+		if !prop.Optional && !prop.Type.Nullable {
+			c.errorf("missing prop %q", name)
+		}
+		return
+	}
+	c.valueOf(name, prop.Type, v)
+}
+
+func (c *checker) valueOf(name string, typ *Type, v reflect.Value) {
+	v = indirect(v)
+	if !v.IsValid() || typ.Kind == Any {
+		return
+	}
+	if !accepts(typ, v.Type()) {
+		c.errorf("prop %q should be %s, not %s", name, article(typ), v.Type())
+		return
+	}
+	switch typ.Kind {
+	case Array:
+		if typ.Elem == nil {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			c.valueOf(fmt.Sprintf("%s[%d]", name, i), typ.Elem, v.Index(i))
+		}
+	case Object:
+		switch v.Kind() {
+		case reflect.Map:
+			for _, field := range typ.Fields {
+				value := indirect(v.MapIndex(reflect.ValueOf(field.Name).Convert(v.Type().Key())))
+				if !value.IsValid() {
+					if !field.Optional && !field.Type.Nullable {
+						c.errorf("missing prop %q", name+"."+field.Name)
+					}
+					continue
+				}
+				c.valueOf(name+"."+field.Name, field.Type, value)
+			}
+		case reflect.Struct:
+			for _, field := range typ.Fields {
+				value := indirect(Field(v, field.Name))
+				if !value.IsValid() {
+					if !field.Optional && !field.Type.Nullable {
+						c.errorf("missing prop %q", name+"."+field.Name)
+					}
+					continue
+				}
+				c.valueOf(name+"."+field.Name, field.Type, value)
+			}
+		}
+	}
+}
+
+// typeOf checks a Go type against the prop's type
+func (c *checker) typeOf(name string, typ *Type, t reflect.Type) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Interfaces could hold anything
+	if t.Kind() == reflect.Interface || typ.Kind == Any {
+		return
+	}
+	if !accepts(typ, t) {
+		c.errorf("prop %q should be %s, not %s", name, article(typ), t)
+		return
+	}
+	switch typ.Kind {
+	case Array:
+		if typ.Elem != nil {
+			c.typeOf(name+"[]", typ.Elem, t.Elem())
+		}
+	case Object:
+		if t.Kind() == reflect.Map {
+			if typ.Elem != nil {
+				c.typeOf(name+"[]", typ.Elem, t.Elem())
+			}
+			return
+		}
+		for _, field := range typ.Fields {
+			f, ok := fieldOf(t, field.Name)
+			if !ok {
+				if !field.Optional && !field.Type.Nullable {
+					c.errorf("missing prop %q", name+"."+field.Name)
+				}
+				continue
+			}
+			c.typeOf(name+"."+field.Name, field.Type, f.Type)
+		}
+	}
+}
+
+// accepts returns true if values of the Go type are the kind of the prop
+func accepts(typ *Type, t reflect.Type) bool {
+	switch typ.Kind {
+	case String:
+		// Values like time.Time are rendered as strings
+		return t.Kind() == reflect.String || t.Implements(stringerType) || t.Implements(textMarshalerType) ||
+			reflect.PointerTo(t).Implements(stringerType) || reflect.PointerTo(t).Implements(textMarshalerType)
+	case Number:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return true
+		}
+		return false
+	case Boolean:
+		return t.Kind() == reflect.Bool
+	case Array:
+		return t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+	case Object:
+		return t.Kind() == reflect.Struct || t.Kind() == reflect.Map && t.Key().Kind() == reflect.String
+	case Function:
+		return t.Kind() == reflect.Func
+	default:
+		return true
+	}
+}
+
+func article(typ *Type) string {
+	s := typ.String()
+	if typ.Name == "" && typ.Kind == Array {
+		return "an array"
+	}
+	if strings.ContainsAny(s[:1], "aeiouAEIOU") {
+		return "an " + s
+	}
+	return "a " + s
+}
+
+// indirect unwraps interfaces and pointers, returning an invalid value for nil
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
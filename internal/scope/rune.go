@@ -0,0 +1,85 @@
+package scope
+
+import (
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// Rune is a Svelte 5 compiler instruction like $state or $derived.by
+type Rune string
+
+const (
+	RuneProps         Rune = "$props"
+	RuneBindable      Rune = "$bindable"
+	RuneState         Rune = "$state"
+	RuneStateRaw      Rune = "$state.raw"
+	RuneStateSnapshot Rune = "$state.snapshot"
+	RuneDerived       Rune = "$derived"
+	RuneDerivedBy     Rune = "$derived.by"
+	RuneEffect        Rune = "$effect"
+	RuneEffectPre     Rune = "$effect.pre"
+	RuneEffectRoot    Rune = "$effect.root"
+	RuneEffectTrack   Rune = "$effect.tracking"
+	RuneInspect       Rune = "$inspect"
+	RuneHost          Rune = "$host"
+)
+
+var runes = map[Rune]bool{
+	RuneProps:         true,
+	RuneBindable:      true,
+	RuneState:         true,
+	RuneStateRaw:      true,
+	RuneStateSnapshot: true,
+	RuneDerived:       true,
+	RuneDerivedBy:     true,
+	RuneEffect:        true,
+	RuneEffectPre:     true,
+	RuneEffectRoot:    true,
+	RuneEffectTrack:   true,
+	RuneInspect:       true,
+	RuneHost:          true,
+}
+
+// ScriptOnly returns true for runes that declare state or effects, which can
+// only be used within the script, not in template expressions
+func (r Rune) ScriptOnly() bool {
+	switch r {
+	case RuneProps, RuneBindable, RuneState, RuneStateRaw, RuneDerived, RuneDerivedBy, RuneEffect, RuneEffectPre:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRune returns true if the identifier is reserved for a rune. Runes aren't
+// variables, so they're never added to the scope.
+func IsRune(name string) bool {
+	return runes[Rune(name)]
+}
+
+// RuneName returns the rune an expression refers to, e.g. `$state` or
+// `$derived.by`.
+func RuneName(expr js.IExpr) (Rune, bool) {
+	switch e := expr.(type) {
+	case *js.Var:
+		name := Rune(e.Data)
+		return name, runes[name]
+	case *js.DotExpr:
+		v, ok := e.X.(*js.Var)
+		if !ok {
+			return "", false
+		}
+		name := Rune(string(v.Data) + "." + e.Y.String())
+		return name, runes[name]
+	default:
+		return "", false
+	}
+}
+
+// RuneOf returns the rune being called in an expression like `$state(0)`.
+func RuneOf(expr js.IExpr) (Rune, bool) {
+	call, ok := expr.(*js.CallExpr)
+	if !ok {
+		return "", false
+	}
+	return RuneName(call.X)
+}
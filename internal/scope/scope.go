@@ -96,6 +96,11 @@ func (s *Scope) New() *Scope {
 	}
 }
 
+// Symbols returns the symbols in this scope, not including the parent's
+func (s *Scope) Symbols() []*Symbol {
+	return s.symbols
+}
+
 func (s *Scope) Parent() *Scope {
 	return s.parent
 }
@@ -168,6 +173,7 @@ type Symbol struct {
 	Name       string  `json:"name,omitempty"`
 	ID         string  `json:"id,omitempty"`
 	Import     *Import // nil if not an import
+	Rune       Rune    // empty if not declared with a rune
 	isDeclared bool
 	isExported bool
 	isMutable  bool
@@ -176,6 +182,7 @@ type Symbol struct {
 type Import struct {
 	Path    string
 	Default bool
+	Name    string // Imported name, * for namespace imports
 }
 
 func (s *Symbol) IsDeclared() bool {
@@ -202,11 +209,18 @@ func (s *Symbol) String() string {
 	if s.isMutable {
 		w.WriteString(" mutable")
 	}
+	if s.Rune != "" {
+		w.WriteString(" rune=")
+		w.WriteString(string(s.Rune))
+	}
 	if s.Import != nil {
 		w.WriteString(" import=")
 		w.WriteString(strconv.Quote(s.Import.Path))
 		if s.Import.Default {
 			w.WriteString(" default")
+		} else if s.Import.Name != "" {
+			w.WriteString(" name=")
+			w.WriteString(s.Import.Name)
 		}
 	}
 	return w.String()
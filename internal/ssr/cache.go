@@ -0,0 +1,77 @@
+package ssr
+
+import (
+	"hash/maphash"
+	"sync"
+
+	"github.com/livebud/duo/internal/ast"
+	"github.com/livebud/duo/internal/parser"
+)
+
+// NewCache creates a parsed-document cache that can be shared across renders
+func NewCache() *Cache {
+	return &Cache{
+		seed: maphash.MakeSeed(),
+		docs: map[string]*cached{},
+	}
+}
+
+// Cache of parsed documents keyed by path and a hash of their contents, so
+// edited files are reparsed even before they've been invalidated. Documents
+// are only read while rendering, so they're safe to share across goroutines.
+type Cache struct {
+	seed maphash.Seed
+	mu   sync.RWMutex
+	docs map[string]*cached
+}
+
+type cached struct {
+	hash uint64
+	doc  *ast.Document
+}
+
+// Parse the code or return the cached document if the code hasn't changed. A
+// nil cache always parses.
+func (c *Cache) Parse(path string, code []byte) (*ast.Document, error) {
+	if c == nil {
+		return parser.Parse(path, string(code))
+	}
+	hash := maphash.Bytes(c.seed, code)
+	c.mu.RLock()
+	entry, ok := c.docs[path]
+	c.mu.RUnlock()
+	if ok && entry.hash == hash {
+		return entry.doc, nil
+	}
+	doc, err := parser.Parse(path, string(code))
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.docs[path] = &cached{hash, doc}
+	c.mu.Unlock()
+	return doc, nil
+}
+
+// Invalidate removes the paths from the cache. The dev server calls this when
+// the watcher sees files change.
+func (c *Cache) Invalidate(paths ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, path := range paths {
+		delete(c.docs, path)
+	}
+}
+
+// Clear removes every document from the cache
+func (c *Cache) Clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.docs = map[string]*cached{}
+}
@@ -0,0 +1,100 @@
+package ssr_test
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/livebud/duo/internal/resolver"
+	"github.com/livebud/duo/internal/ssr"
+	"github.com/matryer/is"
+)
+
+func TestCache(t *testing.T) {
+	is := is.New(t)
+	cache := ssr.NewCache()
+	doc1, err := cache.Parse("index.svelte", []byte(`<h1>{title}</h1>`))
+	is.NoErr(err)
+	doc2, err := cache.Parse("index.svelte", []byte(`<h1>{title}</h1>`))
+	is.NoErr(err)
+	is.True(doc1 == doc2)
+	// Changed contents are reparsed
+	doc3, err := cache.Parse("index.svelte", []byte(`<h2>{title}</h2>`))
+	is.NoErr(err)
+	is.True(doc1 != doc3)
+	// Invalidated paths are reparsed
+	cache.Invalidate("index.svelte")
+	doc4, err := cache.Parse("index.svelte", []byte(`<h2>{title}</h2>`))
+	is.NoErr(err)
+	is.True(doc3 != doc4)
+	cache.Clear()
+	doc5, err := cache.Parse("index.svelte", []byte(`<h2>{title}</h2>`))
+	is.NoErr(err)
+	is.True(doc4 != doc5)
+	// Parse errors aren't cached
+	_, err = cache.Parse("index.svelte", []byte(`{#if}`))
+	is.True(err != nil)
+	doc6, err := cache.Parse("index.svelte", []byte(`<h2>{title}</h2>`))
+	is.NoErr(err)
+	is.True(doc5 == doc6)
+}
+
+func TestCacheSharedAcrossRenders(t *testing.T) {
+	is := is.New(t)
+	fsys := resolver.Embedded{
+		"index.svelte": []byte(`<script>import Title from './Title.svelte'</script><Title {title} />`),
+		"Title.svelte": []byte(`<script>export let title = ""</script><h1>{title}</h1>`),
+	}
+	renderer := ssr.New(fsys)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			str := new(strings.Builder)
+			is.NoErr(renderer.Render(str, "index.svelte", Map{"title": "hi"}))
+			is.Equal(str.String(), `<h1>hi</h1>`)
+		}()
+	}
+	wg.Wait()
+	// Edits are picked up without invalidating
+	fsys["Title.svelte"] = []byte(`<script>export let title = ""</script><h2>{title}</h2>`)
+	str := new(strings.Builder)
+	is.NoErr(renderer.Render(str, "index.svelte", Map{"title": "hi"}))
+	is.Equal(str.String(), `<h2>hi</h2>`)
+}
+
+type hnStory struct {
+	ID          int
+	Title       string
+	URL         string
+	Points      int
+	Author      string
+	NumComments int
+}
+
+func benchmarkHN(b *testing.B, cache *ssr.Cache) {
+	renderer := ssr.New(resolver.New(os.DirFS("../../example/hn/view")))
+	renderer.Cache = cache
+	stories := make([]*hnStory, 30)
+	for i := range stories {
+		stories[i] = &hnStory{ID: i, Title: "Duo", URL: "https://github.com/livebud/duo", Points: 10, Author: "anki", NumComments: 2}
+	}
+	props := Map{"stories": stories}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		str := new(strings.Builder)
+		if err := renderer.Render(str, "index.svelte", props); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkHNUncached(b *testing.B) {
+	benchmarkHN(b, nil)
+}
+
+func BenchmarkHNCached(b *testing.B) {
+	benchmarkHN(b, ssr.NewCache())
+}
@@ -0,0 +1,132 @@
+package ssr
+
+import (
+	"bytes"
+	"html"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/livebud/duo/internal/ast"
+	"github.com/livebud/duo/internal/props"
+	"github.com/livebud/duo/render"
+)
+
+// evaluateCustomElement renders the component within its custom element using
+// declarative shadow DOM, so the shadow root is attached before the element is
+// defined in the browser:
+//
+//	<my-widget name="world"><template shadowrootmode="open">...</template></my-widget>
+func (e *evaluator) evaluateCustomElement(w writer, sc *scope, doc *ast.Document, tag string) error {
+	// Styles don't cross the shadow boundary, so the styles of the component and
+	// its children are collected into the shadow root instead of the page
+	styles := new(Styles)
+	shadow := *e
+	shadow.ctx = WithStyles(e.ctx, styles)
+	shadow.shadowRoot = true
+	body := new(bytes.Buffer)
+	if err := shadow.evaluateFragments(body, sc, doc.Children...); err != nil {
+		return err
+	}
+	w.WriteByte('<')
+	w.WriteString(tag)
+	if err := e.evaluateHostAttributes(w, sc, doc); err != nil {
+		return err
+	}
+	w.WriteString(`><template shadowrootmode="open">`)
+	if css := styles.String(); css != "" {
+		w.WriteString("<style>")
+		w.WriteString(css)
+		w.WriteString("</style>")
+	}
+	w.Write(body.Bytes())
+	w.WriteString("</template>")
+	if err := e.evaluateLightDOM(w, sc); err != nil {
+		return err
+	}
+	w.WriteString("</")
+	w.WriteString(tag)
+	w.WriteByte('>')
+	return nil
+}
+
+// evaluateHostAttributes reflects the primitive props onto the custom element
+// as the attributes it observes
+func (e *evaluator) evaluateHostAttributes(w writer, sc *scope, doc *ast.Document) error {
+	for _, prop := range props.Extract(e.path, doc).Props {
+		value, ok := sc.Lookup(prop.Name)
+		if !ok {
+			continue
+		}
+		value = unwrap(value)
+		if !value.IsValid() {
+			continue
+		}
+		attr := strings.ToLower(prop.Name)
+		switch value.Kind() {
+		case reflect.Bool:
+			if value.Bool() {
+				w.WriteByte(' ')
+				w.WriteString(attr)
+			}
+			continue
+		case reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			// Objects and functions can't be attributes
+			continue
+		}
+		valueString, err := valueToString(value)
+		if err != nil {
+			return e.errorf("unable to evaluate the %s attribute: %w", attr, err)
+		}
+		w.WriteByte(' ')
+		w.WriteString(attr)
+		w.WriteString(`="`)
+		w.WriteString(render.EscapeAttribute(valueString))
+		w.WriteByte('"')
+	}
+	return nil
+}
+
+// evaluateLightDOM renders the content passed into the custom element as its
+// children, where the shadow root's slots will show it
+func (e *evaluator) evaluateLightDOM(w writer, sc *scope) error {
+	slots := sc.Slots()
+	names := make([]string, 0, len(slots))
+	for name := range slots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		content := slots[name]
+		if content == nil || content.isEmpty() {
+			continue
+		}
+		light := *content.evaluator
+		light.lightDOM = true
+		if err := light.evaluateFragments(w, content.scope, content.fragments...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evaluateNativeSlot renders a slot of a custom element as a <slot> element,
+// which shows the light DOM content or the fallback
+func (e *evaluator) evaluateNativeSlot(w writer, sc *scope, node *ast.Slot) error {
+	w.WriteString("<slot")
+	if node.Name != "" {
+		w.WriteString(` name="`)
+		w.WriteString(html.EscapeString(node.Name))
+		w.WriteByte('"')
+	}
+	w.WriteByte('>')
+	if err := e.evaluateFragments(w, sc, node.Fallback...); err != nil {
+		return err
+	}
+	w.WriteString("</slot>")
+	return nil
+}
@@ -0,0 +1,74 @@
+package ssr
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/livebud/duo/internal/resolver"
+)
+
+// Result of rendering a page. Unlike Render, the output is buffered so the
+// CSS of every rendered component is known before the page is written.
+type Result struct {
+	HTML string
+	// Head elements to inject into the layout's <head>, including the CSS
+	Head []string
+	// CSS of every rendered component, de-duplicated
+	CSS string
+	// CSSHash is the hash of the CSS, used to name the stylesheet
+	CSSHash string
+	// CSSHref is the hashed stylesheet URL when the renderer links CSS. The
+	// caller is responsible for serving CSS at that URL.
+	CSSHref string
+}
+
+// HeadHTML returns the head elements as HTML
+func (r *Result) HeadHTML() string {
+	return strings.Join(r.Head, "")
+}
+
+// RenderPage renders the path into a Result
+func (e *Renderer) RenderPage(ctx context.Context, path string, v interface{}) (*Result, error) {
+	file, err := e.Resolver.Resolve(&resolver.Resolve{
+		Path: path,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e.EvaluatePage(ctx, file.Path, file.Code, v)
+}
+
+// EvaluatePage renders the code into a Result. If the context already collects
+// styles with WithStyles, the result's CSS includes the styles collected by
+// earlier renders. This is used to gather the CSS of nested frames.
+func (e *Renderer) EvaluatePage(ctx context.Context, path string, code []byte, v interface{}) (*Result, error) {
+	styles := stylesFrom(ctx)
+	if styles == nil {
+		styles = new(Styles)
+		ctx = WithStyles(ctx, styles)
+	}
+	html := new(bytes.Buffer)
+	if err := e.EvaluateContext(ctx, html, path, code, v); err != nil {
+		return nil, err
+	}
+	result := &Result{
+		HTML: html.String(),
+		CSS:  styles.String(),
+	}
+	if result.CSS == "" {
+		return result, nil
+	}
+	hash := sha256.Sum256([]byte(result.CSS))
+	result.CSSHash = hex.EncodeToString(hash[:8])
+	if e.Stylesheet == nil {
+		result.Head = append(result.Head, "<style>"+result.CSS+"</style>")
+		return result, nil
+	}
+	result.CSSHref = e.Stylesheet(result.CSSHash)
+	result.Head = append(result.Head, `<link rel="stylesheet" href=`+strconv.Quote(result.CSSHref)+`>`)
+	return result, nil
+}
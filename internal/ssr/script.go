@@ -0,0 +1,937 @@
+package ssr
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	duojs "github.com/livebud/duo/internal/js"
+	"github.com/livebud/duo/internal/props"
+	outscope "github.com/livebud/duo/internal/scope"
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// completion describes how a statement finished evaluating
+type completion uint8
+
+const (
+	completeNormal completion = iota
+	completeReturn
+	completeBreak
+	completeContinue
+)
+
+// function is a function declared within a component's script
+type function struct {
+	name   string
+	params js.Params
+	body   *js.BlockStmt
+	scope  *scope
+}
+
+// maxCallDepth limits how deeply script functions can call each other, so
+// unbounded recursion errors instead of overflowing the stack
+const maxCallDepth = 1000
+
+func (f *function) call(args []reflect.Value) (reflect.Value, error) {
+	root := f.scope.root()
+	if root.calls >= maxCallDepth {
+		return reflect.Value{}, fmt.Errorf("ssr: maximum call depth exceeded calling %s", f.String())
+	}
+	root.calls++
+	defer func() { root.calls-- }()
+	sc := f.scope.child()
+	for i, param := range f.params.List {
+		value := reflect.Value{}
+		if i < len(args) {
+			value = args[i]
+		}
+		if !value.IsValid() && param.Default != nil {
+			def, err := evaluateExpr(sc, param.Default)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			value = def
+		}
+		if err := bindPattern(sc, param.Binding, value); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	if f.params.Rest != nil {
+		rest := []interface{}{}
+		for i := len(f.params.List); i < len(args); i++ {
+			rest = append(rest, toInterface(args[i]))
+		}
+		if err := bindPattern(sc, f.params.Rest, reflect.ValueOf(rest)); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	hoistFunctions(sc, f.body.List)
+	c, value, err := evaluateStmts(sc, f.body.List)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if c == completeReturn {
+		return value, nil
+	}
+	return reflect.Value{}, nil
+}
+
+func (f *function) String() string {
+	if f.name == "" {
+		return "anonymous function"
+	}
+	return f.name
+}
+
+// builtin is a function implemented in Go that's callable from scripts and
+// templates
+type builtin func(args ...reflect.Value) (reflect.Value, error)
+
+// globals are available to every script and template. They must not have side
+// effects.
+var globals = map[string]reflect.Value{
+	"URL":                reflect.ValueOf(builtin(newURL)),
+	"String":             reflect.ValueOf(builtin(toStringBuiltin)),
+	"Number":             reflect.ValueOf(builtin(toNumberBuiltin)),
+	"encodeURIComponent": reflect.ValueOf(builtin(encodeURIComponent)),
+	"decodeURIComponent": reflect.ValueOf(builtin(decodeURIComponent)),
+}
+
+// evaluateProgram evaluates the declarations within a component's script.
+// Top-level expression statements (e.g. `setInterval(...)`) are side effects
+// that only run in the browser, so they're skipped on the server.
+func evaluateProgram(sc *scope, program *js.AST) error {
+	hoistFunctions(sc, program.List)
+	for _, stmt := range program.List {
+		if err := evaluateTopLevelStmt(sc, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func evaluateTopLevelStmt(sc *scope, node js.IStmt) error {
+	switch n := node.(type) {
+	case *js.VarDecl:
+		return evaluateVarDecl(sc, n, false)
+	case *js.ExportStmt:
+		if decl, ok := n.Decl.(*js.VarDecl); ok {
+			return evaluateVarDecl(sc, decl, true)
+		}
+		return nil
+	default:
+		// Imports are resolved when rendering components and functions have
+		// already been hoisted
+		return nil
+	}
+}
+
+// hoistFunctions declares the function declarations within a block before
+// evaluating it, like JS does.
+func hoistFunctions(sc *scope, stmts []js.IStmt) {
+	for _, stmt := range stmts {
+		if export, ok := stmt.(*js.ExportStmt); ok {
+			if decl, ok := export.Decl.(*js.FuncDecl); ok {
+				stmt = decl
+			}
+		}
+		decl, ok := stmt.(*js.FuncDecl)
+		if !ok || decl.Name == nil {
+			continue
+		}
+		sc.declare(string(decl.Name.Data), reflect.ValueOf(newFunction(sc, decl)))
+	}
+}
+
+func newFunction(sc *scope, decl *js.FuncDecl) *function {
+	name := ""
+	if decl.Name != nil {
+		name = string(decl.Name.Data)
+	}
+	return &function{name, decl.Params, &decl.Body, sc}
+}
+
+func evaluateVarDecl(sc *scope, node *js.VarDecl, exported bool) error {
+	for _, element := range node.List {
+		if r, ok := outscope.RuneOf(element.Default); ok && r == outscope.RuneProps {
+			if err := bindProps(sc, element.Binding); err != nil {
+				return err
+			}
+			continue
+		}
+		if variable, ok := element.Binding.(*js.Var); ok && exported && node.TokenType != js.ConstToken {
+			// Props passed into the component take precedence over defaults
+			if _, ok := sc.props[string(variable.Data)]; ok {
+				continue
+			}
+		}
+		value := reflect.Value{}
+		if element.Default != nil {
+			v, err := evaluateExpr(sc, element.Default)
+			if err != nil {
+				return err
+			}
+			value = v
+		}
+		if err := bindPattern(sc, element.Binding, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindProps binds the props passed into the component to the `$props()`
+// declaration, applying defaults for missing props.
+func bindProps(sc *scope, binding js.IBinding) error {
+	switch b := binding.(type) {
+	case *js.Var:
+		props := make(map[string]interface{}, len(sc.props))
+		for name, value := range sc.props {
+			props[name] = toInterface(value)
+		}
+		sc.declare(string(b.Data), reflect.ValueOf(props))
+		return nil
+	case *js.BindingObject:
+		seen := map[string]bool{}
+		for _, item := range b.List {
+			key, err := propertyKey(sc, item.Key)
+			if err != nil {
+				return err
+			}
+			seen[key] = true
+			value, ok := sc.props[key]
+			if !ok && item.Value.Default != nil {
+				v, err := evaluateExpr(sc, item.Value.Default)
+				if err != nil {
+					return err
+				}
+				value = v
+			}
+			if err := bindPattern(sc, item.Value.Binding, value); err != nil {
+				return err
+			}
+		}
+		if b.Rest != nil {
+			rest := map[string]interface{}{}
+			for name, value := range sc.props {
+				if !seen[name] {
+					rest[name] = toInterface(value)
+				}
+			}
+			sc.declare(string(b.Rest.Data), reflect.ValueOf(rest))
+		}
+		return nil
+	default:
+		return fmt.Errorf("ssr: unexpected $props() binding %T", binding)
+	}
+}
+
+// evaluateRune computes the initial value of a rune. Effects only run in the
+// browser, so they're skipped.
+func evaluateRune(sc *scope, r outscope.Rune, node js.Args) (reflect.Value, error) {
+	switch r {
+	case outscope.RuneState, outscope.RuneStateRaw, outscope.RuneStateSnapshot,
+		outscope.RuneDerived, outscope.RuneBindable:
+		args, err := evaluateArgs(sc, node)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if len(args) == 0 {
+			return reflect.Value{}, nil
+		}
+		return args[0], nil
+	case outscope.RuneDerivedBy:
+		args, err := evaluateArgs(sc, node)
+		if err != nil {
+			return reflect.Value{}, err
+		} else if len(args) == 0 {
+			return reflect.Value{}, fmt.Errorf("ssr: $derived.by expects a function")
+		}
+		return call(args[0], nil)
+	case outscope.RuneEffect, outscope.RuneEffectPre, outscope.RuneEffectRoot, outscope.RuneInspect:
+		return reflect.Value{}, nil
+	case outscope.RuneEffectTrack:
+		return falseValue, nil
+	default:
+		return reflect.Value{}, fmt.Errorf("ssr: unexpected %s() outside of a variable declaration", r)
+	}
+}
+
+// bindPattern declares the variables within a binding pattern
+func bindPattern(sc *scope, binding js.IBinding, value reflect.Value) error {
+	value = unwrap(value)
+	switch b := binding.(type) {
+	case *js.Var:
+		sc.declare(string(b.Data), value)
+		return nil
+	case *js.BindingObject:
+		seen := map[string]bool{}
+		for _, item := range b.List {
+			key, err := propertyKey(sc, item.Key)
+			if err != nil {
+				return err
+			}
+			seen[key] = true
+			field, err := member(value, key)
+			if err != nil {
+				return err
+			}
+			if !field.IsValid() && item.Value.Default != nil {
+				if field, err = evaluateExpr(sc, item.Value.Default); err != nil {
+					return err
+				}
+			}
+			if err := bindPattern(sc, item.Value.Binding, field); err != nil {
+				return err
+			}
+		}
+		if b.Rest != nil {
+			rest := map[string]interface{}{}
+			if value.Kind() == reflect.Map {
+				for _, key := range value.MapKeys() {
+					if !seen[key.String()] {
+						rest[key.String()] = value.MapIndex(key).Interface()
+					}
+				}
+			}
+			sc.declare(string(b.Rest.Data), reflect.ValueOf(rest))
+		}
+		return nil
+	case *js.BindingArray:
+		for i, element := range b.List {
+			if element.Binding == nil {
+				continue
+			}
+			item := reflect.Value{}
+			if isList(value) && i < value.Len() {
+				item = unwrap(value.Index(i))
+			}
+			if !item.IsValid() && element.Default != nil {
+				v, err := evaluateExpr(sc, element.Default)
+				if err != nil {
+					return err
+				}
+				item = v
+			}
+			if err := bindPattern(sc, element.Binding, item); err != nil {
+				return err
+			}
+		}
+		if b.Rest != nil {
+			rest := []interface{}{}
+			if isList(value) {
+				for i := len(b.List); i < value.Len(); i++ {
+					rest = append(rest, value.Index(i).Interface())
+				}
+			}
+			return bindPattern(sc, b.Rest, reflect.ValueOf(rest))
+		}
+		return nil
+	default:
+		return fmt.Errorf("ssr: unexpected binding %T", binding)
+	}
+}
+
+func propertyKey(sc *scope, name *js.PropertyName) (string, error) {
+	if name.IsComputed() {
+		key, err := evaluateExpr(sc, name.Computed)
+		if err != nil {
+			return "", err
+		}
+		return toString(key), nil
+	}
+	if name.Literal.TokenType == js.StringToken {
+		return duojs.Unquote(string(name.Literal.Data))
+	}
+	return string(name.Literal.Data), nil
+}
+
+func evaluateStmts(sc *scope, stmts []js.IStmt) (completion, reflect.Value, error) {
+	for _, stmt := range stmts {
+		c, value, err := evaluateStmt(sc, stmt)
+		if err != nil {
+			return completeNormal, reflect.Value{}, err
+		} else if c != completeNormal {
+			return c, value, nil
+		}
+	}
+	return completeNormal, reflect.Value{}, nil
+}
+
+func evaluateStmt(sc *scope, node js.IStmt) (completion, reflect.Value, error) {
+	switch n := node.(type) {
+	case *js.ExprStmt:
+		_, err := evaluateExpr(sc, n.Value)
+		return completeNormal, reflect.Value{}, err
+	case *js.VarDecl:
+		return completeNormal, reflect.Value{}, evaluateVarDecl(sc, n, false)
+	case *js.FuncDecl:
+		// Already hoisted
+		return completeNormal, reflect.Value{}, nil
+	case *js.ReturnStmt:
+		if n.Value == nil {
+			return completeReturn, reflect.Value{}, nil
+		}
+		value, err := evaluateExpr(sc, n.Value)
+		return completeReturn, value, err
+	case *js.IfStmt:
+		return evaluateIfStmt(sc, n)
+	case *js.BlockStmt:
+		blockScope := sc.child()
+		hoistFunctions(blockScope, n.List)
+		return evaluateStmts(blockScope, n.List)
+	case *js.SwitchStmt:
+		return evaluateSwitchStmt(sc, n)
+	case *js.ForOfStmt:
+		return evaluateForOfStmt(sc, n)
+	case *js.BranchStmt:
+		if n.Label != nil {
+			return completeNormal, reflect.Value{}, fmt.Errorf("ssr: labeled statements are not supported")
+		}
+		if n.Type == js.ContinueToken {
+			return completeContinue, reflect.Value{}, nil
+		}
+		return completeBreak, reflect.Value{}, nil
+	case *js.EmptyStmt:
+		return completeNormal, reflect.Value{}, nil
+	case *js.ThrowStmt:
+		value, err := evaluateExpr(sc, n.Value)
+		if err != nil {
+			return completeNormal, reflect.Value{}, err
+		}
+		return completeNormal, reflect.Value{}, fmt.Errorf("ssr: uncaught exception %s", toString(value))
+	default:
+		return completeNormal, reflect.Value{}, fmt.Errorf("ssr: unsupported statement %T", n)
+	}
+}
+
+func evaluateIfStmt(sc *scope, node *js.IfStmt) (completion, reflect.Value, error) {
+	cond, err := evaluateExpr(sc, node.Cond)
+	if err != nil {
+		return completeNormal, reflect.Value{}, err
+	}
+	if isTruthy(cond) {
+		return evaluateStmt(sc, node.Body)
+	} else if node.Else != nil {
+		return evaluateStmt(sc, node.Else)
+	}
+	return completeNormal, reflect.Value{}, nil
+}
+
+func evaluateSwitchStmt(sc *scope, node *js.SwitchStmt) (completion, reflect.Value, error) {
+	discriminant, err := evaluateExpr(sc, node.Init)
+	if err != nil {
+		return completeNormal, reflect.Value{}, err
+	}
+	start := -1
+	for i, clause := range node.List {
+		if clause.TokenType == js.DefaultToken {
+			continue
+		}
+		test, err := evaluateExpr(sc, clause.Cond)
+		if err != nil {
+			return completeNormal, reflect.Value{}, err
+		}
+		equal, err := evaluateStrictEqual(sc, unwrap(discriminant), unwrap(test))
+		if err != nil {
+			return completeNormal, reflect.Value{}, err
+		}
+		if equal.Bool() {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		for i, clause := range node.List {
+			if clause.TokenType == js.DefaultToken {
+				start = i
+				break
+			}
+		}
+	}
+	if start < 0 {
+		return completeNormal, reflect.Value{}, nil
+	}
+	// Fall through the remaining clauses until we break
+	switchScope := sc.child()
+	for _, clause := range node.List[start:] {
+		c, value, err := evaluateStmts(switchScope, clause.List)
+		if err != nil {
+			return completeNormal, reflect.Value{}, err
+		}
+		switch c {
+		case completeBreak:
+			return completeNormal, reflect.Value{}, nil
+		case completeReturn, completeContinue:
+			return c, value, nil
+		}
+	}
+	return completeNormal, reflect.Value{}, nil
+}
+
+func evaluateForOfStmt(sc *scope, node *js.ForOfStmt) (completion, reflect.Value, error) {
+	list, err := evaluateExpr(sc, node.Value)
+	if err != nil {
+		return completeNormal, reflect.Value{}, err
+	}
+	list = unwrap(list)
+	if !isList(list) {
+		return completeNormal, reflect.Value{}, fmt.Errorf("ssr: unable to iterate over %s", list.Kind())
+	}
+	for i := 0; i < list.Len(); i++ {
+		loopScope := sc.child()
+		item := unwrap(list.Index(i))
+		switch init := node.Init.(type) {
+		case *js.VarDecl:
+			if len(init.List) != 1 {
+				return completeNormal, reflect.Value{}, fmt.Errorf("ssr: expected one binding in for...of")
+			}
+			if err := bindPattern(loopScope, init.List[0].Binding, item); err != nil {
+				return completeNormal, reflect.Value{}, err
+			}
+		default:
+			if err := assign(loopScope, node.Init, item); err != nil {
+				return completeNormal, reflect.Value{}, err
+			}
+		}
+		hoistFunctions(loopScope, node.Body.List)
+		c, value, err := evaluateStmts(loopScope, node.Body.List)
+		if err != nil {
+			return completeNormal, reflect.Value{}, err
+		}
+		switch c {
+		case completeBreak:
+			return completeNormal, reflect.Value{}, nil
+		case completeReturn:
+			return c, value, nil
+		}
+	}
+	return completeNormal, reflect.Value{}, nil
+}
+
+// assign a value to the target of an assignment expression
+func assign(sc *scope, target js.IExpr, value reflect.Value) error {
+	switch t := target.(type) {
+	case *js.Var:
+		if !sc.assign(string(t.Data), value) {
+			return fmt.Errorf("ssr: assignment to undeclared variable %s", t.Data)
+		}
+		return nil
+	case *js.DotExpr:
+		object, err := evaluateExpr(sc, t.X)
+		if err != nil {
+			return err
+		}
+		return setMember(unwrap(object), t.Y.String(), value)
+	case *js.IndexExpr:
+		object, err := evaluateExpr(sc, t.X)
+		if err != nil {
+			return err
+		}
+		index, err := evaluateExpr(sc, t.Y)
+		if err != nil {
+			return err
+		}
+		object = unwrap(object)
+		if isList(object) {
+			i, ok := toInt(unwrap(index))
+			if !ok || i < 0 || int(i) >= object.Len() {
+				return fmt.Errorf("ssr: index out of range")
+			}
+			return setValue(object.Index(int(i)), value)
+		}
+		return setMember(object, toString(index), value)
+	default:
+		return fmt.Errorf("ssr: invalid assignment target %T", target)
+	}
+}
+
+func setMember(object reflect.Value, name string, value reflect.Value) error {
+	switch object.Kind() {
+	case reflect.Map:
+		if object.IsNil() {
+			return fmt.Errorf("ssr: unable to set %s on a nil map", name)
+		}
+		key := reflect.ValueOf(name)
+		if !value.IsValid() {
+			object.SetMapIndex(key, reflect.Zero(object.Type().Elem()))
+			return nil
+		}
+		if !value.Type().AssignableTo(object.Type().Elem()) {
+			return fmt.Errorf("ssr: unable to set %s to %s", name, value.Type())
+		}
+		object.SetMapIndex(key, value)
+		return nil
+	case reflect.Pointer:
+		return setMember(object.Elem(), name, value)
+	case reflect.Struct:
+		return setValue(fieldByName(object, name), value)
+	default:
+		return fmt.Errorf("ssr: unable to set %s on %s", name, object.Kind())
+	}
+}
+
+func setValue(target reflect.Value, value reflect.Value) error {
+	if !target.IsValid() || !target.CanSet() {
+		return fmt.Errorf("ssr: value is not assignable")
+	}
+	if !value.IsValid() {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+	if !value.Type().AssignableTo(target.Type()) {
+		if !value.Type().ConvertibleTo(target.Type()) {
+			return fmt.Errorf("ssr: unable to assign %s to %s", value.Type(), target.Type())
+		}
+		value = value.Convert(target.Type())
+	}
+	target.Set(value)
+	return nil
+}
+
+// member looks up a property on an object
+func member(object reflect.Value, name string) (reflect.Value, error) {
+	object = unwrap(object)
+	// Errors from rejected {#await} blocks expose their message like JS errors
+	if name == "message" && object.IsValid() && object.Type().Implements(errorType) && !(object.Kind() == reflect.Pointer && object.IsNil()) {
+		return reflect.ValueOf(object.Interface().(error).Error()), nil
+	}
+	switch object.Kind() {
+	case reflect.Pointer:
+		if object.IsNil() {
+			return reflect.Value{}, fmt.Errorf("ssr: cannot read property %q of null", name)
+		}
+		return member(object.Elem(), name)
+	case reflect.Struct:
+		return unwrap(fieldByName(object, name)), nil
+	case reflect.Map:
+		if object.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, fmt.Errorf("ssr: unexpected map key type %s", object.Type().Key())
+		}
+		return unwrap(object.MapIndex(reflect.ValueOf(name).Convert(object.Type().Key()))), nil
+	case reflect.String:
+		if name == "length" {
+			return reflect.ValueOf(int64(len([]rune(object.String())))), nil
+		}
+		return stringMethod(object.String(), name)
+	case reflect.Slice, reflect.Array:
+		if name == "length" {
+			return reflect.ValueOf(int64(object.Len())), nil
+		}
+		return arrayMethod(object, name)
+	case reflect.Invalid:
+		return reflect.Value{}, fmt.Errorf("ssr: cannot read property %q of undefined", name)
+	default:
+		return reflect.Value{}, nil
+	}
+}
+
+// fieldByName looks up the field by its Go name, falling back to the json tag
+// or a case-insensitive match, so `story.title` reads the Title field
+func fieldByName(object reflect.Value, name string) reflect.Value {
+	if field := object.FieldByName(name); field.IsValid() {
+		return field
+	}
+	return props.Field(object, name)
+}
+
+func stringMethod(s string, name string) (reflect.Value, error) {
+	switch name {
+	case "toUpperCase":
+		return reflect.ValueOf(builtin(func(...reflect.Value) (reflect.Value, error) {
+			return reflect.ValueOf(strings.ToUpper(s)), nil
+		})), nil
+	case "toLowerCase":
+		return reflect.ValueOf(builtin(func(...reflect.Value) (reflect.Value, error) {
+			return reflect.ValueOf(strings.ToLower(s)), nil
+		})), nil
+	case "trim":
+		return reflect.ValueOf(builtin(func(...reflect.Value) (reflect.Value, error) {
+			return reflect.ValueOf(strings.TrimSpace(s)), nil
+		})), nil
+	case "includes":
+		return reflect.ValueOf(builtin(func(args ...reflect.Value) (reflect.Value, error) {
+			return reflect.ValueOf(len(args) > 0 && strings.Contains(s, toString(args[0]))), nil
+		})), nil
+	case "startsWith":
+		return reflect.ValueOf(builtin(func(args ...reflect.Value) (reflect.Value, error) {
+			return reflect.ValueOf(len(args) > 0 && strings.HasPrefix(s, toString(args[0]))), nil
+		})), nil
+	default:
+		return reflect.Value{}, nil
+	}
+}
+
+func arrayMethod(list reflect.Value, name string) (reflect.Value, error) {
+	switch name {
+	case "join":
+		return reflect.ValueOf(builtin(func(args ...reflect.Value) (reflect.Value, error) {
+			sep := ","
+			if len(args) > 0 && args[0].IsValid() {
+				sep = toString(args[0])
+			}
+			parts := make([]string, list.Len())
+			for i := range parts {
+				parts[i] = toString(list.Index(i))
+			}
+			return reflect.ValueOf(strings.Join(parts, sep)), nil
+		})), nil
+	case "includes":
+		return reflect.ValueOf(builtin(func(args ...reflect.Value) (reflect.Value, error) {
+			if len(args) == 0 {
+				return reflect.ValueOf(false), nil
+			}
+			for i := 0; i < list.Len(); i++ {
+				equal, err := evaluateStrictEqual(nil, unwrap(list.Index(i)), unwrap(args[0]))
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				if equal.Bool() {
+					return equal, nil
+				}
+			}
+			return reflect.ValueOf(false), nil
+		})), nil
+	default:
+		return reflect.Value{}, nil
+	}
+}
+
+// call a function value with the given arguments
+func call(fn reflect.Value, args []reflect.Value) (reflect.Value, error) {
+	fn = unwrap(fn)
+	if !fn.IsValid() {
+		return reflect.Value{}, fmt.Errorf("ssr: undefined is not a function")
+	}
+	switch f := fn.Interface().(type) {
+	case *function:
+		return f.call(args)
+	case builtin:
+		return f(args...)
+	}
+	if fn.Kind() != reflect.Func {
+		return reflect.Value{}, fmt.Errorf("ssr: %s is not a function", fn.Kind())
+	}
+	// Call Go functions passed in as props
+	fnType := fn.Type()
+	if fnType.IsVariadic() {
+		return reflect.Value{}, fmt.Errorf("ssr: variadic functions are not supported")
+	}
+	in := make([]reflect.Value, fnType.NumIn())
+	for i := range in {
+		paramType := fnType.In(i)
+		if i >= len(args) || !args[i].IsValid() {
+			in[i] = reflect.Zero(paramType)
+			continue
+		}
+		arg := unwrap(args[i])
+		switch {
+		case arg.Type().AssignableTo(paramType):
+			in[i] = arg
+		case arg.Type().ConvertibleTo(paramType):
+			in[i] = arg.Convert(paramType)
+		default:
+			return reflect.Value{}, fmt.Errorf("ssr: unable to pass %s as %s", arg.Type(), paramType)
+		}
+	}
+	out := fn.Call(in)
+	if len(out) == 0 {
+		return reflect.Value{}, nil
+	}
+	if last := out[len(out)-1]; last.Type() == errorType {
+		if !last.IsNil() {
+			return reflect.Value{}, last.Interface().(error)
+		}
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return reflect.Value{}, nil
+	}
+	return out[0], nil
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// unwrap interface values to get at the underlying value
+func unwrap(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	return value
+}
+
+func toInterface(value reflect.Value) interface{} {
+	if !value.IsValid() {
+		return nil
+	}
+	return value.Interface()
+}
+
+func isFunction(value reflect.Value) bool {
+	value = unwrap(value)
+	if value.Kind() == reflect.Func {
+		return true
+	}
+	if !value.IsValid() || !value.CanInterface() {
+		return false
+	}
+	_, ok := value.Interface().(*function)
+	return ok
+}
+
+func isList(value reflect.Value) bool {
+	return value.Kind() == reflect.Slice || value.Kind() == reflect.Array
+}
+
+func isInt(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isNumber(value reflect.Value) bool {
+	return isInt(value) || value.Kind() == reflect.Float32 || value.Kind() == reflect.Float64
+}
+
+func toInt(value reflect.Value) (int64, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return int64(value.Float()), true
+	default:
+		return 0, false
+	}
+}
+
+func toFloat(value reflect.Value) (float64, bool) {
+	switch value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	case reflect.String:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value.String()), 64)
+		return f, err == nil
+	case reflect.Bool:
+		if value.Bool() {
+			return 1, true
+		}
+		return 0, true
+	default:
+		n, ok := toInt(value)
+		return float64(n), ok
+	}
+}
+
+// toString converts a value to a string like JS's String(value)
+func toString(value reflect.Value) string {
+	value = unwrap(value)
+	if !value.IsValid() {
+		return "undefined"
+	}
+	str := new(strings.Builder)
+	if err := writeValue(str, value); err != nil {
+		return fmt.Sprintf("%v", value.Interface())
+	}
+	return str.String()
+}
+
+func newURL(args ...reflect.Value) (reflect.Value, error) {
+	if len(args) == 0 {
+		return reflect.Value{}, fmt.Errorf("ssr: URL requires an argument")
+	}
+	u, err := url.Parse(toString(args[0]))
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("ssr: invalid URL: %w", err)
+	}
+	if len(args) > 1 && args[1].IsValid() {
+		base, err := url.Parse(toString(args[1]))
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("ssr: invalid base URL: %w", err)
+		}
+		u = base.ResolveReference(u)
+	}
+	if !u.IsAbs() {
+		return reflect.Value{}, fmt.Errorf("ssr: invalid URL %q", u.String())
+	}
+	search := ""
+	if u.RawQuery != "" {
+		search = "?" + u.RawQuery
+	}
+	hash := ""
+	if u.Fragment != "" {
+		hash = "#" + u.Fragment
+	}
+	pathname := u.EscapedPath()
+	if pathname == "" {
+		pathname = "/"
+	}
+	return reflect.ValueOf(map[string]interface{}{
+		"href":     u.String(),
+		"protocol": u.Scheme + ":",
+		"host":     u.Host,
+		"hostname": u.Hostname(),
+		"port":     u.Port(),
+		"origin":   u.Scheme + "://" + u.Host,
+		"pathname": pathname,
+		"search":   search,
+		"hash":     hash,
+	}), nil
+}
+
+func toStringBuiltin(args ...reflect.Value) (reflect.Value, error) {
+	if len(args) == 0 {
+		return reflect.ValueOf(""), nil
+	}
+	return reflect.ValueOf(toString(args[0])), nil
+}
+
+func toNumberBuiltin(args ...reflect.Value) (reflect.Value, error) {
+	if len(args) == 0 {
+		return reflect.ValueOf(int64(0)), nil
+	}
+	arg := unwrap(args[0])
+	if isInt(arg) {
+		n, _ := toInt(arg)
+		return reflect.ValueOf(n), nil
+	}
+	f, ok := toFloat(arg)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("ssr: unable to convert %s to a number", arg.Kind())
+	}
+	return normalizeNumber(f), nil
+}
+
+func encodeURIComponent(args ...reflect.Value) (reflect.Value, error) {
+	if len(args) == 0 {
+		return reflect.ValueOf("undefined"), nil
+	}
+	return reflect.ValueOf(strings.ReplaceAll(url.QueryEscape(toString(args[0])), "+", "%20")), nil
+}
+
+func decodeURIComponent(args ...reflect.Value) (reflect.Value, error) {
+	if len(args) == 0 {
+		return reflect.ValueOf("undefined"), nil
+	}
+	decoded, err := url.PathUnescape(toString(args[0]))
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("ssr: malformed URI: %w", err)
+	}
+	return reflect.ValueOf(decoded), nil
+}
+
+// normalizeNumber returns an integer if the float doesn't have a fraction
+func normalizeNumber(f float64) reflect.Value {
+	if f == float64(int64(f)) {
+		return reflect.ValueOf(int64(f))
+	}
+	return reflect.ValueOf(f)
+}
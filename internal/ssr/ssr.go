@@ -2,8 +2,12 @@ package ssr
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"html"
+	"html/template"
 	"io"
+	"math"
 	"path"
 	"reflect"
 	"strconv"
@@ -11,62 +15,166 @@ import (
 	"time"
 
 	"github.com/livebud/duo/internal/ast"
-	"github.com/livebud/duo/internal/parser"
+	duojs "github.com/livebud/duo/internal/js"
+	"github.com/livebud/duo/internal/props"
 	"github.com/livebud/duo/internal/resolver"
 	outscope "github.com/livebud/duo/internal/scope"
+	"github.com/livebud/duo/render"
 	"github.com/tdewolff/parse/v2/js"
 )
 
 func New(resolver resolver.Interface) *Renderer {
-	return &Renderer{resolver}
+	return &Renderer{
+		Resolver: resolver,
+		Flush:    FlushHead | FlushAwait,
+		Cache:    NewCache(),
+	}
 }
 
+// FlushPoint is a point where the buffered output is flushed while streaming
+type FlushPoint uint8
+
+const (
+	// FlushHead flushes after </head>, so the browser can start loading
+	// stylesheets and scripts while the body renders
+	FlushHead FlushPoint = 1 << iota
+	// FlushAwait flushes before waiting on an {#await} block and again after it
+	// resolves
+	FlushAwait
+)
+
 type Renderer struct {
 	Resolver resolver.Interface
+	Flush    FlushPoint
+	Cache    *Cache // shared across renders, nil to always parse
+	// Stylesheet returns the URL of a page's stylesheet from the hash of its
+	// CSS. When nil, pages inline their CSS in a <style> element.
+	Stylesheet func(hash string) string
+	// Strict validates the props against the component's declarations before
+	// rendering, instead of rendering missing props as empty
+	Strict bool
 }
 
 func (e *Renderer) Render(w io.Writer, path string, v interface{}) error {
+	return e.RenderContext(context.Background(), w, path, v)
+}
+
+// RenderContext streams the rendered path to w. Rendering stops when the
+// context is cancelled.
+func (e *Renderer) RenderContext(ctx context.Context, w io.Writer, path string, v interface{}) error {
 	file, err := e.Resolver.Resolve(&resolver.Resolve{
 		Path: path,
 	})
 	if err != nil {
 		return err
 	}
-	return e.Evaluate(w, file.Path, file.Code, v)
+	return e.EvaluateContext(ctx, w, file.Path, file.Code, v)
 }
 
 func (e *Renderer) Evaluate(w io.Writer, path string, code []byte, v interface{}) error {
-	doc, err := parser.Parse(path, string(code))
+	return e.EvaluateContext(context.Background(), w, path, code, v)
+}
+
+// EvaluateContext streams the rendered code to w. Output is buffered and only
+// written at the renderer's flush points, so nothing is written if rendering
+// fails before the first flush. Errors after a flush leave the output
+// truncated, so callers that stream need to signal the failure themselves.
+func (e *Renderer) EvaluateContext(ctx context.Context, w io.Writer, path string, code []byte, v interface{}) error {
+	doc, err := e.Cache.Parse(path, code)
 	if err != nil {
 		return err
 	}
 	value := reflect.ValueOf(v)
-	scope, err := toScope(value)
+	// The schema maps struct fields to props
+	var schema *props.Schema
+	if e.Strict || reflect.Indirect(value).Kind() == reflect.Struct {
+		schema = props.Extract(path, doc)
+	}
+	if e.Strict {
+		if err := schema.Validate(value); err != nil {
+			return err
+		}
+	}
+	// Only scripts can change the props, so the caller's values are only
+	// copied for components with a script
+	_, hasScript := doc.Script()
+	scope, err := toScope(value, schema, hasScript)
 	if err != nil {
 		return err
 	}
+	if stylesFrom(ctx) == nil {
+		ctx = WithStyles(ctx, &Styles{inline: true})
+	}
 	evaluator := &evaluator{
+		ctx:      ctx,
+		flush:    e.Flush,
 		path:     path,
+		doc:      doc,
 		scope:    doc.Scope,
 		resolver: e.Resolver,
+		docs:     e.Cache,
 		cache:    map[string]*ast.Document{},
 	}
-	if err := evaluator.evaluateDocument(&ioWriter{w}, scope, doc); err != nil {
+	stream := newStreamWriter(w)
+	if err := evaluator.evaluateDocument(stream, scope, doc); err != nil {
 		return err
 	}
-	return nil
+	return stream.Flush()
 }
 
-func toScope(value reflect.Value) (*scope, error) {
+// Check that values of the Go type satisfy the props declared by the
+// component at path
+func (e *Renderer) Check(path string, t reflect.Type) error {
+	file, err := e.Resolver.Resolve(&resolver.Resolve{
+		Path: path,
+	})
+	if err != nil {
+		return err
+	}
+	doc, err := e.Cache.Parse(file.Path, file.Code)
+	if err != nil {
+		return err
+	}
+	return props.Extract(file.Path, doc).Check(t)
+}
+
+func toScope(value reflect.Value, schema *props.Schema, mutable bool) (*scope, error) {
 	scope := newScope()
+	// Props share one set of copies, so values reachable from several props
+	// are still shared after copying
+	seen := map[copied]reflect.Value{}
+	prop := func(value reflect.Value) reflect.Value {
+		if !mutable {
+			return value
+		}
+		return clone(value, seen)
+	}
+	scope.slots = map[string]*slot{}
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
 	// Handles nil
-	if !value.IsValid() {
+	if !value.IsValid() || value.Kind() == reflect.Pointer {
 		return scope, nil
 	}
 	switch value.Kind() {
 	case reflect.Map:
 		for _, key := range value.MapKeys() {
-			scope.props[key.String()] = value.MapIndex(key)
+			scope.props[key.String()] = prop(value.MapIndex(key))
+		}
+		return scope, nil
+	case reflect.Struct:
+		// Fields are matched to the declared props. Missing fields and nil
+		// pointers fall back to the prop's default, so use a pointer field to
+		// distinguish an unset prop from its zero value.
+		for _, p := range schema.Props {
+			field := props.Field(value, p.Name)
+			if !field.IsValid() || field.Kind() == reflect.Pointer && field.IsNil() {
+				continue
+			} else if field.Kind() == reflect.Pointer {
+				field = field.Elem()
+			}
+			scope.props[p.Name] = prop(field)
 		}
 		return scope, nil
 	default:
@@ -74,21 +182,162 @@ func toScope(value reflect.Value) (*scope, error) {
 	}
 }
 
+// copied is a pointer that's been copied. A pointer to a struct and a pointer to
+// its first field share an address, so pointers are told apart by their type.
+type copied struct {
+	pointer uintptr
+	typ     reflect.Type
+}
+
+func copiedKey(value reflect.Value) copied {
+	return copied{value.Pointer(), value.Type()}
+}
+
+// clone deeply copies the maps, slices, structs and pointers passed in as
+// props, so assignments within scripts don't write through to the caller's
+// values. Seen tracks the pointers that have already been copied to handle
+// cycles and shared values.
+func clone(value reflect.Value, seen map[copied]reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+		return clone(value.Elem(), seen)
+	case reflect.Pointer:
+		if value.IsNil() {
+			return value
+		}
+		key := copiedKey(value)
+		if out, ok := seen[key]; ok {
+			return out
+		}
+		out := reflect.New(value.Type().Elem())
+		seen[key] = out
+		out.Elem().Set(clone(value.Elem(), seen))
+		return out
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		key := copiedKey(value)
+		if out, ok := seen[key]; ok {
+			return out
+		}
+		out := reflect.MakeMapWithSize(value.Type(), value.Len())
+		seen[key] = out
+		for _, key := range value.MapKeys() {
+			out.SetMapIndex(key, clone(value.MapIndex(key), seen))
+		}
+		return out
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		out := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			out.Index(i).Set(clone(value.Index(i), seen))
+		}
+		return out
+	case reflect.Array, reflect.Struct:
+		out := reflect.New(value.Type()).Elem()
+		// Copies unexported fields as-is
+		out.Set(value)
+		if value.Kind() == reflect.Array {
+			for i := 0; i < value.Len(); i++ {
+				out.Index(i).Set(clone(value.Index(i), seen))
+			}
+			return out
+		}
+		for i := 0; i < value.NumField(); i++ {
+			if field := out.Field(i); field.CanSet() {
+				field.Set(clone(value.Field(i), seen))
+			}
+		}
+		return out
+	default:
+		return value
+	}
+}
+
 func newScope() *scope {
 	return &scope{
 		props: map[string]reflect.Value{},
-		slots: map[string]strings.Builder{},
 	}
 }
 
 type scope struct {
 	parent *scope
 	props  map[string]reflect.Value
-	slot   strings.Builder
-	slots  map[string]strings.Builder
+	slots  map[string]*slot // Only set on a component's root scope
+	calls  int              // Depth of script function calls, tracked on the root scope
+}
+
+// slot is the content passed into a component's slot. The content is rendered
+// lazily within the caller's scope, so the slot props are available.
+type slot struct {
+	evaluator *evaluator
+	scope     *scope
+	lets      []*ast.Let
+	fragments []ast.Fragment
+}
+
+// slotName is the name of the slot a fragment is distributed to
+func slotName(node ast.Fragment) string {
+	var attrs []ast.Attribute
+	switch n := node.(type) {
+	case *ast.Element:
+		attrs = n.Attributes
+	case *ast.Component:
+		attrs = n.Attributes
+	}
+	for _, attr := range attrs {
+		if named, ok := attr.(*ast.NamedSlot); ok {
+			return named.Name
+		}
+	}
+	return ""
+}
+
+// letsOf returns the slot props exposed to the slot content
+func letsOf(attrs []ast.Attribute) (lets []*ast.Let) {
+	for _, attr := range attrs {
+		if let, ok := attr.(*ast.Let); ok {
+			lets = append(lets, let)
+		}
+	}
+	return lets
+}
+
+// isEmpty returns true if the slot content is only whitespace
+func (s *slot) isEmpty() bool {
+	for _, fragment := range s.fragments {
+		text, ok := fragment.(*ast.Text)
+		if !ok || strings.TrimSpace(text.Value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Slots returns the slots passed into the nearest component
+func (s *scope) Slots() map[string]*slot {
+	if s.slots != nil || s.parent == nil {
+		return s.slots
+	}
+	return s.parent.Slots()
 }
 
 func (s *scope) Lookup(name string) (reflect.Value, bool) {
+	if name == "$$slots" {
+		slots := map[string]interface{}{}
+		for name, slot := range s.Slots() {
+			if !slot.isEmpty() {
+				slots[name] = true
+			}
+		}
+		return reflect.ValueOf(slots), true
+	}
 	value, ok := s.props[name]
 	if ok {
 		return value, true
@@ -96,31 +345,80 @@ func (s *scope) Lookup(name string) (reflect.Value, bool) {
 	if s.parent != nil {
 		return s.parent.Lookup(name)
 	}
-	return reflect.Value{}, false
+	value, ok = globals[name]
+	return value, ok
+}
+
+// root returns the outermost scope
+func (s *scope) root() *scope {
+	for s.parent != nil {
+		s = s.parent
+	}
+	return s
+}
+
+// child creates a new lexical scope within the current scope
+func (s *scope) child() *scope {
+	child := newScope()
+	child.parent = s
+	return child
+}
+
+// declare a variable in the current scope
+func (s *scope) declare(name string, value reflect.Value) {
+	s.props[name] = value
+}
+
+// assign a value to an existing variable, returning false if the variable
+// hasn't been declared
+func (s *scope) assign(name string, value reflect.Value) bool {
+	if _, ok := s.props[name]; ok {
+		s.props[name] = value
+		return true
+	}
+	if s.parent != nil {
+		return s.parent.assign(name, value)
+	}
+	return false
 }
 
 type evaluator struct {
+	ctx      context.Context
+	flush    FlushPoint
 	path     string
+	doc      *ast.Document // component being rendered
 	scope    *outscope.Scope
 	resolver resolver.Interface
-	cache    map[string]*ast.Document
-}
+	docs     *Cache
+	cache    map[string]*ast.Document // resolved components for this render
 
-type ioWriter struct {
-	io.Writer
+	shadowRoot bool // rendering the shadow root of a custom element
+	lightDOM   bool // rendering the content passed into a custom element
+
+	selected *reflect.Value // bound value of the <select> we're rendering
 }
 
-func (w *ioWriter) WriteRune(r rune) (int, error) {
-	return w.Writer.Write([]byte(string(r)))
+func newStreamWriter(w io.Writer) *streamWriter {
+	return &streamWriter{new(bytes.Buffer), w}
 }
 
-func (w *ioWriter) WriteString(s string) (int, error) {
-	return w.Writer.Write([]byte(s))
+// streamWriter buffers all writes until the next flush point. Unlike
+// bufio.Writer, it never flushes on its own when the buffer fills up.
+type streamWriter struct {
+	*bytes.Buffer
+	w io.Writer
 }
 
-func (w *ioWriter) WriteByte(b byte) error {
-	_, err := w.Writer.Write([]byte{b})
-	return err
+// Flush the buffered output through to the underlying writer, including
+// http.ResponseWriter's that implement http.Flusher
+func (s *streamWriter) Flush() error {
+	if _, err := s.Buffer.WriteTo(s.w); err != nil {
+		return err
+	}
+	if flusher, ok := s.w.(interface{ Flush() }); ok {
+		flusher.Flush()
+	}
+	return nil
 }
 
 type writer interface {
@@ -134,7 +432,26 @@ func (e *evaluator) errorf(format string, args ...interface{}) error {
 	return fmt.Errorf("ssr: "+format, args...)
 }
 
+// flushAt flushes the output if we're streaming and the flush point is enabled
+func (e *evaluator) flushAt(w writer, point FlushPoint) error {
+	stream, ok := w.(*streamWriter)
+	if !ok || e.flush&point == 0 {
+		return nil
+	}
+	return stream.Flush()
+}
+
 func (e *evaluator) evaluateDocument(w writer, sc *scope, node *ast.Document) error {
+	// Evaluate the script first to initialize the component's state
+	if script, ok := node.Script(); ok && script.Program != nil {
+		if err := evaluateProgram(sc, script.Program); err != nil {
+			return err
+		}
+	}
+	// Custom elements render into declarative shadow DOM
+	if options, ok := node.Options(); ok && options.CustomElement != "" {
+		return e.evaluateCustomElement(w, sc, node, options.CustomElement)
+	}
 	return e.evaluateFragments(w, sc, node.Children...)
 }
 
@@ -148,6 +465,10 @@ func (e *evaluator) evaluateFragments(w writer, sc *scope, nodes ...ast.Fragment
 }
 
 func (e *evaluator) evaluateFragment(w writer, sc *scope, node ast.Fragment) error {
+	// Stop rendering once the context has been cancelled
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
 	switch n := node.(type) {
 	case *ast.Element:
 		return e.evaluateElement(w, sc, n)
@@ -155,6 +476,8 @@ func (e *evaluator) evaluateFragment(w writer, sc *scope, node ast.Fragment) err
 		return e.evaluateText(w, sc, n)
 	case *ast.Mustache:
 		return e.evaluateMustache(w, sc, n)
+	case *ast.HTML:
+		return e.evaluateHTML(w, sc, n)
 	case *ast.Script:
 		return e.evaluateScript(w, sc, n)
 	case *ast.Style:
@@ -163,21 +486,45 @@ func (e *evaluator) evaluateFragment(w writer, sc *scope, node ast.Fragment) err
 		return e.evaluateIfBlock(w, sc, n)
 	case *ast.EachBlock:
 		return e.evaluateEachBlock(w, sc, n)
+	case *ast.AwaitBlock:
+		return e.evaluateAwaitBlock(w, sc, n)
 	case *ast.Component:
 		return e.evaluateComponent(w, sc, n)
 	case *ast.Slot:
 		return e.evaluateSlot(w, sc, n)
+	case *ast.Options:
+		return nil
 	default:
 		return fmt.Errorf("ssr: unknown fragment %T", n)
 	}
 }
 
 func (e *evaluator) evaluateElement(w writer, sc *scope, node *ast.Element) error {
+	// Selects render their bound value by marking the matching options
+	if binding, ok := node.Binding("value"); ok && node.Name == "select" {
+		value, err := evaluateValue(sc, binding.Value)
+		if err != nil {
+			return err
+		}
+		selected := e.selected
+		e.selected = &value
+		defer func() { e.selected = selected }()
+	}
 	w.WriteByte('<')
 	w.WriteString(node.Name)
+	merged := map[string]bool{}
 	for _, attr := range node.Attributes {
 		buf := new(bytes.Buffer)
-		if err := e.evaluateAttribute(buf, sc, attr); err != nil {
+		// Class and style directives are merged into a single attribute
+		if key, ok := node.MergedKey(attr); ok {
+			if merged[key] {
+				continue
+			}
+			merged[key] = true
+			if err := e.evaluateMerged(buf, sc, node, key); err != nil {
+				return err
+			}
+		} else if err := e.evaluateAttribute(buf, sc, node, attr); err != nil {
 			return err
 		}
 		if buf.Len() > 0 {
@@ -185,11 +532,31 @@ func (e *evaluator) evaluateElement(w writer, sc *scope, node *ast.Element) erro
 			w.Write(buf.Bytes())
 		}
 	}
+	if node.Name == "option" && e.selected != nil {
+		selected, err := e.isSelected(sc, node)
+		if err != nil {
+			return err
+		} else if selected {
+			w.WriteString(" selected")
+		}
+	}
 	if node.SelfClosing {
 		w.WriteString("/>")
 		return nil
 	}
 	w.WriteString(">")
+	// Textareas render their bound value as their content
+	if binding, ok := node.Binding("value"); ok && node.Name == "textarea" {
+		value, err := evaluateValue(sc, binding.Value)
+		if err != nil {
+			return err
+		}
+		text, err := valueToString(value)
+		if err != nil {
+			return err
+		}
+		w.WriteString(render.EscapeText(text))
+	}
 	for _, child := range node.Children {
 		if err := e.evaluateFragment(w, sc, child); err != nil {
 			return err
@@ -198,6 +565,9 @@ func (e *evaluator) evaluateElement(w writer, sc *scope, node *ast.Element) erro
 	w.WriteString("</")
 	w.WriteString(node.Name)
 	w.WriteString(">")
+	if node.Name == "head" {
+		return e.flushAt(w, FlushHead)
+	}
 	return nil
 }
 
@@ -205,18 +575,34 @@ func (e *evaluator) evaluateScript(_ writer, _ *scope, _ *ast.Script) error {
 	return nil
 }
 
-func (e *evaluator) evaluateStyle(_ writer, _ *scope, _ *ast.Style) error {
+// evaluateStyle collects the component's scoped CSS. When the caller collects
+// the styles with WithStyles, it decides where the CSS goes. Otherwise the CSS
+// is written inline the first time the component renders.
+func (e *evaluator) evaluateStyle(w writer, _ *scope, node *ast.Style) error {
+	styles := stylesFrom(e.ctx)
+	if css, ok := styles.add(node); ok && styles.inline {
+		w.WriteString("<style>" + css + "</style>")
+	}
 	return nil
 }
 
-func (e *evaluator) evaluateAttribute(w writer, sc *scope, node ast.Attribute) error {
+func (e *evaluator) evaluateAttribute(w writer, sc *scope, el *ast.Element, node ast.Attribute) error {
 	switch n := node.(type) {
 	case *ast.Field:
 		return e.evaluateField(w, sc, n)
 	case *ast.Binding:
-		return e.evaluateBinding(w, sc, n)
+		return e.evaluateBinding(w, sc, el, n)
 	case *ast.AttributeShorthand:
 		return e.evaluateAttributeShorthand(w, sc, n)
+	case *ast.NamedSlot:
+		// Custom elements distribute their light DOM with the slot attribute
+		if e.lightDOM {
+			w.WriteString(`slot="` + render.EscapeAttribute(n.Name) + `"`)
+		}
+		return nil
+	case *ast.Let:
+		// Only used to distribute slot content
+		return nil
 	default:
 		return fmt.Errorf("ssr: unknown attribute %T", n)
 	}
@@ -235,6 +621,10 @@ func (e *evaluator) evaluateField(w writer, sc *scope, node *ast.Field) error {
 	if err != nil {
 		return err
 	}
+	// Functions only make sense in the browser
+	if isFunction(value) {
+		return nil
+	}
 	switch value.Kind() {
 	case reflect.Bool:
 		if value.Bool() {
@@ -251,22 +641,197 @@ func (e *evaluator) evaluateField(w writer, sc *scope, node *ast.Field) error {
 	w.WriteString(node.Key)
 	w.WriteByte('=')
 	w.WriteByte('"')
-	w.WriteString(valueString)
+	w.WriteString(render.EscapeAttribute(valueString))
 	w.WriteByte('"')
 	return nil
 }
 
-func (e *evaluator) evaluateBinding(w writer, sc *scope, node *ast.Binding) error {
-	w.WriteString(node.Key)
+// evaluateBinding renders the initial state of a two-way binding
+func (e *evaluator) evaluateBinding(w writer, sc *scope, el *ast.Element, node *ast.Binding) error {
+	switch {
+	case node.Key == "this":
+		// Elements are only bound in the browser
+		return nil
+	case node.Key == "value" && el.Name == "textarea":
+		// Rendered as the content instead
+		return nil
+	case node.Key == "value" && el.Name == "select":
+		// Rendered by marking the selected options instead
+		return nil
+	}
+	value, err := evaluateValue(sc, node.Value)
+	if err != nil {
+		return err
+	}
+	key := node.Key
+	if key == "group" {
+		checked, err := isGrouped(sc, el, value)
+		if err != nil {
+			return err
+		}
+		key, value = "checked", reflect.ValueOf(checked)
+	}
+	value = unwrap(value)
+	if !value.IsValid() || isFunction(value) {
+		return nil
+	}
+	if value.Kind() == reflect.Bool {
+		if value.Bool() {
+			w.WriteString(key)
+		}
+		return nil
+	}
+	valueString, err := valueToString(value)
+	if err != nil {
+		return e.errorf("unable to evaluate bind:%s: %w", node.Key, err)
+	}
+	w.WriteString(key)
 	w.WriteByte('=')
 	w.WriteByte('"')
-	if err := e.evaluateValue(w, sc, node.Value); err != nil {
-		return err
+	w.WriteString(render.EscapeAttribute(valueString))
+	w.WriteByte('"')
+	return nil
+}
+
+// evaluateMerged renders the class or style attribute along with its
+// directives, like `class="item" class:active={true}` to `class="item active"`
+func (e *evaluator) evaluateMerged(w writer, sc *scope, el *ast.Element, key string) error {
+	var parts []string
+	for _, attr := range el.Attributes {
+		var value reflect.Value
+		var err error
+		switch a := attr.(type) {
+		case *ast.Field:
+			if a.Key != key {
+				continue
+			}
+			value, err = e.evaluateValues(sc, a.Values...)
+		case *ast.AttributeShorthand:
+			if a.Key != key {
+				continue
+			}
+			value, err = evaluateExpr(sc, &js.Var{Data: []byte(a.Key)})
+		case *ast.Class:
+			if key != "class" {
+				continue
+			}
+			value, err = evaluateValue(sc, a.Value)
+			if err != nil {
+				return err
+			}
+			if isTruthy(value) {
+				parts = append(parts, a.Name)
+			}
+			continue
+		case *ast.StyleDirective:
+			if key != "style" {
+				continue
+			}
+			value, err = e.evaluateValues(sc, a.Values...)
+			if err != nil {
+				return err
+			}
+			if value = unwrap(value); isNullish(value) {
+				continue
+			}
+			declaration, err := valueToString(value)
+			if err != nil {
+				return e.errorf("unable to evaluate style:%s: %w", a.Name, err)
+			}
+			parts = append(parts, a.Name+": "+declaration)
+			continue
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if value = unwrap(value); isNullish(value) || isFunction(value) {
+			continue
+		}
+		base, err := valueToString(value)
+		if err != nil {
+			return e.errorf("unable to evaluate %s: %w", key, err)
+		}
+		parts = append(parts, base)
+	}
+	merged := render.Style(parts...)
+	if key == "class" {
+		merged = render.Class(parts...)
+	}
+	if merged == nil {
+		return nil
 	}
+	w.WriteString(key)
+	w.WriteString(`="`)
+	w.WriteString(render.EscapeAttribute(merged.(string)))
 	w.WriteByte('"')
 	return nil
 }
 
+// isGrouped returns true if the input's value is selected by the group, either
+// matching it for radio buttons or within it for checkboxes
+func isGrouped(sc *scope, el *ast.Element, group reflect.Value) (bool, error) {
+	var value reflect.Value
+	for _, attr := range el.Attributes {
+		if _, ok := attr.(*ast.Binding); ok || attr.GetKey() != "value" {
+			continue
+		}
+		v, err := evaluateAttribute(sc, attr)
+		if err != nil {
+			return false, err
+		}
+		value = v
+	}
+	return includes(sc, group, value)
+}
+
+// isSelected returns true if the option's value is selected by the bound value
+// of its <select>. Options without a value attribute use their text.
+func (e *evaluator) isSelected(sc *scope, option *ast.Element) (bool, error) {
+	for _, attr := range option.Attributes {
+		if attr.GetKey() != "value" {
+			continue
+		}
+		value, err := evaluateAttribute(sc, attr)
+		if err != nil {
+			return false, err
+		}
+		return includes(sc, *e.selected, value)
+	}
+	text := new(bytes.Buffer)
+	for _, child := range option.Children {
+		if err := e.evaluateFragment(text, sc, child); err != nil {
+			return false, err
+		}
+	}
+	return includes(sc, *e.selected, reflect.ValueOf(strings.TrimSpace(html.UnescapeString(text.String()))))
+}
+
+// includes returns true if the value matches the selection or is within it for
+// selections that allow multiple values
+func includes(sc *scope, selection, value reflect.Value) (bool, error) {
+	selection = unwrap(selection)
+	switch selection.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < selection.Len(); i++ {
+			equal, err := evaluateStrictEqual(sc, unwrap(selection.Index(i)), unwrap(value))
+			if err != nil {
+				return false, err
+			} else if isTruthy(equal) {
+				return true, nil
+			}
+		}
+		return false, nil
+	default:
+		equal, err := evaluateStrictEqual(sc, selection, unwrap(value))
+		if err != nil {
+			return false, err
+		}
+		return isTruthy(equal), nil
+	}
+}
+
 func (e *evaluator) evaluateAttributeShorthand(w writer, sc *scope, node *ast.AttributeShorthand) error {
 	// buf := new(bytes.Buffer)
 	// Skip event handlers
@@ -279,41 +844,24 @@ func (e *evaluator) evaluateAttributeShorthand(w writer, sc *scope, node *ast.At
 	})
 	if err != nil {
 		return err
-	} else if !value.IsValid() {
+	} else if !value.IsValid() || isFunction(value) {
+		// Functions only make sense in the browser
 		return nil
 	}
+	valueString, err := valueToString(value)
+	if err != nil {
+		return err
+	}
 	w.WriteString(node.Key)
 	w.WriteByte('=')
 	w.WriteByte('"')
-	if err := writeValue(w, value); err != nil {
-		return err
-	}
+	w.WriteString(render.EscapeAttribute(valueString))
 	w.WriteByte('"')
 	return nil
 }
 
 func (e *evaluator) evaluateValues(sc *scope, values ...ast.Value) (reflect.Value, error) {
-	if len(values) == 1 {
-		return evaluateValue(sc, values[0])
-	}
-	buf := new(bytes.Buffer)
-	for _, value := range values {
-		if err := e.evaluateValue(buf, sc, value); err != nil {
-			return reflect.Value{}, err
-		}
-	}
-	return reflect.ValueOf(buf.String()), nil
-}
-
-func (e *evaluator) evaluateValue(w writer, sc *scope, node ast.Value) error {
-	switch n := node.(type) {
-	case *ast.Text:
-		return e.evaluateText(w, sc, n)
-	case *ast.Mustache:
-		return e.evaluateMustache(w, sc, n)
-	default:
-		return fmt.Errorf("ssr: unknown attribute value %T", n)
-	}
+	return evaluateValues(sc, values)
 }
 
 func (e *evaluator) evaluateText(w writer, _ *scope, node *ast.Text) error {
@@ -326,9 +874,35 @@ func (e *evaluator) evaluateMustache(w writer, sc *scope, node *ast.Mustache) er
 	if err != nil {
 		return err
 	}
-	return writeValue(w, value)
+	// Trusted markup like the page rendered into a layout isn't escaped
+	if value = unwrap(value); value.IsValid() && value.Type() == trustedHTMLType {
+		w.WriteString(value.String())
+		return nil
+	}
+	text, err := valueToString(value)
+	if err != nil {
+		return err
+	}
+	w.WriteString(render.EscapeText(text))
+	return nil
 }
 
+// evaluateHTML writes the value of {@html expr} without escaping it
+func (e *evaluator) evaluateHTML(w writer, sc *scope, node *ast.HTML) error {
+	value, err := evaluateExpr(sc, node.Expr)
+	if err != nil {
+		return err
+	}
+	text, err := valueToString(unwrap(value))
+	if err != nil {
+		return err
+	}
+	w.WriteString(text)
+	return nil
+}
+
+var trustedHTMLType = reflect.TypeOf(template.HTML(""))
+
 func writeValue(w writer, value reflect.Value) error {
 	if !value.IsValid() {
 		return nil
@@ -344,29 +918,59 @@ func writeValue(w writer, value reflect.Value) error {
 	case int:
 		w.WriteString(strconv.Itoa(value))
 		return nil
+	case bool:
+		w.WriteString(strconv.FormatBool(value))
+		return nil
+	case float64:
+		w.WriteString(formatFloat(value))
+		return nil
 	case time.Time:
 		w.WriteString(value.Format(time.RFC3339))
 		return nil
+	case error:
+		w.WriteString(value.Error())
+		return nil
+	}
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.WriteString(strconv.FormatInt(value.Int(), 10))
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		w.WriteString(strconv.FormatUint(value.Uint(), 10))
+		return nil
+	case reflect.Float32:
+		w.WriteString(formatFloat(value.Float()))
+		return nil
+	case reflect.String:
+		w.WriteString(value.String())
+		return nil
 	default:
-		return fmt.Errorf("ssr: unexpected value %T", value)
+		return fmt.Errorf("ssr: unexpected value %T", v)
 	}
 }
 
+// formatFloat formats a float like JS does
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
 func valueToString(value reflect.Value) (string, error) {
 	if !value.IsValid() {
 		return "", nil
 	}
-	v := value.Interface()
-	switch value := v.(type) {
-	case string:
-		return value, nil
-	case int64:
-		return strconv.FormatInt(value, 10), nil
-	case int:
-		return strconv.Itoa(value), nil
-	default:
-		return "", fmt.Errorf("ssr: unexpected value %T", value)
+	str := new(strings.Builder)
+	if err := writeValue(str, value); err != nil {
+		return "", err
 	}
+	return str.String(), nil
 }
 
 func evaluateAttribute(sc *scope, node ast.Attribute) (reflect.Value, error) {
@@ -385,7 +989,13 @@ func evaluateAttribute(sc *scope, node ast.Attribute) (reflect.Value, error) {
 		if err != nil {
 			return reflect.Value{}, err
 		}
-		return value, nil
+		return value, nil
+	case *ast.Binding:
+		// Components are only bound in the browser
+		if a.Key == "this" {
+			return reflect.Value{}, nil
+		}
+		return evaluateValue(sc, a.Value)
 	default:
 		return reflect.Value{}, fmt.Errorf("ssr: unknown attribute %T", a)
 	}
@@ -415,7 +1025,8 @@ func evaluateValues(scope *scope, values []ast.Value) (reflect.Value, error) {
 func evaluateValue(scope *scope, node ast.Value) (reflect.Value, error) {
 	switch n := node.(type) {
 	case *ast.Text:
-		return reflect.ValueOf(n.Value), nil
+		// Decode entities like &amp; so the value matches what the browser sees
+		return reflect.ValueOf(html.UnescapeString(n.Value)), nil
 	case *ast.Mustache:
 		return evaluateExpr(scope, n.Expr)
 	default:
@@ -431,14 +1042,30 @@ func evaluateExpr(scope *scope, node js.IExpr) (reflect.Value, error) {
 		return evaluateVar(scope, n)
 	case *js.BinaryExpr:
 		return evaluateBinaryExpr(scope, n)
+	case *js.UnaryExpr:
+		return evaluateUnaryExpr(scope, n)
 	case *js.CondExpr:
 		return evaluateCondExpr(scope, n)
+	case *js.GroupExpr:
+		return evaluateExpr(scope, n.X)
 	case *js.DotExpr:
 		return evaluateDotExpr(scope, n)
+	case *js.IndexExpr:
+		return evaluateIndexExpr(scope, n)
 	case *js.TemplateExpr:
 		return evaluateTemplateExpr(scope, n)
 	case *js.CallExpr:
 		return evaluateCallExpr(scope, n)
+	case *js.NewExpr:
+		return evaluateNewExpr(scope, n)
+	case *js.ArrayExpr:
+		return evaluateArrayExpr(scope, n)
+	case *js.ObjectExpr:
+		return evaluateObjectExpr(scope, n)
+	case *js.ArrowFunc:
+		return reflect.ValueOf(&function{"", n.Params, &n.Body, scope}), nil
+	case *js.FuncDecl:
+		return reflect.ValueOf(newFunction(scope, n)), nil
 	default:
 		return reflect.Value{}, fmt.Errorf("ssr: unknown expression %T", n)
 	}
@@ -449,84 +1076,183 @@ func evaluateLiteralExpr(scope *scope, node *js.LiteralExpr) (reflect.Value, err
 	case js.IdentifierToken:
 		return evaluateIdentifier(scope, node)
 	case js.StringToken:
-		// TODO: more robust unquoting.
-		// strconv.Unquote doesn't support multi-char single quotes
-		value := strings.Trim(string(node.Data), `'"`)
-		return reflect.ValueOf(value), nil
+		s, err := duojs.Unquote(string(node.Data))
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("ssr: %w", err)
+		}
+		return reflect.ValueOf(s), nil
 	case js.DecimalToken:
-		// TODO: handle floats
-		// TODO: make sure 64b is right
 		n, err := strconv.ParseInt(string(node.Data), 10, 64)
+		if err == nil {
+			return reflect.ValueOf(n), nil
+		}
+		f, err := strconv.ParseFloat(string(node.Data), 64)
 		if err != nil {
 			return reflect.Value{}, err
 		}
-		return reflect.ValueOf(n), nil
+		return reflect.ValueOf(f), nil
 	case js.TrueToken:
 		return reflect.ValueOf(true), nil
 	case js.FalseToken:
 		return reflect.ValueOf(false), nil
+	case js.NullToken:
+		return reflect.Value{}, nil
 	default:
 		return reflect.Value{}, fmt.Errorf("ssr: unknown literal expression %s", node.TokenType.String())
 	}
 }
 
 func evaluateVar(scope *scope, node *js.Var) (reflect.Value, error) {
-	switch node.Decl {
-	case js.NoDecl:
-		// Since there's no parse expression function, identifiers are considered a non-declared variable
-		return evaluateIdentifier(scope, &js.LiteralExpr{
-			Data:      node.Data,
-			TokenType: js.IdentifierToken,
-		})
-	default:
-		return reflect.Value{}, fmt.Errorf("ssr: unknown decl %s", node.Decl.String())
-	}
+	return evaluateIdentifier(scope, &js.LiteralExpr{
+		Data:      node.Data,
+		TokenType: js.IdentifierToken,
+	})
 }
 
 func evaluateBinaryExpr(scope *scope, node *js.BinaryExpr) (reflect.Value, error) {
+	switch node.Op {
+	case js.EqToken:
+		right, err := evaluateExpr(scope, node.Y)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return right, assign(scope, node.X, right)
+	case js.AddEqToken, js.SubEqToken, js.MulEqToken, js.DivEqToken, js.ModEqToken:
+		left, err := evaluateExpr(scope, node.X)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		right, err := evaluateExpr(scope, node.Y)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		value, err := evaluateArithmetic(assignmentOps[node.Op], unwrap(left), unwrap(right))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return value, assign(scope, node.X, value)
+	case js.AndToken, js.OrToken, js.NullishToken:
+		// Short-circuit the right side
+		left, err := evaluateExpr(scope, node.X)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		left = unwrap(left)
+		switch {
+		case node.Op == js.AndToken && !isTruthy(left),
+			node.Op == js.OrToken && isTruthy(left),
+			node.Op == js.NullishToken && !isNullish(left):
+			return left, nil
+		}
+		return evaluateExpr(scope, node.Y)
+	}
 	left, err := evaluateExpr(scope, node.X)
 	if err != nil {
 		return reflect.Value{}, err
 	}
-	if left.Kind() == reflect.Interface {
-		left = left.Elem()
-	}
+	left = unwrap(left)
 	right, err := evaluateExpr(scope, node.Y)
 	if err != nil {
 		return reflect.Value{}, err
 	}
-	if right.Kind() == reflect.Interface {
-		right = right.Elem()
-	}
+	right = unwrap(right)
 	switch node.Op {
-	case js.AddToken:
-		return evaluateAdd(scope, left, right)
+	case js.AddToken, js.SubToken, js.MulToken, js.DivToken, js.ModToken:
+		return evaluateArithmetic(node.Op, left, right)
 	case js.EqEqToken:
 		return evaluateEqual(scope, left, right)
+	case js.NotEqToken:
+		equal, err := evaluateEqual(scope, left, right)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(!equal.Bool()), nil
 	case js.EqEqEqToken:
 		return evaluateStrictEqual(scope, left, right)
-	case js.OrToken:
-		return evaluateOr(scope, left, right)
+	case js.NotEqEqToken:
+		equal, err := evaluateStrictEqual(scope, left, right)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(!equal.Bool()), nil
+	case js.LtToken, js.LtEqToken, js.GtToken, js.GtEqToken:
+		return evaluateCompare(node.Op, left, right)
 	default:
 		return reflect.Value{}, fmt.Errorf("ssr: unknown binary expression %s", node.Op.String())
 	}
 }
 
-func evaluateCondExpr(scope *scope, node *js.CondExpr) (reflect.Value, error) {
-	cond, err := evaluateExpr(scope, node.Cond)
+var assignmentOps = map[js.TokenType]js.TokenType{
+	js.AddEqToken: js.AddToken,
+	js.SubEqToken: js.SubToken,
+	js.MulEqToken: js.MulToken,
+	js.DivEqToken: js.DivToken,
+	js.ModEqToken: js.ModToken,
+}
+
+func evaluateUnaryExpr(scope *scope, node *js.UnaryExpr) (reflect.Value, error) {
+	value, err := evaluateExpr(scope, node.X)
 	if err != nil {
 		return reflect.Value{}, err
 	}
-	left := true
-	switch cond.Kind() {
-	case reflect.Bool:
-		left = cond.Bool()
-	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int8, reflect.Int16:
-		left = cond.Int() != 0
+	value = unwrap(value)
+	switch node.Op {
+	case js.NotToken:
+		return reflect.ValueOf(!isTruthy(value)), nil
+	case js.NegToken:
+		return evaluateArithmetic(js.SubToken, reflect.ValueOf(int64(0)), value)
+	case js.PosToken:
+		return toNumberBuiltin(value)
+	case js.TypeofToken:
+		return reflect.ValueOf(typeOf(value)), nil
+	case js.PreIncrToken, js.PreDecrToken, js.PostIncrToken, js.PostDecrToken:
+		op := js.AddToken
+		if node.Op == js.PreDecrToken || node.Op == js.PostDecrToken {
+			op = js.SubToken
+		}
+		next, err := evaluateArithmetic(op, value, reflect.ValueOf(int64(1)))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if err := assign(scope, node.X, next); err != nil {
+			return reflect.Value{}, err
+		}
+		if node.Op == js.PostIncrToken || node.Op == js.PostDecrToken {
+			return value, nil
+		}
+		return next, nil
+	default:
+		return reflect.Value{}, fmt.Errorf("ssr: unknown unary expression %s", node.Op.String())
+	}
+}
+
+func typeOf(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.Invalid:
+		return "undefined"
 	case reflect.String:
-		left = cond.String() != ""
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Func:
+		return "function"
+	}
+	if isNumber(value) {
+		return "number"
+	}
+	switch value.Interface().(type) {
+	case *function, builtin:
+		return "function"
+	}
+	return "object"
+}
+
+func evaluateCondExpr(scope *scope, node *js.CondExpr) (reflect.Value, error) {
+	cond, err := evaluateExpr(scope, node.Cond)
+	if err != nil {
+		return reflect.Value{}, err
 	}
-	if left {
+	if isTruthy(cond) {
 		return evaluateExpr(scope, node.X)
 	}
 	return evaluateExpr(scope, node.Y)
@@ -538,23 +1264,44 @@ func evaluateDotExpr(scope *scope, node *js.DotExpr) (reflect.Value, error) {
 	if err != nil {
 		return reflect.Value{}, err
 	}
-	if x.Kind() == reflect.Pointer {
-		x = x.Elem()
+	x = unwrap(x)
+	if node.Optional && isNullish(x) {
+		return reflect.Value{}, nil
 	}
-	switch x.Kind() {
-	case reflect.Struct:
-		value := x.FieldByName(node.Y.String())
-		return value, nil
-	// case reflect.Map:
-	default:
-		return reflect.Value{}, fmt.Errorf("ssr: unexpected member %s type: %s", x, x.Kind().String())
+	return member(x, node.Y.String())
+}
+
+func evaluateIndexExpr(scope *scope, node *js.IndexExpr) (reflect.Value, error) {
+	// x[y]
+	x, err := evaluateExpr(scope, node.X)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	x = unwrap(x)
+	if node.Optional && isNullish(x) {
+		return reflect.Value{}, nil
+	}
+	y, err := evaluateExpr(scope, node.Y)
+	if err != nil {
+		return reflect.Value{}, err
 	}
+	y = unwrap(y)
+	if isList(x) && isNumber(y) {
+		i, _ := toInt(y)
+		if i < 0 || int(i) >= x.Len() {
+			return reflect.Value{}, nil
+		}
+		return unwrap(x.Index(int(i))), nil
+	}
+	return member(x, toString(y))
 }
 
 func evaluateTemplateExpr(scope *scope, node *js.TemplateExpr) (reflect.Value, error) {
 	str := new(strings.Builder)
 	for _, part := range node.List {
-		left := strings.TrimSuffix(strings.TrimLeft(string(part.Value), "`"), "${")
+		// Each part is surrounded by either "`" or "}" on the left and "${" on
+		// the right
+		left := strings.TrimSuffix(string(part.Value[1:]), "${")
 		str.WriteString(left)
 		expr, err := evaluateExpr(scope, part.Expr)
 		if err != nil {
@@ -564,64 +1311,202 @@ func evaluateTemplateExpr(scope *scope, node *js.TemplateExpr) (reflect.Value, e
 			return reflect.Value{}, err
 		}
 	}
-	str.WriteString(strings.TrimRight(string(node.Tail), "}`"))
-	fmt.Println(str.String())
+	str.WriteString(strings.TrimSuffix(string(node.Tail[1:]), "`"))
 	return reflect.ValueOf(str.String()), nil
 }
 
 func evaluateCallExpr(scope *scope, node *js.CallExpr) (reflect.Value, error) {
-	// TODO: handle function calls
-	return reflect.Value{}, fmt.Errorf("ssr: call expression not implemented")
+	if r, ok := outscope.RuneName(node.X); ok {
+		return evaluateRune(scope, r, node.Args)
+	}
+	fn, err := evaluateExpr(scope, node.X)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if node.Optional && isNullish(unwrap(fn)) {
+		return reflect.Value{}, nil
+	}
+	args, err := evaluateArgs(scope, node.Args)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return call(fn, args)
 }
 
-func evaluateAdd(_ *scope, left, right reflect.Value) (reflect.Value, error) {
-	switch left.Kind() {
-	case reflect.String:
-		switch right.Kind() {
-		case reflect.String:
-			return reflect.ValueOf(left.String() + right.String()), nil
-		default:
-			return reflect.Value{}, fmt.Errorf("ssr: unexpected right value %s", right.Kind().String())
+func evaluateNewExpr(scope *scope, node *js.NewExpr) (reflect.Value, error) {
+	constructor, err := evaluateExpr(scope, node.X)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if _, ok := unwrap(constructor).Interface().(builtin); !ok {
+		return reflect.Value{}, fmt.Errorf("ssr: unable to construct %s", node.X.JS())
+	}
+	var args []reflect.Value
+	if node.Args != nil {
+		if args, err = evaluateArgs(scope, *node.Args); err != nil {
+			return reflect.Value{}, err
 		}
-	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int8, reflect.Int16:
-		switch right.Kind() {
-		case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int8, reflect.Int16:
-			return reflect.ValueOf(left.Int() + right.Int()), nil
-		default:
-			return reflect.Value{}, fmt.Errorf("ssr: unexpected right value %s", right.Kind().String())
+	}
+	return call(constructor, args)
+}
+
+func evaluateArgs(scope *scope, node js.Args) (args []reflect.Value, err error) {
+	for _, arg := range node.List {
+		value, err := evaluateExpr(scope, arg.Value)
+		if err != nil {
+			return nil, err
+		}
+		value = unwrap(value)
+		if arg.Rest && isList(value) {
+			for i := 0; i < value.Len(); i++ {
+				args = append(args, unwrap(value.Index(i)))
+			}
+			continue
+		}
+		args = append(args, value)
+	}
+	return args, nil
+}
+
+func evaluateArrayExpr(scope *scope, node *js.ArrayExpr) (reflect.Value, error) {
+	list := []interface{}{}
+	for _, element := range node.List {
+		if element.Value == nil {
+			list = append(list, nil)
+			continue
+		}
+		value, err := evaluateExpr(scope, element.Value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		value = unwrap(value)
+		if element.Spread && isList(value) {
+			for i := 0; i < value.Len(); i++ {
+				list = append(list, value.Index(i).Interface())
+			}
+			continue
+		}
+		list = append(list, toInterface(value))
+	}
+	return reflect.ValueOf(list), nil
+}
+
+func evaluateObjectExpr(scope *scope, node *js.ObjectExpr) (reflect.Value, error) {
+	object := map[string]interface{}{}
+	for _, property := range node.List {
+		value, err := evaluateExpr(scope, property.Value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		value = unwrap(value)
+		if property.Spread {
+			if value.Kind() == reflect.Map {
+				for _, key := range value.MapKeys() {
+					object[toString(key)] = value.MapIndex(key).Interface()
+				}
+			}
+			continue
+		}
+		if property.Name == nil {
+			return reflect.Value{}, fmt.Errorf("ssr: unexpected object property %s", property.JS())
+		}
+		key, err := propertyKey(scope, property.Name)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		object[key] = toInterface(value)
+	}
+	return reflect.ValueOf(object), nil
+}
+
+func evaluateArithmetic(op js.TokenType, left, right reflect.Value) (reflect.Value, error) {
+	if op == js.AddToken && (left.Kind() == reflect.String || right.Kind() == reflect.String) {
+		return reflect.ValueOf(toString(left) + toString(right)), nil
+	}
+	if isInt(left) && isInt(right) {
+		l, _ := toInt(left)
+		r, _ := toInt(right)
+		switch op {
+		case js.AddToken:
+			return reflect.ValueOf(l + r), nil
+		case js.SubToken:
+			return reflect.ValueOf(l - r), nil
+		case js.MulToken:
+			return reflect.ValueOf(l * r), nil
+		case js.DivToken:
+			if r != 0 && l%r == 0 {
+				return reflect.ValueOf(l / r), nil
+			}
+		case js.ModToken:
+			if r != 0 {
+				return reflect.ValueOf(l % r), nil
+			}
 		}
+	}
+	l, ok := toFloat(left)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("ssr: unexpected left value %s", left.Kind().String())
+	}
+	r, ok := toFloat(right)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("ssr: unexpected right value %s", right.Kind().String())
+	}
+	switch op {
+	case js.AddToken:
+		return reflect.ValueOf(l + r), nil
+	case js.SubToken:
+		return reflect.ValueOf(l - r), nil
+	case js.MulToken:
+		return reflect.ValueOf(l * r), nil
+	case js.DivToken:
+		return reflect.ValueOf(l / r), nil
+	case js.ModToken:
+		return reflect.ValueOf(math.Mod(l, r)), nil
 	default:
-		return reflect.Value{}, fmt.Errorf("ssr: unexpected left value %s", left.String())
+		return reflect.Value{}, fmt.Errorf("ssr: unknown arithmetic operator %s", op.String())
 	}
 }
 
-func evaluateOr(_ *scope, left, right reflect.Value) (reflect.Value, error) {
-	switch left.Kind() {
-	case reflect.Bool:
-		if left.Bool() {
-			return left, nil
+func evaluateCompare(op js.TokenType, left, right reflect.Value) (reflect.Value, error) {
+	var cmp int
+	if left.Kind() == reflect.String && right.Kind() == reflect.String {
+		cmp = strings.Compare(left.String(), right.String())
+	} else {
+		l, ok := toFloat(left)
+		if !ok {
+			return falseValue, nil
 		}
-		return right, nil
-	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int8, reflect.Int16:
-		if left.Int() != 0 {
-			return left, nil
+		r, ok := toFloat(right)
+		if !ok {
+			return falseValue, nil
 		}
-		return right, nil
-	case reflect.String:
-		if left.String() != "" {
-			return left, nil
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
 		}
-		return right, nil
-	case reflect.Invalid:
-		return right, nil
+	}
+	switch op {
+	case js.LtToken:
+		return reflect.ValueOf(cmp < 0), nil
+	case js.LtEqToken:
+		return reflect.ValueOf(cmp <= 0), nil
+	case js.GtToken:
+		return reflect.ValueOf(cmp > 0), nil
 	default:
-		return reflect.Value{}, fmt.Errorf("ssr: unexpected left value %s", left.String())
+		return reflect.ValueOf(cmp >= 0), nil
 	}
 }
 
 var falseValue = reflect.ValueOf(false)
 
 func evaluateEqual(_ *scope, left, right reflect.Value) (reflect.Value, error) {
+	if isNumber(left) && isNumber(right) {
+		l, _ := toFloat(left)
+		r, _ := toFloat(right)
+		return reflect.ValueOf(l == r), nil
+	}
 	switch left.Kind() {
 	case reflect.String:
 		switch right.Kind() {
@@ -638,8 +1523,6 @@ func evaluateEqual(_ *scope, left, right reflect.Value) (reflect.Value, error) {
 		case reflect.String:
 			leftString := strconv.FormatInt(left.Int(), 10)
 			return reflect.ValueOf(leftString == right.String()), nil
-		case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int8, reflect.Int16:
-			return reflect.ValueOf(left.Int() == right.Int()), nil
 		case reflect.Bool:
 			leftBool := left.Int() > 0
 			return reflect.ValueOf(leftBool == right.Bool()), nil
@@ -667,11 +1550,16 @@ func evaluateEqual(_ *scope, left, right reflect.Value) (reflect.Value, error) {
 			return reflect.ValueOf(false), nil
 		}
 	default:
-		return reflect.Value{}, fmt.Errorf("ssr: unexpected left value %s", left.Kind().String())
+		return evaluateStrictEqual(nil, left, right)
 	}
 }
 
 func evaluateStrictEqual(_ *scope, left, right reflect.Value) (reflect.Value, error) {
+	if isNumber(left) && isNumber(right) {
+		l, _ := toFloat(left)
+		r, _ := toFloat(right)
+		return reflect.ValueOf(l == r), nil
+	}
 	switch left.Kind() {
 	case reflect.String:
 		switch right.Kind() {
@@ -680,13 +1568,6 @@ func evaluateStrictEqual(_ *scope, left, right reflect.Value) (reflect.Value, er
 		default:
 			return falseValue, nil
 		}
-	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int8, reflect.Int16:
-		switch right.Kind() {
-		case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int8, reflect.Int16:
-			return reflect.ValueOf(left.Int() == right.Int()), nil
-		default:
-			return falseValue, nil
-		}
 	case reflect.Bool:
 		switch right.Kind() {
 		case reflect.Bool:
@@ -701,8 +1582,14 @@ func evaluateStrictEqual(_ *scope, left, right reflect.Value) (reflect.Value, er
 		default:
 			return reflect.ValueOf(false), nil
 		}
+	case reflect.Map, reflect.Slice, reflect.Pointer, reflect.Func:
+		// Objects are compared by reference
+		if left.Kind() != right.Kind() {
+			return falseValue, nil
+		}
+		return reflect.ValueOf(left.Pointer() == right.Pointer()), nil
 	default:
-		return reflect.Value{}, fmt.Errorf("ssr: unexpected left value %s", left.Kind().String())
+		return falseValue, nil
 	}
 }
 
@@ -734,10 +1621,31 @@ func isTruthy(value reflect.Value) bool {
 		return value.Bool()
 	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int8, reflect.Int16:
 		return value.Int() != 0
+	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint8, reflect.Uint16:
+		return value.Uint() != 0
+	case reflect.Float64, reflect.Float32:
+		return value.Float() != 0 && !math.IsNaN(value.Float())
 	case reflect.String:
 		return value.String() != ""
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func:
+		// Objects are truthy, but nil values are treated like null
+		return !value.IsNil()
 	case reflect.Invalid:
 		return false
+	default:
+		return true
+	}
+}
+
+// isNullish returns true for null and undefined values
+func isNullish(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Interface:
+		return value.IsNil() || isNullish(value.Elem())
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func:
+		return value.IsNil()
 	default:
 		return false
 	}
@@ -786,33 +1694,160 @@ func (e *evaluator) evaluateEachBlock(w writer, sc *scope, node *ast.EachBlock)
 	return nil
 }
 
-func (e *evaluator) evaluateComponent(w writer, sc *scope, node *ast.Component) error {
-	symbol, ok := e.scope.LookupByName(node.Name)
-	if !ok {
-		return fmt.Errorf("ssr: component %s not found", node.Name)
-	} else if symbol.Import == nil {
-		return fmt.Errorf("ssr: component %s not imported", node.Name)
+// evaluateAwaitBlock waits on channels and functions before rendering the then
+// or catch branch. The server never renders the pending branch. Instead, what's
+// been rendered so far is flushed to the client while we wait.
+func (e *evaluator) evaluateAwaitBlock(w writer, sc *scope, node *ast.AwaitBlock) error {
+	promise, err := evaluateExpr(sc, node.Promise)
+	if err != nil {
+		return err
 	}
-	cachePath := path.Join(path.Dir(e.path), symbol.Import.Path)
-	doc := e.cache[cachePath]
-	if e.cache[cachePath] == nil {
-		file, err := e.resolver.Resolve(&resolver.Resolve{
-			From: e.path,
-			Path: symbol.Import.Path,
-		})
-		if err != nil {
+	value, rejected := unwrap(promise), error(nil)
+	if isPromise(value) {
+		if err := e.flushAt(w, FlushAwait); err != nil {
 			return err
 		}
-		d, err := parser.Parse(file.Path, string(file.Code))
+		value, rejected, err = e.resolve(value)
 		if err != nil {
 			return err
 		}
-		e.cache[cachePath] = d
-		doc = d
+	} else if value.IsValid() && value.Type().Implements(errorType) && !isNullish(value) {
+		rejected = value.Interface().(error)
+	}
+	awaitScope := sc.child()
+	if rejected != nil {
+		if node.Error != nil {
+			awaitScope.declare(string(node.Error.Data), reflect.ValueOf(rejected))
+		}
+		if err := e.evaluateFragments(w, awaitScope, node.Catch...); err != nil {
+			return err
+		}
+	} else {
+		if node.Value != nil {
+			awaitScope.declare(string(node.Value.Data), value)
+		}
+		if err := e.evaluateFragments(w, awaitScope, node.Then...); err != nil {
+			return err
+		}
+	}
+	return e.flushAt(w, FlushAwait)
+}
+
+// isPromise returns true for values that need to be awaited: receive channels
+// and Go functions without arguments, optionally taking a context.
+func isPromise(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Chan:
+		return value.Type().ChanDir()&reflect.RecvDir != 0
+	case reflect.Func:
+		t := value.Type()
+		if t.NumOut() < 1 || t.NumOut() > 2 || (t.NumOut() == 2 && t.Out(1) != errorType) {
+			return false
+		}
+		return t.NumIn() == 0 || (t.NumIn() == 1 && t.In(0) == contextType)
+	default:
+		return false
+	}
+}
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// resolve a promise, returning the resolved value or the rejection. The
+// returned error is only set when the context is cancelled.
+func (e *evaluator) resolve(promise reflect.Value) (value reflect.Value, rejected error, err error) {
+	if promise.Kind() == reflect.Chan {
+		// Stop waiting on channels that never send once the context is done
+		chosen, value, ok := reflect.Select([]reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(e.ctx.Done())},
+			{Dir: reflect.SelectRecv, Chan: promise},
+		})
+		if chosen == 0 {
+			return reflect.Value{}, nil, e.ctx.Err()
+		} else if !ok {
+			return reflect.Value{}, nil, nil
+		}
+		if err, ok := toInterface(value).(error); ok && err != nil {
+			return reflect.Value{}, err, nil
+		}
+		return value, nil, nil
+	}
+	type result struct {
+		value    reflect.Value
+		rejected error
+	}
+	// Buffered so the goroutine can exit after we've stopped waiting on it
+	results := make(chan result, 1)
+	go func() {
+		// The promise runs outside of the request's goroutine, so a panic would
+		// take down the whole process. Reject the promise instead.
+		defer func() {
+			if r := recover(); r != nil {
+				results <- result{rejected: fmt.Errorf("ssr: panic resolving promise: %v", r)}
+			}
+		}()
+		var args []reflect.Value
+		if promise.Type().NumIn() == 1 {
+			args = append(args, reflect.ValueOf(e.ctx))
+		}
+		outs := promise.Call(args)
+		if len(outs) == 2 && !outs[1].IsNil() {
+			results <- result{rejected: outs[1].Interface().(error)}
+			return
+		}
+		results <- result{value: outs[0]}
+	}()
+	select {
+	case <-e.ctx.Done():
+		return reflect.Value{}, nil, e.ctx.Err()
+	case result := <-results:
+		return result.value, result.rejected, nil
+	}
+}
+
+// component resolves the document of a component and its path. <svelte:self>
+// is the component being rendered.
+func (e *evaluator) component(node *ast.Component) (string, *ast.Document, error) {
+	if node.IsSelf() {
+		return e.path, e.doc, nil
+	}
+	symbol, ok := e.scope.LookupByName(node.Name)
+	if !ok {
+		return "", nil, fmt.Errorf("ssr: component %s not found", node.Name)
+	} else if symbol.Import == nil {
+		return "", nil, fmt.Errorf("ssr: component %s not imported", node.Name)
+	} else if !symbol.Import.Default {
+		return "", nil, fmt.Errorf("ssr: component %s must be a default import", node.Name)
+	}
+	cachePath := path.Join(path.Dir(e.path), symbol.Import.Path)
+	if doc := e.cache[cachePath]; doc != nil {
+		return cachePath, doc, nil
+	}
+	file, err := e.resolver.Resolve(&resolver.Resolve{
+		From: e.path,
+		Path: symbol.Import.Path,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	doc, err := e.docs.Parse(file.Path, file.Code)
+	if err != nil {
+		return "", nil, err
+	}
+	e.cache[cachePath] = doc
+	return cachePath, doc, nil
+}
+
+func (e *evaluator) evaluateComponent(w writer, sc *scope, node *ast.Component) error {
+	cachePath, doc, err := e.component(node)
+	if err != nil {
+		return err
 	}
 	// Build props from attributes
 	componentScope := newScope()
 	for _, attr := range node.Attributes {
+		if _, ok := attr.(*ast.Let); ok {
+			continue
+		}
 		value, err := evaluateAttribute(sc, attr)
 		if err != nil {
 			return err
@@ -823,30 +1858,64 @@ func (e *evaluator) evaluateComponent(w writer, sc *scope, node *ast.Component)
 		}
 		componentScope.props[attr.GetKey()] = value
 	}
-	// Build the slots
+	// Distribute the children into their slots
+	componentScope.slots = map[string]*slot{
+		"": {e, sc, letsOf(node.Attributes), nil},
+	}
 	for _, fragment := range node.Children {
+		name := slotName(fragment)
+		if name == "" {
+			componentScope.slots[""].fragments = append(componentScope.slots[""].fragments, fragment)
+			continue
+		}
+		if componentScope.slots[name] == nil {
+			componentScope.slots[name] = &slot{e, sc, nil, nil}
+		}
+		named := componentScope.slots[name]
+		named.fragments = append(named.fragments, fragment)
 		switch n := fragment.(type) {
-		case *ast.Slot:
-			return fmt.Errorf("ssr: named slots not implemented yet")
-		default:
-			if err := e.evaluateFragment(&componentScope.slot, sc, n); err != nil {
-				return err
-			}
+		case *ast.Element:
+			named.lets = append(named.lets, letsOf(n.Attributes)...)
+		case *ast.Component:
+			named.lets = append(named.lets, letsOf(n.Attributes)...)
 		}
 	}
-	// Evaluate the component
-	return e.evaluateDocument(w, componentScope, doc)
+	// Evaluate the component relative to its own path and imports
+	component := &evaluator{
+		ctx:      e.ctx,
+		flush:    e.flush,
+		path:     cachePath,
+		doc:      doc,
+		scope:    doc.Scope,
+		resolver: e.resolver,
+		docs:     e.docs,
+		cache:    e.cache,
+	}
+	return component.evaluateDocument(w, componentScope, doc)
 }
 
 func (e *evaluator) evaluateSlot(w writer, sc *scope, node *ast.Slot) error {
-	if node.Name != "" {
-		return fmt.Errorf("ssr: named slots not implemented yet")
-	}
-	if sc.slot.Len() == 0 && len(node.Fallback) > 0 {
-		if err := e.evaluateFragments(&sc.slot, sc, node.Fallback...); err != nil {
-			return err
+	if e.shadowRoot {
+		return e.evaluateNativeSlot(w, sc, node)
+	}
+	content := sc.Slots()[node.Name]
+	if content == nil || content.isEmpty() {
+		return e.evaluateFragments(w, sc, node.Fallback...)
+	}
+	// Pass the slot props into the slot content
+	slotScope := content.scope.child()
+	if len(content.lets) > 0 {
+		props := map[string]reflect.Value{}
+		for _, attr := range node.Attributes {
+			value, err := evaluateAttribute(sc, attr)
+			if err != nil {
+				return err
+			}
+			props[attr.GetKey()] = value
+		}
+		for _, let := range content.lets {
+			slotScope.declare(let.Var(), props[let.Name])
 		}
 	}
-	w.WriteString(sc.slot.String())
-	return nil
+	return content.evaluator.evaluateFragments(w, slotScope, content.fragments...)
 }
@@ -1,13 +1,19 @@
 package ssr_test
 
 import (
+	"context"
+	"errors"
+	"html/template"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/livebud/duo/internal/resolver"
 	"github.com/livebud/duo/internal/ssr"
+	"github.com/matryer/is"
 	"github.com/matthewmueller/diff"
 )
 
@@ -98,6 +104,59 @@ func TestEventHandler(t *testing.T) {
 	equal(t, "", "<button onMouseOut={() => count++}>+</button>", Map{}, `<button>+</button>`)
 	equal(t, "", "<button onClick={increment} onDragStart={() => count++}>+</button>", Map{}, `<button>+</button>`)
 	equal(t, "", "<button {onClick} {onDragStart}>+</button>", Map{}, `<button>+</button>`)
+	equal(t, "", "<button on:click|preventDefault|once={increment}>+</button>", Map{}, `<button>+</button>`)
+	equal(t, "", "<button on:click>+</button>", Map{}, `<button>+</button>`)
+	equalMap(t, map[string]string{
+		"button.duo": `<button on:click>{label}</button>`,
+		"main.duo":   `<script>import Button from "./button.duo";</script><Button label="+" on:click={increment} />`,
+	}, Map{}, `<button>+</button>`)
+}
+
+func TestBinding(t *testing.T) {
+	equal(t, "", `<input bind:value={name} />`, Map{"name": "Jo"}, `<input value="Jo"/>`)
+	equal(t, "", `<input bind:value={name} />`, Map{}, `<input/>`)
+	equal(t, "", `<input type="checkbox" bind:checked={done} />`, Map{"done": true}, `<input type="checkbox" checked/>`)
+	equal(t, "", `<input type="checkbox" bind:checked={done} />`, Map{"done": false}, `<input type="checkbox"/>`)
+	equal(t, "", `<input type="radio" bind:group={flavor} value="mint" /><input type="radio" bind:group={flavor} value="lemon" />`, Map{"flavor": "lemon"}, `<input type="radio" value="mint"/><input type="radio" checked value="lemon"/>`)
+	equal(t, "", `<input type="checkbox" bind:group={toppings} value={a} /><input type="checkbox" bind:group={toppings} value={b} />`, Map{"toppings": []string{"nuts"}, "a": "nuts", "b": "fudge"}, `<input type="checkbox" checked value="nuts"/><input type="checkbox" value="fudge"/>`)
+	equal(t, "", `<textarea bind:value={bio}></textarea>`, Map{"bio": "hello"}, `<textarea>hello</textarea>`)
+	equal(t, "", `<div bind:this={el}>x</div>`, Map{}, `<div>x</div>`)
+	equal(t, "", `<input type="checkbox" bind:checked />`, Map{"checked": true}, `<input type="checkbox" checked/>`)
+	equal(t, "", `<select bind:value={flavor}><option value="mint">Mint</option><option value="lemon">Lemon</option></select>`, Map{"flavor": "lemon"}, `<select><option value="mint">Mint</option><option value="lemon" selected>Lemon</option></select>`)
+	equal(t, "", `<select bind:value={n}>{#each list as i}<option value={i}>{i}</option>{/each}</select>`, Map{"n": 2, "list": []int{1, 2}}, `<select><option value="1">1</option><option value="2" selected>2</option></select>`)
+	equal(t, "", `<select bind:value={flavor}><option>mint</option><option>lemon</option></select>`, Map{"flavor": "mint"}, `<select><option selected>mint</option><option>lemon</option></select>`)
+	equal(t, "", `<select multiple bind:value={flavors}><option>mint</option><option>lemon</option><option>fudge</option></select>`, Map{"flavors": []string{"mint", "fudge"}}, `<select multiple><option selected>mint</option><option>lemon</option><option selected>fudge</option></select>`)
+	equalMap(t, map[string]string{
+		"Input.duo": `<script>let { value = $bindable("") } = $props()</script><input bind:value={value} />`,
+		"main.duo":  `<script>import Input from "./Input.duo"; let name = "Jo"; let ref</script><Input bind:value={name} bind:this={ref} />`,
+	}, Map{}, `<input value="Jo"/>`)
+}
+
+func TestEscape(t *testing.T) {
+	equal(t, "", `<p title={text}>{text}</p>`, Map{"text": `<script>alert("&")</script>`}, `<p title="&lt;script>alert(&quot;&amp;&quot;)&lt;/script>">&lt;script>alert("&amp;")&lt;/script></p>`)
+	equal(t, "", `<p title="A &amp; {b}">A &amp; {b}</p>`, Map{"b": `"B"`}, `<p title="A &amp; &quot;B&quot;">A &amp; "B"</p>`)
+	equal(t, "", `<p class="a {b}" class:active>x</p>`, Map{"active": true, "b": `"b"`}, `<p class="a &quot;b&quot; active">x</p>`)
+	equal(t, "", `<textarea bind:value={bio}></textarea>`, Map{"bio": "</textarea>"}, `<textarea>&lt;/textarea></textarea>`)
+	// Trusted markup isn't escaped
+	equal(t, "", `<main>{children}</main>`, Map{"children": template.HTML("<h1>hi</h1>")}, `<main><h1>hi</h1></main>`)
+	// {@html} writes its value as-is
+	equal(t, "", `<div>{@html comment.text}</div>`, Map{"comment": Map{"text": `<p>Hi & "bye"</p>`}}, `<div><p>Hi & "bye"</p></div>`)
+	equal(t, "", `<div>{@html missing}</div>`, Map{}, `<div></div>`)
+}
+
+func TestClassDirective(t *testing.T) {
+	equal(t, "", `<span class:checked={item.status}>x</span>`, Map{"item": Map{"status": true}}, `<span class="checked">x</span>`)
+	equal(t, "", `<span class:checked={item.status}>x</span>`, Map{"item": Map{"status": false}}, `<span>x</span>`)
+	equal(t, "", `<span class="item {kind}" class:active class:done={count > 2}>x</span>`, Map{"kind": "todo", "active": true, "count": 3}, `<span class="item todo active done">x</span>`)
+	equal(t, "", `<span id="a" class:active class={extra}>x</span>`, Map{"extra": "big", "active": false}, `<span id="a" class="big">x</span>`)
+	equal(t, "", `<span class="item" class:active>x</span>`, Map{}, `<span class="item">x</span>`)
+}
+
+func TestStyleDirective(t *testing.T) {
+	equal(t, "", `<span style:color={color}>x</span>`, Map{"color": "red"}, `<span style="color: red;">x</span>`)
+	equal(t, "", `<span style:color>x</span>`, Map{}, `<span>x</span>`)
+	equal(t, "", `<span style="margin: 0;" style:color style:width="{width}px">x</span>`, Map{"color": "red", "width": 10}, `<span style="margin: 0; color: red; width: 10px;">x</span>`)
+	equal(t, "", `<span style:background-color="blue" style={style}>x</span>`, Map{"style": "margin: 0"}, `<span style="background-color: blue; margin: 0;">x</span>`)
 }
 
 func TestExpr(t *testing.T) {
@@ -115,7 +174,7 @@ func TestExpr(t *testing.T) {
 }
 
 func TestFile(t *testing.T) {
-	equalFile(t, "01-greeting.html", Map{}, "\n\n<h1></h1>")
+	equalFile(t, "01-greeting.html", Map{}, "\n\n<h1>hello</h1>")
 	equalFile(t, "01-greeting.html", Map{"greeting": "hi"}, "\n\n<h1>hi</h1>")
 	equalFile(t, "02-attribute.html", Map{}, "<div>\n  <hr/>\n  <hr name=\"\"/>\n  <hr name=\"\"/>\n  <hr name=\"-\"/>\n  <hr name=\"\"/>\n</div>")
 	equalFile(t, "02-attribute.html", Map{"name": "anki"}, "<div>\n  <hr name=\"anki\"/>\n  <hr name=\"anki\"/>\n  <hr name=\"anki\"/>\n  <hr name=\"-anki\"/>\n  <hr name=\"\"/>\n</div>")
@@ -200,6 +259,20 @@ func TestComponent(t *testing.T) {
 		"component.duo": `<h1>{title}</h1>`,
 		"main.duo":      `<script>import Component from "./component.duo";</script><Component title={h1}>hello</Component>`,
 	}, Map{"title": "hi"}, `<h1></h1>`)
+	equalMap(t, map[string]string{
+		"component.duo": `<h1>{title}</h1>`,
+		"main.duo":      `<script>import { default as Component } from "./component.duo";</script><Component title="hi" />`,
+	}, Map{}, `<h1>hi</h1>`)
+	equalMap(t, map[string]string{
+		"component.duo": `<h1>{title}</h1>`,
+		"main.duo":      `<script>import { Component } from "./component.duo";</script><Component />`,
+	}, Map{}, `ssr: component Component must be a default import`)
+	// Components can import themselves to render recursively
+	equalMap(t, map[string]string{
+		"main.duo": `<script>import Tree from "./main.duo"; export let node = {}</script><li>{node.name}{#each node.children as node}<ul><Tree {node} /></ul>{/each}</li>`,
+	}, Map{"node": Map{"name": "a", "children": []Map{{"name": "b", "children": []Map{}}}}}, `<li>a<ul><li>b</li></ul></li>`)
+	// Or with <svelte:self>
+	equal(t, "", `<script>export let node = {}</script><li>{node.name}{#each node.children as node}<ul><svelte:self {node} /></ul>{/each}</li>`, Map{"node": Map{"name": "a", "children": []Map{{"name": "b", "children": []Map{{"name": "c", "children": []Map{}}}}}}}, `<li>a<ul><li>b<ul><li>c</li></ul></li></ul></li>`)
 }
 
 func TestSlot(t *testing.T) {
@@ -237,3 +310,429 @@ func TestCoerce(t *testing.T) {
 func TestStyle(t *testing.T) {
 	// equal(t, "", `<style></style>`, Map{}, ``)
 }
+
+func TestScript(t *testing.T) {
+	equal(t, "", `<script>export let name = "world"</script><h1>hello {name}</h1>`, Map{}, `<h1>hello world</h1>`)
+	equal(t, "", `<script>export let name = "world"</script><h1>hello {name}</h1>`, Map{"name": "anki"}, `<h1>hello anki</h1>`)
+	equal(t, "", `<script>let { count = 10 } = $props()</script><p>{count}</p>`, Map{}, `<p>10</p>`)
+	equal(t, "", `<script>let { count = 10 } = $props()</script><p>{count}</p>`, Map{"count": 3}, `<p>3</p>`)
+	equal(t, "", `<script>let { title: heading = "untitled", ...rest } = $props()</script><h1>{heading} {rest.id}</h1>`, Map{"title": "hi", "id": 1}, `<h1>hi 1</h1>`)
+	equal(t, "", `<script>const props = $props()</script><h1>{props.title}</h1>`, Map{"title": "hi"}, `<h1>hi</h1>`)
+	equal(t, "", `<script>const a = 1; let b = a + 2; const c = `+"`${a}-${b}`"+`</script><p>{c}</p>`, Map{}, `<p>1-3</p>`)
+	equal(t, "", `<script>let count = 1; setInterval(() => count++, 1000)</script><p>{count}</p>`, Map{}, `<p>1</p>`)
+	equal(t, "", `<script>function double(n) { return n * 2 }</script><p>{double(4)}</p>`, Map{}, `<p>8</p>`)
+	equal(t, "", `<script>const triple = (n) => n * 3</script><p>{triple(2)}</p>`, Map{}, `<p>6</p>`)
+	equal(t, "", `<script>function sum(list) { let total = 0; for (const n of list) { total += n } return total }</script><p>{sum([1, 2, 3])}</p>`, Map{}, `<p>6</p>`)
+	equal(t, "", `<script>const user = { name: "anki", tags: ["a", "b"] }</script><p>{user.name} {user.tags.length} {user.tags[1]}</p>`, Map{}, `<p>anki 2 b</p>`)
+	equal(t, "url.duo", `<script>const url = new URL("https://news.ycombinator.com/item?id=1")</script><p>{url.host}{url.pathname}{url.search}</p>`, Map{}, `<p>news.ycombinator.com/item?id=1</p>`)
+	equal(t, "", `<script>let label = "café ❌ 😀"; const escaped = 'caf\xE9 \u274C \u{1F600}\
+!'</script><p title={label}>{label} {escaped}</p>`, Map{}, `<p title="café ❌ 😀">café ❌ 😀 café ❌ 😀!</p>`)
+	equal(t, "", `<script lang="ts">const label: string = "café 😀"</script><p title={"é" as string}>{label}</p>`, Map{}, `<p title="é">café 😀</p>`)
+	equal(t, "", `<script>function fail() { throw "oops" }</script><p>{fail()}</p>`, Map{}, `ssr: uncaught exception oops`)
+	equal(t, "", `<script>function fail() { while (true) {} }</script><p>{fail()}</p>`, Map{}, `ssr: unsupported statement *js.WhileStmt`)
+	equal(t, "", `<script>function f() { return f() }</script><p>{f()}</p>`, Map{}, `ssr: maximum call depth exceeded calling f`)
+	equal(t, "", `<script>const even = (n) => n == 0 || odd(n - 1); const odd = (n) => n != 0 && even(n - 1)</script><p>{even(10)} {even(5000)}</p>`, Map{}, `ssr: maximum call depth exceeded calling anonymous function`)
+}
+
+func TestScriptMutateProps(t *testing.T) {
+	is := is.New(t)
+	type User struct {
+		Name string
+		Tags []string
+	}
+	resolver := resolver.Embedded{
+		"user.svelte": []byte(`<script>let { user } = $props(); function rename() { user.name = "b"; user.tags[0] = "y"; return user.name + user.tags[0] }</script><p>{rename()}</p>`),
+	}
+	renderer := ssr.New(resolver)
+	str := new(strings.Builder)
+	props := Map{"user": Map{"name": "a", "tags": []string{"x"}}}
+	is.NoErr(renderer.Render(str, "user.svelte", props))
+	is.Equal(str.String(), `<p>by</p>`)
+	is.Equal(props["user"], Map{"name": "a", "tags": []string{"x"}})
+	user := &User{Name: "a", Tags: []string{"x"}}
+	str.Reset()
+	is.NoErr(renderer.Render(str, "user.svelte", Map{"user": user}))
+	is.Equal(str.String(), `<p>by</p>`)
+	is.Equal(user, &User{Name: "a", Tags: []string{"x"}})
+}
+
+func TestScriptPropsSharingAddress(t *testing.T) {
+	is := is.New(t)
+	type Inner struct{ Name string }
+	type Outer struct{ Inner Inner }
+	type Pair struct {
+		A *Outer
+		B *Inner
+	}
+	resolver := resolver.Embedded{
+		"show.svelte": []byte(`<script>let { outer, inner, list, pair } = $props()</script><p>{outer.Inner.Name} {inner.Name} {list[1].Name} {pair.B.Name}</p>`),
+	}
+	renderer := ssr.New(resolver)
+	str := new(strings.Builder)
+	// A pointer to a struct and a pointer to its first field share an address
+	outer := &Outer{Inner: Inner{Name: "in"}}
+	props := struct {
+		Outer *Outer
+		Inner *Inner
+		List  []interface{}
+		Pair  Pair
+	}{outer, &outer.Inner, []interface{}{outer, &outer.Inner}, Pair{outer, &outer.Inner}}
+	is.NoErr(renderer.Render(str, "show.svelte", props))
+	is.Equal(str.String(), `<p>in in in in</p>`)
+}
+
+func TestScriptSwitch(t *testing.T) {
+	input := `<script>
+	function label(n) {
+		switch (n) {
+			case 0:
+			case 1:
+				return "few"
+			case 2:
+				n = 3
+				break
+			default:
+				return ` + "`${n || 0} many`" + `
+		}
+		return "two, now " + n
+	}
+</script><p>{label(count)}</p>`
+	equal(t, "switch-0", input, Map{"count": 0}, `<p>few</p>`)
+	equal(t, "switch-1", input, Map{"count": 1}, `<p>few</p>`)
+	equal(t, "switch-2", input, Map{"count": 2}, `<p>two, now 3</p>`)
+	equal(t, "switch-5", input, Map{"count": 5}, `<p>5 many</p>`)
+}
+
+func TestScriptStory(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("..", "..", "example", "hn", "view", "Story.svelte"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type Story struct {
+		ID          int
+		Title       string
+		URL         string
+		Points      int
+		Author      string
+		NumComments int
+	}
+	renderer := ssr.New(resolver.Embedded{"Story.svelte": input})
+	str := new(strings.Builder)
+	props := Map{"story": &Story{ID: 1, Title: "Duo", URL: "https://github.com/livebud/duo", Points: 10, Author: "anki", NumComments: 2}}
+	if err := renderer.Render(str, "Story.svelte", props); err != nil {
+		t.Fatal(err)
+	}
+	actual := str.String()
+	if !strings.Contains(actual, `<a class="title svelte-1tbs0et" href="https://github.com/livebud/duo">Duo</a>`) {
+		t.Fatalf("unexpected story title in %s", actual)
+	}
+	if !strings.Contains(actual, `10 points by anki`) {
+		t.Fatalf("unexpected story meta in %s", actual)
+	}
+}
+
+func TestRunes(t *testing.T) {
+	equal(t, "", `<script>let greeting = $state("hello")</script><h1>{greeting}</h1>`, Map{}, `<h1>hello</h1>`)
+	equal(t, "", `<script>let items = $state.raw([1, 2])</script><p>{items.length}</p>`, Map{}, `<p>2</p>`)
+	equal(t, "", `<script>let count = $state(2); let doubled = $derived(count * 2)</script><p>{doubled}</p>`, Map{}, `<p>4</p>`)
+	equal(t, "", `<script>let { count = 1 } = $props(); let total = $derived.by(() => { let sum = 0; for (const n of [1, 2]) sum += n * count; return sum })</script><p>{total}</p>`, Map{"count": 2}, `<p>6</p>`)
+	equal(t, "", `<script>let { todos = $bindable([]) } = $props()</script><p>{todos.length}</p>`, Map{}, `<p>0</p>`)
+	equal(t, "", `<script>let { todos = $bindable([]) } = $props()</script><p>{todos.length}</p>`, Map{"todos": []string{"a", "b"}}, `<p>2</p>`)
+	equal(t, "", `<script>let count = $state(0); $effect(() => { count = 10 })</script><p>{count}</p>`, Map{}, `<p>0</p>`)
+	equal(t, "", `<p>{$state(1)}</p>`, Map{}, `parser: <p>{$state(1)}</p>: $state(...) can only be used in the script`)
+	equal(t, "", `<p>{$props()}</p>`, Map{}, `parser: <p>{$props()}</p>: $props(...) can only be used in the script`)
+	equal(t, "", `<script>let all = [$props()]</script><p>{all.length}</p>`, Map{}, `ssr: unexpected $props() outside of a variable declaration`)
+}
+
+func TestRunesTestdata(t *testing.T) {
+	read := func(name string) string {
+		code, err := os.ReadFile(filepath.Join("..", "..", "testdata", name, "input.svelte"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(code)
+	}
+	equal(t, "02-greeting.svelte", read("02-greeting"), Map{}, "\n\n<h1 name=\"hello-hello-cool\">hello</h1>\n")
+	equal(t, "03-counter.svelte", read("03-counter"), Map{}, "\n\n<button>\n  Clicked 10\n  times\n</button>\n")
+	equal(t, "03-counter.svelte", read("03-counter"), Map{"count": 1}, "\n\n<button>\n  Clicked 1\n  time\n</button>\n")
+}
+
+func TestNamedSlot(t *testing.T) {
+	card := `<article><header><slot name="header">Untitled</slot></header><slot /><footer><slot name="footer" /></footer></article>`
+	equalMap(t, map[string]string{
+		"Card.duo": card,
+		"main.duo": `<script>import Card from './Card.duo';</script><Card><h1 slot="header">{title}</h1><p>body</p><small slot="footer">footer</small></Card>`,
+	}, Map{"title": "hi"}, `<article><header><h1>hi</h1></header><p>body</p><footer><small>footer</small></footer></article>`)
+	equalMap(t, map[string]string{
+		"Card.duo": card,
+		"main.duo": `<script>import Card from './Card.duo';</script><Card>  </Card>`,
+	}, Map{}, `<article><header>Untitled</header><footer></footer></article>`)
+	equalMap(t, map[string]string{
+		"Card.duo": `<div>{#if $$slots.header}<header><slot name="header" /></header>{/if}{#if $$slots.default}<main><slot /></main>{/if}</div>`,
+		"main.duo": `<script>import Card from './Card.duo';</script><Card><h1 slot="header">hi</h1></Card>`,
+	}, Map{}, `<div><header><h1>hi</h1></header></div>`)
+	equalMap(t, map[string]string{
+		"Inner.duo": `<span><slot name="label">inner</slot></span>`,
+		"Outer.duo": `<script>import Inner from './Inner.duo';</script><Inner><b slot="label"><slot /></b></Inner>`,
+		"main.duo":  `<script>import Outer from './Outer.duo';</script><Outer>{title}</Outer>`,
+	}, Map{"title": "nested"}, `<span><b>nested</b></span>`)
+}
+
+func TestSlotProps(t *testing.T) {
+	list := `<ul>{#each items as item}<li><slot item={item} index="{item}!">{item}</slot></li>{/each}</ul>`
+	equalMap(t, map[string]string{
+		"List.duo": list,
+		"main.duo": `<script>import List from './List.duo';</script><List items={items} let:item>[{item}]</List>`,
+	}, Map{"items": []string{"a", "b"}}, `<ul><li>[a]</li><li>[b]</li></ul>`)
+	equalMap(t, map[string]string{
+		"List.duo": list,
+		"main.duo": `<script>import List from './List.duo';</script><List items={items} let:index={label}>{label}</List>`,
+	}, Map{"items": []string{"a", "b"}}, `<ul><li>a!</li><li>b!</li></ul>`)
+	equalMap(t, map[string]string{
+		"List.duo": list,
+		"main.duo": `<script>import List from './List.duo';</script><List items={items} />`,
+	}, Map{"items": []string{"a", "b"}}, `<ul><li>a</li><li>b</li></ul>`)
+	equalMap(t, map[string]string{
+		"Table.duo": `<table><tbody>{#each rows as row}<tr><slot name="row" {row} /></tr>{/each}</tbody></table>`,
+		"main.duo":  `<script>import Table from './Table.duo';</script><Table rows={rows}><td slot="row" let:row>{row}</td></Table>`,
+	}, Map{"rows": []int{1, 2}}, `<table><tbody><tr><td>1</td></tr><tr><td>2</td></tr></tbody></table>`)
+}
+
+func TestAwait(t *testing.T) {
+	equal(t, "", `{#await story}<p>loading</p>{:then s}<h1>{s}</h1>{/await}`, Map{"story": "hi"}, `<h1>hi</h1>`)
+	equal(t, "", `{#await story then s}<h1>{s}</h1>{/await}`, Map{"story": func() string { return "hi" }}, `<h1>hi</h1>`)
+	equal(t, "", `{#await story}<p>loading</p>{:then s}<h1>{s}</h1>{:catch err}<p>{err.message}</p>{/await}`, Map{"story": func() (string, error) { return "", errors.New("not found") }}, `<p>not found</p>`)
+	equal(t, "", `{#await story catch err}<p>{err}</p>{/await}`, Map{"story": func(ctx context.Context) (string, error) { return "", errors.New("not found") }}, `<p>not found</p>`)
+	equal(t, "", `{#await story catch err}<p>{err.message}</p>{/await}`, Map{"story": func() string { panic("boom") }}, `<p>ssr: panic resolving promise: boom</p>`)
+	stories := make(chan []string, 1)
+	stories <- []string{"a", "b"}
+	equal(t, "", `<ul>{#await stories then stories}{#each stories as story}<li>{story}</li>{/each}{/await}</ul>`, Map{"stories": stories}, `<ul><li>a</li><li>b</li></ul>`)
+}
+
+// flushRecorder records the output at each flush
+type flushRecorder struct {
+	strings.Builder
+	flushes []string
+}
+
+func (f *flushRecorder) Flush() {
+	f.flushes = append(f.flushes, f.String())
+}
+
+func TestStreamFlush(t *testing.T) {
+	is := is.New(t)
+	renderer := ssr.New(resolver.Embedded{
+		"index.duo": []byte(`<html><head><title>{title}</title></head><body>{#await story then s}<h1>{s}</h1>{/await}<footer></footer></body></html>`),
+	})
+	story := make(chan string, 1)
+	story <- "hi"
+	recorder := new(flushRecorder)
+	err := renderer.Render(recorder, "index.duo", Map{"title": "news", "story": story})
+	is.NoErr(err)
+	is.Equal(recorder.flushes, []string{
+		`<html><head><title>news</title></head>`,
+		`<html><head><title>news</title></head><body>`,
+		`<html><head><title>news</title></head><body><h1>hi</h1>`,
+		`<html><head><title>news</title></head><body><h1>hi</h1><footer></footer></body></html>`,
+	})
+	// Disable flushing
+	renderer.Flush = 0
+	story <- "hi"
+	recorder = new(flushRecorder)
+	err = renderer.Render(recorder, "index.duo", Map{"title": "news", "story": story})
+	is.NoErr(err)
+	is.Equal(recorder.flushes, []string{
+		`<html><head><title>news</title></head><body><h1>hi</h1><footer></footer></body></html>`,
+	})
+}
+
+func TestStreamCancel(t *testing.T) {
+	is := is.New(t)
+	renderer := ssr.New(resolver.Embedded{
+		"index.duo": []byte(`<head><title>news</title></head>{#await story then s}<h1>{s}</h1>{/await}`),
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	story := func() string {
+		cancel()
+		<-ctx.Done()
+		return "too late"
+	}
+	recorder := new(flushRecorder)
+	err := renderer.RenderContext(ctx, recorder, "index.duo", Map{"story": story})
+	is.True(errors.Is(err, context.Canceled))
+	is.Equal(recorder.String(), `<head><title>news</title></head>`)
+	// Nothing is rendered once the context is cancelled
+	recorder = new(flushRecorder)
+	err = renderer.RenderContext(ctx, recorder, "index.duo", Map{})
+	is.True(errors.Is(err, context.Canceled))
+	is.Equal(recorder.String(), ``)
+	// Stop waiting on channels that never send
+	chanCtx, cancelChan := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancelChan)
+	err = renderer.RenderContext(chanCtx, new(flushRecorder), "index.duo", Map{"story": make(chan string)})
+	is.True(errors.Is(err, context.Canceled))
+	// Promises that take a context are cancelled along with the render
+	funcCtx, cancelFunc := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	slow := func(ctx context.Context) string {
+		defer close(stopped)
+		cancelFunc()
+		<-ctx.Done()
+		return "too late"
+	}
+	err = renderer.RenderContext(funcCtx, new(flushRecorder), "index.duo", Map{"story": slow})
+	is.True(errors.Is(err, context.Canceled))
+	<-stopped
+}
+
+func TestStreamBuffer(t *testing.T) {
+	is := is.New(t)
+	renderer := ssr.New(resolver.Embedded{
+		"index.duo": []byte(`<ul>{#each items as item}<li>{item}</li>{/each}</ul><p>{story.title}</p>`),
+	})
+	items := make([]string, 1000)
+	for i := range items {
+		items[i] = "item"
+	}
+	// Output is only written at flush points, even past bufio's 4KB buffer
+	recorder := new(flushRecorder)
+	err := renderer.Render(recorder, "index.duo", Map{"items": items})
+	is.True(err != nil)
+	is.Equal(err.Error(), `ssr: cannot read property "title" of undefined`)
+	is.Equal(recorder.String(), ``)
+	is.Equal(len(recorder.flushes), 0)
+}
+
+func TestScopedStyles(t *testing.T) {
+	is := is.New(t)
+	renderer := ssr.New(resolver.Embedded{
+		"index.svelte": []byte(`<script>import Item from "./Item.svelte"</script><ul><Item /><Item /></ul><p>done</p><style>ul { margin: 0; }</style>`),
+		"Item.svelte":  []byte(`<li class="done">item</li><style>li.done { color: gray; } :global(body) li { padding: 0; }</style>`),
+	})
+	styles := new(ssr.Styles)
+	ctx := ssr.WithStyles(context.Background(), styles)
+	str := new(strings.Builder)
+	err := renderer.RenderContext(ctx, str, "index.svelte", Map{})
+	is.NoErr(err)
+	is.Equal(str.String(), `<ul class="svelte-84gu7p"><li class="done svelte-54oudm">item</li><li class="done svelte-54oudm">item</li></ul><p>done</p>`)
+	// Each component's CSS is only collected once
+	is.Equal(styles.String(), "li.done.svelte-54oudm { color: gray }\nbody li.svelte-54oudm { padding: 0 }\nul.svelte-84gu7p { margin: 0 }")
+	// Without collecting the styles, each component's CSS is written inline once
+	str.Reset()
+	err = renderer.Render(str, "index.svelte", Map{})
+	is.NoErr(err)
+	is.Equal(str.String(), `<ul class="svelte-84gu7p"><li class="done svelte-54oudm">item</li><style>li.done.svelte-54oudm { color: gray }`+"\n"+`body li.svelte-54oudm { padding: 0 }</style><li class="done svelte-54oudm">item</li></ul><p>done</p><style>ul.svelte-84gu7p { margin: 0 }</style>`)
+}
+
+func TestCustomElement(t *testing.T) {
+	is := is.New(t)
+	renderer := ssr.New(resolver.Embedded{
+		"index.svelte":  []byte(`<script>import Widget from "./Widget.svelte"</script><main><Widget name={name} open={true}><b slot="title">{title}</b>body</Widget></main><style>main { padding: 0; }</style>`),
+		"Widget.svelte": []byte(`<svelte:options customElement="my-widget" /><script>import Icon from "./Icon.svelte"; export let name = "world"; export let open = false; export let items = [];</script><h1><Icon /><slot name="title">Hello</slot> {name}!</h1><slot /><style>h1 { color: red; }</style>`),
+		"Icon.svelte":   []byte(`<i>*</i><style>i { color: blue; }</style>`),
+	})
+	styles := new(ssr.Styles)
+	ctx := ssr.WithStyles(context.Background(), styles)
+	str := new(strings.Builder)
+	err := renderer.RenderContext(ctx, str, "index.svelte", Map{"name": "Mark & co", "title": "Hi"})
+	is.NoErr(err)
+	is.Equal(str.String(), `<main class="svelte-lldymo"><my-widget name="Mark &amp; co" open><template shadowrootmode="open"><style>i.svelte-yasxpm { color: blue }`+"\n"+`h1.svelte-1erwonp { color: red }</style><h1 class="svelte-1erwonp"><i class="svelte-yasxpm">*</i><slot name="title">Hello</slot> Mark &amp; co!</h1><slot></slot></template>body<b slot="title">Hi</b></my-widget></main>`)
+	// Styles within the shadow root aren't added to the page
+	is.Equal(styles.String(), "main.svelte-lldymo { padding: 0 }")
+}
+
+func TestRenderPage(t *testing.T) {
+	is := is.New(t)
+	renderer := ssr.New(resolver.Embedded{
+		"index.svelte": []byte(`<script>import Item from "./Item.svelte"</script><ul><Item /><Item /></ul><style>ul { margin: 0; }</style>`),
+		"Item.svelte":  []byte(`<li>item</li><style>li { color: gray; }</style>`),
+		"plain.svelte": []byte(`<p>plain</p>`),
+	})
+	result, err := renderer.RenderPage(context.Background(), "index.svelte", Map{})
+	is.NoErr(err)
+	is.Equal(result.HTML, `<ul class="svelte-84gu7p"><li class="svelte-tk2e3p">item</li><li class="svelte-tk2e3p">item</li></ul>`)
+	is.Equal(result.CSS, "li.svelte-tk2e3p { color: gray }\nul.svelte-84gu7p { margin: 0 }")
+	is.Equal(result.CSSHref, "")
+	is.Equal(result.HeadHTML(), "<style>li.svelte-tk2e3p { color: gray }\nul.svelte-84gu7p { margin: 0 }</style>")
+	// Link to a hashed stylesheet instead
+	renderer.Stylesheet = func(hash string) string { return "/styles/" + hash + ".css" }
+	linked, err := renderer.RenderPage(context.Background(), "index.svelte", Map{})
+	is.NoErr(err)
+	is.Equal(linked.HTML, result.HTML)
+	is.Equal(linked.CSS, result.CSS)
+	is.True(strings.HasPrefix(linked.CSSHref, "/styles/"))
+	is.Equal(linked.HeadHTML(), `<link rel="stylesheet" href="`+linked.CSSHref+`">`)
+	// No head elements without styles
+	plain, err := renderer.RenderPage(context.Background(), "plain.svelte", Map{})
+	is.NoErr(err)
+	is.Equal(plain.HTML, `<p>plain</p>`)
+	is.Equal(len(plain.Head), 0)
+}
+
+func TestStrict(t *testing.T) {
+	is := is.New(t)
+	resolver := resolver.Embedded{
+		"story.svelte": []byte(`<script lang="ts">let { title, points = 0 }: { title: string; points?: number } = $props()</script><h1>{title} ({points})</h1>`),
+	}
+	renderer := ssr.New(resolver)
+	renderer.Strict = true
+	str := new(strings.Builder)
+	is.NoErr(renderer.Render(str, "story.svelte", map[string]any{"title": "hi"}))
+	is.Equal(str.String(), `<h1>hi (0)</h1>`)
+	err := renderer.Render(new(strings.Builder), "story.svelte", map[string]any{"points": "1", "url": "/"})
+	is.True(err != nil)
+	diff.TestString(t, err.Error(), "props: story.svelte: missing prop \"title\"\nprops: story.svelte: prop \"points\" should be a number, not string\nprops: story.svelte: unknown prop \"url\"")
+	// Without strict mode, missing props render as empty
+	renderer.Strict = false
+	str.Reset()
+	is.NoErr(renderer.Render(str, "story.svelte", map[string]any{}))
+	is.Equal(str.String(), `<h1> (0)</h1>`)
+}
+
+func TestStructProps(t *testing.T) {
+	is := is.New(t)
+	resolver := resolver.Embedded{
+		"story.svelte": []byte(`<script>export let title = ""; export let points = 0</script><h1>{title} ({points})</h1>`),
+	}
+	type Props struct {
+		Title  string
+		Points int `json:"points"`
+	}
+	renderer := ssr.New(resolver)
+	str := new(strings.Builder)
+	is.NoErr(renderer.Render(str, "story.svelte", &Props{Title: "hi", Points: 3}))
+	is.Equal(str.String(), `<h1>hi (3)</h1>`)
+	is.NoErr(renderer.Check("story.svelte", reflect.TypeOf(Props{})))
+	err := renderer.Check("story.svelte", reflect.TypeOf(struct{ Title []string }{}))
+	is.True(err != nil)
+	is.Equal(err.Error(), `props: story.svelte: prop "title" should be a string, not []string`)
+	// Nested fields match by json tag or case-insensitively
+	type Story struct {
+		Title string
+		Link  string `json:"url"`
+	}
+	resolver["show.svelte"] = []byte(`<script lang="ts">let { story }: { story: { title: string; url: string } } = $props()</script><a href={story.url}>{story.title}</a>`)
+	str.Reset()
+	is.NoErr(renderer.Render(str, "show.svelte", struct{ Story Story }{Story{"hi", "/hi"}}))
+	is.Equal(str.String(), `<a href="/hi">hi</a>`)
+	// Zero values are passed through, while missing fields and nil pointers
+	// fall back to the defaults
+	resolver["story.svelte"] = []byte(`<script>export let title = "untitled"; export let points = 10</script><h1>{title} ({points})</h1>`)
+	str.Reset()
+	is.NoErr(renderer.Render(str, "story.svelte", &Props{}))
+	is.Equal(str.String(), `<h1> (0)</h1>`)
+	type OptionalProps struct {
+		Title  *string
+		Points *int
+	}
+	str.Reset()
+	is.NoErr(renderer.Render(str, "story.svelte", &OptionalProps{}))
+	is.Equal(str.String(), `<h1>untitled (10)</h1>`)
+	title, points := "", 0
+	str.Reset()
+	is.NoErr(renderer.Render(str, "story.svelte", &OptionalProps{&title, &points}))
+	is.Equal(str.String(), `<h1> (0)</h1>`)
+	str.Reset()
+	is.NoErr(renderer.Render(str, "story.svelte", struct{ Title string }{}))
+	is.Equal(str.String(), `<h1> (10)</h1>`)
+}
@@ -0,0 +1,58 @@
+package ssr
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/livebud/duo/internal/ast"
+)
+
+type stylesKey struct{}
+
+// WithStyles collects the scoped CSS of every component rendered with the
+// returned context into styles
+func WithStyles(ctx context.Context, styles *Styles) context.Context {
+	return context.WithValue(ctx, stylesKey{}, styles)
+}
+
+func stylesFrom(ctx context.Context) *Styles {
+	styles, _ := ctx.Value(stylesKey{}).(*Styles)
+	return styles
+}
+
+// Styles is the CSS collected while rendering. Each component's styles are
+// only included once, in the order the components were rendered.
+type Styles struct {
+	mu     sync.Mutex
+	scopes map[string]bool
+	css    []string
+	inline bool // write each component's CSS where it first renders
+}
+
+// add the style's CSS if it hasn't been added already, returning the CSS when
+// it's added
+func (s *Styles) add(style *ast.Style) (string, bool) {
+	if s == nil || style.StyleSheet == nil || len(style.StyleSheet.Rules) == 0 {
+		return "", false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.scopes == nil {
+		s.scopes = map[string]bool{}
+	}
+	if s.scopes[style.Scope] {
+		return "", false
+	}
+	s.scopes[style.Scope] = true
+	css := style.StyleSheet.String()
+	s.css = append(s.css, css)
+	return css, true
+}
+
+// String returns the collected CSS
+func (s *Styles) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strings.Join(s.css, "\n")
+}
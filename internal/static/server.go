@@ -1,16 +1,19 @@
 package static
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html/template"
+	"io"
 	"io/fs"
 	"net"
 	"net/http"
 	"os"
 	"path"
 	"strings"
+	"sync"
 
 	"github.com/evanw/esbuild/pkg/api"
 	"github.com/livebud/duo/internal/esbuild"
@@ -22,7 +25,7 @@ func Serve(ln net.Listener, dir string) error {
 	return http.Serve(ln, Dir(dir))
 }
 
-func Dir(dir string) http.Handler {
+func Dir(dir string) *Server {
 	fsys := os.DirFS(dir)
 	resolver := resolver.New(fsys)
 	return &Server{
@@ -52,6 +55,19 @@ type Server struct {
 	Frames     resolver.Interface
 	Errors     resolver.Interface
 	ClientPath func(filePath string) string
+
+	stylesheets sync.Map // css hash -> css, when the renderer links stylesheets
+}
+
+// Invalidate the cached documents for the changed paths. Paths are relative to
+// the served directory. Changes can affect any page's CSS, so the linked
+// stylesheets are cleared too.
+func (s *Server) Invalidate(paths ...string) {
+	s.SSR.Cache.Invalidate(paths...)
+	s.stylesheets.Range(func(hash, _ any) bool {
+		s.stylesheets.Delete(hash)
+		return true
+	})
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -60,6 +76,8 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	switch ext {
 	case ".js":
 		s.serveJS(w, urlPath)
+	case ".css":
+		s.serveCSS(w, r, urlPath)
 	case "":
 		s.serveHTML(w, r, urlPath)
 	default:
@@ -67,7 +85,7 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-const defaultLayout = `<html><head></head><body><main id="svelte">{children}</main>{script}</body></html>`
+const defaultLayout = `<html><head>{head}</head><body><main id="svelte">{children}</main>{script}</body></html>`
 const defaultError = `<html><head></head><body><main id="svelte"><h1>Internal Server Error</h1></main>{script}</body></html>`
 
 func (s *Server) serveHTML(w http.ResponseWriter, r *http.Request, urlPath string) {
@@ -145,37 +163,49 @@ type Page struct {
 	Content *resolver.File
 }
 
+// Render the page within its frames and layout. The CSS of the page, its frames
+// and the layout is injected into the layout with the {head} prop.
 func (s *Server) Render(w http.ResponseWriter, page *Page, props map[string]interface{}) error {
-	children := new(bytes.Buffer)
 	jsonProps, err := json.Marshal(props)
 	if err != nil {
 		return err
 	}
-	if err := s.SSR.Evaluate(children, page.Content.Path, page.Content.Code, props); err != nil {
+	// Share the styles across the page and its frames
+	ctx := ssr.WithStyles(context.Background(), new(ssr.Styles))
+	result, err := s.SSR.EvaluatePage(ctx, page.Content.Path, page.Content.Code, props)
+	if err != nil {
 		return s.SSR.Evaluate(w, page.Error.Path, page.Error.Code, props)
 	}
 	for _, frame := range page.Frames {
-		props["children"] = children.String()
-		children = new(bytes.Buffer)
-		if err := s.SSR.Evaluate(children, frame.Path, frame.Code, props); err != nil {
+		props["children"] = template.HTML(result.HTML)
+		result, err = s.SSR.EvaluatePage(ctx, frame.Path, frame.Code, props)
+		if err != nil {
 			return s.SSR.Evaluate(w, page.Error.Path, page.Error.Code, props)
 		}
 	}
-	props["children"] = children.String()
-	props["script"] = fmt.Sprintf(`<script type="module" src=%q></script><script id="props" type="text/template">%s</script>`, s.ClientPath(page.Content.Path), string(jsonProps))
-	children = new(bytes.Buffer)
-	if err := s.SSR.Evaluate(children, page.Layout.Path, page.Layout.Code, props); err != nil {
+	props["children"] = template.HTML(result.HTML)
+	// The head isn't known until the layout's styles have been collected too
+	props["head"] = template.HTML(headPlaceholder)
+	props["script"] = template.HTML(fmt.Sprintf(`<script type="module" src=%q></script><script id="props" type="text/template">%s</script>`, s.ClientPath(page.Content.Path), string(jsonProps)))
+	layout, err := s.SSR.EvaluatePage(ctx, page.Layout.Path, page.Layout.Code, props)
+	if err != nil {
 		return err
 	}
+	if layout.CSSHref != "" {
+		s.stylesheets.Store(layout.CSSHash, layout.CSS)
+	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if _, err := children.WriteTo(w); err != nil {
+	if _, err := io.WriteString(w, strings.Replace(layout.HTML, headPlaceholder, layout.HeadHTML(), 1)); err != nil {
 		return err
 	}
 	return nil
 }
 
+// headPlaceholder marks where the head elements go in the rendered layout
+const headPlaceholder = "<!--duo:head-->"
+
 const entryCode = `
-	import { hydrate } from "https://esm.run/svelte@next";
+	import { hydrate } from "svelte";
 	import Content from "./%[1]s";
 	const props = document.getElementById("props")?.textContent || "{}";
 	hydrate(Content, {
@@ -217,6 +247,18 @@ func (s *Server) serveJS(w http.ResponseWriter, urlPath string) {
 	w.Write(file.Contents)
 }
 
+// serveCSS serves the stylesheets linked from rendered pages
+func (s *Server) serveCSS(w http.ResponseWriter, r *http.Request, urlPath string) {
+	hash := strings.TrimSuffix(path.Base(urlPath), ".css")
+	css, ok := s.stylesheets.Load(hash)
+	if !ok || s.SSR.Stylesheet == nil || s.SSR.Stylesheet(hash) != "/"+urlPath {
+		s.serveAsset(w, r, urlPath)
+		return
+	}
+	w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	w.Write([]byte(css.(string)))
+}
+
 func (s *Server) serveAsset(w http.ResponseWriter, r *http.Request, urlPath string) {
 	// ...
 }
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/livebud/duo/internal/resolver"
 	"github.com/livebud/duo/internal/static"
 	"github.com/matryer/is"
 	"github.com/matthewmueller/diff"
@@ -65,9 +66,74 @@ func TestHelloWorld(t *testing.T) {
 	contains(t, handler, req,
 		`HTTP/1.1 200 OK`,
 		`Content-Type: application/javascript`,
-		`// http-url:https://esm.run/svelte@next`,
-		`return h2("h1", {}, ["hello, world!"]);`,
+		`// http-url:https://esm.sh/svelte@5.0.0-next.216`,
+		"`<h1>hello, world!</h1>`",
 		`target: document.getElementById("svelte"),`,
 		`document.getElementById("props")?.textContent || "{}"`,
 	)
 }
+
+func TestStyles(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	fsys := virt.Map{
+		"index.svelte": `<h1>hello</h1><style>h1 { color: red; }</style>`,
+	}
+	is.NoErr(virt.Sync(fsys, dir))
+	handler := static.Dir(dir)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	equal(t, handler, req, `
+		HTTP/1.1 200 OK
+		Connection: close
+		Content-Type: text/html; charset=utf-8
+
+		<html><head><style>h1.svelte-1erwonp { color: red }</style></head><body><main id="svelte"><h1 class="svelte-1erwonp">hello</h1></main><script type="module" src="/index.svelte.js"></script><script id="props" type="text/template">{}</script></body></html>
+	`)
+
+	// Link to the stylesheet instead
+	handler.SSR.Stylesheet = func(hash string) string { return "/duo/" + hash + ".css" }
+	req = httptest.NewRequest("GET", "/", nil)
+	contains(t, handler, req, `<head><link rel="stylesheet" href="/duo/`)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	start := strings.Index(rec.Body.String(), `href="`) + len(`href="`)
+	href := rec.Body.String()[start : start+strings.Index(rec.Body.String()[start:], `"`)]
+	req = httptest.NewRequest("GET", href, nil)
+	equal(t, handler, req, `
+		HTTP/1.1 200 OK
+		Connection: close
+		Content-Type: text/css; charset=utf-8
+
+		h1.svelte-1erwonp { color: red }
+	`)
+}
+
+func TestLayoutStyles(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	fsys := virt.Map{
+		"index.svelte": `<h1>hello</h1><style>h1 { color: red; }</style>`,
+	}
+	is.NoErr(virt.Sync(fsys, dir))
+	handler := static.Dir(dir)
+	handler.Layouts = resolver.Embedded{
+		"index.svelte": []byte(`<html><head>{head}</head><body><nav>nav</nav><main id="svelte">{children}</main>{script}</body></html><style>nav { color: blue; }</style>`),
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	contains(t, handler, req, `<head><style>h1.svelte-1erwonp { color: red }`+"\n"+`nav.svelte-`)
+
+	// Linked stylesheets are cleared when files change
+	handler.SSR.Stylesheet = func(hash string) string { return "/duo/" + hash + ".css" }
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	start := strings.Index(rec.Body.String(), `href="`) + len(`href="`)
+	href := rec.Body.String()[start : start+strings.Index(rec.Body.String()[start:], `"`)]
+	req = httptest.NewRequest("GET", href, nil)
+	contains(t, handler, req, `Content-Type: text/css`, `h1.svelte-1erwonp { color: red }`, `nav.svelte-`)
+	handler.Invalidate("index.svelte")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	is.True(!strings.Contains(rec.Body.String(), `color: red`))
+}
@@ -0,0 +1,301 @@
+// Package style scopes a component's CSS the same way Svelte does. Selectors
+// get a `svelte-xxxx` class based on a hash of the CSS and the class is added
+// to every element that one of the selectors could match.
+package style
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/livebud/duo/internal/ast"
+	css "github.com/matthewmueller/css/ast"
+	"github.com/matthewmueller/css/scoper"
+)
+
+// Hash returns the scoping class for the CSS. This matches Svelte's default
+// cssHash, so classes line up with what the Svelte compiler generates.
+func Hash(code string) string {
+	code = strings.ReplaceAll(code, "\r", "")
+	h := int32(5381)
+	runes := utf16(code)
+	for i := len(runes) - 1; i >= 0; i-- {
+		h = ((h << 5) - h) ^ int32(runes[i])
+	}
+	return "svelte-" + strconv.FormatUint(uint64(uint32(h)), 36)
+}
+
+// utf16 returns the UTF-16 code units, since that's what JavaScript hashes
+func utf16(code string) []uint16 {
+	units := make([]uint16, 0, len(code))
+	for _, r := range code {
+		if r >= 0x10000 {
+			r -= 0x10000
+			units = append(units, uint16(0xD800+(r>>10)), uint16(0xDC00+(r&0x3FF)))
+			continue
+		}
+		units = append(units, uint16(r))
+	}
+	return units
+}
+
+// Scope the document's stylesheet and add the scoping class to the elements
+// it could match. Documents without styles are left alone.
+func Scope(path string, doc *ast.Document) error {
+	style, ok := doc.Style()
+	if !ok || style.StyleSheet == nil || len(style.StyleSheet.Rules) == 0 {
+		return nil
+	}
+	style.Scope = Hash(style.Code)
+	// Find the compound selectors before they're scoped
+	compounds := compoundsOf(style.StyleSheet.Rules)
+	if _, err := scoper.ScopeAST(path, "."+style.Scope, style.StyleSheet); err != nil {
+		return err
+	}
+	addClass(doc, compounds, style.Scope)
+	return nil
+}
+
+// compound is a sequence of selector components without combinators, like
+// `a.link:hover`
+type compound []css.SelectorComponent
+
+// compoundsOf returns the scoped compound selectors within the rules
+func compoundsOf(rules []css.Rule) (compounds []compound) {
+	for _, selector := range selectorsOf(rules) {
+		compounds = append(compounds, splitSelector(selector)...)
+	}
+	return compounds
+}
+
+// splitSelector splits a selector on its combinators, skipping :global(...)
+func splitSelector(selector *css.Selector) (compounds []compound) {
+	current := compound{}
+	for _, component := range selector.Components {
+		if _, ok := component.(*css.CombinatorComponent); ok {
+			if len(current) > 0 && !isGlobal(current) {
+				compounds = append(compounds, current)
+			}
+			current = compound{}
+			continue
+		}
+		current = append(current, component)
+	}
+	if len(current) > 0 && !isGlobal(current) {
+		compounds = append(compounds, current)
+	}
+	return compounds
+}
+
+func isGlobal(c compound) bool {
+	for _, component := range c {
+		if pseudo, ok := component.(*css.PseudoClassComponent); ok && pseudo.Name == "global" {
+			return true
+		}
+	}
+	return false
+}
+
+// addClass adds the scoping class to the elements matching a compound. Slot
+// content passed to components belongs to this component, so it's included.
+func addClass(doc *ast.Document, compounds []compound, class string) {
+	ast.Inspect(doc, func(node ast.Node) bool {
+		if el, ok := node.(*ast.Element); ok && matchesAny(el, compounds) {
+			appendClass(el, class)
+		}
+		return true
+	})
+}
+
+func matchesAny(el *ast.Element, compounds []compound) bool {
+	for _, c := range compounds {
+		if matches(el, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns true if the element could match the compound selector.
+// Dynamic attributes are assumed to match.
+func matches(el *ast.Element, c compound) bool {
+	for _, component := range c {
+		switch s := component.(type) {
+		case *css.ElementComponent:
+			if !strings.EqualFold(s.Name, el.Name) {
+				return false
+			}
+		case *css.ClassComponent:
+			if !hasClass(el, s.Name) {
+				return false
+			}
+		case *css.IdComponent:
+			if !hasAttribute(el, "id", s.Name) {
+				return false
+			}
+		case *css.AttributeComponent:
+			if !hasAttribute(el, s.Name, "") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func hasClass(el *ast.Element, name string) bool {
+	for _, attr := range el.Attributes {
+		switch a := attr.(type) {
+		case *ast.Class:
+			if a.Name == name {
+				return true
+			}
+		case *ast.AttributeShorthand:
+			if a.Key == "class" {
+				return true
+			}
+		case *ast.Field:
+			if a.Key != "class" {
+				continue
+			}
+			for _, value := range a.Values {
+				switch v := value.(type) {
+				case *ast.Mustache:
+					return true
+				case *ast.Text:
+					for _, class := range strings.Fields(v.Value) {
+						if class == name {
+							return true
+						}
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// hasAttribute returns true if the element has the attribute. If value isn't
+// empty, the attribute must also have that value.
+func hasAttribute(el *ast.Element, key, value string) bool {
+	for _, attr := range el.Attributes {
+		switch a := attr.(type) {
+		case *ast.Field:
+			if a.Key != key {
+				continue
+			}
+			if value == "" {
+				return true
+			}
+			text := new(strings.Builder)
+			for _, v := range a.Values {
+				switch v := v.(type) {
+				case *ast.Mustache:
+					return true
+				case *ast.Text:
+					text.WriteString(v.Value)
+				}
+			}
+			return text.String() == value
+		case *ast.Binding, *ast.AttributeShorthand:
+			if attr.GetKey() == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// appendClass adds the class to the element's class attribute
+func appendClass(el *ast.Element, class string) {
+	for _, attr := range el.Attributes {
+		field, ok := attr.(*ast.Field)
+		if !ok || field.Key != "class" {
+			continue
+		}
+		if len(field.Values) > 0 {
+			class = " " + class
+		}
+		field.Values = append(field.Values, &ast.Text{Value: class})
+		return
+	}
+	el.Attributes = append(el.Attributes, &ast.Field{
+		Key:    "class",
+		Values: []ast.Value{&ast.Text{Value: class}},
+	})
+}
+
+// Unused returns the selectors in the scoped stylesheet that can't match any
+// element in the document. The scoping class is removed from the returned
+// selectors.
+func Unused(doc *ast.Document) (unused []*css.Selector) {
+	style, ok := doc.Style()
+	if !ok || style.StyleSheet == nil || style.Scope == "" {
+		return nil
+	}
+	for _, selector := range selectorsOf(style.StyleSheet.Rules) {
+		if !isUsed(doc, selector, style.Scope) {
+			unused = append(unused, unscoped(selector, style.Scope))
+		}
+	}
+	return unused
+}
+
+// selectorsOf returns the style rule selectors within the rules
+func selectorsOf(rules []css.Rule) (selectors []*css.Selector) {
+	for _, rule := range rules {
+		switch r := rule.(type) {
+		case *css.StyleRule:
+			selectors = append(selectors, r.Selectors...)
+		case *css.MediaRule:
+			selectors = append(selectors, selectorsOf(r.Rules)...)
+		case *css.SupportsRule:
+			selectors = append(selectors, selectorsOf(r.Rules)...)
+		}
+	}
+	return selectors
+}
+
+// isUsed returns true if each scoped compound in the selector matches an
+// element. Compounds without the scoping class came from :global(...).
+func isUsed(doc *ast.Document, selector *css.Selector, class string) bool {
+	for _, c := range splitSelector(selector) {
+		if !isScoped(c, class) {
+			continue
+		}
+		if !anyMatch(doc, c) {
+			return false
+		}
+	}
+	return true
+}
+
+func isScoped(c compound, class string) bool {
+	for _, component := range c {
+		if s, ok := component.(*css.ClassComponent); ok && s.Name == class {
+			return true
+		}
+	}
+	return false
+}
+
+// anyMatch returns true if an element within the document matches
+func anyMatch(doc *ast.Document, c compound) (found bool) {
+	ast.Inspect(doc, func(node ast.Node) bool {
+		if el, ok := node.(*ast.Element); ok && matches(el, c) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// unscoped returns a copy of the selector without the scoping class
+func unscoped(selector *css.Selector, class string) *css.Selector {
+	copy := &css.Selector{}
+	for _, component := range selector.Components {
+		if s, ok := component.(*css.ClassComponent); ok && s.Name == class {
+			continue
+		}
+		copy.Components = append(copy.Components, component)
+	}
+	return copy
+}
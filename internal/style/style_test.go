@@ -0,0 +1,67 @@
+package style_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/livebud/duo/internal/parser"
+	"github.com/livebud/duo/internal/style"
+	"github.com/matthewmueller/diff"
+)
+
+func equal(t *testing.T, input, expected string) {
+	t.Helper()
+	t.Run(input, func(t *testing.T) {
+		t.Helper()
+		doc, err := parser.Parse("input.svelte", input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := doc.String()
+		actual = strings.ReplaceAll(actual, "\t", "")
+		actual = strings.ReplaceAll(actual, "\n", "")
+		diff.TestString(t, actual, expected)
+	})
+}
+
+func TestHash(t *testing.T) {
+	diff.TestString(t, style.Hash("span { background-color: blue; }"), "svelte-14dblqe")
+	diff.TestString(t, style.Hash("span {\r\n background-color: blue; }"), style.Hash("span {\n background-color: blue; }"))
+}
+
+func TestTodo(t *testing.T) {
+	code, err := os.ReadFile("../../testdata/04-todo/input.svelte")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parser.Parse("input.svelte", string(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, ok := doc.Style()
+	if !ok {
+		t.Fatal("expected a style")
+	}
+	// Matches the class in _dom.js
+	diff.TestString(t, node.Scope, "svelte-1mf3hor")
+	diff.TestString(t, node.StyleSheet.String(), ".checked.svelte-1mf3hor { text-decoration: line-through }")
+}
+
+func TestScope(t *testing.T) {
+	equal(t, `<h1>hi</h1><p>there</p><style>h1 { color: red; }</style>`, `<h1 class="svelte-1erwonp">hi</h1><p>there</p><style>h1.svelte-1erwonp { color: red }</style>`)
+	equal(t, `<div class="a b">x</div><div class="c">y</div><style>.b { color: red; }</style>`, `<div class="a b svelte-1erwqim">x</div><div class="c">y</div><style>.b.svelte-1erwqim { color: red }</style>`)
+	equal(t, `<div class={cls}>x</div><style>.b { color: red; }</style>`, `<div class="{cls} svelte-1erwqim">x</div><style>.b.svelte-1erwqim { color: red }</style>`)
+	equal(t, `<a id="home">x</a><a>y</a><style>#home { color: red; }</style>`, `<a id="home" class="svelte-16n1l94">x</a><a>y</a><style>#home.svelte-16n1l94 { color: red }</style>`)
+	equal(t, `<ul><li>x</li></ul><style>ul li { color: red; }</style>`, `<ul class="svelte-16phdpq"><li class="svelte-16phdpq">x</li></ul><style>ul.svelte-16phdpq li.svelte-16phdpq { color: red }</style>`)
+	equal(t, `{#if x}<p>a</p>{:else}<p>b</p>{/if}<style>p { color: red; }</style>`, `{#if x}<p class="svelte-14l9336">a</p>{:else}<p class="svelte-14l9336">b</p>{/if}<style>p.svelte-14l9336 { color: red }</style>`)
+}
+
+func TestGlobal(t *testing.T) {
+	equal(t, `<p>a</p><style>:global(body) p { margin: 0; }</style>`, `<p class="svelte-1k30g5i">a</p><style>body p.svelte-1k30g5i { margin: 0 }</style>`)
+	equal(t, `<p>a</p><style>:global(p) { margin: 0; }</style>`, `<p>a</p><style>p { margin: 0 }</style>`)
+}
+
+func TestNoStyle(t *testing.T) {
+	equal(t, `<p>a</p>`, `<p>a</p>`)
+}
@@ -40,6 +40,8 @@ const (
 	Colon Type = ":" // :
 	Comma Type = "," // ,
 	Hash  Type = "#" // #
+	At    Type = "@" // @
+	Pipe  Type = "|" // |
 
 	Comment Type = "comment" // <!-- ... -->
 
@@ -63,8 +65,12 @@ const (
 	Each      Type = "each"    // each
 	SlashEach Type = "/each"   // /each
 	As        Type = "as"      // as
+	Await     Type = "await"   // await
+	Then      Type = "then"    // then
+	Catch     Type = "catch"   // catch
 	ElseIf    Type = "else_if" // elseif
 	Else      Type = "else"    // else
+	HTML      Type = "html"    // html
 
 	Quote Type = "quote" // " or '
 )
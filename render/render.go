@@ -0,0 +1,553 @@
+// Package render is the runtime used by Go code compiled from .svelte files
+// with `duo generate`. It follows the same JavaScript-like semantics as the
+// server renderer for values whose types aren't known ahead of time.
+package render
+
+import (
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewWriter wraps w for generated render functions. Write errors are sticky,
+// so generated code only needs to check Err once at the end.
+func NewWriter(w io.Writer) *Writer {
+	if writer, ok := w.(*Writer); ok {
+		return writer
+	}
+	return &Writer{w: w}
+}
+
+type Writer struct {
+	w      io.Writer
+	err    error
+	styles map[string]bool // scopes of the styles that have been written
+}
+
+var _ io.Writer = (*Writer)(nil)
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
+
+// WriteString writes a string as-is
+func (w *Writer) WriteString(s string) {
+	if w.err != nil {
+		return
+	}
+	if _, err := io.WriteString(w.w, s); err != nil {
+		w.err = err
+	}
+}
+
+// Print writes a value as escaped text. Undefined values and functions write
+// nothing.
+func (w *Writer) Print(v interface{}) {
+	w.Text(String(v))
+}
+
+// Text writes a string, escaping it like the server renderer escapes dynamic
+// text
+func (w *Writer) Text(s string) {
+	w.WriteString(EscapeText(s))
+}
+
+// Attr writes an attribute with a leading space and an escaped value. True
+// booleans write the key, while false, undefined and function values skip the
+// attribute entirely.
+func (w *Writer) Attr(key string, v interface{}) {
+	switch value := v.(type) {
+	case nil:
+		return
+	case bool:
+		if value {
+			w.WriteString(" " + key)
+		}
+		return
+	}
+	if isFunc(v) {
+		return
+	}
+	w.WriteString(" " + key + `="` + EscapeAttribute(String(v)) + `"`)
+}
+
+var (
+	textEscaper      = strings.NewReplacer("&", "&amp;", "<", "&lt;")
+	attributeEscaper = strings.NewReplacer("&", "&amp;", `"`, "&quot;", "<", "&lt;")
+)
+
+// EscapeText escapes dynamic text content like Svelte does. Static text is
+// written as it was authored.
+func EscapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// EscapeAttribute escapes a double-quoted attribute value
+func EscapeAttribute(s string) string {
+	return attributeEscaper.Replace(s)
+}
+
+// Style writes a component's scoped CSS the first time the component renders
+func (w *Writer) Style(scope, css string) {
+	if w.styles[scope] {
+		return
+	}
+	if w.styles == nil {
+		w.styles = map[string]bool{}
+	}
+	w.styles[scope] = true
+	w.WriteString("<style>" + css + "</style>")
+}
+
+// Err returns the first write error
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// String converts a value to a string the way it would be rendered
+func String(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case int:
+		return strconv.Itoa(value)
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case bool:
+		return strconv.FormatBool(value)
+	case float64:
+		return formatFloat(value)
+	case time.Time:
+		return value.Format(time.RFC3339)
+	case error:
+		return value.Error()
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return formatFloat(rv.Float())
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool())
+	case reflect.Func:
+		return ""
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return ""
+		}
+		return String(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		// Arrays are joined with commas like in JavaScript
+		items := make([]string, rv.Len())
+		for i := range items {
+			items[i] = String(rv.Index(i).Interface())
+		}
+		return strings.Join(items, ",")
+	default:
+		return "[object Object]"
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Float converts a value to a number, returning NaN if it's not numeric
+func Float(v interface{}) float64 {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1
+		}
+		return 0
+	case reflect.String:
+		s := strings.TrimSpace(rv.String())
+		if s == "" {
+			return 0
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return math.NaN()
+		}
+		return f
+	case reflect.Invalid:
+		return math.NaN()
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return 0
+		}
+		return Float(rv.Elem().Interface())
+	default:
+		return math.NaN()
+	}
+}
+
+// Truthy returns true if the value is truthy in JavaScript. Nil pointers,
+// maps, slices and functions are treated like null.
+func Truthy(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint() != 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() != 0 && !math.IsNaN(rv.Float())
+	case reflect.String:
+		return rv.String() != ""
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Interface, reflect.Chan:
+		return !rv.IsNil()
+	case reflect.Invalid:
+		return false
+	default:
+		return true
+	}
+}
+
+// Nullish returns true for nil values
+func Nullish(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Interface, reflect.Chan:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
+
+// Member returns the field or map entry called name. Missing members are nil.
+func Member(v interface{}, name string) interface{} {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		if field := rv.FieldByName(name); field.IsValid() && field.CanInterface() {
+			return field.Interface()
+		}
+		return nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		value := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !value.IsValid() {
+			return nil
+		}
+		return value.Interface()
+	case reflect.String, reflect.Slice, reflect.Array:
+		if name == "length" {
+			return Len(rv.Interface())
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Index returns the element at index in a slice or the entry of a map
+func Index(v interface{}, index interface{}) interface{} {
+	if name, ok := index.(string); ok {
+		return Member(v, name)
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		i := Float(index)
+		if i != math.Trunc(i) || i < 0 || int(i) >= rv.Len() {
+			return nil
+		}
+		return rv.Index(int(i)).Interface()
+	default:
+		return nil
+	}
+}
+
+// Len returns the length of a string, slice, array or map
+func Len(v interface{}) int {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return 0
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		return len([]rune(rv.String()))
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len()
+	default:
+		return 0
+	}
+}
+
+// Each returns the elements of a slice or array to loop over. Anything else is
+// treated as an empty list.
+func Each(v interface{}) []interface{} {
+	if items, ok := v.([]interface{}); ok {
+		return items
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = rv.Index(i).Interface()
+		}
+		return items
+	default:
+		return nil
+	}
+}
+
+// Grouped returns true if an input's value is selected by its bind:group,
+// either matching the group for radio buttons or within it for checkboxes
+func Grouped(group, value interface{}) bool {
+	rv := reflect.ValueOf(group)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		for _, item := range Each(group) {
+			if Equal(item, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return Equal(group, value)
+}
+
+// Class joins the non-empty classes of a class attribute merged with its
+// class: directives. Without any classes it returns nil to skip the attribute.
+func Class(classes ...string) interface{} {
+	var out []string
+	for _, class := range classes {
+		if class = strings.TrimSpace(class); class != "" {
+			out = append(out, class)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return strings.Join(out, " ")
+}
+
+// Style joins the non-empty declarations of a style attribute merged with its
+// style: directives. Without any declarations it returns nil to skip the
+// attribute.
+func Style(declarations ...string) interface{} {
+	var out []string
+	for _, declaration := range declarations {
+		if declaration = strings.Trim(declaration, " ;"); declaration != "" {
+			out = append(out, declaration)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return strings.Join(out, "; ") + ";"
+}
+
+// Declaration returns the style declaration for a style: directive. Nullish
+// values are left out.
+func Declaration(property string, v interface{}) string {
+	if Nullish(v) || isFunc(v) {
+		return ""
+	}
+	return property + ": " + String(v)
+}
+
+// Equal compares values like ===, except that numbers of different Go types
+// are compared by value
+func Equal(a, b interface{}) bool {
+	if isNumber(a) && isNumber(b) {
+		return Float(a) == Float(b)
+	}
+	if a == nil || b == nil {
+		return Nullish(a) && Nullish(b)
+	}
+	ra, rb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if ra.Type() != rb.Type() {
+		return false
+	}
+	switch ra.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		// Objects are compared by reference
+		return ra.Pointer() == rb.Pointer()
+	}
+	if !ra.Type().Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// Compare returns -1, 0 or 1. Strings are compared lexically and everything
+// else numerically. Comparisons with NaN return 2 so they always fail.
+func Compare(a, b interface{}) int {
+	as, aok := a.(string)
+	bs, bok := b.(string)
+	if aok && bok {
+		return strings.Compare(as, bs)
+	}
+	af, bf := Float(a), Float(b)
+	switch {
+	case math.IsNaN(af) || math.IsNaN(bf):
+		return 2
+	case af < bf:
+		return -1
+	case af > bf:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Add adds numbers or concatenates strings like JavaScript's + operator
+func Add(a, b interface{}) interface{} {
+	if isNumber(a) && isNumber(b) {
+		if isInt(a) && isInt(b) {
+			return int(Float(a)) + int(Float(b))
+		}
+		return Float(a) + Float(b)
+	}
+	return String(a) + String(b)
+}
+
+// Mod returns the floating-point remainder of a / b
+func Mod(a, b interface{}) float64 {
+	return math.Mod(Float(a), Float(b))
+}
+
+// Or returns a if it's truthy, otherwise b
+func Or[T any](a, b T) T {
+	if Truthy(a) {
+		return a
+	}
+	return b
+}
+
+// And returns b if a is truthy, otherwise a
+func And[T any](a, b T) T {
+	if !Truthy(a) {
+		return a
+	}
+	return b
+}
+
+// Coalesce returns a unless it's nil, otherwise b
+func Coalesce[T any](a, b T) T {
+	if !Nullish(a) {
+		return a
+	}
+	return b
+}
+
+// Cond returns a if cond is true, otherwise b
+func Cond[T any](cond bool, a, b T) T {
+	if cond {
+		return a
+	}
+	return b
+}
+
+// Ptr returns a pointer to the value, for props with defaults
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// As converts a value to T, returning the zero value if it can't be converted
+func As[T any](v interface{}) (t T) {
+	if value, ok := v.(T); ok {
+		return value
+	}
+	rv := reflect.ValueOf(v)
+	tt := reflect.TypeOf(&t).Elem()
+	if rv.IsValid() && rv.Type().ConvertibleTo(tt) && tt.Kind() != reflect.String {
+		return rv.Convert(tt).Interface().(T)
+	}
+	switch any(t).(type) {
+	case string:
+		return any(String(v)).(T)
+	case float64:
+		return any(Float(v)).(T)
+	case bool:
+		return any(Truthy(v)).(T)
+	}
+	return t
+}
+
+func isNumber(v interface{}) bool {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isInt(v interface{}) bool {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isFunc(v interface{}) bool {
+	return reflect.ValueOf(v).Kind() == reflect.Func
+}
@@ -0,0 +1,136 @@
+package render_test
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/livebud/duo/render"
+	"github.com/matryer/is"
+)
+
+type story struct {
+	Title    string
+	Comments []string
+}
+
+func TestString(t *testing.T) {
+	is := is.New(t)
+	is.Equal(render.String(nil), "")
+	is.Equal(render.String(1.5), "1.5")
+	is.Equal(render.String(int32(3)), "3")
+	is.Equal(render.String(math.Inf(1)), "Infinity")
+	is.Equal(render.String([]int{1, 2}), "1,2")
+	is.Equal(render.String(errors.New("oops")), "oops")
+	is.Equal(render.String(func() {}), "")
+}
+
+func TestTruthy(t *testing.T) {
+	is := is.New(t)
+	is.True(!render.Truthy(nil))
+	is.True(!render.Truthy(""))
+	is.True(!render.Truthy(0.0))
+	is.True(!render.Truthy(math.NaN()))
+	is.True(!render.Truthy((*story)(nil)))
+	is.True(render.Truthy(&story{}))
+	is.True(render.Truthy([]string{}))
+	is.True(render.Truthy("0"))
+}
+
+func TestMember(t *testing.T) {
+	is := is.New(t)
+	s := &story{Title: "Duo", Comments: []string{"a", "b"}}
+	is.Equal(render.Member(s, "Title"), "Duo")
+	is.Equal(render.Member(s, "Missing"), nil)
+	is.Equal(render.Member(render.Member(s, "Comments"), "length"), 2)
+	is.Equal(render.Index(render.Member(s, "Comments"), 1), "b")
+	is.Equal(render.Index(render.Member(s, "Comments"), 5), nil)
+	is.Equal(render.Member(map[string]any{"title": "Duo"}, "title"), "Duo")
+	is.Equal(render.Member((*story)(nil), "Title"), nil)
+	is.Equal(len(render.Each(s.Comments)), 2)
+	is.Equal(len(render.Each(nil)), 0)
+}
+
+func TestOperators(t *testing.T) {
+	is := is.New(t)
+	is.True(render.Equal(1, 1.0))
+	is.True(!render.Equal(1, "1"))
+	s := &story{}
+	is.True(render.Equal(s, s))
+	is.True(!render.Equal(s, &story{}))
+	is.Equal(render.Compare("a", "b"), -1)
+	is.Equal(render.Compare(2, 1.5), 1)
+	is.Equal(render.Add(1, 2), 3)
+	is.Equal(render.Add(1, 0.5), 1.5)
+	is.Equal(render.Add("a", 1), "a1")
+	is.Equal(render.Or("", "b"), "b")
+	is.Equal(render.And[any](true, "b"), "b")
+	is.Equal(render.Coalesce[any](nil, 1), 1)
+	is.Equal(render.As[int](2.0), 2)
+	is.Equal(render.As[string](2), "2")
+	is.Equal(render.As[float64]("1.5"), 1.5)
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("closed")
+}
+
+func TestWriter(t *testing.T) {
+	is := is.New(t)
+	w := new(strings.Builder)
+	out := render.NewWriter(w)
+	out.WriteString("<a")
+	out.Attr("href", "/")
+	out.Attr("hidden", false)
+	out.Attr("disabled", true)
+	out.Attr("onclick", func() {})
+	out.Attr("title", nil)
+	out.WriteString(">")
+	out.Print(1)
+	is.NoErr(out.Err())
+	is.Equal(w.String(), `<a href="/" disabled>1`)
+	is.True(render.NewWriter(out) == out)
+	out = render.NewWriter(failWriter{})
+	out.WriteString("a")
+	out.Print("b")
+	is.Equal(out.Err().Error(), "closed")
+}
+
+func TestWriterEscape(t *testing.T) {
+	is := is.New(t)
+	w := new(strings.Builder)
+	out := render.NewWriter(w)
+	out.WriteString("<p")
+	out.Attr("title", `"><script>alert('&')</script>`)
+	out.WriteString(">")
+	out.Print(`<script>alert("&")</script>`)
+	out.Text("a < b")
+	out.WriteString("</p>")
+	is.NoErr(out.Err())
+	is.Equal(w.String(), `<p title="&quot;>&lt;script>alert('&amp;')&lt;/script>">&lt;script>alert("&amp;")&lt;/script>a &lt; b</p>`)
+	is.Equal(*render.Ptr(0), 0)
+	is.Equal(render.EscapeText(`<a href="/">&</a>`), `&lt;a href="/">&amp;&lt;/a>`)
+	is.Equal(render.EscapeAttribute(`<a href="/">&</a>`), `&lt;a href=&quot;/&quot;>&amp;&lt;/a>`)
+}
+
+func TestGrouped(t *testing.T) {
+	is := is.New(t)
+	is.True(render.Grouped("mint", "mint"))
+	is.True(!render.Grouped("mint", "lemon"))
+	is.True(render.Grouped([]string{"nuts", "fudge"}, "fudge"))
+	is.True(!render.Grouped([]string{"nuts"}, "fudge"))
+	is.True(render.Grouped([]interface{}{1, 2}, 2.0))
+	is.True(!render.Grouped(nil, "mint"))
+}
+
+func TestClassAndStyle(t *testing.T) {
+	is := is.New(t)
+	is.Equal(render.Class("item ", "", "active"), "item active")
+	is.Equal(render.Class("", " "), nil)
+	is.Equal(render.Style("margin: 0;", render.Declaration("color", "red"), render.Declaration("width", nil)), "margin: 0; color: red;")
+	is.Equal(render.Style(render.Declaration("color", nil)), nil)
+	is.Equal(render.Declaration("width", 10), "width: 10")
+}
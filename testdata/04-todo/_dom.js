@@ -11,7 +11,7 @@ function addToList(_, todoList, newItem) {
 	$.set(newItem, "");
 }
 
-var root_1 = $.template(`<input type="checkbox"> <span class="svelte-1mf3hor"> </span> <span>❌</span> <br>`, 1);
+var root_1 = $.template(`<input type="checkbox"> <span class="svelte-1mf3hor"> </span> <button>❌</button> <br>`, 1);
 var root = $.template(`<input type="text" placeholder="new todo item"> <button>Add</button> <br> <!>`, 1);
 
 export default function Input($$anchor, $$props) {
@@ -47,11 +47,11 @@ export default function Input($$anchor, $$props) {
 
 		$.reset(span);
 
-		var span_1 = $.sibling($.sibling(span, true));
+		var button_1 = $.sibling($.sibling(span, true));
 
-		span_1.__click = () => removeFromList(index);
+		button_1.__click = () => removeFromList(index);
 
-		var br_1 = $.sibling($.sibling(span_1, true));
+		var br_1 = $.sibling($.sibling(button_1, true));
 
 		$.template_effect(() => {
 			$.toggle_class(span, "checked", $.unwrap(item).status);